@@ -0,0 +1,198 @@
+package pcre
+
+import (
+	"sort"
+	"strconv"
+)
+
+// PatternWarning describes a construct in a pattern which is prone to
+// catastrophic backtracking, as reported by AnalyzePattern.
+type PatternWarning struct {
+	Offset  int    // Byte position of the construct in the pattern
+	Message string // Description of the problem
+}
+
+// String converts a pattern warning to a string
+func (w PatternWarning) String() string {
+	return strconv.Itoa(w.Offset) + ": " + w.Message
+}
+
+// AnalyzePattern compiles the pattern to check its syntax, then looks
+// for structures that can make matching take exponential or polynomial
+// time on subjects which almost match:
+//
+//   - nested unbounded quantifiers, such as (a+)+ or (\w+\s?)*,
+//   - repeated alternations whose alternatives can start with the same
+//     character, such as (a|ab)*,
+//   - adjacent unbounded quantifiers over overlapping characters, such
+//     as \d+\d+ or .*.*
+//
+// Repetitions made possessive or wrapped in atomic groups do not
+// backtrack and are not reported.  The analysis is heuristic: it can
+// miss problems and report harmless patterns, so it is meant for
+// reviewing rules before deployment, together with match limits.
+// If compilation fails, the error is a *CompileError.
+func AnalyzePattern(pattern string, flags int) ([]PatternWarning, error) {
+	re, err := Compile(pattern, flags)
+	if err != nil {
+		return nil, err
+	}
+	re.FreeRegexp()
+	tree, _, err := parse(pattern, flags)
+	if err != nil {
+		return nil, err
+	}
+	a := &analyzer{seen: make(map[PatternWarning]bool)}
+	a.walk(tree, nil)
+	sort.SliceStable(a.warnings, func(i, j int) bool {
+		return a.warnings[i].Offset < a.warnings[j].Offset
+	})
+	return a.warnings, nil
+}
+
+type analyzer struct {
+	warnings []PatternWarning
+	seen     map[PatternWarning]bool
+}
+
+func (a *analyzer) warn(offset int, msg string) {
+	w := PatternWarning{Offset: offset, Message: msg}
+	if !a.seen[w] {
+		a.seen[w] = true
+		a.warnings = append(a.warnings, w)
+	}
+}
+
+// walk checks n, where outer is the innermost enclosing unbounded
+// repetition that can backtrack, or nil.
+func (a *analyzer) walk(n *node, outer *node) {
+	switch n.op {
+	case opRepeat:
+		if n.possessive {
+			a.walk(n.subs[0], nil)
+			return
+		}
+		if n.max >= 0 {
+			a.walk(n.subs[0], outer)
+			return
+		}
+		if set, _ := firstSet(n.subs[0]); len(set) == 0 {
+			a.walk(n.subs[0], outer)
+			return
+		}
+		if outer != nil {
+			a.warn(outer.pos, "nested unbounded quantifiers: the quantifier at offset "+
+				strconv.Itoa(n.pos)+" is repeated without bound")
+		}
+		if alt := stripGroups(n.subs[0]); alt.op == opAlternate && overlapping(alt.subs) {
+			a.warn(alt.pos, "alternatives of a repeated group can match the same text")
+		}
+		a.walk(n.subs[0], n)
+	case opGroup:
+		switch n.group {
+		case groupAtomic, groupLookahead, groupNegLookahead,
+			groupLookbehind, groupNegLookbehind:
+			// Atomic groups and assertions are not backtracked into.
+			outer = nil
+		}
+		a.walk(n.subs[0], outer)
+	case opConcat:
+		for i, sub := range n.subs {
+			if i > 0 && adjacentOverlap(n.subs[i-1], sub) {
+				a.warn(n.subs[i-1].pos, "adjacent unbounded quantifiers match overlapping characters")
+			}
+			a.walk(sub, outer)
+		}
+	default:
+		for _, sub := range n.subs {
+			a.walk(sub, outer)
+		}
+	}
+}
+
+// stripGroups returns the content of non-atomic groups around n.
+func stripGroups(n *node) *node {
+	for n.op == opGroup && (n.group == groupCapture || n.group == groupNonCapture) {
+		n = n.subs[0]
+	}
+	return n
+}
+
+// overlapping reports whether two alternatives can start alike.
+func overlapping(alts []*node) bool {
+	sets := make([][]rune, len(alts))
+	for i, alt := range alts {
+		sets[i], _ = firstSet(alt)
+		for j := 0; j < i; j++ {
+			if intersectRanges(sets[i], sets[j]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// adjacentOverlap reports whether x and y are backtracking unbounded
+// repetitions of overlapping characters.
+func adjacentOverlap(x, y *node) bool {
+	if x.op != opRepeat || y.op != opRepeat ||
+		x.max >= 0 || y.max >= 0 || x.possessive || y.possessive {
+		return false
+	}
+	xs, _ := firstSet(x.subs[0])
+	ys, _ := firstSet(y.subs[0])
+	return intersectRanges(xs, ys)
+}
+
+// firstSet returns the characters a match of n can start with, and
+// whether n can match the empty string.  Constructs which are not
+// analyzed, such as back references, can start with anything.
+func firstSet(n *node) (set []rune, nullable bool) {
+	switch n.op {
+	case opLiteral:
+		set = []rune{n.r, n.r}
+		if n.caseless {
+			set = foldRanges(set)
+		}
+		return set, false
+	case opClass:
+		if n.caseless {
+			return foldRanges(n.ranges), false
+		}
+		return n.ranges, false
+	case opAny:
+		if n.dotall {
+			return allRanges, false
+		}
+		return negateRanges(newlineRanges), false
+	case opBackref, opRecurse, opConditional:
+		return allRanges, true
+	case opGroup:
+		switch n.group {
+		case groupLookahead, groupNegLookahead,
+			groupLookbehind, groupNegLookbehind:
+			return nil, true
+		}
+		return firstSet(n.subs[0])
+	case opConcat:
+		for _, sub := range n.subs {
+			s, null := firstSet(sub)
+			set = unionRanges(set, s)
+			if !null {
+				return set, false
+			}
+		}
+		return set, true
+	case opAlternate:
+		for _, sub := range n.subs {
+			s, null := firstSet(sub)
+			set = unionRanges(set, s)
+			nullable = nullable || null
+		}
+		return set, nullable
+	case opRepeat:
+		set, nullable = firstSet(n.subs[0])
+		return set, nullable || n.min == 0
+	}
+	return nil, true
+}
@@ -0,0 +1,109 @@
+package pcre
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestAnalyzePattern(t *testing.T) {
+	var check = func(p string, want ...PatternWarning) {
+		got, err := AnalyzePattern(p, 0)
+		if err != nil {
+			t.Error(p, err)
+			return
+		}
+		if len(got) != len(want) || len(want) > 0 && !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: expected %v, got %v", p, want, got)
+		}
+	}
+	nested := func(off, inner int) PatternWarning {
+		return PatternWarning{off, "nested unbounded quantifiers: the quantifier at offset " +
+			strconv.Itoa(inner) + " is repeated without bound"}
+	}
+	check("(a+)+$", nested(0, 1))
+	check(`(\w+\s?)*$`, nested(0, 1))
+	check("(a|ab)*c", PatternWarning{1, "alternatives of a repeated group can match the same text"})
+	check(`\d+\d+`, PatternWarning{0, "adjacent unbounded quantifiers match overlapping characters"})
+	check("(?>a+)+b")
+	check("(a++)+b")
+	check(`^[a-z]+@[a-z]+\.com$`)
+	check("(a|b)*c")
+	check("a{2,5}b*")
+
+	if _, err := AnalyzePattern("(a", 0); err == nil {
+		t.Error("expected CompileError")
+	}
+}
+
+func TestParse(t *testing.T) {
+	var check = func(p string, flags, groups int) {
+		_, ps, err := parse(p, flags)
+		if err != nil {
+			t.Error(p, err)
+			return
+		}
+		if ps.ncap != groups {
+			t.Error(p, "groups", ps.ncap)
+		}
+	}
+	check(`(a)(?:b)(?<n>c)(?'m'd)(?P<o>e)`, 0, 4)
+	check(`(?|(a)|(b)(c))(d)`, 0, 3)
+	check(`[]a-z\d[:alpha:]-]+\Q(x)\E{2}`, 0, 0)
+	check(`(?x) ( a  # comment
+	  ) b`, 0, 1)
+	check(`(?(1)a|b)(?(?=x)y)\g{-1}\k<n>(?R)(?&n)\x{263a}\p{Lu}`, 0, 0)
+	check(`(*UTF8)(?i:a(?-i)b)\1`, 0, 0)
+
+	// Comments and \E may stand between an atom and its quantifier.
+	for _, p := range []string{`a(?#c)+`, `a\E+`, `a\Q\E*`, `x(?#a)(?#b){2}`, `(a)(?#c)?`} {
+		tree, _, err := parse(p, 0)
+		if err != nil {
+			t.Error(p, err)
+		} else if tree.op != opRepeat {
+			t.Errorf("%s: quantifier not applied", p)
+		}
+	}
+	for _, p := range []string{`(?#c)+`, `\E+`} {
+		if _, _, err := parse(p, 0); err == nil {
+			t.Error(p, "expected error")
+		}
+	}
+
+	// [ starts a POSIX class only if a terminator follows before the
+	// first ], otherwise it is a literal.
+	for _, p := range []string{`[[:]0`, `[[:a]b:]`, `[[:\]x]`, `[[:alpha:][:^digit:]]`} {
+		if _, _, err := parse(p, 0); err != nil {
+			t.Error(p, err)
+		}
+	}
+	for _, p := range []string{`[[::]]`, `[[:nope:]]`, `[[.a.]]`, `[[=a=]]`, `[:alpha:]`} {
+		if _, _, err := parse(p, 0); err == nil {
+			t.Error(p, "expected error")
+		}
+	}
+}
+
+// FuzzParse checks that the parser accepts every pattern PCRE compiles.
+// The parser need not reject every pattern PCRE rejects: the pattern
+// tools compile a pattern before parsing it, and some errors, such as
+// references to missing groups, are only found by PCRE.
+func FuzzParse(f *testing.F) {
+	for _, p := range []string{
+		`[[:]0`, `[[:a]b:]`, `[[:alpha:]]`, `[[:^digit:]x]`, `[]a-z\d-]`,
+		`a(?#c)+b\E*`, `(?<n>a)\k<n>`, `x{2,3}?`, `\p{Lu}+`, `(?|(a)|(b))`,
+	} {
+		f.Add(p)
+	}
+	f.Fuzz(func(t *testing.T, pattern string) {
+		re, err := Compile(pattern, 0)
+		_, _, perr := parse(pattern, 0)
+		if err != nil {
+			return
+		}
+		re.FreeRegexp()
+		if perr != nil {
+			t.Errorf("%q compiles, but parse fails: %v", pattern, perr)
+		}
+	})
+}
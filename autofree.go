@@ -0,0 +1,57 @@
+//go:build go1.24
+
+package pcre
+
+// #include "./pcre.h"
+import "C"
+
+import "runtime"
+
+// AUTO_FREE_CLEANUP reports how unreachable Regexps are freed.  When
+// true, as with Go 1.24 and later, a cleanup registered with
+// runtime.AddCleanup frees the C memory in the garbage collection
+// cycle which finds the Regexp unreachable, and the cleanups of
+// different Regexps may run concurrently.  When false, a finalizer
+// does it: the Regexp is only reclaimed by the following cycle, and
+// finalizers run one at a time on a single goroutine.
+const AUTO_FREE_CLEANUP = true
+
+// autoFree frees the C memory of a Regexp once it is unreachable.  It
+// keeps its own copy of the pointers, as a cleanup must not refer to
+// the Regexp.
+type autoFree struct {
+	mem     *patternMemory
+	cleanup runtime.Cleanup
+}
+
+// patternMemory is the C memory of a compiled pattern.
+type patternMemory struct {
+	ptr     *C.pcre
+	extra   *C.pcre_extra
+	pattern string
+	trackID uint64
+}
+
+func (mem *patternMemory) free() {
+	freePattern(mem.ptr, mem.extra, mem.pattern, mem.trackID)
+}
+
+// startAutoFree registers the cleanup of a compiled Regexp.
+func (re *Regexp) startAutoFree() {
+	mem := &patternMemory{ptr: re.ptr, pattern: re.pattern, trackID: re.trackID}
+	re.autoFree = autoFree{mem, runtime.AddCleanup(re, (*patternMemory).free, mem)}
+}
+
+// autoFreeExtra records new study data.  The caller holds re.mu.
+func (re *Regexp) autoFreeExtra() {
+	if re.autoFree.mem != nil {
+		re.autoFree.mem.extra = re.extra
+	}
+}
+
+// stopAutoFree cancels the cleanup of a Regexp freed explicitly.  The
+// caller holds re.mu.
+func (re *Regexp) stopAutoFree() {
+	re.autoFree.cleanup.Stop()
+	re.autoFree.mem = nil
+}
@@ -0,0 +1,30 @@
+//go:build !go1.24
+
+package pcre
+
+import "runtime"
+
+// AUTO_FREE_CLEANUP reports how unreachable Regexps are freed.  When
+// true, as with Go 1.24 and later, a cleanup registered with
+// runtime.AddCleanup frees the C memory in the garbage collection
+// cycle which finds the Regexp unreachable, and the cleanups of
+// different Regexps may run concurrently.  When false, a finalizer
+// does it: the Regexp is only reclaimed by the following cycle, and
+// finalizers run one at a time on a single goroutine.
+const AUTO_FREE_CLEANUP = false
+
+// autoFree is empty, as the finalizer is the Regexp's FreeRegexp.
+type autoFree struct{}
+
+// startAutoFree sets the finalizer of a compiled Regexp.
+func (re *Regexp) startAutoFree() {
+	runtime.SetFinalizer(re, (*Regexp).FreeRegexp)
+}
+
+// autoFreeExtra does nothing, as FreeRegexp frees the study data.
+func (re *Regexp) autoFreeExtra() {}
+
+// stopAutoFree clears the finalizer of a Regexp freed explicitly.
+func (re *Regexp) stopAutoFree() {
+	runtime.SetFinalizer(re, nil)
+}
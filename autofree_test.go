@@ -0,0 +1,28 @@
+package pcre
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestAutoFree(t *testing.T) {
+	TrackRegexps(true)
+	defer TrackRegexps(false)
+	func() {
+		re := MustCompile("unreachable", 0)
+		if err := re.Study(0); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	for i := 0; i < 50 && len(LiveRegexps()) > 0; i++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+	if live := LiveRegexps(); len(live) != 0 {
+		t.Errorf("unreachable regexp not freed %+v", live)
+	}
+	// Freeing explicitly cancels the automatic free.
+	MustCompile("freed", 0).FreeRegexp()
+	runtime.GC()
+}
@@ -1,6 +1,28 @@
 #!/bin/bash
+# Usage: ./build_lib.sh [arch]
+#
+# arch defaults to the architecture of the build machine.  On macOS it
+# may be set to amd64 or arm64 to cross-build the archive for the other
+# architecture with the system toolchain.
 TEMP=$(mktemp -d)
 SRC="pcre-8.45"
+PLATFORM="$(uname -s)"
+ARCH="${1:-$(uname -m)}"
+case "${ARCH}" in
+  i*86 | 386)         OUTARCH=386;;
+  x86_64 | amd64)     OUTARCH=amd64;;
+  arm64 | aarch64)    OUTARCH=arm64;;
+  arm*)               OUTARCH=arm;;
+esac
+CONFIGURE_ENV=()
+case "${PLATFORM}" in
+  Darwin*)
+    case "${OUTARCH}" in
+      amd64) CLANG_ARCH=x86_64;;
+      arm64) CLANG_ARCH=arm64;;
+    esac
+    CONFIGURE_ENV=("CFLAGS=-arch ${CLANG_ARCH} -O2" "--host=${CLANG_ARCH}-apple-darwin");;
+esac
 echo "Using temp directory $TEMP to build $SRC"
 (
   cd "$TEMP"
@@ -15,20 +37,17 @@ echo "Using temp directory $TEMP to build $SRC"
       --disable-cpp \
       --enable-newline-is-any \
       --with-match-limit=500000 \
-      --with-match-limit-recursion=50000
-    make -j$(nproc)
+      --with-match-limit-recursion=50000 \
+      "${CONFIGURE_ENV[@]}"
+    make -j$(nproc 2>/dev/null || sysctl -n hw.ncpu)
   )
 )
-PLATFORM="$(uname -s)"
-ARCH="$(uname -m)"
-case "${ARCH}" in
-  i*86)               OUTARCH=386;;
-  x86_64)             OUTARCH=amd64;;
-  arm64 | aarch64)    OUTARCH=arm64;;
-  arm*)               OUTARCH=armhf;;
-esac
+LIBC=""
+if [ "${PLATFORM}" = "Linux" ] && ldd --version 2>&1 | grep -qi musl; then
+  LIBC="musl_"
+fi
 case "${PLATFORM}" in
-  Linux*)  OUTPUT=libpcre_linux_${OUTARCH}.a;;
+  Linux*)  OUTPUT=libpcre_linux_${LIBC}${OUTARCH}.a;;
   Darwin*) OUTPUT=libpcre_darwin_${OUTARCH}.a;;
   MINGW*)  OUTPUT=libpcre_windows_${OUTARCH}.a;;
   *)       OUTPUT=libpcre_${OUTARCH}.a
@@ -0,0 +1,165 @@
+package pcre
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// CacheOptions bounds a Cache and selects how patterns are studied.
+// Zero limits are unlimited.
+type CacheOptions struct {
+	MaxEntries int           // Number of cached Regexps
+	MaxBytes   int           // Total memory of the compiled patterns and their study data
+	TTL        time.Duration // Time after which an entry is compiled afresh
+	Study      bool          // Whether to study the compiled patterns
+	StudyFlags int           // Study flags, see Study
+}
+
+// Cache keeps compiled Regexps by pattern and flags, for programs
+// which compile the same patterns again and again, such as patterns
+// supplied by users.  When a limit is exceeded, the least recently
+// used entries are evicted; entries older than the TTL are evicted
+// when the Cache is next used, or by Prune.
+//
+// An evicted Regexp is freed as soon as it is no longer in use: Compile
+// returns a release function, like Swappable.Load, to be called when
+// the caller is done with the Regexp.  A Cache may be used
+// concurrently.
+type Cache struct {
+	opts    CacheOptions
+	mu      sync.Mutex
+	entries map[cacheKey]*list.Element
+	lru     list.List // of *cacheEntry, most recently used first
+	bytes   int
+	now     func() time.Time
+}
+
+type cacheKey struct {
+	pattern string
+	flags   int
+}
+
+type cacheEntry struct {
+	key     cacheKey
+	ref     *swapRef // counts the Cache and the callers of Compile
+	size    int
+	expires time.Time
+}
+
+// NewCache returns an empty Cache.
+func NewCache(opts CacheOptions) *Cache {
+	return &Cache{
+		opts:    opts,
+		entries: make(map[cacheKey]*list.Element),
+		now:     time.Now,
+	}
+}
+
+// Compile returns the Regexp for pattern and flags, compiling and
+// caching it if it is not cached.  The caller must call release
+// exactly once when it no longer uses the Regexp and its Matchers.
+// Patterns which fail to compile or study are not cached.
+func (c *Cache) Compile(pattern string, flags int) (re *Regexp, release func(), err error) {
+	key := cacheKey{pattern, flags}
+	c.mu.Lock()
+	c.pruneLocked()
+	if e := c.entries[key]; e != nil {
+		c.lru.MoveToFront(e)
+		ref := e.Value.(*cacheEntry).ref
+		ref.refs.Add(1)
+		c.mu.Unlock()
+		return ref.re, ref.release, nil
+	}
+	c.mu.Unlock()
+
+	// Compile without holding the lock, so that other patterns can
+	// be looked up meanwhile.
+	re, err = Compile(pattern, flags)
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.opts.Study {
+		if err := re.Study(c.opts.StudyFlags); err != nil {
+			re.FreeRegexp()
+			return nil, nil, err
+		}
+	}
+	size := re.Size()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e := c.entries[key]; e != nil {
+		// Another goroutine cached the pattern first.
+		re.FreeRegexp()
+		c.lru.MoveToFront(e)
+		ref := e.Value.(*cacheEntry).ref
+		ref.refs.Add(1)
+		return ref.re, ref.release, nil
+	}
+	entry := &cacheEntry{key: key, ref: &swapRef{re: re}, size: size}
+	entry.ref.refs.Store(2) // the Cache and the caller
+	if c.opts.TTL > 0 {
+		entry.expires = c.now().Add(c.opts.TTL)
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	c.bytes += size
+	for c.lru.Len() > 0 && (c.opts.MaxEntries > 0 && c.lru.Len() > c.opts.MaxEntries ||
+		c.opts.MaxBytes > 0 && c.bytes > c.opts.MaxBytes) {
+		c.evict(c.lru.Back())
+	}
+	return re, entry.ref.release, nil
+}
+
+// evict removes an entry.  The caller holds c.mu.
+func (c *Cache) evict(e *list.Element) {
+	entry := c.lru.Remove(e).(*cacheEntry)
+	delete(c.entries, entry.key)
+	c.bytes -= entry.size
+	entry.ref.release()
+}
+
+// Prune evicts the entries older than the TTL.
+func (c *Cache) Prune() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pruneLocked()
+}
+
+func (c *Cache) pruneLocked() {
+	if c.opts.TTL <= 0 {
+		return
+	}
+	now := c.now()
+	for e := c.lru.Front(); e != nil; {
+		next := e.Next()
+		if !now.Before(e.Value.(*cacheEntry).expires) {
+			c.evict(e)
+		}
+		e = next
+	}
+}
+
+// Purge evicts all entries.
+func (c *Cache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.lru.Len() > 0 {
+		c.evict(c.lru.Back())
+	}
+}
+
+// Len returns the number of cached Regexps.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Bytes returns the memory used by the cached Regexps, as counted
+// for CacheOptions.MaxBytes.
+func (c *Cache) Bytes() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.bytes
+}
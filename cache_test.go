@@ -0,0 +1,68 @@
+package pcre
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	c := NewCache(CacheOptions{MaxEntries: 2})
+	a, releaseA, err := c.Compile("a", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a2, releaseA2, _ := c.Compile("a", 0)
+	if a2 != a {
+		t.Error("cached Regexp not reused")
+	}
+	releaseA2()
+	_, releaseB, _ := c.Compile("b", 0)
+	releaseB()
+	_, releaseC, _ := c.Compile("c", CASELESS)
+	releaseC()
+	// "a" was used least recently, but is still in use.
+	if c.Len() != 2 || !a.valid() {
+		t.Fatal("unexpected eviction", c.Len())
+	}
+	releaseA()
+	if a.valid() {
+		t.Error("evicted Regexp not freed on release")
+	}
+	if _, _, err := c.Compile("(", 0); err == nil || c.Len() != 2 {
+		t.Error("invalid pattern cached", err)
+	}
+	c.Purge()
+	if c.Len() != 0 || c.Bytes() != 0 {
+		t.Error("Purge left entries", c.Len(), c.Bytes())
+	}
+}
+
+func TestCacheLimits(t *testing.T) {
+	now := time.Now()
+	c := NewCache(CacheOptions{TTL: time.Minute, Study: true})
+	c.now = func() time.Time { return now }
+	re, release, err := c.Compile(`\w+@\w+`, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	size := c.Bytes()
+	if size <= 0 || size != re.Size() || !re.StudyInfo().Studied {
+		t.Fatal("unexpected size", size)
+	}
+	now = now.Add(time.Minute)
+	c.Prune()
+	if c.Len() != 0 || re.valid() {
+		t.Error("expired entry not evicted")
+	}
+
+	c = NewCache(CacheOptions{MaxBytes: size, Study: true})
+	for _, p := range []string{`\w+@\w+`, `\w+@\W+`} {
+		_, release, _ := c.Compile(p, 0)
+		release()
+	}
+	if c.Len() > 1 || c.Bytes() > size {
+		t.Error("byte limit exceeded", c.Len(), c.Bytes())
+	}
+	c.Purge()
+}
@@ -0,0 +1,61 @@
+package pcre
+
+import "context"
+
+// FindAllContext returns the matches of the pattern in subject, found
+// as by FindAllNamed, like FindAll.  It stops when ctx is done, and
+// returns the matches found so far with the error of ctx.  Long match
+// attempts are interrupted too: they run with increasing match limits,
+// as in MatchTimeout, and ctx is checked in between.
+func (re *Regexp) FindAllContext(ctx context.Context, subject string, flags int) ([]Match, error) {
+	if !re.valid() {
+		return nil, uninitialized("Regexp.FindAllContext")
+	}
+	matches := make([]Match, 0)
+	err := re.forEachMatchContext(ctx, subject, flags, func(m *Matcher) {
+		from, end := int(m.ovector[0]), int(m.ovector[1])
+		matches = append(matches, Match{subject[from:end], []int{from, end}})
+	})
+	return matches, err
+}
+
+// ReplaceAllContext returns a copy of subject with the matches of the
+// pattern, found as by FindAllNamed, replaced by repl.  It gives up
+// when ctx is done, and returns nil and the error of ctx.  See
+// FindAllContext.
+func (re *Regexp) ReplaceAllContext(ctx context.Context, subject, repl []byte, flags int) ([]byte, error) {
+	if !re.valid() {
+		return nil, uninitialized("Regexp.ReplaceAllContext")
+	}
+	s := bytesString(subject)
+	out := make([]byte, 0, len(subject))
+	last := 0
+	err := re.forEachMatchContext(ctx, s, flags, func(m *Matcher) {
+		from, end := int(m.ovector[0]), int(m.ovector[1])
+		out = append(append(out, subject[last:from]...), repl...)
+		last = end
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(out, subject[last:]...), nil
+}
+
+// forEachMatchContext calls fn for every match in subject until ctx is
+// done.
+func (re *Regexp) forEachMatchContext(ctx context.Context, subject string, flags int, fn func(m *Matcher)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m := re.AcquireMatcher()
+	defer m.Release()
+	m.ctx = ctx
+	err := forEachMatchString(m, subject, 0, flags, func() bool {
+		fn(m)
+		return ctx.Err() == nil
+	})
+	if cerr := ctx.Err(); cerr != nil {
+		return cerr
+	}
+	return err
+}
@@ -0,0 +1,48 @@
+package pcre
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFindAllContext(t *testing.T) {
+	re := MustCompile(`a+`, 0)
+	defer re.FreeRegexp()
+	ctx := context.Background()
+	matches, err := re.FindAllContext(ctx, "xaayaz", 0)
+	if err != nil || len(matches) != 2 || matches[1].Finding != "a" || matches[1].Loc[0] != 4 {
+		t.Errorf("unexpected matches %v, %v", matches, err)
+	}
+	out, err := re.ReplaceAllContext(ctx, []byte("xaayaz"), []byte("-"), 0)
+	if err != nil || string(out) != "x-y-z" {
+		t.Errorf("unexpected replacement %q, %v", out, err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := re.FindAllContext(cancelled, "aaa", 0); !errors.Is(err, context.Canceled) {
+		t.Error("cancelled context not reported", err)
+	}
+	if _, err := re.ReplaceAllContext(cancelled, []byte("aaa"), nil, 0); !errors.Is(err, context.Canceled) {
+		t.Error("cancelled context not reported", err)
+	}
+}
+
+func TestFindAllContextLongMatch(t *testing.T) {
+	re := MustCompile(`(a+)+$`, 0)
+	defer re.FreeRegexp()
+	re.SetLimits(1<<31, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := re.FindAllContext(ctx, strings.Repeat("a", 40)+"b", 0)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("expected deadline exceeded, got", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Error("match not interrupted", d)
+	}
+}
@@ -0,0 +1,103 @@
+package pcre
+
+// #include "./pcre.h"
+import "C"
+
+import (
+	"errors"
+	"unsafe"
+)
+
+// Features describes the PCRE library the package is linked against,
+// as reported by pcre_config and pcre_version.
+type Features struct {
+	Version        string // Library version, such as "8.45 2021-06-15"
+	UTF8           bool   // Patterns can be compiled with UTF8
+	UCP            bool   // Unicode properties such as \p{Lu} and UCP are supported
+	JIT            bool   // Study can use the JIT compiler
+	JITTarget      string // Architecture of the JIT compiler, or ""
+	Newline        int    // Default newline convention, one of the NEWLINE flags
+	BSRAnyCRLF     bool   // \R matches only CR, LF and CRLF by default
+	LinkSize       int    // Size of internal offsets, which limits pattern size
+	MatchLimit     uint32 // Default match limit
+	RecursionLimit uint32 // Default recursion limit
+	StackRecurse   bool   // pcre_exec recurses on the machine stack
+}
+
+var features Features
+
+func init() {
+	features = readFeatures()
+}
+
+// Capabilities returns the features of the PCRE library, determined
+// when the package is initialized.  Programs which depend on optional
+// features can check for them at startup, see RequireJIT and
+// RequireUCP.
+func Capabilities() Features {
+	return features
+}
+
+// RequireJIT returns an error unless the library supports JIT
+// compilation.
+func RequireJIT() error {
+	if !features.JIT {
+		return errors.New("pcre: PCRE " + features.Version + " was built without JIT support")
+	}
+	return nil
+}
+
+// RequireUCP returns an error unless the library supports UTF-8 and
+// Unicode properties.
+func RequireUCP() error {
+	if !features.UTF8 || !features.UCP {
+		return errors.New("pcre: PCRE " + features.Version + " was built without Unicode property support")
+	}
+	return nil
+}
+
+func configInt(what C.int) int {
+	var value C.int
+	C.pcre_config(what, unsafe.Pointer(&value))
+	return int(value)
+}
+
+func configULong(what C.int) uint32 {
+	var value C.ulong
+	C.pcre_config(what, unsafe.Pointer(&value))
+	return uint32(value)
+}
+
+func readFeatures() Features {
+	f := Features{
+		Version:        C.GoString(C.pcre_version()),
+		UTF8:           configInt(C.PCRE_CONFIG_UTF8) != 0,
+		UCP:            configInt(C.PCRE_CONFIG_UNICODE_PROPERTIES) != 0,
+		JIT:            configInt(C.PCRE_CONFIG_JIT) != 0,
+		BSRAnyCRLF:     configInt(C.PCRE_CONFIG_BSR) != 0,
+		LinkSize:       configInt(C.PCRE_CONFIG_LINK_SIZE),
+		MatchLimit:     configULong(C.PCRE_CONFIG_MATCH_LIMIT),
+		RecursionLimit: configULong(C.PCRE_CONFIG_MATCH_LIMIT_RECURSION),
+		StackRecurse:   configInt(C.PCRE_CONFIG_STACKRECURSE) != 0,
+	}
+	if f.JIT {
+		var target *C.char
+		C.pcre_config(C.PCRE_CONFIG_JITTARGET, unsafe.Pointer(&target))
+		f.JITTarget = C.GoString(target)
+	}
+	// pcre_config reports the newline character code, or a negative
+	// value for the ANY and ANYCRLF conventions.
+	switch configInt(C.PCRE_CONFIG_NEWLINE) {
+	case '\r':
+		f.Newline = NEWLINE_CR
+	case '\n':
+		f.Newline = NEWLINE_LF
+	case '\r'<<8 | '\n':
+		f.Newline = NEWLINE_CRLF
+	case -1:
+		f.Newline = NEWLINE_ANY
+	case -2:
+		f.Newline = NEWLINE_ANYCRLF
+	}
+	return f
+}
@@ -0,0 +1,28 @@
+package pcre
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCapabilities(t *testing.T) {
+	f := Capabilities()
+	if !strings.HasPrefix(f.Version, "8.") {
+		t.Error("version", f.Version)
+	}
+	if !f.UTF8 {
+		t.Error("expected UTF-8 support")
+	}
+	if f.LinkSize < 2 || f.MatchLimit == 0 || f.Newline == 0 {
+		t.Errorf("unexpected features %+v", f)
+	}
+	if err := RequireJIT(); (err == nil) != f.JIT {
+		t.Error("RequireJIT", err)
+	}
+	if f.JIT != (f.JITTarget != "") {
+		t.Error("JIT target", f.JITTarget)
+	}
+	if err := RequireUCP(); (err == nil) != f.UCP {
+		t.Error("RequireUCP", err)
+	}
+}
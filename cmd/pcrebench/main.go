@@ -0,0 +1,196 @@
+// Command pcrebench measures how patterns perform on sample data, with
+// and without JIT compilation, and compared with the standard
+// library's regexp package.
+//
+// Usage:
+//
+//	pcrebench [-e pattern]... [-f file] [-n count] [-whole] corpus...
+//
+// Patterns are given with -e, or read from a file with one pattern per
+// line.  Each corpus file is searched line by line, or as a whole with
+// -whole, and every measurement is averaged over count runs.  For each
+// pattern, pcrebench prints the time to compile, to study with JIT,
+// to search the corpora interpreted and with JIT, and the time the
+// regexp package needs, if it accepts the pattern.  A "!" after the
+// match count marks patterns whose engines disagreed on it.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gijsbers/go-pcre"
+)
+
+type patternList []string
+
+func (p *patternList) String() string     { return strings.Join(*p, ", ") }
+func (p *patternList) Set(s string) error { *p = append(*p, s); return nil }
+
+func main() {
+	var patterns patternList
+	flag.Var(&patterns, "e", "pattern to benchmark (repeatable)")
+	file := flag.String("f", "", "read patterns from `file`, one per line")
+	count := flag.Int("n", 10, "number of runs to average")
+	whole := flag.Bool("whole", false, "match each corpus file as a whole instead of by line")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: pcrebench [-e pattern]... [-f file] [-n count] [-whole] corpus...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *file != "" {
+		data, err := os.ReadFile(*file)
+		if err != nil {
+			fatal(err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimRight(line, "\r"); line != "" {
+				patterns = append(patterns, line)
+			}
+		}
+	}
+	if len(patterns) == 0 || flag.NArg() == 0 || *count < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	var subjects [][]byte
+	for _, name := range flag.Args() {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			fatal(err)
+		}
+		if *whole {
+			subjects = append(subjects, data)
+		} else {
+			subjects = append(subjects, splitLines(data)...)
+		}
+	}
+	if err := run(os.Stdout, patterns, subjects, *count); err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "pcrebench:", err)
+	os.Exit(1)
+}
+
+func splitLines(data []byte) [][]byte {
+	var lines [][]byte
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(nil, len(data)+1)
+	for sc.Scan() {
+		lines = append(lines, sc.Bytes())
+	}
+	return lines
+}
+
+// result holds the averaged measurements for one pattern.
+type result struct {
+	compile, study, interp, jit time.Duration
+	stdCompile, std             time.Duration
+	matches                     int
+	stdOK, agree                bool
+}
+
+// run benchmarks each pattern and writes a table to w.
+func run(w io.Writer, patterns []string, subjects [][]byte, n int) error {
+	size := 0
+	for _, s := range subjects {
+		size += len(s)
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "compile\tstudy\tinterp\tjit\tspeedup\tstd compile\tstd\tmatches\tpattern\t")
+	for _, p := range patterns {
+		r, err := bench(p, subjects, n)
+		if err != nil {
+			return err
+		}
+		stdCompile, std := "n/a", "n/a"
+		if r.stdOK {
+			stdCompile, std = r.stdCompile.String(), r.std.String()
+		}
+		agree := ""
+		if !r.agree {
+			agree = "!"
+		}
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%.1fx\t%s\t%s\t%d%s\t%s\t\n",
+			r.compile, r.study, r.interp, r.jit,
+			float64(r.interp)/float64(max(r.jit, 1)), stdCompile, std, r.matches, agree, p)
+	}
+	fmt.Fprintf(tw, "\n%d patterns, %d subjects, %d bytes, average of %d runs\n",
+		len(patterns), len(subjects), size, n)
+	return tw.Flush()
+}
+
+// average returns the mean duration of n calls to fn.
+func average(n int, fn func()) time.Duration {
+	start := time.Now()
+	for i := 0; i < n; i++ {
+		fn()
+	}
+	return time.Since(start) / time.Duration(n)
+}
+
+func bench(pattern string, subjects [][]byte, n int) (r result, err error) {
+	interp, err := pcre.Compile(pattern, 0)
+	if err != nil {
+		return r, err
+	}
+	defer interp.FreeRegexp()
+	r.compile = average(n, func() {
+		re, _ := pcre.Compile(pattern, 0)
+		re.FreeRegexp()
+	})
+	r.study = average(n, func() {
+		re, _ := pcre.Compile(pattern, 0)
+		re.Study(pcre.STUDY_JIT_COMPILE)
+		re.FreeRegexp()
+	})
+	r.study -= r.compile
+	jit, err := pcre.CompileJIT(pattern, 0, pcre.STUDY_JIT_COMPILE)
+	if err != nil {
+		return r, err
+	}
+	defer jit.FreeRegexp()
+
+	count := func(m *pcre.Matcher) (matches int) {
+		for _, s := range subjects {
+			if m.Match(s, 0) {
+				matches++
+			}
+		}
+		return
+	}
+	mi, mj := interp.NewMatcher(), jit.NewMatcher()
+	r.matches = count(mi)
+	r.agree = count(mj) == r.matches
+	r.interp = average(n, func() { count(mi) })
+	r.jit = average(n, func() { count(mj) })
+
+	std, err := regexp.Compile(pattern)
+	if err != nil {
+		return r, nil
+	}
+	r.stdOK = true
+	r.stdCompile = average(n, func() { regexp.Compile(pattern) })
+	stdCount := func() (matches int) {
+		for _, s := range subjects {
+			if std.Match(s) {
+				matches++
+			}
+		}
+		return
+	}
+	r.agree = r.agree && stdCount() == r.matches
+	r.std = average(n, func() { stdCount() })
+	return r, nil
+}
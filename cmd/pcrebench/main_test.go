@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	subjects := splitLines([]byte("foo 1\nbar 22\nbaz\n"))
+	if len(subjects) != 3 {
+		t.Fatal("lines", len(subjects))
+	}
+	var buf bytes.Buffer
+	if err := run(&buf, []string{`\d+`, `(?<=a)r`, `ba.`}, subjects, 2); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+	for i, want := range []string{"2", "1", "2"} {
+		fields := strings.Fields(lines[i+1])
+		if fields[len(fields)-2] != want {
+			t.Errorf("line %d: expected %s matches: %s", i+1, want, lines[i+1])
+		}
+	}
+	if !strings.Contains(lines[2], "n/a") {
+		t.Error("lookbehind should not be supported by regexp:", lines[2])
+	}
+	if err := run(&buf, []string{"("}, subjects, 1); err == nil {
+		t.Error("expected compile error")
+	}
+}
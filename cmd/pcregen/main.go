@@ -0,0 +1,443 @@
+// Command pcregen compiles a list of named patterns at generate time
+// and writes a Go file declaring them, so that a bad pattern fails
+// the build instead of panicking at startup.
+//
+// Usage:
+//
+//	pcregen [-o file] [-pkg name] patterns.txt
+//
+// Typically it is run by go generate:
+//
+//	//go:generate pcregen -o patterns_gen.go patterns.txt
+//
+// Each line of the input holds a Go identifier, optional flag letters
+// after a slash, a colon and the pattern.  Blank lines and lines
+// starting with # are ignored:
+//
+//	# ISO dates
+//	Date: (?<year>\d{4})-(?<month>\d\d)-(?<day>\d\d)
+//	Word/iu: \w+
+//
+// The flag letters are those of pcre.Field: i m s x U u D J n A X.
+// For the Date pattern above, pcregen declares the compiled Regexp
+// Date, the constants DateYear, DateMonth and DateDay holding the
+// group names, a struct DateMatch with a string field per named
+// group, a function MatchDate that matches a subject and fills the
+// struct, and a function ParseDate that does the same, but returns
+// an error instead of false.
+//
+// The fields can be given other types in parentheses after the flags,
+// as group names followed by Go types:
+//
+//	Access (status int, size int64, took time.Duration): ...
+//
+// The types are string, []byte, bool, the integer and float types,
+// and time.Duration.  Patterns with typed groups get only the Parse
+// function, which fails with a *pcre.ScanError naming the group that
+// did not convert, or with pcre.ErrNoMatch.
+// The package name defaults to $GOPACKAGE, which go generate sets.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gijsbers/go-pcre"
+)
+
+// flagNames maps the flag letters of pcre.Field to the constants
+// written into the generated code.
+var flagNames = []struct {
+	letter rune
+	name   string
+	value  int
+}{
+	{'i', "CASELESS", pcre.CASELESS},
+	{'m', "MULTILINE", pcre.MULTILINE},
+	{'s', "DOTALL", pcre.DOTALL},
+	{'x', "EXTENDED", pcre.EXTENDED},
+	{'U', "UNGREEDY", pcre.UNGREEDY},
+	{'u', "UTF8", pcre.UTF8},
+	{'D', "DOLLAR_ENDONLY", pcre.DOLLAR_ENDONLY},
+	{'J', "DUPNAMES", pcre.DUPNAMES},
+	{'n', "NO_AUTO_CAPTURE", pcre.NO_AUTO_CAPTURE},
+	{'A', "ANCHORED", pcre.ANCHORED},
+	{'X', "EXTRA", pcre.EXTRA},
+}
+
+func main() {
+	out := flag.String("o", "", "write the generated code to `file` instead of standard output")
+	pkg := flag.String("pkg", os.Getenv("GOPACKAGE"), "package `name` of the generated file")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: pcregen [-o file] [-pkg name] patterns.txt")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 || *pkg == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	input := flag.Arg(0)
+	f, err := os.Open(input)
+	if err != nil {
+		fatal(err)
+	}
+	patterns, err := parse(input, f)
+	f.Close()
+	if err != nil {
+		fatal(err)
+	}
+	src, err := generate(*pkg, input, patterns)
+	if err != nil {
+		fatal(err)
+	}
+	if *out == "" {
+		_, err = os.Stdout.Write(src)
+	} else {
+		err = os.WriteFile(*out, src, 0o666)
+	}
+	if err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "pcregen:", err)
+	os.Exit(1)
+}
+
+// pattern is one validated entry of the input.
+type pattern struct {
+	name   string
+	expr   string
+	flags  []string // constant names, in input order
+	groups []group
+	typed  bool // whether group types were given
+}
+
+// group is a named capture group.
+type group struct {
+	name    string // as written in the pattern
+	field   string // exported Go identifier derived from name
+	typ     string // Go type of the field
+	indexes []int  // more than one with DUPNAMES
+}
+
+// conversion tells how to convert the text of a group to a field
+// type: call parse on it, wrapped by the type conversion if the
+// parser returns a wider type.  Parsers which cannot fail have no
+// error result.
+type conversion struct {
+	parse   string // with %s for the text
+	convert bool   // whether to convert the parsed value to the type
+	fails   bool
+	imports []string
+}
+
+var conversions = map[string]conversion{
+	"string":        {parse: "%s"},
+	"[]byte":        {parse: "[]byte(%s)"},
+	"bool":          {parse: "strconv.ParseBool(%s)", fails: true, imports: []string{"strconv"}},
+	"int":           {parse: "strconv.ParseInt(%s, 10, 0)", convert: true, fails: true, imports: []string{"strconv"}},
+	"int8":          {parse: "strconv.ParseInt(%s, 10, 8)", convert: true, fails: true, imports: []string{"strconv"}},
+	"int16":         {parse: "strconv.ParseInt(%s, 10, 16)", convert: true, fails: true, imports: []string{"strconv"}},
+	"int32":         {parse: "strconv.ParseInt(%s, 10, 32)", convert: true, fails: true, imports: []string{"strconv"}},
+	"int64":         {parse: "strconv.ParseInt(%s, 10, 64)", fails: true, imports: []string{"strconv"}},
+	"uint":          {parse: "strconv.ParseUint(%s, 10, 0)", convert: true, fails: true, imports: []string{"strconv"}},
+	"uint8":         {parse: "strconv.ParseUint(%s, 10, 8)", convert: true, fails: true, imports: []string{"strconv"}},
+	"uint16":        {parse: "strconv.ParseUint(%s, 10, 16)", convert: true, fails: true, imports: []string{"strconv"}},
+	"uint32":        {parse: "strconv.ParseUint(%s, 10, 32)", convert: true, fails: true, imports: []string{"strconv"}},
+	"uint64":        {parse: "strconv.ParseUint(%s, 10, 64)", fails: true, imports: []string{"strconv"}},
+	"float32":       {parse: "strconv.ParseFloat(%s, 32)", convert: true, fails: true, imports: []string{"strconv"}},
+	"float64":       {parse: "strconv.ParseFloat(%s, 64)", fails: true, imports: []string{"strconv"}},
+	"time.Duration": {parse: "time.ParseDuration(%s)", fails: true, imports: []string{"time"}},
+}
+
+// parse reads and validates the patterns in r.  Errors are prefixed
+// with file:line.
+func parse(file string, r io.Reader) ([]pattern, error) {
+	var patterns []pattern
+	// The line each generated identifier is declared on, or 0 for
+	// those the generated file cannot declare.
+	declared := map[string]int{"pcre": 0, "init": 0}
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || text[0] == '#' {
+			continue
+		}
+		p, err := parseLine(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", file, line, err)
+		}
+		for _, id := range p.identifiers() {
+			prev, ok := declared[id]
+			switch {
+			case !ok:
+				declared[id] = line
+			case prev == 0:
+				return nil, fmt.Errorf("%s:%d: %s: cannot declare %s", file, line, p.name, id)
+			case prev == line:
+				return nil, fmt.Errorf("%s:%d: %s: %s is declared twice", file, line, p.name, id)
+			default:
+				return nil, fmt.Errorf("%s:%d: %s already declared on line %d", file, line, id, prev)
+			}
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, sc.Err()
+}
+
+func parseLine(text string) (p pattern, err error) {
+	head, expr, ok := strings.Cut(text, ":")
+	if !ok {
+		return p, fmt.Errorf("missing ':' after name")
+	}
+	head, typeList, typed := strings.Cut(strings.TrimSpace(head), "(")
+	types := make(map[string]string)
+	if typed {
+		if types, err = parseTypes(typeList); err != nil {
+			return p, err
+		}
+	}
+	name, letters, _ := strings.Cut(strings.TrimSpace(head), "/")
+	if !token.IsIdentifier(name) {
+		return p, fmt.Errorf("%q is not a Go identifier", name)
+	}
+	p.name = name
+	p.expr = strings.TrimSpace(expr)
+	flags := 0
+letters:
+	for _, c := range letters {
+		for _, f := range flagNames {
+			if f.letter == c {
+				flags |= f.value
+				p.flags = append(p.flags, f.name)
+				continue letters
+			}
+		}
+		return p, fmt.Errorf("unknown flag %q", c)
+	}
+	re, err := pcre.Compile(p.expr, flags)
+	if err != nil {
+		return p, fmt.Errorf("%s: %v", name, err)
+	}
+	defer re.FreeRegexp()
+	fields := make(map[string]int) // index into p.groups
+	for i, g := range re.SubexpNames() {
+		if g == "" {
+			continue
+		}
+		field := exported(g)
+		if j, ok := fields[field]; ok {
+			if prev := p.groups[j].name; prev != g {
+				return p, fmt.Errorf("%s: groups %q and %q both map to %s", name, prev, g, field)
+			}
+			p.groups[j].indexes = append(p.groups[j].indexes, i)
+			continue
+		}
+		fields[field] = len(p.groups)
+		typ := types[g]
+		if typ == "" {
+			typ = "string"
+		}
+		delete(types, g)
+		p.groups = append(p.groups, group{name: g, field: field, typ: typ, indexes: []int{i}})
+	}
+	for g := range types {
+		return p, fmt.Errorf("%s: type given for unknown group %q", name, g)
+	}
+	p.typed = typed
+	return p, nil
+}
+
+// parseTypes parses the group types of a pattern, as in
+// "status int, size int64)".
+func parseTypes(list string) (map[string]string, error) {
+	list, ok := strings.CutSuffix(strings.TrimSpace(list), ")")
+	if !ok {
+		return nil, fmt.Errorf("missing ')' after group types")
+	}
+	types := make(map[string]string)
+	for _, decl := range strings.Split(list, ",") {
+		fields := strings.Fields(decl)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("group type %q is not a group name and a type", strings.TrimSpace(decl))
+		}
+		if _, ok := conversions[fields[1]]; !ok {
+			return nil, fmt.Errorf("unsupported type %s for group %q", fields[1], fields[0])
+		}
+		if _, ok := types[fields[0]]; ok {
+			return nil, fmt.Errorf("group %q has more than one type", fields[0])
+		}
+		types[fields[0]] = fields[1]
+	}
+	return types, nil
+}
+
+// exported turns a group name such as remote_addr into RemoteAddr.
+func exported(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, c := range name {
+		switch {
+		case c == '_':
+			upper = true
+		case upper:
+			b.WriteRune(unicode.ToUpper(c))
+			upper = false
+		default:
+			b.WriteRune(c)
+		}
+	}
+	if b.Len() == 0 || !unicode.IsLetter(rune(b.String()[0])) {
+		return "G" + b.String()
+	}
+	return b.String()
+}
+
+// identifiers returns the package-level identifiers which generate
+// declares for p.
+func (p pattern) identifiers() []string {
+	ids := []string{p.name}
+	if len(p.groups) == 0 {
+		return ids
+	}
+	for _, g := range p.groups {
+		ids = append(ids, p.name+g.field)
+	}
+	ids = append(ids, p.name+"Match", prefixed("Parse", p.name))
+	if !p.typed {
+		ids = append(ids, prefixed("Match", p.name))
+	}
+	return ids
+}
+
+// prefixed returns prefix followed by name, with prefix lower-cased
+// if name is unexported.
+func prefixed(prefix, name string) string {
+	if unicode.IsLower(rune(name[0])) {
+		prefix = strings.ToLower(prefix)
+	}
+	return prefix + strings.ToUpper(name[:1]) + name[1:]
+}
+
+// generate returns the gofmt-ed Go source for patterns.
+func generate(pkg, input string, patterns []pattern) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by pcregen from %s; DO NOT EDIT.\n\n", input)
+	fmt.Fprintf(&b, "package %s\n\n", pkg)
+	var imports []string
+	seen := make(map[string]bool)
+	for _, p := range patterns {
+		for _, g := range p.groups {
+			for _, imp := range conversions[g.typ].imports {
+				if !seen[imp] {
+					seen[imp] = true
+					imports = append(imports, imp)
+				}
+			}
+		}
+	}
+	if len(imports) == 0 {
+		fmt.Fprintf(&b, "import \"github.com/gijsbers/go-pcre\"\n")
+	} else {
+		sort.Strings(imports)
+		fmt.Fprintf(&b, "import (\n%s\n\n\"github.com/gijsbers/go-pcre\"\n)\n", quoteAll(imports))
+	}
+	for _, p := range patterns {
+		flags := "0"
+		if len(p.flags) > 0 {
+			flags = "pcre." + strings.Join(p.flags, "|pcre.")
+		}
+		fmt.Fprintf(&b, "\n// %s is compiled from %s.\n", p.name, strconv.Quote(p.expr))
+		fmt.Fprintf(&b, "var %s = pcre.MustCompile(%s, %s)\n", p.name, strconv.Quote(p.expr), flags)
+		if len(p.groups) == 0 {
+			continue
+		}
+		match := p.name + "Match"
+		fmt.Fprintf(&b, "\n// Group names of %s.\nconst (\n", p.name)
+		for _, g := range p.groups {
+			fmt.Fprintf(&b, "%s%s = %s\n", p.name, g.field, strconv.Quote(g.name))
+		}
+		fmt.Fprintf(&b, ")\n\n// %s holds the named groups of a match of %s.\ntype %s struct {\n", match, p.name, match)
+		for _, g := range p.groups {
+			fmt.Fprintf(&b, "%s %s\n", g.field, g.typ)
+		}
+		fmt.Fprintf(&b, "}\n")
+		generateParse(&b, p)
+		if p.typed {
+			continue
+		}
+		fmt.Fprintf(&b, "\n// %s matches subject against %s, and reports whether it matched.\n",
+			prefixed("Match", p.name), p.name)
+		fmt.Fprintf(&b, "func %s(subject string) (m %s, ok bool) {\n", prefixed("Match", p.name), match)
+		fmt.Fprintf(&b, "matcher := %s.MatcherString(subject, 0)\nif !matcher.Matches() {\nreturn m, false\n}\n", p.name)
+		for _, g := range p.groups {
+			if len(g.indexes) == 1 {
+				fmt.Fprintf(&b, "m.%s = matcher.GroupString(%d)\n", g.field, g.indexes[0])
+				continue
+			}
+			// Duplicate names: take the first group that matched.
+			fmt.Fprintf(&b, "for _, i := range %#v {\nif matcher.Present(i) {\nm.%s = matcher.GroupString(i)\nbreak\n}\n}\n",
+				g.indexes, g.field)
+		}
+		fmt.Fprintf(&b, "return m, true\n}\n")
+	}
+	return format.Source(b.Bytes())
+}
+
+// generateParse writes the Parse function of p, which converts each
+// named group to the type of its field.
+func generateParse(b *bytes.Buffer, p pattern) {
+	parse := prefixed("Parse", p.name)
+	fmt.Fprintf(b, "\n// %s matches subject against %s, and converts its named groups.\n", parse, p.name)
+	fmt.Fprintf(b, "func %s(subject string) (m %sMatch, err error) {\n", parse, p.name)
+	fmt.Fprintf(b, "matcher := %s.MatcherString(subject, 0)\nif !matcher.Matches() {\n", p.name)
+	fmt.Fprintf(b, "if err := matcher.Err(); err != nil {\nreturn m, err\n}\nreturn m, pcre.ErrNoMatch\n}\n")
+	for _, g := range p.groups {
+		if len(g.indexes) == 1 {
+			fmt.Fprintf(b, "if i := %d; matcher.Present(i) {\n", g.indexes[0])
+		} else {
+			// Duplicate names: convert the first group that matched.
+			fmt.Fprintf(b, "for _, i := range %#v {\nif !matcher.Present(i) {\ncontinue\n}\n", g.indexes)
+		}
+		c := conversions[g.typ]
+		value := fmt.Sprintf(c.parse, "matcher.GroupString(i)")
+		if !c.fails {
+			fmt.Fprintf(b, "m.%s = %s\n", g.field, value)
+		} else {
+			fmt.Fprintf(b, "v, err := %s\nif err != nil {\n", value)
+			fmt.Fprintf(b, "return m, &pcre.ScanError{Group: i, Name: %s, Err: err}\n}\n", strconv.Quote(g.name))
+			if c.convert {
+				fmt.Fprintf(b, "m.%s = %s(v)\n", g.field, g.typ)
+			} else {
+				fmt.Fprintf(b, "m.%s = v\n", g.field)
+			}
+		}
+		if len(g.indexes) > 1 {
+			fmt.Fprintf(b, "break\n")
+		}
+		fmt.Fprintf(b, "}\n")
+	}
+	fmt.Fprintf(b, "return m, nil\n}\n")
+}
+
+// quoteAll returns the quoted strings, one per line.
+func quoteAll(s []string) string {
+	quoted := make([]string, len(s))
+	for i := range s {
+		quoted[i] = strconv.Quote(s[i])
+	}
+	return strings.Join(quoted, "\n")
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	const input = `# comment
+
+Date: (?<year>\d{4})-(?<month>\d\d)-(?<day_of_month>\d\d)
+word/iu: \w+
+Either/J: (?<v>a)|(?<v>b)
+`
+	patterns, err := parse("patterns.txt", strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := generate("dates", "patterns.txt", patterns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"// Code generated by pcregen from patterns.txt; DO NOT EDIT.",
+		"package dates",
+		`var Date = pcre.MustCompile("(?<year>\\d{4})-(?<month>\\d\\d)-(?<day_of_month>\\d\\d)", 0)`,
+		`DateDayOfMonth = "day_of_month"`,
+		"type DateMatch struct {",
+		"func MatchDate(subject string) (m DateMatch, ok bool) {",
+		"m.Month = matcher.GroupString(2)",
+		`var word = pcre.MustCompile("\\w+", pcre.CASELESS|pcre.UTF8)`,
+		"for _, i := range []int{1, 2} {",
+		"func ParseDate(subject string) (m DateMatch, err error) {",
+		"return m, pcre.ErrNoMatch",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("missing %q in\n%s", want, src)
+		}
+	}
+	if strings.Contains(string(src), "matchWord") {
+		t.Error("accessor generated for pattern without named groups")
+	}
+}
+
+func TestGenerateTyped(t *testing.T) {
+	const input = `Access (status int, size int64, took time.Duration): ` +
+		`(?<host>\S+) (?<status>\d+) (?<size>\d+) (?<took>\S+)
+Pair/J (n uint8): (?<n>\d+)|x(?<n>\d+)
+`
+	patterns, err := parse("patterns.txt", strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := generate("logs", "patterns.txt", patterns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"import (\n\t\"strconv\"\n\t\"time\"\n\n\t\"github.com/gijsbers/go-pcre\"\n)",
+		"\tHost   string\n\tStatus int\n\tSize   int64\n\tTook   time.Duration\n",
+		"func ParseAccess(subject string) (m AccessMatch, err error) {",
+		"v, err := strconv.ParseInt(matcher.GroupString(i), 10, 0)",
+		`return m, &pcre.ScanError{Group: i, Name: "status", Err: err}`,
+		"m.Status = int(v)",
+		"m.Size = v",
+		"v, err := time.ParseDuration(matcher.GroupString(i))",
+		"m.Host = matcher.GroupString(i)",
+		"m.N = uint8(v)",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("missing %q in\n%s", want, src)
+		}
+	}
+	if strings.Contains(string(src), "func MatchAccess") {
+		t.Error("Match function generated for typed pattern")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for input, want := range map[string]string{
+		"A: ok\nB: (unclosed":         "p:2: B: ",
+		"A: x\n\nA: y":                "p:3: A already declared on line 1",
+		"1x: a":                       `p:1: "1x" is not a Go identifier`,
+		"A/q: a":                      "p:1: unknown flag 'q'",
+		"no colon":                    "p:1: missing ':'",
+		"A: (?<a_b>x)(?<aB>y)":        `groups "a_b" and "aB" both map to AB`,
+		"A/J: (?<x>x)|(?<x>y)\nB:":    "",
+		"A (x int: (?<x>1)":           "missing ')'",
+		"A (x complex128): (?<x>1)":   "unsupported type complex128",
+		"A (x): (?<x>1)":              `"x" is not a group name and a type`,
+		"A (y int): (?<x>1)":          `type given for unknown group "y"`,
+		"A (x int, x bool): (?<x>1)":  `group "x" has more than one type`,
+		"Date: (?<match>x)":           "p:1: Date: DateMatch is declared twice",
+		"Date: (?<y>x)\nDateY: y":     "p:2: DateY already declared on line 1",
+		"Date: (?<y>x)\nParseDate: y": "p:2: ParseDate already declared on line 1",
+		"MatchA: a\nA: (?<x>b)":       "p:2: MatchA already declared on line 1",
+		"pcre: a":                     "p:1: pcre: cannot declare pcre",
+	} {
+		_, err := parse("p", strings.NewReader(input))
+		switch {
+		case want == "" && err != nil:
+			t.Errorf("%q: %v", input, err)
+		case want != "" && (err == nil || !strings.Contains(err.Error(), want)):
+			t.Errorf("%q: expected %q, got %v", input, want, err)
+		}
+	}
+}
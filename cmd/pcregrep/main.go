@@ -0,0 +1,525 @@
+// Command pcregrep searches files for lines matching a PCRE pattern.
+//
+// Usage:
+//
+//	pcregrep [options] pattern [file...]
+//
+// With no files, or the file "-", standard input is read.  Short
+// options may be combined, as in -rin.  The options are:
+//
+//	-i             ignore case
+//	-n             prefix output lines with their line number
+//	-r             search directories recursively
+//	-v             select lines which do not match
+//	-c             print only the number of selected lines per file
+//	-l             print only the names of files with selected lines
+//	-H, -h         always, or never, prefix output with file names
+//	-o, -oN        print only the match, or capture group N of it
+//	-A N, -B N     print N lines of context after, or before, selected lines
+//	-C N           print N lines of context before and after
+//	-M             multiline mode: match across line boundaries
+//	-u             treat pattern and files as UTF-8
+//	-Z, --null     terminate file names with NUL instead of ':'
+//	--include=GLOB only search files whose name matches GLOB
+//	--exclude=GLOB skip files whose name matches GLOB
+//
+// Context lines are prefixed with '-' instead of ':', and groups of
+// lines are separated by "--".
+//
+// The exit status is 0 if a line was selected, 1 if none was, and 2
+// on errors.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gijsbers/go-pcre"
+)
+
+type options struct {
+	ignoreCase bool
+	lineNumber bool
+	recursive  bool
+	invert     bool
+	count      bool
+	filesOnly  bool
+	withName   int // -1 for -h, 1 for -H, 0 for the default
+	only       bool
+	group      int
+	multiline  bool
+	utf8       bool
+	null       bool
+	include    []string
+	exclude    []string
+	before     int
+	after      int
+
+	re      *pcre.Regexp
+	matched bool
+	grouped bool // a group of lines with context was printed
+	errors  bool
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: pcregrep [-cHhilMnoruvZ] [-oN] [-A N] [-B N] [-C N] [--include=GLOB] [--exclude=GLOB] pattern [file...]")
+	os.Exit(2)
+}
+
+// parseArgs parses the command line into opts, and returns the
+// remaining arguments.
+func parseArgs(opts *options, args []string) ([]string, error) {
+	for len(args) > 0 {
+		arg := args[0]
+		if arg == "--" {
+			return args[1:], nil
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			break
+		}
+		args = args[1:]
+		if strings.HasPrefix(arg, "--") {
+			name, value, hasValue := strings.Cut(arg[2:], "=")
+			switch name {
+			case "include", "exclude":
+				if !hasValue {
+					if len(args) == 0 {
+						return nil, errors.New("--" + name + " needs a value")
+					}
+					value, args = args[0], args[1:]
+				}
+				if _, err := filepath.Match(value, ""); err != nil {
+					return nil, fmt.Errorf("--%s: %v", name, err)
+				}
+				if name == "include" {
+					opts.include = append(opts.include, value)
+				} else {
+					opts.exclude = append(opts.exclude, value)
+				}
+			case "null":
+				opts.null = true
+			default:
+				return nil, errors.New("unknown option " + arg)
+			}
+			continue
+		}
+		for i := 1; i < len(arg); i++ {
+			switch arg[i] {
+			case 'i':
+				opts.ignoreCase = true
+			case 'n':
+				opts.lineNumber = true
+			case 'r':
+				opts.recursive = true
+			case 'v':
+				opts.invert = true
+			case 'c':
+				opts.count = true
+			case 'l':
+				opts.filesOnly = true
+			case 'H':
+				opts.withName = 1
+			case 'h':
+				opts.withName = -1
+			case 'M':
+				opts.multiline = true
+			case 'u':
+				opts.utf8 = true
+			case 'Z':
+				opts.null = true
+			case 'o':
+				opts.only = true
+				j := i + 1
+				for j < len(arg) && arg[j] >= '0' && arg[j] <= '9' {
+					j++
+				}
+				if j > i+1 {
+					opts.group, _ = strconv.Atoi(arg[i+1 : j])
+				}
+				i = j - 1
+			case 'A', 'B', 'C':
+				// The number is the rest of the argument, or the next.
+				value := arg[i+1:]
+				if value == "" {
+					if len(args) == 0 {
+						return nil, fmt.Errorf("-%c needs a number", arg[i])
+					}
+					value, args = args[0], args[1:]
+				}
+				n, err := strconv.Atoi(value)
+				if err != nil || n < 0 {
+					return nil, fmt.Errorf("-%c: invalid number %q", arg[i], value)
+				}
+				if arg[i] != 'A' {
+					opts.before = n
+				}
+				if arg[i] != 'B' {
+					opts.after = n
+				}
+				i = len(arg)
+			default:
+				return nil, fmt.Errorf("unknown option -%c", arg[i])
+			}
+		}
+	}
+	return args, nil
+}
+
+func main() {
+	opts := new(options)
+	args, err := parseArgs(opts, os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "pcregrep:", err)
+		usage()
+	}
+	if len(args) == 0 {
+		usage()
+	}
+	if opts.multiline && opts.invert {
+		fmt.Fprintln(os.Stderr, "pcregrep: -v cannot be combined with -M")
+		os.Exit(2)
+	}
+	if opts.multiline && opts.before+opts.after > 0 {
+		fmt.Fprintln(os.Stderr, "pcregrep: context lines cannot be combined with -M")
+		os.Exit(2)
+	}
+	flags := 0
+	if opts.ignoreCase {
+		flags |= pcre.CASELESS
+	}
+	if opts.multiline {
+		flags |= pcre.MULTILINE
+	}
+	if opts.utf8 {
+		flags |= pcre.UTF8
+	}
+	opts.re, err = pcre.Compile(args[0], flags)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "pcregrep:", err)
+		os.Exit(2)
+	}
+	if opts.group > opts.re.Groups() {
+		fmt.Fprintf(os.Stderr, "pcregrep: -o%d: pattern has %d groups\n", opts.group, opts.re.Groups())
+		os.Exit(2)
+	}
+	opts.re.Study(0)
+	files := args[1:]
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	if opts.withName == 0 && (len(files) > 1 || opts.recursive) {
+		opts.withName = 1
+	}
+	out := bufio.NewWriter(os.Stdout)
+	for _, name := range files {
+		opts.search(out, name)
+	}
+	out.Flush()
+	switch {
+	case opts.errors:
+		os.Exit(2)
+	case !opts.matched:
+		os.Exit(1)
+	}
+}
+
+// search greps the named file, or the files below it with -r.
+func (opts *options) search(out *bufio.Writer, name string) {
+	if name == "-" {
+		opts.grepFile(out, "(standard input)", os.Stdin)
+		return
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		opts.fail(err)
+		return
+	}
+	if !info.IsDir() {
+		opts.grepPath(out, name)
+		return
+	}
+	if !opts.recursive {
+		opts.fail(errors.New(name + ": is a directory"))
+		return
+	}
+	filepath.WalkDir(name, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			opts.fail(err)
+		} else if d.Type().IsRegular() && opts.selected(d.Name()) {
+			opts.grepPath(out, path)
+		}
+		return nil
+	})
+}
+
+// selected applies --include and --exclude to a file name.
+func (opts *options) selected(name string) bool {
+	for _, glob := range opts.exclude {
+		if ok, _ := filepath.Match(glob, name); ok {
+			return false
+		}
+	}
+	for _, glob := range opts.include {
+		if ok, _ := filepath.Match(glob, name); ok {
+			return true
+		}
+	}
+	return len(opts.include) == 0
+}
+
+func (opts *options) grepPath(out *bufio.Writer, path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		opts.fail(err)
+		return
+	}
+	defer f.Close()
+	opts.grepFile(out, path, f)
+}
+
+func (opts *options) fail(err error) {
+	fmt.Fprintln(os.Stderr, "pcregrep:", err)
+	opts.errors = true
+}
+
+// grepFile writes the selected lines of r to out.
+func (opts *options) grepFile(out *bufio.Writer, name string, r io.Reader) {
+	g := &grepper{opts: opts, out: out, name: name, m: opts.re.NewMatcher()}
+	var err error
+	switch {
+	case opts.multiline:
+		err = g.grepAll(r)
+	case opts.before+opts.after > 0 && !opts.only && !g.quiet():
+		err = g.grepContext(r)
+	default:
+		err = g.grepLines(r)
+	}
+	if err != nil {
+		opts.fail(fmt.Errorf("%s: %v", name, err))
+	}
+	if g.selected > 0 {
+		opts.matched = true
+	}
+	switch {
+	case opts.filesOnly && g.selected > 0:
+		out.WriteString(name)
+		if opts.null {
+			out.WriteByte(0)
+		} else {
+			out.WriteByte('\n')
+		}
+	case opts.count && !opts.filesOnly:
+		g.prefix(0)
+		fmt.Fprintln(out, g.selected)
+	}
+}
+
+type grepper struct {
+	opts     *options
+	out      *bufio.Writer
+	name     string
+	m        *pcre.Matcher
+	selected int
+}
+
+// quiet reports whether selected lines are counted but not printed.
+func (g *grepper) quiet() bool {
+	return g.opts.count || g.opts.filesOnly
+}
+
+// prefix writes the file name and line number, as configured.
+func (g *grepper) prefix(line int) {
+	g.prefixSep(line, ':')
+}
+
+// prefixSep is like prefix, but separates the parts with sep.
+func (g *grepper) prefixSep(line int, sep byte) {
+	if g.opts.withName > 0 {
+		g.out.WriteString(g.name)
+		if g.opts.null {
+			g.out.WriteByte(0)
+		} else {
+			g.out.WriteByte(sep)
+		}
+	}
+	if line > 0 && g.opts.lineNumber {
+		g.out.WriteString(strconv.Itoa(line))
+		g.out.WriteByte(sep)
+	}
+}
+
+// matches iterates over the matches in subject, calling fn with the
+// offsets of the selected group.  It stops when fn returns false.
+func (g *grepper) matches(subject []byte, fn func(start, end int) bool) error {
+	for pos := 0; pos <= len(subject); {
+		// Matching starts at pos in the whole subject, so that \b, ^
+		// and lookbehind assertions see the text before it.
+		rc := g.m.ExecRange(subject, pos, len(subject), 0)
+		if rc == pcre.ERROR_NOMATCH {
+			return nil
+		}
+		if rc < 0 {
+			return execError(rc)
+		}
+		loc := g.m.Index()
+		if group := g.m.GroupIndices(g.opts.group); group != nil &&
+			!fn(group[0], group[1]) {
+			return nil
+		}
+		pos = loc[1]
+		if loc[0] == loc[1] {
+			// Continue one character past an empty match; with -u,
+			// a character may span several bytes.
+			n := 1
+			if g.opts.utf8 && pos < len(subject) {
+				_, n = utf8.DecodeRune(subject[pos:])
+			}
+			pos += n
+		}
+	}
+	return nil
+}
+
+// execError describes an error code returned by ExecRange.
+func execError(rc int) error {
+	switch rc {
+	case pcre.ERROR_BADUTF8:
+		return errors.New("invalid UTF-8")
+	case pcre.ERROR_MATCHLIMIT, pcre.ERROR_RECURSIONLIMIT:
+		return errors.New("match limit exceeded")
+	}
+	return fmt.Errorf("match error %d", rc)
+}
+
+func (g *grepper) grepLines(r io.Reader) error {
+	br := bufio.NewReader(r)
+	for n := 1; ; n++ {
+		line, err := br.ReadBytes('\n')
+		if len(line) == 0 && err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		line = bytes.TrimSuffix(line, []byte{'\n'})
+		if err := g.grepLine(n, line); err != nil {
+			return err
+		}
+	}
+}
+
+func (g *grepper) grepLine(n int, line []byte) error {
+	if g.opts.only && !g.opts.invert {
+		selected := false
+		return g.matches(line, func(start, end int) bool {
+			if start == end {
+				return true
+			}
+			if !selected {
+				// -c counts lines, not matches.
+				selected = true
+				g.selected++
+			}
+			if g.quiet() {
+				return false
+			}
+			g.prefix(n)
+			g.out.Write(line[start:end])
+			g.out.WriteByte('\n')
+			return true
+		})
+	}
+	found := g.m.Match(line, 0)
+	if err := g.m.Err(); err != nil {
+		return err
+	}
+	if found == g.opts.invert {
+		return nil
+	}
+	g.selected++
+	if !g.quiet() {
+		g.prefix(n)
+		g.out.Write(line)
+		g.out.WriteByte('\n')
+	}
+	return nil
+}
+
+// grepContext prints the selected lines with the context lines around
+// them.
+func (g *grepper) grepContext(r io.Reader) error {
+	s := pcre.NewContextScanner(r, g.opts.re, pcre.ContextOptions{
+		Before: g.opts.before,
+		After:  g.opts.after,
+		Invert: g.opts.invert,
+	})
+	for s.Scan() {
+		if g.opts.grouped {
+			g.out.WriteString("--\n")
+		}
+		g.opts.grouped = true
+		for _, line := range s.Group() {
+			sep := byte('-')
+			if line.Selected {
+				g.selected++
+				sep = ':'
+			}
+			g.prefixSep(line.Number, sep)
+			g.out.Write(line.Text)
+			g.out.WriteByte('\n')
+		}
+	}
+	return s.Err()
+}
+
+// grepAll matches the whole input at once, so that matches can span
+// lines, and prints the lines containing each match.
+func (g *grepper) grepAll(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	printed := -1 // end of the last selected line
+	return g.matches(data, func(start, end int) bool {
+		first := max(bytes.LastIndexByte(data[:start], '\n')+1, printed+1)
+		lineEnd := end
+		if end > start && data[end-1] == '\n' {
+			lineEnd--
+		}
+		last := len(data)
+		if i := bytes.IndexByte(data[lineEnd:], '\n'); i >= 0 {
+			last = lineEnd + i
+		}
+		// With -o, as for single lines, empty matches select nothing.
+		selected := first <= last && !(g.opts.only && start == end)
+		if selected {
+			// -c counts the lines of the match not selected by an
+			// earlier one, not matches.
+			g.selected += 1 + bytes.Count(data[first:last], []byte{'\n'})
+			printed = last
+		}
+		switch {
+		case g.quiet():
+		case g.opts.only:
+			if start < end {
+				g.prefix(1 + bytes.Count(data[:start], []byte{'\n'}))
+				g.out.Write(data[start:end])
+				g.out.WriteByte('\n')
+			}
+		case selected:
+			g.prefix(1 + bytes.Count(data[:first], []byte{'\n'}))
+			g.out.Write(data[first:last])
+			g.out.WriteByte('\n')
+		}
+		return true
+	})
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gijsbers/go-pcre"
+)
+
+func TestParseArgs(t *testing.T) {
+	opts := new(options)
+	args, err := parseArgs(opts, []string{"-rin", "-o2", "--include=*.go", "--exclude", "*_test.go", "-Z", "pat", "dir"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(args, []string{"pat", "dir"}) {
+		t.Error("args", args)
+	}
+	if !opts.recursive || !opts.ignoreCase || !opts.lineNumber || !opts.only ||
+		opts.group != 2 || !opts.null {
+		t.Errorf("unexpected options %+v", opts)
+	}
+	if !opts.selected("a.go") || opts.selected("a_test.go") || opts.selected("a.c") {
+		t.Error("include/exclude")
+	}
+	if _, err := parseArgs(new(options), []string{"-q"}); err == nil {
+		t.Error("expected error for unknown option")
+	}
+	opts = new(options)
+	if _, err := parseArgs(opts, []string{"-nA", "2", "-B1", "pat"}); err != nil || opts.after != 2 || opts.before != 1 {
+		t.Errorf("context options %+v, %v", opts, err)
+	}
+	if _, err := parseArgs(opts, []string{"-C3"}); err != nil || opts.after != 3 || opts.before != 3 {
+		t.Errorf("-C %+v, %v", opts, err)
+	}
+	if _, err := parseArgs(new(options), []string{"-A"}); err == nil {
+		t.Error("expected error for -A without number")
+	}
+	if args, _ := parseArgs(new(options), []string{"--", "-v"}); len(args) != 1 || args[0] != "-v" {
+		t.Error("args after --", args)
+	}
+}
+
+func grepString(opts *options, pattern string, flags int, input string) string {
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+	opts.re = pcre.MustCompile(pattern, flags)
+	opts.grepFile(out, "f", strings.NewReader(input))
+	out.Flush()
+	return buf.String()
+}
+
+func TestGrep(t *testing.T) {
+	const input = "foo=1\nbar=2\nfoo=3, foo=4\n"
+	var check = func(opts *options, pattern string, flags int, want string) {
+		t.Helper()
+		if got := grepString(opts, pattern, flags, input); got != want {
+			t.Errorf("%s: expected %q, got %q", pattern, want, got)
+		}
+	}
+	check(&options{}, "foo", 0, "foo=1\nfoo=3, foo=4\n")
+	check(&options{lineNumber: true, withName: 1}, "bar", 0, "f:2:bar=2\n")
+	check(&options{invert: true}, "foo", 0, "bar=2\n")
+	check(&options{only: true, group: 1}, `foo=(\d)`, 0, "1\n3\n4\n")
+	check(&options{only: true}, `o*`, 0, "oo\noo\noo\n")
+	check(&options{count: true}, "foo", 0, "2\n")
+	check(&options{filesOnly: true, null: true}, "bar", 0, "f\x00")
+	check(&options{withName: 1, null: true}, "bar", 0, "f\x00bar=2\n")
+	check(&options{multiline: true, lineNumber: true}, `1\nbar`, pcre.MULTILINE, "1:foo=1\nbar=2\n")
+	check(&options{multiline: true}, `^foo`, pcre.MULTILINE, "foo=1\nfoo=3, foo=4\n")
+	check(&options{multiline: true, only: true}, `\d$`, pcre.MULTILINE, "1\n2\n4\n")
+	check(&options{only: true}, `\b\w`, 0, "f\n1\nb\n2\nf\n3\nf\n4\n")
+	check(&options{only: true}, `(?<=, )\w+`, 0, "foo\n")
+	check(&options{only: true, count: true}, "foo", 0, "2\n")
+	check(&options{multiline: true, count: true}, "foo", pcre.MULTILINE, "2\n")
+	check(&options{multiline: true, only: true, count: true}, `\d\n`, pcre.MULTILINE, "3\n")
+	if got := grepString(&options{only: true, utf8: true}, `x*|b`, pcre.UTF8, "äb\n"); got != "b\n" {
+		t.Errorf("empty matches in UTF-8 mode: %q", got)
+	}
+}
+
+func TestGrepContext(t *testing.T) {
+	const input = "a\nb\nmatch 1\nc\nd\ne\nmatch 2\nf\n"
+	opts := &options{before: 1, after: 1, lineNumber: true, withName: 1}
+	want := "f-2-b\nf:3:match 1\nf-4-c\n--\nf-6-e\nf:7:match 2\nf-8-f\n"
+	if got := grepString(opts, "match", 0, input); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	opts = &options{before: 2, count: true}
+	if got := grepString(opts, "match", 0, input); got != "2\n" {
+		t.Errorf("count with context: %q", got)
+	}
+}
@@ -0,0 +1,112 @@
+package pcre
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// PatternSpec is a pattern to compile with CompileAll.
+type PatternSpec struct {
+	Name       string // Identifies the pattern in errors, may be empty
+	Pattern    string
+	Flags      int  // Compile flags
+	Study      bool // Whether to study the compiled pattern
+	StudyFlags int  // Study flags, see Study
+}
+
+// PatternError reports a pattern which CompileAll could not compile
+// or study.
+type PatternError struct {
+	Index int    // Index of the pattern in the specs
+	Name  string // Name of the pattern
+	Err   error  // The *CompileError, or the error of Study
+}
+
+// Error converts a pattern error to a string.
+func (e *PatternError) Error() string {
+	s := "pattern " + strconv.Itoa(e.Index)
+	if e.Name != "" {
+		s += " (" + e.Name + ")"
+	}
+	return s + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *PatternError) Unwrap() error {
+	return e.Err
+}
+
+// CompileErrors is the error returned by CompileAll, with one
+// *PatternError per failed pattern, in the order of the specs.
+type CompileErrors []*PatternError
+
+// Error converts the errors to a string, one line per pattern.
+func (e CompileErrors) Error() string {
+	lines := make([]string, len(e))
+	for i, err := range e {
+		lines[i] = err.Error()
+	}
+	return "pcre.CompileAll: " + strconv.Itoa(len(e)) + " patterns failed:\n" +
+		strings.Join(lines, "\n")
+}
+
+// Unwrap returns the pattern errors, so that errors.Is and errors.As
+// examine each of them.
+func (e CompileErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// CompileAll compiles, and studies if requested, the patterns of
+// specs, in parallel on up to GOMAXPROCS goroutines.  It returns the
+// Regexps in the order of the specs.  Rather than stop at the first
+// failure, it tries every pattern and returns a CompileErrors listing
+// all that failed; their Regexps are nil, while the others can still
+// be used or freed.
+func CompileAll(specs []PatternSpec) ([]*Regexp, error) {
+	res := make([]*Regexp, len(specs))
+	errs := make([]error, len(specs))
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for w := min(runtime.GOMAXPROCS(0), len(specs)); w > 0; w-- {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := int(next.Add(1) - 1); i < len(specs); i = int(next.Add(1) - 1) {
+				res[i], errs[i] = compileSpec(specs[i])
+			}
+		}()
+	}
+	wg.Wait()
+	var failed CompileErrors
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, &PatternError{Index: i, Name: specs[i].Name, Err: err})
+		}
+	}
+	if failed != nil {
+		return res, failed
+	}
+	return res, nil
+}
+
+// compileSpec compiles and studies a pattern of CompileAll.
+func compileSpec(spec PatternSpec) (*Regexp, error) {
+	re, err := Compile(spec.Pattern, spec.Flags)
+	if err != nil {
+		return nil, err
+	}
+	if spec.Study {
+		if err := re.Study(spec.StudyFlags); err != nil {
+			re.FreeRegexp()
+			return nil, err
+		}
+	}
+	return re, nil
+}
@@ -0,0 +1,45 @@
+package pcre
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCompileAll(t *testing.T) {
+	specs := []PatternSpec{
+		{Name: "digits", Pattern: `\d+`, Study: true},
+		{Name: "open", Pattern: `(a`},
+		{Pattern: `b`, Flags: CASELESS},
+		{Pattern: `c[`},
+	}
+	res, err := CompileAll(specs)
+	var errs CompileErrors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("expected 2 pattern errors, got %v", err)
+	}
+	if errs[0].Index != 1 || errs[0].Name != "open" || errs[1].Index != 3 {
+		t.Errorf("unexpected errors %v", err)
+	}
+	if !strings.Contains(err.Error(), "pattern 1 (open): (a (2): missing )") {
+		t.Errorf("unexpected message %q", err)
+	}
+	var cerr *CompileError
+	if !errors.As(err, &cerr) || cerr.Pattern != "(a" || !IsSyntax(err) {
+		t.Errorf("compile error not found in %v", err)
+	}
+	if len(res) != 4 || res[1] != nil || res[3] != nil {
+		t.Fatalf("unexpected regexps %v", res)
+	}
+	defer res[0].FreeRegexp()
+	defer res[2].FreeRegexp()
+	if !res[0].StudyInfo().Studied || !res[2].MatcherString("B", 0).Matches() {
+		t.Error("patterns not studied or compiled with their flags")
+	}
+
+	res, err = CompileAll(specs[2:3])
+	if err != nil || len(res) != 1 {
+		t.Fatal(err)
+	}
+	res[0].FreeRegexp()
+}
@@ -0,0 +1,181 @@
+package pcre
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Categories of compile errors, matched by errors.Is on a
+// *CompileError.  See IsSyntax, IsUnsupported and IsTooLarge.
+var (
+	ErrSyntax          = errors.New("pcre: syntax error in pattern")
+	ErrUnsupported     = errors.New("pcre: pattern uses an unsupported feature")
+	ErrPatternTooLarge = errors.New("pcre: pattern exceeds a size limit")
+)
+
+// PCRE error numbers used for errors detected by the package.
+const (
+	errUnknownOption = 17
+	errTooLarge      = 20
+)
+
+// unsupportedCodes are the PCRE error numbers of valid patterns that
+// the library or its build does not support.
+var unsupportedCodes = map[int]bool{
+	25:               true, // lookbehind assertion is not fixed length
+	31:               true, // POSIX collating elements are not supported
+	32:               true, // compiled without UTF support
+	36:               true, // \C not allowed in lookbehind assertion
+	37:               true, // PCRE does not support \L, \l, \N{name}, \U, or \u
+	45:               true, // support for \P, \p, and \X has not been compiled
+	67:               true, // not compiled with Unicode property support
+	71:               true, // \N is not supported in a class
+	78:               true, // setting UTF is disabled by the application
+	errUnknownOption: true,
+}
+
+// tooLargeCodes are the PCRE error numbers of patterns that exceed a
+// limit of the library.
+var tooLargeCodes = map[int]bool{
+	errTooLarge: true, // regular expression is too large
+	21:          true, // failed to get memory
+	23:          true, // internal error: code overflow
+	48:          true, // subpattern name is too long
+	49:          true, // too many named subpatterns
+	52:          true, // internal error: overran compiling workspace
+	72:          true, // too many forward references
+	75:          true, // name is too long in (*MARK), (*PRUNE), (*SKIP), or (*THEN)
+	82:          true, // parentheses are too deeply nested
+	85:          true, // parentheses are too deeply nested (stack check)
+}
+
+// category returns the sentinel error of the category of e.
+func (e *CompileError) category() error {
+	switch {
+	case unsupportedCodes[e.Code]:
+		return ErrUnsupported
+	case tooLargeCodes[e.Code]:
+		return ErrPatternTooLarge
+	}
+	return ErrSyntax
+}
+
+// Is reports whether target is a *CompileError with the same code,
+// or errors.ErrUnsupported for an error in that category.
+func (e *CompileError) Is(target error) bool {
+	if t, ok := target.(*CompileError); ok {
+		return t.Code == e.Code
+	}
+	return target == errors.ErrUnsupported && e.category() == ErrUnsupported
+}
+
+// Unwrap returns the category of the error: ErrSyntax,
+// ErrUnsupported or ErrPatternTooLarge.
+func (e *CompileError) Unwrap() error {
+	return e.category()
+}
+
+// IsSyntax reports whether err is or wraps a *CompileError for a
+// pattern which is not valid PCRE syntax.
+func IsSyntax(err error) bool {
+	return errors.Is(err, ErrSyntax)
+}
+
+// IsUnsupported reports whether err is or wraps a *CompileError for a
+// pattern using a feature which this build of PCRE does not support,
+// such as a lookbehind assertion of variable length.
+func IsUnsupported(err error) bool {
+	return errors.Is(err, ErrUnsupported)
+}
+
+// IsTooLarge reports whether err is or wraps a *CompileError for a
+// pattern exceeding a limit, such as the size of the compiled pattern
+// or the nesting depth of parentheses.
+func IsTooLarge(err error) bool {
+	return errors.Is(err, ErrPatternTooLarge)
+}
+
+// compileHints are short explanations of common compile errors, by
+// PCRE error number.
+var compileHints = map[int]string{
+	1:  `escape the backslash as \\ to match it literally`,
+	6:  `a character class opened with [ must be closed with ]; escape [ as \[ to match it`,
+	9:  `a quantifier such as *, + or ? must follow something to repeat; escape it to match it literally`,
+	12: `only the group syntax documented in pcrepattern may follow (?`,
+	14: `every ( needs a matching ); escape ( as \( to match it`,
+	15: `the pattern refers to a group it does not define`,
+	22: `every ) needs a matching (; escape ) as \) to match it`,
+	25: `each alternative of a lookbehind assertion must match a fixed number of characters`,
+	37: `use (?i) or a character class instead of case-changing escapes`,
+	43: `group names must be unique unless DUPNAMES is set`,
+	82: `nest fewer groups inside each other`,
+}
+
+// maxDetailWidth is the width in characters of the pattern excerpt
+// shown by Detail.
+const maxDetailWidth = 72
+
+// Detail describes the error on several lines, for display to the
+// author of the pattern: the message with the position of the error,
+// the line of the pattern containing it, a caret under the error, and
+// for common errors a hint on how to fix it.  Of a long line, only the
+// part around the error is shown.
+func (e *CompileError) Detail() string {
+	offset := min(max(e.Offset, 0), len(e.Pattern))
+	start := strings.LastIndexByte(e.Pattern[:offset], '\n') + 1
+	end := strings.IndexByte(e.Pattern[offset:], '\n')
+	if end < 0 {
+		end = len(e.Pattern)
+	} else {
+		end += offset
+	}
+	line := []rune(e.Pattern[start:end])
+	col := utf8.RuneCountInString(e.Pattern[start:offset])
+
+	var b strings.Builder
+	b.WriteString(e.Message)
+	if start == 0 && end == len(e.Pattern) {
+		fmt.Fprintf(&b, " at offset %d\n", e.Offset)
+	} else {
+		n := strings.Count(e.Pattern[:start], "\n") + 1
+		fmt.Fprintf(&b, " at line %d, column %d\n", n, col+1)
+	}
+
+	// Cut a long line down to a window around the error.
+	prefix, suffix := "", ""
+	if len(line) > maxDetailWidth {
+		from := min(max(col-maxDetailWidth/2, 0), len(line)-maxDetailWidth)
+		if from > 0 {
+			prefix = "..."
+		}
+		if from+maxDetailWidth < len(line) {
+			suffix = "..."
+		}
+		line = line[from : from+maxDetailWidth]
+		col -= from
+	}
+	b.WriteString("    " + prefix)
+	for _, r := range line {
+		if r != '\t' && !unicode.IsPrint(r) {
+			r = '?'
+		}
+		b.WriteRune(r)
+	}
+	b.WriteString(suffix + "\n    " + strings.Repeat(" ", len(prefix)))
+	// Repeat tabs, so that the caret lines up however they are shown.
+	for _, r := range line[:col] {
+		if r == '\t' {
+			b.WriteByte('\t')
+		} else {
+			b.WriteByte(' ')
+		}
+	}
+	b.WriteString("^\n")
+	if hint, ok := compileHints[e.Code]; ok {
+		b.WriteString("hint: " + hint + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,90 @@
+package pcre
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCompileErrorCategories(t *testing.T) {
+	deep := strings.Repeat("(", 1000) + strings.Repeat(")", 1000)
+	for _, test := range []struct {
+		pattern                       string
+		code                          int
+		syntax, unsupported, tooLarge bool
+	}{
+		{"(", 14, true, false, false},
+		{"a\\", 1, true, false, false},
+		{"(?<=a+)b", 25, false, true, false},
+		{deep, 82, false, false, true},
+	} {
+		_, err := Compile(test.pattern, 0)
+		var cerr *CompileError
+		if !errors.As(err, &cerr) {
+			t.Errorf("%.20q: expected *CompileError, got %v", test.pattern, err)
+			continue
+		}
+		if cerr.Code != test.code {
+			t.Errorf("%.20q: code %d, expected %d", test.pattern, cerr.Code, test.code)
+		}
+		// Classification sees through wrapping.
+		err = fmt.Errorf("rule 3: %w", err)
+		if IsSyntax(err) != test.syntax || IsUnsupported(err) != test.unsupported ||
+			IsTooLarge(err) != test.tooLarge {
+			t.Errorf("%.20q: syntax %v, unsupported %v, too large %v", test.pattern,
+				IsSyntax(err), IsUnsupported(err), IsTooLarge(err))
+		}
+		if !errors.Is(err, &CompileError{Code: test.code}) {
+			t.Errorf("%.20q: errors.Is does not match the code", test.pattern)
+		}
+	}
+	_, err := Compile("(?<=a|bc)d", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Compile("(?<=a*)d", 0)
+	if !errors.Is(err, errors.ErrUnsupported) {
+		t.Error("errors.ErrUnsupported does not match", err)
+	}
+	_, err = UntrustedLimits{MaxPatternLen: 2}.Compile("abc", 0)
+	if !IsTooLarge(err) {
+		t.Error("overlong untrusted pattern not too large", err)
+	}
+}
+
+func TestCompileErrorDetail(t *testing.T) {
+	long := strings.Repeat("a", 100) + "(" + strings.Repeat("b", 100)
+	for _, test := range []struct {
+		err  CompileError
+		want string
+	}{
+		{CompileError{Pattern: "ab(c", Message: "missing )", Offset: 4, Code: 14},
+			"missing ) at offset 4\n" +
+				"    ab(c\n" +
+				"        ^\n" +
+				"hint: every ( needs a matching ); escape ( as \\( to match it\n"},
+		{CompileError{Pattern: "a\n\tb)\nc", Message: "unmatched parentheses", Offset: 4, Code: 22},
+			"unmatched parentheses at line 2, column 3\n" +
+				"    \tb)\n" +
+				"    \t ^\n" +
+				"hint: every ) needs a matching (; escape ) as \\) to match it\n"},
+		{CompileError{Pattern: "ä*+*", Message: "nothing to repeat", Offset: 4},
+			"nothing to repeat at offset 4\n" +
+				"    ä*+*\n" +
+				"       ^\n"},
+		{CompileError{Pattern: long, Message: "missing )", Offset: len(long), Code: 14},
+			"missing ) at offset 201\n" +
+				"    ..." + strings.Repeat("b", 72) + "\n" +
+				"       " + strings.Repeat(" ", 72) + "^\n" +
+				"hint: every ( needs a matching ); escape ( as \\( to match it\n"},
+		{CompileError{Pattern: long, Message: "error", Offset: 101},
+			"error at offset 101\n" +
+				"    ..." + strings.Repeat("a", 35) + "(" + strings.Repeat("b", 36) + "...\n" +
+				"       " + strings.Repeat(" ", 36) + "^\n"},
+	} {
+		if got := test.err.Detail(); got != test.want {
+			t.Errorf("Detail of %.20q:\n%s\nexpected:\n%s", test.err.Pattern, got, test.want)
+		}
+	}
+}
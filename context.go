@@ -0,0 +1,128 @@
+package pcre
+
+import (
+	"bufio"
+	"io"
+)
+
+// ContextOptions configures a ContextScanner, like the -B, -A and -v
+// options of grep.
+type ContextOptions struct {
+	Before int  // Lines of context before each selected line
+	After  int  // Lines of context after each selected line
+	Invert bool // Select the lines which do not match
+}
+
+// ContextLine is a line returned by a ContextScanner.
+type ContextLine struct {
+	Number   int    // Line number, starting at 1
+	Text     []byte // The line without its line ending
+	Selected bool   // Whether the line was selected, or is context
+}
+
+// ContextScanner reads lines and groups the selected ones with their
+// context lines, like grep -B, -A and -C.  Groups whose context
+// overlaps or touches are merged, so each line belongs to at most one
+// group; grep separates groups with "--".
+//
+// Use it like bufio.Scanner: call Scan until it returns false, then
+// check Err.
+type ContextScanner struct {
+	r      *bufio.Reader
+	m      *Matcher
+	opts   ContextOptions
+	number int           // number of the last line read
+	ring   []ContextLine // unselected lines that may precede a group
+	open   []ContextLine // the group being collected
+	after  int           // context lines still to add to open
+	gap    int           // lines read since the end of open
+	group  []ContextLine
+	err    error
+	done   bool
+}
+
+// NewContextScanner returns a ContextScanner reading lines from r and
+// selecting those that re matches.  Lines are matched without their
+// line endings.
+func NewContextScanner(r io.Reader, re *Regexp, opts ContextOptions) *ContextScanner {
+	return &ContextScanner{
+		r:    bufio.NewReader(r),
+		m:    re.NewMatcher(),
+		opts: opts,
+	}
+}
+
+// Scan advances to the next group of lines, which is then available
+// through Group.  It returns false at the end of the input or on an
+// error.
+func (s *ContextScanner) Scan() bool {
+	for !s.done {
+		line, err := s.r.ReadBytes('\n')
+		if len(line) > 0 {
+			s.number++
+			text := trimLineEnding(line)
+			found := s.m.Match(text, 0)
+			if err := s.m.Err(); err != nil {
+				s.err, s.done = err, true
+				return false
+			}
+			l := ContextLine{Number: s.number, Text: text, Selected: found != s.opts.Invert}
+			if s.add(l) {
+				return true
+			}
+		}
+		if err != nil {
+			if err != io.EOF {
+				s.err = err
+			}
+			s.done = true
+		}
+	}
+	if len(s.open) == 0 || s.err != nil {
+		return false
+	}
+	s.group, s.open = s.open, nil
+	return true
+}
+
+// add adds a line to the open group or the lines before it, and
+// reports whether that completed a group.
+func (s *ContextScanner) add(l ContextLine) bool {
+	switch {
+	case l.Selected:
+		// The lines since the end of the open group are all in the
+		// ring, or the group would have been completed.
+		s.open = append(s.open, s.ring...)
+		s.open = append(s.open, l)
+		s.ring = s.ring[:0]
+		s.after, s.gap = s.opts.After, 0
+	case len(s.open) > 0 && s.after > 0:
+		s.open = append(s.open, l)
+		s.after--
+	default:
+		s.ring = append(s.ring, l)
+		if len(s.ring) > s.opts.Before {
+			copy(s.ring, s.ring[1:])
+			s.ring = s.ring[:len(s.ring)-1]
+		}
+		if len(s.open) > 0 {
+			if s.gap++; s.gap > s.opts.Before {
+				s.group, s.open = s.open, nil
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Group returns the lines of the group found by the last call to
+// Scan, in order.  The slice is not reused by later calls.
+func (s *ContextScanner) Group() []ContextLine {
+	return s.group
+}
+
+// Err returns the first error other than io.EOF encountered while
+// reading or matching.
+func (s *ContextScanner) Err() error {
+	return s.err
+}
@@ -0,0 +1,52 @@
+package pcre
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestContextScanner(t *testing.T) {
+	input := "1\n2 x\n3\n4\n5\n6 x\n7\n8\n9\n10\n11 x\n12 x\n"
+	re := MustCompile(`x`, 0)
+	defer re.FreeRegexp()
+	for _, tc := range []struct {
+		opts ContextOptions
+		want string
+	}{
+		{ContextOptions{}, "2 x|6 x|11 x,12 x"},
+		{ContextOptions{Before: 1, After: 1}, "1,2 x,3|5,6 x,7|10,11 x,12 x"},
+		{ContextOptions{After: 2}, "2 x,3,4|6 x,7,8|11 x,12 x"},
+		{ContextOptions{Before: 2, After: 1}, "1,2 x,3,4,5,6 x,7|9,10,11 x,12 x"},
+		{ContextOptions{Before: 3}, "1,2 x,3,4,5,6 x|8,9,10,11 x,12 x"},
+		{ContextOptions{Before: 2}, "1,2 x|4,5,6 x|9,10,11 x,12 x"},
+		{ContextOptions{Invert: true}, "1|3,4,5|7,8,9,10"},
+	} {
+		s := NewContextScanner(strings.NewReader(input), re, tc.opts)
+		var groups []string
+		for s.Scan() {
+			var lines []string
+			for _, l := range s.Group() {
+				if l.Selected != (strings.Contains(string(l.Text), "x") != tc.opts.Invert) {
+					t.Errorf("%+v: line %d selected %v", tc.opts, l.Number, l.Selected)
+				}
+				if l.Number != atoi(string(l.Text)) {
+					t.Errorf("%+v: line %q numbered %d", tc.opts, l.Text, l.Number)
+				}
+				lines = append(lines, string(l.Text))
+			}
+			groups = append(groups, strings.Join(lines, ","))
+		}
+		if err := s.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(groups, "|"); got != tc.want {
+			t.Errorf("%+v: expected %s, got %s", tc.opts, tc.want, got)
+		}
+	}
+}
+
+func atoi(s string) (n int) {
+	fmt.Sscan(s, &n)
+	return
+}
@@ -0,0 +1,139 @@
+package pcre
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync/atomic"
+)
+
+// DatabaseEntry is a pattern of a Database, with the ID reported for
+// its matches.
+type DatabaseEntry struct {
+	ID      int
+	Pattern string
+	Flags   int
+}
+
+// Database is a set of patterns which are compiled once and scanned
+// together, in the style of Hyperscan.  Scanning needs a Scratch
+// allocated for the Database; a Database may be scanned by several
+// goroutines at once, each with its own Scratch.
+type Database struct {
+	entries []DatabaseEntry
+	res     []*Regexp
+}
+
+// Scratch holds the match state of one Scan at a time.  Allocate one
+// per goroutine with NewScratch and reuse it across scans.
+type Scratch struct {
+	db       *Database
+	matchers []*Matcher
+	events   []scanEvent
+	inUse    atomic.Bool
+}
+
+type scanEvent struct {
+	entry, from, to int
+}
+
+// NewDatabase compiles and studies the patterns of entries.  If one
+// fails to compile, the error names its ID and wraps the
+// *CompileError.
+func NewDatabase(entries []DatabaseEntry) (*Database, error) {
+	db := &Database{entries: append([]DatabaseEntry(nil), entries...)}
+	for _, e := range db.entries {
+		re, err := Compile(e.Pattern, e.Flags)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("pcre.NewDatabase: id %d: %w", e.ID, err)
+		}
+		re.Study(0)
+		db.res = append(db.res, re)
+	}
+	return db, nil
+}
+
+// Len returns the number of patterns in the Database.
+func (db *Database) Len() int {
+	return len(db.entries)
+}
+
+// NewScratch allocates match state for scanning the Database.
+func (db *Database) NewScratch() *Scratch {
+	s := &Scratch{db: db, matchers: make([]*Matcher, len(db.res))}
+	for i, re := range db.res {
+		s.matchers[i] = re.NewMatcher()
+	}
+	return s
+}
+
+// Scan reports every match of the patterns in subject to onMatch,
+// with the ID of the pattern and the byte offsets of the match.  The
+// matches of a pattern do not overlap, but those of different
+// patterns may.  Matches are reported in order of their end offset,
+// then the order of the entries, then their start offset.
+//
+// Patterns whose match fails with an error, such as an exceeded
+// match limit, report the matches found until then; their errors are
+// returned, joined.
+func (db *Database) Scan(subject []byte, scratch *Scratch, onMatch func(id, from, to int)) error {
+	if scratch == nil || scratch.db != db {
+		return errors.New("Database.Scan: scratch not allocated for this database")
+	}
+	if !scratch.inUse.CompareAndSwap(false, true) {
+		return errors.New("Database.Scan: scratch in use")
+	}
+	defer scratch.inUse.Store(false)
+	events := scratch.events[:0]
+	var errs []error
+	for i, m := range scratch.matchers {
+		var err error
+		if events, err = scanEntry(m, subject, i, events); err != nil {
+			errs = append(errs, fmt.Errorf("Database.Scan: id %d: %w", db.entries[i].ID, err))
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		a, b := events[i], events[j]
+		if a.to != b.to {
+			return a.to < b.to
+		}
+		if a.entry != b.entry {
+			return a.entry < b.entry
+		}
+		return a.from < b.from
+	})
+	scratch.events = events
+	for _, e := range events {
+		onMatch(db.entries[e.entry].ID, e.from, e.to)
+	}
+	return errors.Join(errs...)
+}
+
+// ScanString is equivalent to Scan with a string subject.
+func (db *Database) ScanString(subject string, scratch *Scratch, onMatch func(id, from, to int)) error {
+	return db.Scan([]byte(subject), scratch, onMatch)
+}
+
+// scanEntry appends the matches of the pattern of m in subject to
+// events.  Matching continues at an offset in the whole subject, so
+// that \b, ^ and lookbehind assertions see the text before it.
+func scanEntry(m *Matcher, subject []byte, entry int, events []scanEvent) ([]scanEvent, error) {
+	m.Init(m.re)
+	s := bytesString(subject)
+	err := forEachMatchString(m, s, 0, 0, func() bool {
+		events = append(events, scanEvent{entry, int(m.ovector[0]), int(m.ovector[1])})
+		return true
+	})
+	m.subjects = ""
+	return events, err
+}
+
+// Close frees the compiled patterns.  The Database and its Scratch
+// objects must not be in use.
+func (db *Database) Close() {
+	for _, re := range db.res {
+		re.FreeRegexp()
+	}
+	db.res = nil
+}
@@ -0,0 +1,106 @@
+package pcre
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestDatabase(t *testing.T) {
+	db, err := NewDatabase([]DatabaseEntry{
+		{ID: 10, Pattern: `fo+`},
+		{ID: 20, Pattern: `o`},
+		{ID: 30, Pattern: `^b`, Flags: MULTILINE},
+		{ID: 40, Pattern: `x*`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if db.Len() != 4 {
+		t.Error("Len", db.Len())
+	}
+	scratch := db.NewScratch()
+	var got [][3]int
+	onMatch := func(id, from, to int) {
+		got = append(got, [3]int{id, from, to})
+	}
+	if err := db.ScanString("foo\nbo", scratch, onMatch); err != nil {
+		t.Fatal(err)
+	}
+	want := [][3]int{
+		{40, 0, 0},
+		{40, 1, 1},
+		{20, 1, 2},
+		{40, 2, 2},
+		{10, 0, 3},
+		{20, 2, 3},
+		{40, 3, 3},
+		{40, 4, 4},
+		{30, 4, 5},
+		{40, 5, 5},
+		{20, 5, 6},
+		{40, 6, 6},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	// Scratch objects are reused, one per goroutine.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s := db.NewScratch()
+			for j := 0; j < 100; j++ {
+				n := 0
+				if err := db.ScanString("fooo", s, func(id, from, to int) { n++ }); err != nil || n != 9 {
+					t.Errorf("unexpected %d matches, %v", n, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	other, err := NewDatabase(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	if err := db.ScanString("foo", other.NewScratch(), onMatch); err == nil {
+		t.Error("expected error for scratch of another database")
+	}
+}
+
+func TestDatabaseCompileError(t *testing.T) {
+	_, err := NewDatabase([]DatabaseEntry{{ID: 1, Pattern: `a`}, {ID: 2, Pattern: `a(`}})
+	var cerr *CompileError
+	if !errors.As(err, &cerr) || cerr.Pattern != "a(" {
+		t.Errorf("expected CompileError, got %v", err)
+	}
+}
+
+func TestDatabaseContext(t *testing.T) {
+	db, err := NewDatabase([]DatabaseEntry{
+		{ID: 1, Pattern: `\bx`},
+		{ID: 2, Pattern: `(?<=a)b`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var got [][3]int
+	err = db.ScanString("xx ab", db.NewScratch(), func(id, from, to int) {
+		got = append(got, [3]int{id, from, to})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][3]int{{1, 0, 1}, {2, 4, 5}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
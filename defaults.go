@@ -0,0 +1,42 @@
+package pcre
+
+import "sync/atomic"
+
+var defaultCompileFlags, defaultExecFlags atomic.Int64
+
+// SetDefaultCompileFlags sets flags which Compile adds to the flags of
+// every pattern, and through it the other functions compiling
+// patterns, so that a program can impose settings such as UTF8|UCP
+// without passing them at each call.  Call it at startup, before
+// patterns are compiled; patterns compiled earlier keep their flags.
+// Flags which do not suit every pattern, such as NO_AUTO_CAPTURE for
+// code which relies on numbered groups, may break functions of the
+// package; CompileMulti rejects NO_AUTO_CAPTURE as usual.
+func SetDefaultCompileFlags(flags int) {
+	defaultCompileFlags.Store(int64(flags))
+}
+
+// DefaultCompileFlags returns the flags set by SetDefaultCompileFlags.
+func DefaultCompileFlags() int {
+	return int(defaultCompileFlags.Load())
+}
+
+// SetDefaultExecFlags sets flags which are added to the flags of every
+// match, by the Match and Exec methods, the DFA matcher, and the
+// functions of the package built on them.  Call it at startup, before
+// matching.  Only match flags are allowed; flags which change where a
+// match is found, such as ANCHORED or NOTEMPTY, also change how the
+// Find and Replace functions step through a subject, and are best
+// left to the call sites.
+func SetDefaultExecFlags(flags int) error {
+	if err := checkMatchFlags("pcre.SetDefaultExecFlags", flags); err != nil {
+		return err
+	}
+	defaultExecFlags.Store(int64(flags))
+	return nil
+}
+
+// DefaultExecFlags returns the flags set by SetDefaultExecFlags.
+func DefaultExecFlags() int {
+	return int(defaultExecFlags.Load())
+}
@@ -0,0 +1,29 @@
+package pcre
+
+import "testing"
+
+func TestDefaultFlags(t *testing.T) {
+	SetDefaultCompileFlags(CASELESS)
+	re := MustCompile("a", 0)
+	SetDefaultCompileFlags(0)
+	defer re.FreeRegexp()
+	if !re.MatcherString("A", 0).Matches() {
+		t.Error("default CASELESS not applied")
+	}
+	if DefaultCompileFlags() != 0 {
+		t.Error("DefaultCompileFlags not reset")
+	}
+
+	if err := SetDefaultExecFlags(NOTEMPTY); err != nil {
+		t.Fatal(err)
+	}
+	defer SetDefaultExecFlags(0)
+	re2 := MustCompile("x*", 0)
+	defer re2.FreeRegexp()
+	if re2.MatcherString("b", 0).Matches() {
+		t.Error("default NOTEMPTY not applied")
+	}
+	if err := SetDefaultExecFlags(CASELESS); err == nil || DefaultExecFlags() != NOTEMPTY {
+		t.Errorf("compile flag accepted: %v, flags %#x", err, DefaultExecFlags())
+	}
+}
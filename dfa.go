@@ -0,0 +1,187 @@
+package pcre
+
+// #include "./pcre.h"
+import "C"
+
+import (
+	"errors"
+	"strconv"
+	"unsafe"
+)
+
+// Flags for MatchDFA
+const (
+	DFA_SHORTEST = C.PCRE_DFA_SHORTEST // stop at the shortest match
+)
+
+// DFAMatch holds the result of MatchDFA: every match found at the
+// leftmost position where the pattern matches.
+type DFAMatch struct {
+	Start   int   // Start of the matches
+	Ends    []int // Ends of the matches, longest first
+	Partial bool  // Whether the subject ended in a partial match, see PARTIAL_SOFT
+}
+
+// Default initial and maximum sizes of the DFA workspace in bytes,
+// see SetDFAWorkspace.
+const (
+	DFA_WORKSPACE     = 4 << 10
+	DFA_MAX_WORKSPACE = 16 << 20
+)
+
+// Initial and maximum numbers of match ends retrieved.
+const (
+	dfaEnds    = 16
+	dfaMaxEnds = 1 << 16
+)
+
+// ErrDFAWorkspace is reported by MatchDFA when the workspace needed
+// exceeds the maximum set by SetDFAWorkspace.
+var ErrDFAWorkspace = errors.New("pcre: DFA workspace limit exceeded")
+
+// SetDFAWorkspace sets the initial and maximum size in bytes of the
+// workspace of MatchDFA, which holds the paths through the pattern
+// followed at once.  The workspace starts at the initial size and is
+// doubled whenever pcre_dfa_exec runs out of it, up to the maximum; a
+// Regexp remembers the size it needed for later matches.  Zero sizes
+// select DFA_WORKSPACE and DFA_MAX_WORKSPACE.  Call SetDFAWorkspace
+// before the Regexp is used concurrently.
+func (re *Regexp) SetDFAWorkspace(initial, limit int) {
+	if re == nil {
+		uninitialized("Regexp.SetDFAWorkspace")
+		return
+	}
+	re.dfaInitial, re.dfaMax = initial, limit
+	re.dfaNeeded.Store(0)
+}
+
+// dfaWorkspaceSizes returns the initial and maximum workspace sizes in
+// ints.
+func (re *Regexp) dfaWorkspaceSizes() (initial, limit int) {
+	initial, limit = re.dfaInitial, re.dfaMax
+	if initial <= 0 {
+		initial = DFA_WORKSPACE
+	}
+	if limit <= 0 {
+		limit = DFA_MAX_WORKSPACE
+	}
+	const intSize = int(unsafe.Sizeof(C.int(0)))
+	// pcre_dfa_exec needs at least 20 ints.
+	initial, limit = max(initial/intSize, 20), max(limit/intSize, 20)
+	if needed := int(re.dfaNeeded.Load()); needed > initial {
+		initial = needed
+	}
+	return min(initial, limit), limit
+}
+
+// MatchDFA matches the pattern against subject with the alternative
+// algorithm of pcre_dfa_exec, which follows all paths through the
+// pattern at once.  Rather than the first match of the usual
+// backtracking algorithm, it finds all matches starting at the
+// leftmost position where the pattern matches, such as every possible
+// end of a token, and returns their ends, longest first.  With
+// DFA_SHORTEST, it stops at the shortest match.  It returns nil if
+// there is no match.
+//
+// The DFA algorithm does not capture groups, and does not support
+// back references, conditions on groups and some other items; such
+// patterns fail with an error.  Match limits do not apply.
+func (re *Regexp) MatchDFA(subject []byte, flags int) (*DFAMatch, error) {
+	if !re.valid() {
+		return nil, uninitialized("Regexp.MatchDFA")
+	}
+	if uint64(len(subject)) > maxLength {
+		return nil, ErrSubjectTooLarge
+	}
+	if err := checkMatchFlags("Regexp.MatchDFA", flags&^DFA_SHORTEST); err != nil {
+		return nil, err
+	}
+	length := len(subject)
+	if length == 0 {
+		subject = nullbyte // make first character adressable
+	}
+	return re.dfaExec((*C.char)(unsafe.Pointer(&subject[0])), length, flags)
+}
+
+// MatchDFAString is equivalent to MatchDFA with a string subject.
+func (re *Regexp) MatchDFAString(subject string, flags int) (*DFAMatch, error) {
+	if !re.valid() {
+		return nil, uninitialized("Regexp.MatchDFAString")
+	}
+	if uint64(len(subject)) > maxLength {
+		return nil, ErrSubjectTooLarge
+	}
+	if err := checkMatchFlags("Regexp.MatchDFAString", flags&^DFA_SHORTEST); err != nil {
+		return nil, err
+	}
+	length := len(subject)
+	if length == 0 {
+		subject = "\000" // make first character addressable
+	}
+	return re.dfaExec((*C.char)(unsafe.Pointer(unsafe.StringData(subject))), length, flags)
+}
+
+// dfaExec runs pcre_dfa_exec, growing the workspace and the offsets
+// vector as needed.
+func (re *Regexp) dfaExec(subjectptr *C.char, length, flags int) (*DFAMatch, error) {
+	size, limit := re.dfaWorkspaceSizes()
+	workspace := make([]C.int, size)
+	ovector := make([]C.int, 2*dfaEnds)
+	for {
+		rc := re.dfaExec1(subjectptr, length, flags, ovector, workspace)
+		switch {
+		case rc == 0 && len(ovector) < 2*dfaMaxEnds:
+			// Not all matches fit; get them all.
+			ovector = make([]C.int, 2*len(ovector))
+			continue
+		case rc == C.PCRE_ERROR_DFA_WSSIZE && len(workspace) < limit:
+			workspace = make([]C.int, min(2*len(workspace), limit))
+			re.dfaNeeded.Store(int64(len(workspace)))
+			continue
+		case rc == 0:
+			rc = len(ovector) / 2
+		}
+		if rc < 0 {
+			return dfaError(rc, ovector)
+		}
+		m := &DFAMatch{Start: int(ovector[0]), Ends: make([]int, rc)}
+		for i := range m.Ends {
+			m.Ends[i] = int(ovector[2*i+1])
+		}
+		return m, nil
+	}
+}
+
+func (re *Regexp) dfaExec1(subjectptr *C.char, length, flags int, ovector, workspace []C.int) int {
+	re.mu.RLock()
+	defer re.mu.RUnlock()
+	if re.ptr == nil {
+		return C.PCRE_ERROR_NULL
+	}
+	return int(C.pcre_dfa_exec(re.ptr, re.extra, subjectptr, C.int(length), 0,
+		C.int(flags|DefaultExecFlags()), &ovector[0], C.int(len(ovector)),
+		&workspace[0], C.int(len(workspace))))
+}
+
+// dfaError returns the result or error of a failed pcre_dfa_exec.
+func dfaError(rc int, ovector []C.int) (*DFAMatch, error) {
+	switch rc {
+	case C.PCRE_ERROR_NOMATCH:
+		return nil, nil
+	case C.PCRE_ERROR_PARTIAL:
+		return &DFAMatch{Start: int(ovector[0]), Ends: []int{int(ovector[1])}, Partial: true}, nil
+	case C.PCRE_ERROR_NULL:
+		return nil, uninitialized("Regexp.MatchDFA")
+	case C.PCRE_ERROR_BADUTF8:
+		return nil, &UTF8Error{Offset: int(ovector[0]), Reason: int(ovector[1])}
+	case C.PCRE_ERROR_DFA_UITEM:
+		return nil, errors.New("Regexp.MatchDFA: pattern item not supported by the DFA algorithm")
+	case C.PCRE_ERROR_DFA_UCOND:
+		return nil, errors.New("Regexp.MatchDFA: condition not supported by the DFA algorithm")
+	case C.PCRE_ERROR_DFA_RECURSE:
+		return nil, errors.New("Regexp.MatchDFA: recursion too deep for the DFA algorithm")
+	case C.PCRE_ERROR_DFA_WSSIZE:
+		return nil, ErrDFAWorkspace
+	}
+	return nil, errors.New("Regexp.MatchDFA: unexpected return code from pcre_dfa_exec: " + strconv.Itoa(rc))
+}
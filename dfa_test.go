@@ -0,0 +1,61 @@
+package pcre
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchDFA(t *testing.T) {
+	re := MustCompile("<.*>", 0)
+	defer re.FreeRegexp()
+	m, err := re.MatchDFAString("x<a> <b>", 0)
+	if err != nil || m == nil || m.Start != 1 || !reflect.DeepEqual(m.Ends, []int{8, 4}) {
+		t.Errorf("unexpected result %+v, %v", m, err)
+	}
+	m, err = re.MatchDFA([]byte("x<a> <b>"), DFA_SHORTEST)
+	if err != nil || m == nil || !reflect.DeepEqual(m.Ends, []int{4}) {
+		t.Errorf("unexpected shortest result %+v, %v", m, err)
+	}
+	if m, err := re.MatchDFAString("no tags", 0); m != nil || err != nil {
+		t.Errorf("unexpected match %+v, %v", m, err)
+	}
+	if m, err := re.MatchDFAString("x<a", PARTIAL_SOFT); err != nil || m == nil || !m.Partial || m.Start != 1 {
+		t.Errorf("unexpected partial result %+v, %v", m, err)
+	}
+
+	backref := MustCompile(`(a)\1`, 0)
+	defer backref.FreeRegexp()
+	if _, err := backref.MatchDFAString("aa", 0); err == nil {
+		t.Error("back reference accepted by DFA")
+	}
+}
+
+func TestMatchDFAManyEnds(t *testing.T) {
+	re := MustCompile("a+", 0)
+	defer re.FreeRegexp()
+	subject := make([]byte, 100)
+	for i := range subject {
+		subject[i] = 'a'
+	}
+	m, err := re.MatchDFA(subject, 0)
+	if err != nil || len(m.Ends) != 100 || m.Ends[0] != 100 || m.Ends[99] != 1 {
+		t.Errorf("unexpected result %v, %v", m, err)
+	}
+}
+
+func TestDFAWorkspace(t *testing.T) {
+	re := MustCompile("(?:a|b|c|d|e|f|g|h|i|j)+(?:a|b|c|d|e|f|g|h|i|j)+x", 0)
+	defer re.FreeRegexp()
+	re.SetDFAWorkspace(80, 80)
+	if _, err := re.MatchDFAString("abcdefghijx", 0); err != ErrDFAWorkspace {
+		t.Fatal("expected ErrDFAWorkspace, got", err)
+	}
+	re.SetDFAWorkspace(80, 0)
+	m, err := re.MatchDFAString("abcdefghijx", 0)
+	if err != nil || m == nil || m.Ends[0] != 11 {
+		t.Fatalf("unexpected result %+v, %v", m, err)
+	}
+	if needed := re.dfaNeeded.Load(); needed <= 20 {
+		t.Error("grown workspace size not remembered", needed)
+	}
+}
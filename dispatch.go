@@ -0,0 +1,123 @@
+package pcre
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+// Dispatcher routes subjects to handlers by pattern.  Handlers are
+// registered with a priority; Dispatch runs the first matching
+// handler in order of descending priority, and then registration
+// order, while DispatchAll runs every matching handler in that order.
+//
+// Each distinct pattern and flags pair is compiled and studied once,
+// however many handlers use it.  The zero value is an empty
+// Dispatcher; it is safe for concurrent use.  Close frees the
+// compiled patterns.
+type Dispatcher struct {
+	mu       sync.RWMutex
+	rules    []dispatchRule
+	compiled map[dispatchKey]*Regexp
+	matchers sync.Pool
+}
+
+type dispatchKey struct {
+	pattern string
+	flags   int
+}
+
+type dispatchRule struct {
+	re       *Regexp
+	priority int
+	handler  func(MatchResult)
+}
+
+// Register adds a handler for subjects matching pattern.  It returns
+// the *CompileError if the pattern does not compile.
+func (d *Dispatcher) Register(pattern string, flags, priority int, handler func(MatchResult)) error {
+	if handler == nil {
+		return errors.New("Dispatcher.Register: nil handler")
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	key := dispatchKey{pattern, flags}
+	re := d.compiled[key]
+	if re == nil {
+		var err error
+		if re, err = Compile(pattern, flags); err != nil {
+			return err
+		}
+		re.Study(0)
+		if d.compiled == nil {
+			d.compiled = make(map[dispatchKey]*Regexp)
+		}
+		d.compiled[key] = re
+	}
+	// Insert after the rules of the same or higher priority, so equal
+	// priorities keep registration order.
+	i := sort.Search(len(d.rules), func(i int) bool {
+		return d.rules[i].priority < priority
+	})
+	// Dispatch iterates over the old slice without the lock, so the
+	// rules are copied rather than updated in place.
+	rules := make([]dispatchRule, 0, len(d.rules)+1)
+	rules = append(rules, d.rules[:i]...)
+	rules = append(rules, dispatchRule{re, priority, handler})
+	d.rules = append(rules, d.rules[i:]...)
+	return nil
+}
+
+// Dispatch runs the handler of the first rule matching subject, and
+// reports whether there was one.  Rules whose match fails with an
+// error, such as an exceeded match limit, are skipped; their errors
+// are returned, joined.
+func (d *Dispatcher) Dispatch(subject string) (bool, error) {
+	n, err := d.dispatch(subject, false)
+	return n > 0, err
+}
+
+// DispatchAll runs the handlers of all rules matching subject, and
+// returns their number.  Errors are handled as in Dispatch.
+func (d *Dispatcher) DispatchAll(subject string) (int, error) {
+	return d.dispatch(subject, true)
+}
+
+func (d *Dispatcher) dispatch(subject string, all bool) (n int, err error) {
+	// Handlers run without the lock, so they may register rules.
+	d.mu.RLock()
+	rules := d.rules
+	d.mu.RUnlock()
+	m, _ := d.matchers.Get().(*Matcher)
+	if m == nil {
+		m = new(Matcher)
+	}
+	defer d.matchers.Put(m)
+	var errs []error
+	for _, rule := range rules {
+		if !m.ResetString(rule.re, subject, 0) {
+			if m.err != nil {
+				errs = append(errs, m.err)
+			}
+			continue
+		}
+		r, _ := m.Result()
+		rule.handler(r)
+		n++
+		if !all {
+			break
+		}
+	}
+	return n, errors.Join(errs...)
+}
+
+// Close removes all rules and frees the compiled patterns.  The
+// Dispatcher must not be in use.
+func (d *Dispatcher) Close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, re := range d.compiled {
+		re.FreeRegexp()
+	}
+	d.rules, d.compiled = nil, nil
+}
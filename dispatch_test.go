@@ -0,0 +1,105 @@
+package pcre
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDispatcher(t *testing.T) {
+	var d Dispatcher
+	defer d.Close()
+	var got []string
+	handler := func(name string) func(MatchResult) {
+		return func(r MatchResult) {
+			got = append(got, name+":"+r.GroupString(1))
+		}
+	}
+	for _, rule := range []struct {
+		pattern  string
+		priority int
+		name     string
+	}{
+		{`^GET (\S+)`, 0, "get"},
+		{`(\S+)$`, -1, "last"},
+		{`^GET (/admin\S*)`, 10, "admin"},
+		{`^GET (\S+)`, 0, "get2"},
+	} {
+		if err := d.Register(rule.pattern, 0, rule.priority, handler(rule.name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(d.compiled) != 3 {
+		t.Errorf("expected 3 compiled patterns, got %d", len(d.compiled))
+	}
+
+	if ok, err := d.Dispatch("GET /admin/x HTTP/1.1"); !ok || err != nil {
+		t.Error("Dispatch", ok, err)
+	}
+	if ok, _ := d.Dispatch("GET /index HTTP/1.1"); !ok {
+		t.Error("Dispatch")
+	}
+	if want := []string{"admin:/admin/x", "get:/index"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	got = nil
+	if n, err := d.DispatchAll("GET /admin HTTP/1.1"); n != 4 || err != nil {
+		t.Error("DispatchAll", n, err)
+	}
+	if want := []string{"admin:/admin", "get:/admin", "get2:/admin", "last:HTTP/1.1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if ok, _ := d.Dispatch(""); ok {
+		t.Error("Dispatch matched the empty subject")
+	}
+
+	if err := d.Register("(", 0, 0, handler("bad")); err == nil {
+		t.Error("expected compile error")
+	}
+	if err := d.Register("a", 0, 0, nil); err == nil {
+		t.Error("expected error for nil handler")
+	}
+}
+
+func TestDispatcherErrors(t *testing.T) {
+	var d Dispatcher
+	defer d.Close()
+	var got string
+	d.Register("(a+)+$", 0, 1, func(MatchResult) { got = "slow" })
+	d.Register("a", 0, 0, func(MatchResult) { got = "fast" })
+	d.compiled[dispatchKey{"(a+)+$", 0}].SetLimits(100, 0)
+	ok, err := d.Dispatch("aaaaaaaaaaaaaaaaaaaaaaaab")
+	if !ok || err == nil || got != "fast" {
+		t.Error("Dispatch", ok, err, got)
+	}
+}
+
+func TestMatchResult(t *testing.T) {
+	re := MustCompile(`(?<key>\w+)=(\d+)?`, 0)
+	defer re.FreeRegexp()
+	m := re.Matcher([]byte("x key= y"), 0)
+	r, ok := m.Result()
+	if !ok {
+		t.Fatal("Result")
+	}
+	m.MatchString("other=1", 0)
+	if r.Subject != "x key= y" || r.Groups() != 2 {
+		t.Errorf("unexpected %+v", r)
+	}
+	if r.GroupString(0) != "key=" || !reflect.DeepEqual(r.Index(), []int{2, 6}) {
+		t.Error("group 0", r.GroupString(0), r.Index())
+	}
+	if r.Present(2) || r.GroupIndices(2) != nil || r.GroupString(3) != "" {
+		t.Error("absent groups")
+	}
+	if s, ok := r.NamedString("key"); !ok || s != "key" {
+		t.Error("NamedString", s, ok)
+	}
+	if _, ok := r.NamedString("nokey"); ok {
+		t.Error("NamedString of missing name")
+	}
+	m.MatchString("=", 0)
+	if _, ok := m.Result(); ok {
+		t.Error("Result after failed match")
+	}
+}
@@ -0,0 +1,69 @@
+package pcre
+
+// EncodingMode selects whether a pattern and its subjects are text in
+// UTF-8 or raw bytes, see CompileEncoding.
+type EncodingMode int
+
+// Modes for CompileEncoding
+const (
+	// ENCODING_DEFAULT leaves the encoding to the flags and to
+	// settings such as (*UTF8) at the start of the pattern, as
+	// Compile does.
+	ENCODING_DEFAULT EncodingMode = iota
+	// ENCODING_UTF8 compiles the pattern with UTF8, and with UCP if
+	// the library supports Unicode properties, so that \w, \d and
+	// the POSIX classes match Unicode characters.  The pattern and
+	// the subjects are checked for valid UTF-8, see SetInvalidUTF8.
+	ENCODING_UTF8
+	// ENCODING_BYTES matches bytes, as characters of Latin-1, for
+	// binary data and legacy encodings.  UTF8 and UCP are turned off,
+	// also in the default flags, and (*UTF8) in the pattern is an
+	// error.  Any byte sequence is a valid subject, and the helpers
+	// of the package step over and count bytes rather than UTF-8
+	// characters.
+	ENCODING_BYTES
+)
+
+// String returns the name of the mode.
+func (enc EncodingMode) String() string {
+	switch enc {
+	case ENCODING_UTF8:
+		return "ENCODING_UTF8"
+	case ENCODING_BYTES:
+		return "ENCODING_BYTES"
+	}
+	return "ENCODING_DEFAULT"
+}
+
+// compileFlags returns the Compile flags for the mode, given the
+// flags asked for.
+func (enc EncodingMode) compileFlags(flags int) int {
+	switch enc {
+	case ENCODING_UTF8:
+		flags = flags&^(NEVER_UTF|NO_UTF8_CHECK) | UTF8
+		if features.UCP {
+			flags |= UCP
+		}
+	case ENCODING_BYTES:
+		flags = flags&^(UTF8|UCP|NO_UTF8_CHECK) | NEVER_UTF
+	}
+	return flags
+}
+
+// CompileEncoding is like Compile, but sets the UTF8, UCP, NEVER_UTF
+// and NO_UTF8_CHECK flags as the encoding mode requires, overriding
+// flags and the flags set by SetDefaultCompileFlags.  Use
+// ENCODING_BYTES to match binary protocols, whose subjects a pattern
+// compiled with UTF8 rejects as invalid UTF-8.
+func CompileEncoding(pattern string, flags int, enc EncodingMode) (*Regexp, error) {
+	return compile(pattern, enc.compileFlags(flags|DefaultCompileFlags()), enc)
+}
+
+// Encoding returns the encoding mode the Regexp was compiled with, which
+// is ENCODING_DEFAULT unless it was compiled by CompileEncoding.
+func (re *Regexp) Encoding() EncodingMode {
+	if re == nil {
+		return ENCODING_DEFAULT
+	}
+	return re.encoding
+}
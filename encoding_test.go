@@ -0,0 +1,50 @@
+package pcre
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompileEncoding(t *testing.T) {
+	SetDefaultCompileFlags(UTF8)
+	defer SetDefaultCompileFlags(0)
+	subject := []byte("\x01\xff\xfe\x02")
+
+	re, err := CompileEncoding(`\xff\xfe`, 0, ENCODING_BYTES)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer re.FreeRegexp()
+	if re.Encoding() != ENCODING_BYTES || re.pcreOptions()&UTF8 != 0 {
+		t.Errorf("encoding %v, options %#x", re.Encoding(), re.pcreOptions())
+	}
+	m := re.Matcher(subject, 0)
+	if !m.Matches() || m.Err() != nil {
+		t.Fatalf("binary subject: no match, %v", m.Err())
+	}
+	if got := m.RuneIndex(); !reflect.DeepEqual(got, []int{1, 3}) {
+		t.Errorf("RuneIndex: got %v", got)
+	}
+
+	utf := MustCompile(`\xff`, 0)
+	defer utf.FreeRegexp()
+	if utf.Matcher(subject, 0).Err() == nil {
+		t.Error("UTF8 pattern accepted invalid UTF-8")
+	}
+
+	if _, err := CompileEncoding(`(*UTF8)a`, 0, ENCODING_BYTES); err == nil {
+		t.Error("(*UTF8) accepted in ENCODING_BYTES")
+	}
+
+	if !features.UCP {
+		return
+	}
+	re2, err := CompileEncoding(`^\w$`, 0, ENCODING_UTF8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer re2.FreeRegexp()
+	if re2.Encoding() != ENCODING_UTF8 || !re2.MatcherString("é", 0).Matches() {
+		t.Errorf("ENCODING_UTF8: %v does not match a letter", re2.Encoding())
+	}
+}
@@ -0,0 +1,324 @@
+package pcre
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Explanation describes a construct of a pattern and its parts, as
+// returned by ExplainPattern.
+type Explanation struct {
+	Offset      int    // Byte position of the construct in the pattern
+	Text        string // Source text of the construct
+	Kind        string // See ExplainPattern
+	Description string // Human-readable description
+	Group       int    // Number of a capture group or referenced group
+	Name        string // Name of a capture group or referenced group
+	Children    []*Explanation
+}
+
+// ExplainPattern compiles the pattern to check its syntax, and returns
+// a breakdown of its structure for review tools.  The Kind of each
+// construct is one of "sequence", "alternation", "literal", "class",
+// "any", "assertion", "group", "quantifier", "backreference",
+// "recursion", "conditional", "options", "verb", "callout" or
+// "empty".  Groups, quantifiers and conditionals have the constructs
+// they contain as children, alternations their alternatives.  If
+// compilation fails, the error is a *CompileError.
+func ExplainPattern(pattern string, flags int) (*Explanation, error) {
+	re, err := Compile(pattern, flags)
+	if err != nil {
+		return nil, err
+	}
+	re.FreeRegexp()
+	tree, p, err := parse(pattern, flags)
+	if err != nil {
+		return nil, err
+	}
+	return p.explain(tree), nil
+}
+
+// String formats the explanation as an indented tree, one construct
+// per line.
+func (e *Explanation) String() string {
+	var b strings.Builder
+	e.format(&b, 0)
+	return b.String()
+}
+
+func (e *Explanation) format(b *strings.Builder, depth int) {
+	b.WriteString(strings.Repeat("  ", depth))
+	b.WriteString(e.Description)
+	if e.Text != "" {
+		b.WriteString(": " + e.Text)
+	}
+	b.WriteByte('\n')
+	for _, c := range e.Children {
+		c.format(b, depth+1)
+	}
+}
+
+// escapeDescriptions describe the character type escapes.
+var escapeDescriptions = map[string]string{
+	`\d`: "a digit",
+	`\D`: "a character other than a digit",
+	`\w`: "a word character",
+	`\W`: "a character other than a word character",
+	`\s`: "a white space character",
+	`\S`: "a character other than white space",
+	`\h`: "a horizontal white space character",
+	`\H`: "a character other than horizontal white space",
+	`\v`: "a vertical white space character",
+	`\V`: "a character other than vertical white space",
+	`\R`: "a line break",
+	`\N`: "a character other than newline",
+	`\X`: "an extended grapheme cluster",
+	`\C`: "a single byte",
+}
+
+// assertionDescriptions describe the assertions, see opAssert.
+var assertionDescriptions = map[string]string{
+	`\b`: "word boundary",
+	`\B`: "not a word boundary",
+	`\A`: "start of the subject",
+	`\z`: "end of the subject",
+	`\Z`: "end of the subject or before a final newline",
+	`\G`: "position where the match attempt started",
+	`\K`: "reset the start of the reported match",
+}
+
+var groupDescriptions = map[groupKind]string{
+	groupNonCapture:    "non-capturing group",
+	groupAtomic:        "atomic group",
+	groupLookahead:     "positive lookahead",
+	groupNegLookahead:  "negative lookahead",
+	groupLookbehind:    "positive lookbehind",
+	groupNegLookbehind: "negative lookbehind",
+	groupBranchReset:   "branch reset group",
+}
+
+func (p *parser) explain(n *node) *Explanation {
+	e := &Explanation{Offset: n.pos, Text: p.text(n)}
+	switch n.op {
+	case opEmpty:
+		e.Kind, e.Description = "empty", "empty"
+	case opLiteral:
+		e.Kind = "literal"
+		e.Description = "literal " + strconv.QuoteRune(n.r)
+		if n.caseless {
+			e.Description += ", ignoring case"
+		}
+	case opClass:
+		e.Kind = "class"
+		if d, ok := escapeDescriptions[n.name]; ok {
+			e.Description = d
+		} else if strings.HasPrefix(n.name, `\p`) || strings.HasPrefix(n.name, `\P`) {
+			prop := strings.Trim(n.name[2:], "{}")
+			e.Description = "a character with property " + prop
+			if n.name[1] == 'P' {
+				e.Description = "a character without property " + prop
+			}
+		} else if strings.HasPrefix(n.name, "[^") {
+			e.Description = "a character not in the class"
+		} else {
+			e.Description = "a character in the class"
+		}
+		if n.caseless {
+			e.Description += ", ignoring case"
+		}
+	case opAny:
+		e.Kind = "any"
+		e.Description = "any character except newline"
+		if n.dotall {
+			e.Description = "any character"
+		}
+	case opAssert:
+		e.Kind = "assertion"
+		switch {
+		case n.name == "^" && n.multiline:
+			e.Description = "start of a line"
+		case n.name == "^":
+			e.Description = "start of the subject"
+		case n.name == "$" && n.multiline:
+			e.Description = "end of a line"
+		case n.name == "$":
+			e.Description = "end of the subject or before a final newline"
+		default:
+			e.Description = assertionDescriptions[n.name]
+		}
+	case opBackref:
+		e.Kind = "backreference"
+		e.Group, e.Name = p.resolve(n.index, n.name)
+		e.Description = "back reference to " + groupRef(e.Group, e.Name)
+		if n.caseless {
+			e.Description += ", ignoring case"
+		}
+	case opRecurse:
+		e.Kind = "recursion"
+		if n.index == 0 && (n.name == "R" || n.name == "0") {
+			e.Description = "recursion into the whole pattern"
+		} else {
+			e.Group, e.Name = p.resolve(n.index, n.name)
+			e.Description = "subroutine call to " + groupRef(e.Group, e.Name)
+		}
+	case opGroup:
+		e.Kind = "group"
+		if n.group == groupCapture {
+			e.Group, e.Name = n.index, n.name
+			e.Description = "capturing group " + strconv.Itoa(n.index)
+			if n.name != "" {
+				e.Description = "named capturing group " + strconv.Quote(n.name) +
+					" (group " + strconv.Itoa(n.index) + ")"
+			}
+		} else {
+			e.Description = groupDescriptions[n.group]
+		}
+		e.Children = p.explainAll(n.subs)
+	case opConcat:
+		e.Kind, e.Description = "sequence", "sequence"
+		e.Children = p.explainSequence(n.subs)
+		if len(e.Children) == 1 {
+			return e.Children[0]
+		}
+	case opAlternate:
+		e.Kind = "alternation"
+		e.Description = "one of " + strconv.Itoa(len(n.subs)) + " alternatives"
+		e.Children = p.explainAll(n.subs)
+	case opRepeat:
+		e.Kind = "quantifier"
+		e.Description = repeatDescription(n)
+		e.Children = p.explainAll(n.subs)
+	case opOptions:
+		e.Kind = "options"
+		e.Description = "set options " + n.name
+	case opVerb:
+		e.Kind = "verb"
+		e.Description = "control verb " + n.name
+	case opCallout:
+		e.Kind = "callout"
+		e.Description = "callout " + n.name
+	case opConditional:
+		e.Kind = "conditional"
+		e.Description = p.conditionDescription(n.name)
+		e.Children = p.explainAll(n.subs)
+	}
+	return e
+}
+
+func (p *parser) explainAll(nodes []*node) []*Explanation {
+	out := make([]*Explanation, len(nodes))
+	for i, n := range nodes {
+		out[i] = p.explain(n)
+	}
+	return out
+}
+
+// explainSequence explains the items of a sequence, merging runs of
+// literals into one explanation.
+func (p *parser) explainSequence(nodes []*node) []*Explanation {
+	var out []*Explanation
+	for i := 0; i < len(nodes); i++ {
+		n := nodes[i]
+		j := i
+		for j+1 < len(nodes) && nodes[j+1].op == opLiteral && n.op == opLiteral &&
+			nodes[j+1].caseless == n.caseless {
+			j++
+		}
+		if j == i {
+			out = append(out, p.explain(n))
+			continue
+		}
+		var text []rune
+		for _, lit := range nodes[i : j+1] {
+			text = append(text, lit.r)
+		}
+		e := &Explanation{Offset: n.pos, Text: p.pattern[n.pos:nodes[j].end], Kind: "literal",
+			Description: "literal " + strconv.Quote(string(text))}
+		if n.caseless {
+			e.Description += ", ignoring case"
+		}
+		out = append(out, e)
+		i = j
+	}
+	return out
+}
+
+// resolve returns the number and name of a referenced group.
+func (p *parser) resolve(index int, name string) (int, string) {
+	if index > 0 {
+		name = ""
+		if index <= len(p.names) {
+			name = p.names[index-1]
+		}
+		return index, name
+	}
+	for i, n := range p.names {
+		if n == name {
+			return i + 1, name
+		}
+	}
+	return 0, name
+}
+
+// conditionDescription describes a conditional group by its condition.
+func (p *parser) conditionDescription(cond string) string {
+	const branches = ", the first alternative, otherwise the second"
+	switch {
+	case cond == "DEFINE":
+		return "definitions for subroutine calls"
+	case cond == "R":
+		return "if inside any recursion" + branches
+	case strings.HasPrefix(cond, "R&"):
+		return "if inside recursion into " + groupRef(p.resolve(0, cond[2:])) + branches
+	case strings.HasPrefix(cond, "R"):
+		i, _ := strconv.Atoi(cond[1:])
+		return "if inside recursion into " + groupRef(p.resolve(i, "")) + branches
+	case strings.HasPrefix(cond, "(?"):
+		return "if the assertion matches" + branches
+	}
+	name := strings.Trim(cond, "<>'")
+	if cond[0] == '+' || cond[0] == '-' {
+		return "if relative group " + cond + " is set" + branches
+	}
+	if i, err := strconv.Atoi(name); err == nil {
+		return "if " + groupRef(p.resolve(i, "")) + " is set" + branches
+	}
+	return "if " + groupRef(p.resolve(0, name)) + " is set" + branches
+}
+
+func groupRef(index int, name string) string {
+	switch {
+	case name == "":
+		return "group " + strconv.Itoa(index)
+	case index == 0:
+		return "group " + strconv.Quote(name)
+	}
+	return "group " + strconv.Quote(name) + " (group " + strconv.Itoa(index) + ")"
+}
+
+func repeatDescription(n *node) string {
+	var d string
+	switch {
+	case n.min == 0 && n.max == 1:
+		d = "optionally"
+	case n.min == 0 && n.max < 0:
+		d = "zero or more times"
+	case n.min == 1 && n.max < 0:
+		d = "one or more times"
+	case n.max < 0:
+		d = "at least " + strconv.Itoa(n.min) + " times"
+	case n.min == n.max:
+		d = "exactly " + strconv.Itoa(n.min) + " times"
+	default:
+		d = "between " + strconv.Itoa(n.min) + " and " + strconv.Itoa(n.max) + " times"
+	}
+	switch {
+	case n.possessive:
+		d += ", possessive"
+	case n.lazy && n.min != n.max:
+		d += ", as few as possible"
+	case n.min != n.max:
+		d += ", as many as possible"
+	}
+	return d
+}
@@ -0,0 +1,76 @@
+package pcre
+
+import (
+	"testing"
+)
+
+func TestExplainPattern(t *testing.T) {
+	e, err := ExplainPattern(`^(?<year>\d{4})-(?:ab|c)+?\k<year>(?=x)$`, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `sequence: ^(?<year>\d{4})-(?:ab|c)+?\k<year>(?=x)$
+  start of the subject: ^
+  named capturing group "year" (group 1): (?<year>\d{4})
+    exactly 4 times: \d{4}
+      a digit: \d
+  literal '-': -
+  one or more times, as few as possible: (?:ab|c)+?
+    non-capturing group: (?:ab|c)
+      one of 2 alternatives: ab|c
+        literal "ab": ab
+        literal 'c': c
+  back reference to group "year" (group 1): \k<year>
+  positive lookahead: (?=x)
+    literal 'x': x
+  end of the subject or before a final newline: $
+`
+	if got := e.String(); got != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, got)
+	}
+	if e.Kind != "sequence" || len(e.Children) != 7 {
+		t.Fatalf("unexpected %+v", e)
+	}
+	if g := e.Children[1]; g.Kind != "group" || g.Group != 1 || g.Name != "year" || g.Offset != 1 {
+		t.Errorf("unexpected group %+v", g)
+	}
+	if b := e.Children[4]; b.Kind != "backreference" || b.Group != 1 {
+		t.Errorf("unexpected back reference %+v", b)
+	}
+
+	e, err = ExplainPattern(`(?m)^[^a-z]*?(x)++.(?1)`, CASELESS|DOTALL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []string{
+		"set options m",
+		"start of a line",
+		"zero or more times, as few as possible",
+		"one or more times, possessive",
+		"any character",
+		"subroutine call to group 1",
+	} {
+		if got := e.Children[i].Description; got != want {
+			t.Errorf("%d: expected %q, got %q", i, want, got)
+		}
+	}
+	if got := e.Children[2].Children[0].Description; got != "a character not in the class, ignoring case" {
+		t.Error("class", got)
+	}
+
+	e, err = ExplainPattern(`(?<n>a)?(?(<n>)b|\p{Lu})`, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := e.Children[1]
+	if c.Kind != "conditional" || len(c.Children) != 2 ||
+		c.Description != `if group "n" (group 1) is set, the first alternative, otherwise the second` {
+		t.Errorf("unexpected conditional %+v", c)
+	} else if got := c.Children[1].Description; got != "a character with property Lu" {
+		t.Error("property", got)
+	}
+
+	if _, err := ExplainPattern("a(", 0); err == nil {
+		t.Error("expected CompileError")
+	}
+}
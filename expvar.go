@@ -0,0 +1,52 @@
+package pcre
+
+import (
+	"expvar"
+	"sync"
+	"sync/atomic"
+)
+
+// Activity counters, maintained once PublishExpvar has been called.
+var (
+	countersOn    atomic.Bool
+	countCompiled atomic.Int64
+	countFreed    atomic.Int64
+	countExec     atomic.Int64
+	countLimit    atomic.Int64
+)
+
+var publishOnce sync.Once
+
+// PublishExpvar publishes counters of the package's activity as the
+// expvar map "pcre", with the members
+//
+//	live         Regexps compiled and not yet freed
+//	compiled     Regexps compiled successfully
+//	freed        Regexps freed, explicitly or by the finalizer
+//	exec         pcre_exec calls
+//	limit_errors pcre_exec calls which hit the match or recursion limit
+//	memory       bytes allocated by PCRE, see MemoryInUse
+//
+// Counting starts with the first call, which is typically made from
+// an init function; later calls have no effect.
+func PublishExpvar() {
+	publishOnce.Do(func() {
+		countersOn.Store(true)
+		m := expvar.NewMap("pcre")
+		m.Set("live", expvar.Func(func() interface{} {
+			return countCompiled.Load() - countFreed.Load()
+		}))
+		m.Set("compiled", expvar.Func(func() interface{} { return countCompiled.Load() }))
+		m.Set("freed", expvar.Func(func() interface{} { return countFreed.Load() }))
+		m.Set("exec", expvar.Func(func() interface{} { return countExec.Load() }))
+		m.Set("limit_errors", expvar.Func(func() interface{} { return countLimit.Load() }))
+		m.Set("memory", expvar.Func(func() interface{} { return MemoryInUse() }))
+	})
+}
+
+// count adds 1 to c if counting is on.
+func count(c *atomic.Int64) {
+	if countersOn.Load() {
+		c.Add(1)
+	}
+}
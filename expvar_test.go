@@ -0,0 +1,39 @@
+package pcre
+
+import (
+	"expvar"
+	"testing"
+)
+
+func TestPublishExpvar(t *testing.T) {
+	PublishExpvar()
+	PublishExpvar()
+	vars := expvar.Get("pcre").(*expvar.Map)
+	get := func(name string) int64 {
+		return vars.Get(name).(expvar.Func).Value().(int64)
+	}
+	compiled, freed, exec, limit := get("compiled"), get("freed"), get("exec"), get("limit_errors")
+
+	re := MustCompile("(a+)+$", 0)
+	re.SetLimits(100, 0)
+	re.MatcherString("aaaaaaaaaaaaaaaaaaaaaaaab", 0)
+	re.MatcherString("a", 0)
+	re.FreeRegexp()
+	re.FreeRegexp()
+
+	if d := get("compiled") - compiled; d != 1 {
+		t.Error("compiled", d)
+	}
+	if d := get("freed") - freed; d != 1 {
+		t.Error("freed", d)
+	}
+	if d := get("exec") - exec; d != 2 {
+		t.Error("exec", d)
+	}
+	if d := get("limit_errors") - limit; d != 1 {
+		t.Error("limit_errors", d)
+	}
+	if get("live") != get("compiled")-get("freed") {
+		t.Error("live", get("live"))
+	}
+}
@@ -0,0 +1,374 @@
+package pcre
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Field is a pattern in a configuration file, compiled while the file
+// is decoded.  In JSON and YAML, it is either a pattern string, or an
+// object with "pattern" and "flags" members.  Flags is a string of
+// Perl-style option letters:
+//
+//	i  CASELESS         m  MULTILINE        s  DOTALL
+//	x  EXTENDED         U  UNGREEDY         u  UTF8
+//	D  DOLLAR_ENDONLY   J  DUPNAMES         n  NO_AUTO_CAPTURE
+//	A  ANCHORED         X  EXTRA
+//
+// After decoding, the compiled Regexp is embedded in the Field.  A
+// pattern which does not compile fails the decoding with a
+// *FieldError, and leaves the Field zero.
+type Field struct {
+	*Regexp
+	Pattern string
+	Flags   string
+}
+
+// FieldError reports a Field which could not be compiled.
+type FieldError struct {
+	Path    string // Location in the configuration, see DecodeJSON and DecodeYAML
+	Pattern string // The failed pattern
+	Err     error  // The *CompileError, or an error about the flags
+}
+
+// Error converts a field error to a string.
+func (e *FieldError) Error() string {
+	if e.Path == "" {
+		return "pcre: " + e.Err.Error()
+	}
+	return "pcre: " + e.Path + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+var flagLetters = map[rune]int{
+	'i': CASELESS,
+	'm': MULTILINE,
+	's': DOTALL,
+	'x': EXTENDED,
+	'U': UNGREEDY,
+	'u': UTF8,
+	'D': DOLLAR_ENDONLY,
+	'J': DUPNAMES,
+	'n': NO_AUTO_CAPTURE,
+	'A': ANCHORED,
+	'X': EXTRA,
+}
+
+// parseFlagLetters converts option letters to compile flags.
+func parseFlagLetters(letters string) (int, error) {
+	flags := 0
+	for _, c := range letters {
+		f, ok := flagLetters[c]
+		if !ok {
+			return 0, errors.New("unknown flag " + strconv.QuoteRune(c) + " in " + strconv.Quote(letters))
+		}
+		flags |= f
+	}
+	return flags, nil
+}
+
+// compile compiles the pattern with the flags of the field, and frees
+// the Regexp it replaces.  On failure, the field is reset to its zero
+// value.
+func (f *Field) compile() error {
+	if f.Regexp != nil {
+		f.Regexp.FreeRegexp()
+		f.Regexp = nil
+	}
+	flags, err := parseFlagLetters(f.Flags)
+	if err == nil {
+		f.Regexp, err = Compile(f.Pattern, flags)
+	}
+	if err != nil {
+		pattern := f.Pattern
+		*f = Field{}
+		return &FieldError{Pattern: pattern, Err: err}
+	}
+	return nil
+}
+
+type fieldObject struct {
+	Pattern string `json:"pattern" yaml:"pattern"`
+	Flags   string `json:"flags,omitempty" yaml:"flags,omitempty"`
+}
+
+// UnmarshalJSON decodes and compiles a pattern string or object.  As
+// for other types, null leaves the field unchanged.
+func (f *Field) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var obj fieldObject
+	if err := json.Unmarshal(data, &obj.Pattern); err != nil {
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+	}
+	f.Pattern, f.Flags = obj.Pattern, obj.Flags
+	return f.compile()
+}
+
+// MarshalJSON encodes the field as a string, or as an object if it
+// has flags.
+func (f Field) MarshalJSON() ([]byte, error) {
+	if f.Flags == "" {
+		return json.Marshal(f.Pattern)
+	}
+	return json.Marshal(fieldObject{f.Pattern, f.Flags})
+}
+
+// UnmarshalYAML decodes and compiles a pattern string or object.  It
+// implements the Unmarshaler interface of gopkg.in/yaml.v2, which
+// yaml.v3 supports as well.
+func (f *Field) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var obj fieldObject
+	if err := unmarshal(&obj.Pattern); err != nil {
+		if err := unmarshal(&obj); err != nil {
+			return err
+		}
+	}
+	f.Pattern, f.Flags = obj.Pattern, obj.Flags
+	return f.compile()
+}
+
+// DecodeJSON is like json.Unmarshal, but if a Field fails to compile,
+// the *FieldError holds the path of the field in data, such as
+// "rules[2].match".  On failure, *v is reset to its zero value, and
+// the patterns compiled before the failure are freed.
+func DecodeJSON(data []byte, v interface{}) error {
+	return decodeFields(data, v, json.Unmarshal, "json")
+}
+
+// DecodeYAML is like DecodeJSON for YAML data, which is decoded by
+// unmarshal, such as the Unmarshal function of gopkg.in/yaml.v2 or
+// yaml.v3.  Keys are matched as these packages do, by the yaml struct
+// tag or else the lower-cased field name.
+func DecodeYAML(data []byte, v interface{}, unmarshal func([]byte, interface{}) error) error {
+	return decodeFields(data, v, unmarshal, "yaml")
+}
+
+// decodeFields decodes data into v with unmarshal.  On failure, it
+// fills in the path of a *FieldError, reading the keys of structs from
+// tag, and resets v.
+func decodeFields(data []byte, v interface{}, unmarshal func([]byte, interface{}) error, tag string) error {
+	rv := reflect.ValueOf(v)
+	before := make(map[*Regexp]bool)
+	walkFields(rv, func(f *Field) { before[f.Regexp] = true })
+	err := unmarshal(data, v)
+	if err == nil {
+		return nil
+	}
+	var ferr *FieldError
+	if errors.As(err, &ferr) && ferr.Path == "" {
+		var raw interface{}
+		if unmarshal(data, &raw) == nil {
+			ferr.Path = findField(reflect.TypeOf(v), raw, ferr, "", tag)
+		}
+	}
+	walkFields(rv, func(f *Field) {
+		if !before[f.Regexp] {
+			f.FreeRegexp()
+		}
+	})
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv.Elem().Set(reflect.Zero(rv.Elem().Type()))
+	}
+	return err
+}
+
+var fieldType = reflect.TypeOf(Field{})
+
+// walkFields calls fn for every Field with a Regexp reachable from v.
+func walkFields(v reflect.Value, fn func(*Field)) {
+	seen := make(map[uintptr]bool)
+	var walk func(v reflect.Value)
+	walk = func(v reflect.Value) {
+		switch v.Kind() {
+		case reflect.Ptr:
+			if v.IsNil() || seen[v.Pointer()] {
+				return
+			}
+			seen[v.Pointer()] = true
+			walk(v.Elem())
+		case reflect.Interface:
+			if !v.IsNil() {
+				walk(v.Elem())
+			}
+		case reflect.Struct:
+			if v.Type() == fieldType {
+				// Decoders skip unexported fields.
+				if v.CanInterface() {
+					if f := v.Interface().(Field); f.Regexp != nil {
+						fn(&f)
+					}
+				}
+				return
+			}
+			for i := 0; i < v.NumField(); i++ {
+				walk(v.Field(i))
+			}
+		case reflect.Slice, reflect.Array:
+			for i := 0; i < v.Len(); i++ {
+				walk(v.Index(i))
+			}
+		case reflect.Map:
+			iter := v.MapRange()
+			for iter.Next() {
+				walk(iter.Value())
+			}
+		}
+	}
+	walk(v)
+}
+
+// findField walks raw decoded data along type t, and returns the path
+// of the Field which fails with ferr, or "" if it is not found.  Keys
+// are visited in sorted order, so that of several fields failing with
+// the same pattern, the path is always that of the same one.
+func findField(t reflect.Type, raw interface{}, ferr *FieldError, path, tag string) string {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || raw == nil {
+		return ""
+	}
+	if t == fieldType {
+		var f Field
+		if pattern, ok := raw.(string); ok {
+			f.Pattern = pattern
+		} else if obj, ok := rawObject(raw); ok {
+			f.Pattern, _ = lookupKey(obj, "pattern", tag).(string)
+			f.Flags, _ = lookupKey(obj, "flags", tag).(string)
+		}
+		if f.Pattern != ferr.Pattern {
+			return ""
+		}
+		if f.compile() == nil {
+			f.FreeRegexp()
+			return ""
+		}
+		if path == "" {
+			path = "."
+		}
+		return path
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := rawObject(raw)
+		if !ok {
+			return ""
+		}
+		keys := sortedKeys(obj)
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			name, inline := fieldKey(sf, tag)
+			if inline {
+				if p := findField(sf.Type, raw, ferr, path, tag); p != "" {
+					return p
+				}
+				continue
+			}
+			for _, key := range keys {
+				if name != "" && keyMatches(key, name, tag) {
+					if p := findField(sf.Type, obj[key], ferr, joinPath(path, key), tag); p != "" {
+						return p
+					}
+				}
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		list, _ := raw.([]interface{})
+		for i, value := range list {
+			if p := findField(t.Elem(), value, ferr, path+"["+strconv.Itoa(i)+"]", tag); p != "" {
+				return p
+			}
+		}
+	case reflect.Map:
+		obj, _ := rawObject(raw)
+		for _, key := range sortedKeys(obj) {
+			if p := findField(t.Elem(), obj[key], ferr, joinPath(path, key), tag); p != "" {
+				return p
+			}
+		}
+	}
+	return ""
+}
+
+// rawObject returns a decoded object with string keys.  YAML decoders
+// may return maps with keys of any type.
+func rawObject(raw interface{}) (map[string]interface{}, bool) {
+	switch obj := raw.(type) {
+	case map[string]interface{}:
+		return obj, true
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(obj))
+		for key, value := range obj {
+			m[fmt.Sprint(key)] = value
+		}
+		return m, true
+	}
+	return nil, false
+}
+
+func sortedKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// fieldKey returns the key of a struct field in data decoded with the
+// struct tag tag, or "" if the field is not decoded.  Fields whose
+// members are decoded from the enclosing object are inline.
+func fieldKey(sf reflect.StructField, tag string) (key string, inline bool) {
+	name, opts, _ := strings.Cut(sf.Tag.Get(tag), ",")
+	switch {
+	case name == "-":
+		return "", false
+	case tag == "yaml" && strings.Contains(","+opts+",", ",inline,"):
+		return "", true
+	case tag == "json" && sf.Anonymous && name == "":
+		return "", true
+	case !sf.IsExported():
+		return "", false
+	case name != "":
+		return name, false
+	case tag == "yaml":
+		return strings.ToLower(sf.Name), false
+	}
+	return sf.Name, false
+}
+
+// keyMatches reports whether a key in the data selects the struct
+// field with the given key.  encoding/json ignores case.
+func keyMatches(key, name, tag string) bool {
+	return key == name || tag == "json" && strings.EqualFold(key, name)
+}
+
+// lookupKey returns the value of the key selecting name in obj.
+func lookupKey(obj map[string]interface{}, name, tag string) interface{} {
+	for _, key := range sortedKeys(obj) {
+		if keyMatches(key, name, tag) {
+			return obj[key]
+		}
+	}
+	return nil
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
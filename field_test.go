@@ -0,0 +1,179 @@
+package pcre
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+type fieldConfig struct {
+	Name  string
+	Rules []struct {
+		Match Field `json:"match"`
+	} `json:"rules"`
+	Default *Field `json:"default"`
+}
+
+func TestFieldJSON(t *testing.T) {
+	var cfg fieldConfig
+	err := json.Unmarshal([]byte(`{
+		"rules": [{"match": "^a+$"}, {"match": {"pattern": "^B", "flags": "im"}}],
+		"default": "x"
+	}`), &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.Rules[0].Match.MatcherString("aaa", 0).Matches() {
+		t.Error("rule 0 did not match")
+	}
+	if !cfg.Rules[1].Match.MatcherString("a\nb", 0).Matches() {
+		t.Error("rule 1 flags not applied")
+	}
+	if cfg.Default == nil || cfg.Default.Pattern != "x" {
+		t.Error("default", cfg.Default)
+	}
+
+	out, err := json.Marshal(cfg.Rules[1].Match)
+	if string(out) != `{"pattern":"^B","flags":"im"}` || err != nil {
+		t.Error(string(out), err)
+	}
+	out, _ = json.Marshal(cfg.Rules[0].Match)
+	if string(out) != `"^a+$"` {
+		t.Error(string(out))
+	}
+}
+
+func TestFieldJSONNull(t *testing.T) {
+	var cfg fieldConfig
+	if err := json.Unmarshal([]byte(`{"rules": [{"match": null}], "default": null}`), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if f := cfg.Rules[0].Match; f.Regexp != nil || f.Pattern != "" {
+		t.Error("null compiled", f.Pattern)
+	}
+	if cfg.Default != nil {
+		t.Error("null default", cfg.Default)
+	}
+
+	// Decoding again replaces, and frees, the Regexp of the field.
+	var f Field
+	if err := json.Unmarshal([]byte(`"a"`), &f); err != nil {
+		t.Fatal(err)
+	}
+	old := f.Regexp
+	if err := json.Unmarshal([]byte(`null`), &f); err != nil || f.Regexp != old {
+		t.Error("null changed the field", err)
+	}
+	if err := json.Unmarshal([]byte(`"b"`), &f); err != nil {
+		t.Fatal(err)
+	}
+	if old.valid() {
+		t.Error("replaced Regexp not freed")
+	}
+	if !f.MatcherString("b", 0).Matches() {
+		t.Error("new pattern not compiled")
+	}
+	f.FreeRegexp()
+}
+
+func TestFieldErrors(t *testing.T) {
+	var check = func(data, path string, compileError bool) {
+		var cfg fieldConfig
+		err := DecodeJSON([]byte(data), &cfg)
+		var ferr *FieldError
+		if !errors.As(err, &ferr) {
+			t.Errorf("%s: expected *FieldError, got %v", data, err)
+			return
+		}
+		if ferr.Path != path {
+			t.Errorf("%s: expected path %q, got %q", data, path, ferr.Path)
+		}
+		var cerr *CompileError
+		if errors.As(err, &cerr) != compileError {
+			t.Errorf("%s: unexpected error %v", data, err)
+		}
+	}
+	check(`{"rules": [{"match": "ok"}, {"match": "(bad"}]}`, "rules[1].match", true)
+	check(`{"Rules": [{"Match": {"pattern": "a", "flags": "q"}}]}`, "Rules[0].Match", false)
+	check(`{"default": "[z-a]"}`, "default", true)
+
+	var f Field
+	if err := json.Unmarshal([]byte(`{"pattern": "(bad", "flags": "i"}`), &f); err == nil || f != (Field{}) {
+		t.Error("expected error and zero Field", err, f)
+	}
+
+	// The path does not depend on the order of map iteration, and
+	// the configuration is reset.
+	for i := 0; i < 20; i++ {
+		var cfg struct {
+			Default *Field
+			Rules   map[string]Field
+		}
+		err := DecodeJSON([]byte(`{"default": "ok", "rules": {"b": "(", "c": "ok", "a": "("}}`), &cfg)
+		var ferr *FieldError
+		if !errors.As(err, &ferr) || ferr.Path != "rules.a" {
+			t.Fatalf("expected path rules.a, got %v", err)
+		}
+		if cfg.Default != nil || cfg.Rules != nil {
+			t.Fatalf("configuration not reset: %+v", cfg)
+		}
+	}
+}
+
+func TestDecodeYAML(t *testing.T) {
+	type config struct {
+		Rules []struct {
+			Match Field
+		}
+		Other Field `yaml:"other_match"`
+	}
+	// Simulate yaml.v2, which decodes mappings with interface keys.
+	var unmarshal = func(data []byte, v interface{}) error {
+		if raw, ok := v.(*interface{}); ok {
+			*raw = map[interface{}]interface{}{
+				"other_match": "ok",
+				"rules": []interface{}{
+					map[interface{}]interface{}{"match": "ok"},
+					map[interface{}]interface{}{"match": map[interface{}]interface{}{
+						"pattern": "a", "flags": "q"}},
+				},
+			}
+			return nil
+		}
+		cfg := v.(*config)
+		cfg.Other.Pattern = "ok"
+		if err := cfg.Other.compile(); err != nil {
+			return err
+		}
+		var f Field
+		f.Pattern, f.Flags = "a", "q"
+		return f.compile()
+	}
+	var cfg config
+	err := DecodeYAML(nil, &cfg, unmarshal)
+	var ferr *FieldError
+	if !errors.As(err, &ferr) || ferr.Path != "rules[1].match" {
+		t.Fatalf("expected path rules[1].match, got %v", err)
+	}
+	if cfg.Other.Regexp != nil {
+		t.Error("configuration not reset")
+	}
+}
+
+func TestFieldYAML(t *testing.T) {
+	// Simulate the unmarshal callback of a YAML decoder for a mapping.
+	unmarshal := func(v interface{}) error {
+		if obj, ok := v.(*fieldObject); ok {
+			obj.Pattern, obj.Flags = "a.b", "s"
+			return nil
+		}
+		return errors.New("cannot unmarshal !!map into string")
+	}
+	var f Field
+	if err := f.UnmarshalYAML(unmarshal); err != nil {
+		t.Fatal(err)
+	}
+	if !f.MatcherString("a\nb", 0).Matches() {
+		t.Error("DOTALL not applied")
+	}
+}
@@ -0,0 +1,112 @@
+package pcre
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// FilterRule is a pattern of a Filter.
+type FilterRule struct {
+	Field
+	Allow  bool   // Whether the rule is from an allowlist
+	Source string // List name and line number, as "name:line"
+}
+
+// Filter decides whether subjects are allowed by an allowlist and a
+// denylist of patterns: a subject matching a deny rule is denied;
+// otherwise it is allowed if it matches an allow rule, or if there are
+// no allow rules.
+//
+// Lists are read with ReadAllowList and ReadDenyList before the filter
+// is used; it may then be used concurrently.  The zero value allows
+// everything.  Close frees the compiled patterns.
+type Filter struct {
+	allow, deny []*FilterRule
+	matchers    sync.Pool
+}
+
+// ReadAllowList adds the patterns read from r to the allowlist.  The
+// name identifies the list in errors and in the Source of its rules.
+// See ReadDenyList for the format.
+func (f *Filter) ReadAllowList(r io.Reader, name string) error {
+	rules, err := readFilterRules(r, name, true)
+	f.allow = append(f.allow, rules...)
+	return err
+}
+
+// ReadDenyList adds the patterns read from r to the denylist.  The
+// name identifies the list in errors and in the Source of its rules.
+//
+// A list has one pattern per line.  Blank lines and lines starting
+// with # are ignored, as is white space around a pattern.  A line of
+// the form /pattern/flags gives flags as in Field.Flags; this form
+// also serves for patterns starting with # or /, or with white space
+// at either end.  A pattern which does not compile fails with a
+// *FieldError whose Path is the Source of the rule; the rules before
+// it have been added.
+func (f *Filter) ReadDenyList(r io.Reader, name string) error {
+	rules, err := readFilterRules(r, name, false)
+	f.deny = append(f.deny, rules...)
+	return err
+}
+
+// readFilterRules reads and compiles a pattern list.
+func readFilterRules(r io.Reader, name string, allow bool) ([]*FilterRule, error) {
+	var rules []*FilterRule
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		rule := &FilterRule{Allow: allow, Source: name + ":" + strconv.Itoa(n)}
+		rule.Pattern = line
+		if i := strings.LastIndexByte(line, '/'); line[0] == '/' && i > 0 {
+			rule.Pattern, rule.Flags = line[1:i], line[i+1:]
+		}
+		if err := rule.compile(); err != nil {
+			err.(*FieldError).Path = rule.Source
+			return rules, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, s.Err()
+}
+
+// Rules returns the deny rules followed by the allow rules, in the
+// order they are evaluated.
+func (f *Filter) Rules() []*FilterRule {
+	return append(append([]*FilterRule(nil), f.deny...), f.allow...)
+}
+
+// Evaluate reports whether subject is allowed, and the rule that
+// decided it, which is nil if the subject matched no rule.  It fails
+// if a match fails, such as by exceeding the match limit.
+func (f *Filter) Evaluate(subject string) (allowed bool, rule *FilterRule, err error) {
+	m, _ := f.matchers.Get().(*Matcher)
+	if m == nil {
+		m = new(Matcher)
+	}
+	defer f.matchers.Put(m)
+	for _, rules := range [][]*FilterRule{f.deny, f.allow} {
+		for _, rule := range rules {
+			if m.ResetString(rule.Regexp, subject, 0) {
+				return rule.Allow, rule, nil
+			}
+			if err := m.Err(); err != nil {
+				return false, rule, err
+			}
+		}
+	}
+	return len(f.allow) == 0, nil, nil
+}
+
+// Close frees the compiled patterns of the rules.
+func (f *Filter) Close() {
+	for _, rule := range f.Rules() {
+		rule.Regexp.FreeRegexp()
+	}
+}
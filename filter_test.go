@@ -0,0 +1,65 @@
+package pcre
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	var f Filter
+	defer f.Close()
+	if allowed, rule, err := f.Evaluate("anything"); !allowed || rule != nil || err != nil {
+		t.Error("empty filter", allowed, rule, err)
+	}
+	err := f.ReadAllowList(strings.NewReader(`
+# hosts we talk to
+  ^api\.example\.com$
+/^CDN\d*\.example\.net$/i
+
+//admin/
+`), "allow.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.ReadDenyList(strings.NewReader("^api\\.example\\.com$\n/#/\n"), "deny.txt"); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		subject string
+		allowed bool
+		source  string
+	}{
+		{"api.example.com", false, "deny.txt:1"},
+		{"cdn2.example.net", true, "allow.txt:4"},
+		{"/admin/", true, "allow.txt:6"},
+		{"a#b", false, "deny.txt:2"},
+		{"other.example.org", false, ""},
+	} {
+		allowed, rule, err := f.Evaluate(test.subject)
+		source := ""
+		if rule != nil {
+			source = rule.Source
+		}
+		if allowed != test.allowed || source != test.source || err != nil {
+			t.Errorf("%s: expected %v by %q, got %v by %q, %v", test.subject, test.allowed, test.source, allowed, source, err)
+		}
+	}
+	if n := len(f.Rules()); n != 5 {
+		t.Error("expected 5 rules, got", n)
+	}
+
+	var bad Filter
+	defer bad.Close()
+	err = bad.ReadDenyList(strings.NewReader("ok\n\n(unclosed\n"), "bad.txt")
+	var ferr *FieldError
+	if !errors.As(err, &ferr) || ferr.Path != "bad.txt:3" || ferr.Pattern != "(unclosed" {
+		t.Error("expected error at bad.txt:3, got", err)
+	}
+	if err := bad.ReadDenyList(strings.NewReader("/a/q\n"), "flags.txt"); err == nil {
+		t.Error("expected error for unknown flag")
+	}
+	if n := len(bad.Rules()); n != 1 {
+		t.Error("expected the rule before the error, got", n)
+	}
+}
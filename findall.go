@@ -0,0 +1,125 @@
+package pcre
+
+import "unicode/utf8"
+
+// forEachMatchString calls fn after each successive non-overlapping
+// match of the pattern of m in subject from offset start, like the
+// All functions of the regexp package: empty matches abutting a
+// preceding match are ignored.  The text before start is available to
+// lookbehind assertions.  Invalid UTF-8 is handled as SetInvalidUTF8
+// selects.  It stops when fn returns false, and returns the error of a
+// failed match.
+func forEachMatchString(m *Matcher, subject string, start, flags int, fn func() bool) error {
+	utf := m.re.pcreOptions()&UTF8 != 0
+	prev := -1
+	var clean *sanitized
+	for pos := start; pos <= len(subject); {
+		rc := m.execSanitized(subject, pos, flags, &clean)
+		if m.matches, m.err = m.matched(rc); !m.matches {
+			return m.err
+		}
+		from, end := int(m.ovector[0]), int(m.ovector[1])
+		if from < end || from != prev {
+			if !fn() {
+				return nil
+			}
+		}
+		prev, pos = end, end
+		if from == end {
+			// Continue one character past an empty match.
+			size := 1
+			if utf && end < len(subject) {
+				_, size = utf8.DecodeRuneInString(subject[end:])
+			}
+			pos += size
+		}
+	}
+	return nil
+}
+
+// FindAllNamed returns the named capture groups of every match in
+// subject, as maps from name to value.  Groups which are not present
+// are left out; of several groups with the same name, the first
+// present one is used.  Empty matches abutting a preceding match are
+// ignored, as in the regexp package.
+func (re *Regexp) FindAllNamed(subject string, flags int) ([]map[string]string, error) {
+	if !re.valid() {
+		return nil, uninitialized("Regexp.FindAllNamed")
+	}
+	m := re.AcquireMatcher()
+	defer m.Release()
+	var out []map[string]string
+	err := forEachMatchString(m, subject, 0, flags, func() bool {
+		out = append(out, namedGroups(m))
+		return true
+	})
+	return out, err
+}
+
+// FindAllNamedMerged is like FindAllNamed, but collects the values of
+// each name across all matches, in order.
+func (re *Regexp) FindAllNamedMerged(subject string, flags int) (map[string][]string, error) {
+	if !re.valid() {
+		return nil, uninitialized("Regexp.FindAllNamedMerged")
+	}
+	m := re.AcquireMatcher()
+	defer m.Release()
+	out := make(map[string][]string)
+	err := forEachMatchString(m, subject, 0, flags, func() bool {
+		for name, value := range namedGroups(m) {
+			out[name] = append(out[name], value)
+		}
+		return true
+	})
+	return out, err
+}
+
+// namedGroups returns the present named groups of the last match of
+// m.
+func namedGroups(m *Matcher) map[string]string {
+	groups := make(map[string]string)
+	for name := range m.re.names {
+		if n := m.re.groupOf(name, m.Present); m.Present(n) {
+			groups[name] = m.GroupString(n)
+		}
+	}
+	return groups
+}
+
+// Locs holds the matches found by FindAllLoc as offsets into the
+// subject; the text of a match is sliced from the subject only when
+// asked for.
+type Locs struct {
+	Subject string // The subject searched
+	Loc     []int  // Offsets of match i at Loc[2*i] and Loc[2*i+1]
+}
+
+// Len returns the number of matches.
+func (l Locs) Len() int {
+	return len(l.Loc) / 2
+}
+
+// Index returns the start and end offsets of match i.
+func (l Locs) Index(i int) (start, end int) {
+	return l.Loc[2*i], l.Loc[2*i+1]
+}
+
+// Finding returns the text of match i.
+func (l Locs) Finding(i int) string {
+	return l.Subject[l.Loc[2*i]:l.Loc[2*i+1]]
+}
+
+// Match returns match i as FindAll would.  Its Loc shares the
+// offsets of l.
+func (l Locs) Match(i int) Match {
+	return Match{l.Finding(i), l.Loc[2*i : 2*i+2 : 2*i+2]}
+}
+
+// Matches returns all matches as FindAll would, a non-nil slice.
+func (l Locs) Matches() []Match {
+	matches := make([]Match, l.Len())
+	for i := range matches {
+		matches[i] = l.Match(i)
+	}
+	return matches
+}
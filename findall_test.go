@@ -0,0 +1,73 @@
+package pcre
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindAllNamed(t *testing.T) {
+	re := MustCompile(`(?<key>\w+)=(?<value>\w*)(?<flag>!)?`, 0)
+	defer re.FreeRegexp()
+	got, err := re.FindAllNamed("a=1 b=2! c=", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]string{
+		{"key": "a", "value": "1"},
+		{"key": "b", "value": "2", "flag": "!"},
+		{"key": "c", "value": ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	merged, err := re.FindAllNamedMerged("a=1 b=2! c=", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantMerged := map[string][]string{
+		"key":   {"a", "b", "c"},
+		"value": {"1", "2", ""},
+		"flag":  {"!"},
+	}
+	if !reflect.DeepEqual(merged, wantMerged) {
+		t.Errorf("expected %v, got %v", wantMerged, merged)
+	}
+	if got, err := re.FindAllNamed("none", 0); err != nil || got != nil {
+		t.Errorf("no match: %v, %v", got, err)
+	}
+
+	dup := MustCompile(`(?<n>a)|(?<n>b)|x*`, DUPNAMES)
+	defer dup.FreeRegexp()
+	got, _ = dup.FindAllNamed("ab-", 0)
+	want = []map[string]string{{"n": "a"}, {"n": "b"}, {}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("duplicate names: expected %v, got %v", want, got)
+	}
+}
+
+func TestFindAllLoc(t *testing.T) {
+	re := MustCompile(`\w*`, 0)
+	defer re.FreeRegexp()
+	subject := "cat dog"
+	locs, err := re.FindAllLoc(subject, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{0, 3, 3, 3, 4, 7}; !reflect.DeepEqual(locs.Loc, want) {
+		t.Errorf("expected offsets %v, got %v", want, locs.Loc)
+	}
+	if locs.Len() != 3 || locs.Finding(2) != "dog" {
+		t.Errorf("got %d matches, last %q", locs.Len(), locs.Finding(2))
+	}
+	if start, end := locs.Index(1); start != 3 || end != 3 {
+		t.Errorf("Index(1): got %d, %d", start, end)
+	}
+	all, _ := re.FindAll(subject, 0)
+	if !reflect.DeepEqual(locs.Matches(), all) {
+		t.Errorf("Matches: got %v, FindAll %v", locs.Matches(), all)
+	}
+	locs, err = re.FindAllLoc("", 0)
+	if err != nil || locs.Matches() == nil {
+		t.Errorf("empty subject: got %v, %v", locs, err)
+	}
+}
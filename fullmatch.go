@@ -0,0 +1,103 @@
+package pcre
+
+import (
+	"strings"
+)
+
+// FullMatch reports whether the pattern matches all of subject, as if
+// it were enclosed in \A and \z.  Unlike a pattern ending in $, the
+// match may not stop before a final newline, and unlike a check that
+// the first match ends at the end of subject, other alternatives are
+// tried if it does not: a|ab fully matches "ab".
+//
+// The enclosed pattern is compiled on first use and kept until
+// FreeRegexp.  It takes the limits and the invalid UTF-8 mode of re
+// at that time.
+func (re *Regexp) FullMatch(subject []byte, flags int) bool {
+	full := re.fullRegexp("Regexp.FullMatch")
+	if full == nil {
+		return false
+	}
+	m := full.AcquireMatcher()
+	defer m.Release()
+	return m.Match(subject, flags)
+}
+
+// FullMatchString is equivalent to FullMatch with a string subject.
+func (re *Regexp) FullMatchString(subject string, flags int) bool {
+	full := re.fullRegexp("Regexp.FullMatchString")
+	if full == nil {
+		return false
+	}
+	m := full.AcquireMatcher()
+	defer m.Release()
+	return m.MatchString(subject, flags)
+}
+
+// fullRegexp returns the pattern of re enclosed in \A and \z, compiled
+// once.
+func (re *Regexp) fullRegexp(op string) *Regexp {
+	if !re.valid() {
+		uninitialized(op)
+		return nil
+	}
+	re.fullOnce.Do(func() {
+		options := re.pcreOptions()
+		full, err := Compile(enclosePattern(re.pattern, options, `\A`, `\z`), options)
+		if err != nil {
+			return
+		}
+		full.matchLimit, full.recursionLimit = re.matchLimit, re.recursionLimit
+		full.invalidUTF8, full.untrusted = re.invalidUTF8, re.untrusted
+		re.mu.Lock()
+		re.full = full
+		re.mu.Unlock()
+	})
+	re.mu.RLock()
+	defer re.mu.RUnlock()
+	return re.full
+}
+
+// startVerbs are the settings which must stay at the start of a
+// pattern.
+var startVerbs = []string{
+	"(*UTF8)", "(*UTF)", "(*UCP)", "(*NO_START_OPT)", "(*NO_AUTO_POSSESS)",
+	"(*CR)", "(*LF)", "(*CRLF)", "(*ANYCRLF)", "(*ANY)",
+	"(*BSR_ANYCRLF)", "(*BSR_UNICODE)", "(*LIMIT_MATCH=", "(*LIMIT_RECURSION=",
+}
+
+// enclosePattern returns pattern in a non-capturing group between
+// before and after, keeping the settings at its start in front.
+func enclosePattern(pattern string, options int, before, after string) string {
+	prefix, group := splitPattern(pattern, options)
+	return prefix + before + group + after
+}
+
+// splitPattern returns the settings at the start of pattern, and the
+// rest in a non-capturing group.
+func splitPattern(pattern string, options int) (prefix, group string) {
+	rest := pattern
+outer:
+	for {
+		for _, v := range startVerbs {
+			if strings.HasPrefix(rest, v) {
+				end := strings.IndexByte(rest, ')')
+				rest = rest[end+1:]
+				continue outer
+			}
+		}
+		break
+	}
+	prefix = pattern[:len(pattern)-len(rest)]
+	// \E ends a quotation left open at the end of the pattern, and
+	// a newline a comment in extended mode.
+	extended := options&EXTENDED != 0
+	if _, p, err := parse(pattern, options); err == nil {
+		extended = p.flags&EXTENDED != 0
+	}
+	end := `\E)`
+	if extended {
+		end = `\E` + "\n" + `)`
+	}
+	return prefix, `(?:` + rest + end
+}
@@ -0,0 +1,34 @@
+package pcre
+
+import (
+	"testing"
+)
+
+func TestFullMatch(t *testing.T) {
+	for _, tc := range []struct {
+		pattern string
+		flags   int
+		subject string
+		want    bool
+	}{
+		{`\d+`, 0, "123", true},
+		{`\d+`, 0, "123\n", false},
+		{`\d+$`, 0, "123\n", false},
+		{`\d+`, 0, "a123", false},
+		{`a|ab`, 0, "ab", true},
+		{`a|ab`, 0, "abc", false},
+		{`(*UTF8)é+`, 0, "éé", true},
+		{`a # comment`, EXTENDED, "a", true},
+		{`\Qa|b`, 0, "a|b", true},
+		{``, 0, "", true},
+	} {
+		re := MustCompile(tc.pattern, tc.flags)
+		if got := re.FullMatchString(tc.subject, 0); got != tc.want {
+			t.Errorf("%q on %q: expected %v, got %v", tc.pattern, tc.subject, tc.want, got)
+		}
+		if got := re.FullMatch([]byte(tc.subject), 0); got != tc.want {
+			t.Errorf("%q on %q: FullMatch expected %v, got %v", tc.pattern, tc.subject, tc.want, got)
+		}
+		re.FreeRegexp()
+	}
+}
@@ -0,0 +1,44 @@
+package pcre
+
+import "io/fs"
+
+// GlobFS returns the paths of the files and directories in fsys whose
+// names fully match the pattern, like fs.Glob with a regular
+// expression in place of the shell pattern, but searching the whole
+// tree: `.*_test\.go` finds test files at any depth.  The paths are
+// in lexical order, as fs.WalkDir visits them.  As with fs.Glob,
+// directories which cannot be read are skipped.
+func GlobFS(fsys fs.FS, re *Regexp) ([]string, error) {
+	if !re.valid() {
+		return nil, uninitialized("pcre.GlobFS")
+	}
+	return globFS(fsys, re, false), nil
+}
+
+// GlobFSPath is like GlobFS, but matches the pattern against the
+// slash-separated paths relative to the root of fsys, such as
+// "cmd/pcregrep/main.go", instead of the names.
+func GlobFSPath(fsys fs.FS, re *Regexp) ([]string, error) {
+	if !re.valid() {
+		return nil, uninitialized("pcre.GlobFSPath")
+	}
+	return globFS(fsys, re, true), nil
+}
+
+func globFS(fsys fs.FS, re *Regexp, path bool) []string {
+	var matches []string
+	fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || p == "." {
+			return nil
+		}
+		subject := d.Name()
+		if path {
+			subject = p
+		}
+		if re.FullMatchString(subject, 0) {
+			matches = append(matches, p)
+		}
+		return nil
+	})
+	return matches
+}
@@ -0,0 +1,43 @@
+package pcre
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestGlobFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.go":            {},
+		"main_test.go":       {},
+		"cmd/tool/main.go":   {},
+		"cmd/tool/x_test.go": {},
+		"docs/go/README":     {},
+	}
+	for _, test := range []struct {
+		pattern string
+		path    bool
+		want    []string
+	}{
+		{`.*_test\.go`, false, []string{"cmd/tool/x_test.go", "main_test.go"}},
+		{`go`, false, []string{"docs/go"}},
+		{`main\.go`, false, []string{"cmd/tool/main.go", "main.go"}},
+		{`main\.go`, true, []string{"main.go"}},
+		{`cmd/[^/]+/main\.go`, true, []string{"cmd/tool/main.go"}},
+		{`nothing`, false, nil},
+	} {
+		re := MustCompile(test.pattern, 0)
+		glob := GlobFS
+		if test.path {
+			glob = GlobFSPath
+		}
+		got, err := glob(fsys, re)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q (path %v): got %q, want %q", test.pattern, test.path, got, test.want)
+		}
+		re.FreeRegexp()
+	}
+}
@@ -0,0 +1,105 @@
+package pcre
+
+import "unsafe"
+
+// The functions in this file return offsets in the shapes of the
+// regexp package, so that code handling the results of both can be
+// shared: a match is a []int of start and end pairs for the match and
+// each capture group, with -1 for groups which are not present, and
+// the All functions return a [][]int of matches, or nil if there are
+// none.  Errors of the match, such as an exceeded match limit, end
+// the search as if there were no further match.
+
+// SubmatchIndex returns the offsets of the last match and its capture
+// groups, like regexp.Regexp.FindSubmatchIndex, or nil if the last
+// match failed.
+func (m *Matcher) SubmatchIndex() []int {
+	if !m.Matches() {
+		return nil
+	}
+	loc := make([]int, 2*(1+m.groups))
+	for i := 0; i <= m.groups; i++ {
+		loc[2*i], loc[2*i+1] = m.span(i)
+	}
+	return loc
+}
+
+// SubmatchIndex returns the offsets of the match and its capture
+// groups, like regexp.Regexp.FindSubmatchIndex.
+func (r MatchResult) SubmatchIndex() []int {
+	return append([]int(nil), r.loc...)
+}
+
+// FindSubmatchIndex returns the offsets of the first match in b and
+// its capture groups, or nil if there is none, like
+// regexp.Regexp.FindSubmatchIndex.
+func (re *Regexp) FindSubmatchIndex(b []byte, flags int) []int {
+	m := re.AcquireMatcher()
+	defer m.Release()
+	m.Match(b, flags)
+	return m.SubmatchIndex()
+}
+
+// FindStringSubmatchIndex is equivalent to FindSubmatchIndex with a
+// string subject.
+func (re *Regexp) FindStringSubmatchIndex(s string, flags int) []int {
+	m := re.AcquireMatcher()
+	defer m.Release()
+	m.MatchString(s, flags)
+	return m.SubmatchIndex()
+}
+
+// FindAllIndex returns the start and end of the successive matches in
+// b, at most n if n >= 0, like regexp.Regexp.FindAllIndex.  Matches
+// are found as by FindAllNamed.
+func (re *Regexp) FindAllIndex(b []byte, n, flags int) [][]int {
+	return re.findAllIndex(bytesString(b), n, flags, false)
+}
+
+// FindAllStringIndex is equivalent to FindAllIndex with a string
+// subject.
+func (re *Regexp) FindAllStringIndex(s string, n, flags int) [][]int {
+	return re.findAllIndex(s, n, flags, false)
+}
+
+// FindAllSubmatchIndex returns the offsets of the successive matches
+// in b and their capture groups, at most n if n >= 0, like
+// regexp.Regexp.FindAllSubmatchIndex.  Matches are found as by
+// FindAllNamed.
+func (re *Regexp) FindAllSubmatchIndex(b []byte, n, flags int) [][]int {
+	return re.findAllIndex(bytesString(b), n, flags, true)
+}
+
+// FindAllStringSubmatchIndex is equivalent to FindAllSubmatchIndex
+// with a string subject.
+func (re *Regexp) FindAllStringSubmatchIndex(s string, n, flags int) [][]int {
+	return re.findAllIndex(s, n, flags, true)
+}
+
+// bytesString returns b as a string sharing its bytes, for subjects
+// which do not outlive the call.
+func bytesString(b []byte) string {
+	return unsafe.String(unsafe.SliceData(b), len(b))
+}
+
+func (re *Regexp) findAllIndex(subject string, n, flags int, groups bool) [][]int {
+	if !re.valid() {
+		uninitialized("Regexp.FindAllIndex")
+		return nil
+	}
+	if n == 0 {
+		return nil
+	}
+	var all [][]int
+	m := re.AcquireMatcher()
+	defer m.Release()
+	forEachMatchString(m, subject, 0, flags, func() bool {
+		if groups {
+			all = append(all, m.SubmatchIndex())
+		} else {
+			all = append(all, []int{int(m.ovector[0]), int(m.ovector[1])})
+		}
+		return n < 0 || len(all) < n
+	})
+	return all
+}
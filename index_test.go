@@ -0,0 +1,42 @@
+package pcre
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestIndexShapes(t *testing.T) {
+	for _, test := range []struct{ pattern, subject string }{
+		{`a(b)?(c)`, "ac abc xac"},
+		{`x*`, "axxb"},
+		{`(\w+)@(\w+)`, "no address"},
+		{`(?:(a)|b)+`, "abba"},
+	} {
+		re := MustCompile(test.pattern, 0)
+		std := regexp.MustCompile(test.pattern)
+		b := []byte(test.subject)
+		check := func(what string, got, want interface{}) {
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s %q in %q: got %v, want %v", what, test.pattern, test.subject, got, want)
+			}
+		}
+		check("FindSubmatchIndex", re.FindSubmatchIndex(b, 0), std.FindSubmatchIndex(b))
+		check("FindStringSubmatchIndex", re.FindStringSubmatchIndex(test.subject, 0),
+			std.FindStringSubmatchIndex(test.subject))
+		for _, n := range []int{-1, 0, 1, 2} {
+			check("FindAllIndex", re.FindAllIndex(b, n, 0), std.FindAllIndex(b, n))
+			check("FindAllStringIndex", re.FindAllStringIndex(test.subject, n, 0),
+				std.FindAllStringIndex(test.subject, n))
+			check("FindAllSubmatchIndex", re.FindAllSubmatchIndex(b, n, 0),
+				std.FindAllSubmatchIndex(b, n))
+			check("FindAllStringSubmatchIndex", re.FindAllStringSubmatchIndex(test.subject, n, 0),
+				std.FindAllStringSubmatchIndex(test.subject, n))
+		}
+		if m := re.MatcherString(test.subject, 0); m.Matches() {
+			r, _ := m.Result()
+			check("MatchResult.SubmatchIndex", r.SubmatchIndex(), std.FindStringSubmatchIndex(test.subject))
+		}
+		re.FreeRegexp()
+	}
+}
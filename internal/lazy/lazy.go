@@ -0,0 +1,31 @@
+// Package lazy holds the Regexps that the subpackages share between
+// their callers, compiled and studied on first use.
+package lazy
+
+import (
+	"sync"
+
+	"github.com/gijsbers/go-pcre"
+)
+
+// Regexp is a pattern compiled on first use.
+type Regexp struct {
+	once    sync.Once
+	pattern string
+	re      *pcre.Regexp
+}
+
+// New returns a Regexp for pattern, which must compile.
+func New(pattern string) *Regexp {
+	return &Regexp{pattern: pattern}
+}
+
+// Get compiles and studies the pattern on the first call, and returns
+// the shared Regexp.
+func (l *Regexp) Get() *pcre.Regexp {
+	l.once.Do(func() {
+		l.re = pcre.MustCompile(l.pattern, 0)
+		l.re.Study(0)
+	})
+	return l.re
+}
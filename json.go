@@ -0,0 +1,72 @@
+package pcre
+
+import "encoding/json"
+
+// spanJSON is the JSON form of a match or capture group.  Offsets are
+// byte offsets into the subject.
+type spanJSON struct {
+	Text  string `json:"text"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+type matchResultJSON struct {
+	spanJSON
+	Groups []*spanJSON          `json:"groups"`
+	Named  map[string]*spanJSON `json:"named"`
+}
+
+// MarshalJSON encodes the match as an object with the text, start and
+// end of the whole match, a "groups" array holding capture groups 1
+// and up, and a "named" object mapping group names to groups.  Groups
+// are objects like the match, or null if not present; with DUPNAMES,
+// a name maps to the first of its groups that is present.  Offsets
+// are byte offsets into the subject.  The zero MatchResult encodes as
+// null.
+func (r MatchResult) MarshalJSON() ([]byte, error) {
+	if len(r.loc) == 0 {
+		return []byte("null"), nil
+	}
+	span := func(group int) *spanJSON {
+		start, end := r.span(group)
+		if start < 0 {
+			return nil
+		}
+		return &spanJSON{r.Subject[start:end], start, end}
+	}
+	out := matchResultJSON{
+		spanJSON: *span(0),
+		Groups:   make([]*spanJSON, r.Groups()),
+		Named:    make(map[string]*spanJSON),
+	}
+	for i := range out.Groups {
+		out.Groups[i] = span(i + 1)
+	}
+	if r.Regexp != nil {
+		for name := range r.Regexp.names {
+			out.Named[name] = span(r.Regexp.groupOf(name, r.Present))
+		}
+	}
+	return json.Marshal(out)
+}
+
+// MarshalJSON encodes the match as an object with its text, start and
+// end, the same as the whole match of a MatchResult, so results of
+// FindAll can be returned as is.
+func (m Match) MarshalJSON() ([]byte, error) {
+	out := spanJSON{Text: m.Finding, Start: -1, End: -1}
+	if len(m.Loc) == 2 {
+		out.Start, out.End = m.Loc[0], m.Loc[1]
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON decodes a match encoded by MarshalJSON.
+func (m *Match) UnmarshalJSON(data []byte) error {
+	var in spanJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	m.Finding, m.Loc = in.Text, []int{in.Start, in.End}
+	return nil
+}
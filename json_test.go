@@ -0,0 +1,54 @@
+package pcre
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMatchResultJSON(t *testing.T) {
+	re := MustCompile(`(?<key>\w+)=(?<value>\d+)?(x)?`, 0)
+	defer re.FreeRegexp()
+	m := re.MatcherString("a key= b", 0)
+	r, ok := m.Result()
+	if !ok {
+		t.Fatal("no match")
+	}
+	got, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"text":"key=","start":2,"end":6,` +
+		`"groups":[{"text":"key","start":2,"end":5},null,null],` +
+		`"named":{"key":{"text":"key","start":2,"end":5},"value":null}}`
+	if string(got) != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if got, _ := json.Marshal(MatchResult{}); string(got) != "null" {
+		t.Errorf("zero MatchResult: got %s", got)
+	}
+}
+
+func TestMatchJSON(t *testing.T) {
+	re := MustCompile(`b+`, 0)
+	defer re.FreeRegexp()
+	matches, err := re.FindAll("abbcb", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := json.Marshal(matches)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"text":"bb","start":1,"end":3},{"text":"b","start":4,"end":5}]`
+	if string(got) != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	var back []Match
+	if err := json.Unmarshal(got, &back); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(back, matches) {
+		t.Errorf("round trip: got %v, want %v", back, matches)
+	}
+}
@@ -0,0 +1,53 @@
+package pcre
+
+// FindLastIndex returns the start and end of the last match of the
+// pattern in subject, or nil if there is none.  Matches are found as
+// by FindAllNamed, so the last match is the one that iterating over
+// all matches would end with; only its offsets are kept.  An error,
+// such as an exceeded match limit, ends the search and is returned.
+func (re *Regexp) FindLastIndex(subject []byte, flags int) ([]int, error) {
+	if !re.valid() {
+		return nil, uninitialized("Regexp.FindLastIndex")
+	}
+	return re.findLastIndex("Regexp.FindLastIndex", bytesString(subject), flags)
+}
+
+// FindLastStringIndex is equivalent to FindLastIndex with a string
+// subject.
+func (re *Regexp) FindLastStringIndex(subject string, flags int) ([]int, error) {
+	if !re.valid() {
+		return nil, uninitialized("Regexp.FindLastStringIndex")
+	}
+	return re.findLastIndex("Regexp.FindLastStringIndex", subject, flags)
+}
+
+// FindLastString returns the text of the last match of the pattern in
+// subject, or "" if there is none.  Use FindLastStringIndex to tell an
+// empty match from no match.
+func (re *Regexp) FindLastString(subject string, flags int) (string, error) {
+	if !re.valid() {
+		return "", uninitialized("Regexp.FindLastString")
+	}
+	loc, err := re.findLastIndex("Regexp.FindLastString", subject, flags)
+	if loc == nil {
+		return "", err
+	}
+	return subject[loc[0]:loc[1]], nil
+}
+
+func (re *Regexp) findLastIndex(op, subject string, flags int) ([]int, error) {
+	if err := checkMatchFlags(op, flags); err != nil {
+		return nil, err
+	}
+	m := re.AcquireMatcher()
+	defer m.Release()
+	last, found := [2]int{}, false
+	err := forEachMatchString(m, subject, 0, flags, func() bool {
+		last, found = [2]int{int(m.ovector[0]), int(m.ovector[1])}, true
+		return true
+	})
+	if err != nil || !found {
+		return nil, err
+	}
+	return last[:], nil
+}
@@ -0,0 +1,66 @@
+package pcre
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestFindLast(t *testing.T) {
+	for _, test := range []struct {
+		pattern, subject string
+		want             []int
+	}{
+		{`\d+`, "a1 b22 c333 d", []int{8, 11}},
+		{`\d+`, "abc", nil},
+		{`x*`, "axb", []int{3, 3}},
+		{`x*`, "ax", []int{1, 2}},
+		{`a|ab`, "abab", []int{2, 3}},
+		{`^\w`, "ab\ncd", []int{0, 1}},
+		{`(?m)^\w`, "ab\ncd", []int{3, 4}},
+		{`.`, "", nil},
+		{`.*`, "", []int{0, 0}},
+	} {
+		re := MustCompile(test.pattern, 0)
+		if got, err := re.FindLastIndex([]byte(test.subject), 0); err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s on %q: expected %v, got %v, %v", test.pattern, test.subject, test.want, got, err)
+		}
+		if got, err := re.FindLastStringIndex(test.subject, 0); err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s on %q: string: expected %v, got %v, %v", test.pattern, test.subject, test.want, got, err)
+		}
+		re.FreeRegexp()
+	}
+	re := MustCompile(`\w+`, 0)
+	defer re.FreeRegexp()
+	if got, err := re.FindLastString("one two three.", 0); err != nil || got != "three" {
+		t.Errorf("FindLastString: got %q, %v", got, err)
+	}
+
+	// Empty matches advance by a character in UTF-8 mode.
+	utf := MustCompile(`x*`, UTF8)
+	defer utf.FreeRegexp()
+	if got, err := utf.FindLastStringIndex("äö", 0); err != nil || !reflect.DeepEqual(got, []int{4, 4}) {
+		t.Errorf("UTF-8: got %v, %v", got, err)
+	}
+
+	// Errors end the search and are returned.
+	slow := MustCompile(`(a+)+$`, 0)
+	defer slow.FreeRegexp()
+	slow.SetLimits(100, 0)
+	var lerr *LimitError
+	if loc, err := slow.FindLastStringIndex("aaaaaaaaaaaaaaaaaaaaaaaab", 0); loc != nil || !errors.As(err, &lerr) {
+		t.Errorf("expected LimitError, got %v, %v", loc, err)
+	}
+
+	if _, err := re.FindLastStringIndex("a", CASELESS); err == nil {
+		t.Error("expected error for a compile flag")
+	}
+
+	// Invalid UTF-8 is handled as by the other Find methods.
+	b := MustCompile("b", UTF8)
+	defer b.FreeRegexp()
+	b.SetInvalidUTF8(INVALID_UTF8_REPLACE)
+	if got, err := b.FindLastStringIndex("\xffb\xffb", 0); err != nil || !reflect.DeepEqual(got, []int{3, 4}) {
+		t.Errorf("INVALID_UTF8_REPLACE: got %v, %v", got, err)
+	}
+}
@@ -0,0 +1,113 @@
+package pcre
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"sync"
+)
+
+// LineFilter selects lines by include and exclude patterns, which are
+// evaluated in the order they were added: the first pattern that
+// matches a line decides whether it is selected.  A line that no
+// pattern matches is selected if the filter has no include patterns.
+// Invert reverses the selection, like grep -v.
+//
+// The zero value selects every line.  Patterns are added before the
+// filter is used; it may then be used concurrently.  The Regexp
+// objects must stay valid while the filter is in use.
+type LineFilter struct {
+	Invert   bool
+	rules    []lineRule
+	includes bool
+	matchers sync.Pool
+}
+
+type lineRule struct {
+	re      *Regexp
+	include bool
+}
+
+// Include adds a pattern selecting the lines it matches.
+func (f *LineFilter) Include(re *Regexp) {
+	f.rules = append(f.rules, lineRule{re, true})
+	f.includes = true
+}
+
+// Exclude adds a pattern rejecting the lines it matches.
+func (f *LineFilter) Exclude(re *Regexp) {
+	f.rules = append(f.rules, lineRule{re, false})
+}
+
+// Select reports whether the line, without its line ending, is
+// selected.  It fails if a match fails, such as by exceeding the
+// match limit.
+func (f *LineFilter) Select(line []byte) (bool, error) {
+	m, _ := f.matchers.Get().(*Matcher)
+	if m == nil {
+		m = new(Matcher)
+	}
+	defer f.matchers.Put(m)
+	return f.selectWith(m, line)
+}
+
+func (f *LineFilter) selectWith(m *Matcher, line []byte) (bool, error) {
+	selected := !f.includes
+	for _, rule := range f.rules {
+		if m.Reset(rule.re, line, 0) {
+			selected = rule.include
+			break
+		}
+		if err := m.Err(); err != nil {
+			return false, err
+		}
+	}
+	return selected != f.Invert, nil
+}
+
+// Reader returns a reader which passes through the selected lines of
+// r, with their line endings.  A failed match is returned as error by
+// Read.
+func (f *LineFilter) Reader(r io.Reader) io.Reader {
+	return &lineFilterReader{f: f, r: bufio.NewReader(r), m: new(Matcher)}
+}
+
+type lineFilterReader struct {
+	f   *LineFilter
+	r   *bufio.Reader
+	m   *Matcher
+	buf []byte // rest of the selected line being read
+	err error
+}
+
+func (lr *lineFilterReader) Read(p []byte) (int, error) {
+	for len(lr.buf) == 0 {
+		if lr.err != nil {
+			return 0, lr.err
+		}
+		line, err := lr.r.ReadBytes('\n')
+		lr.err = err
+		if len(line) == 0 {
+			continue
+		}
+		selected, err := lr.f.selectWith(lr.m, trimLineEnding(line))
+		if err != nil {
+			lr.err = err
+			return 0, err
+		}
+		if selected {
+			lr.buf = line
+		}
+	}
+	n := copy(p, lr.buf)
+	lr.buf = lr.buf[n:]
+	return n, nil
+}
+
+// FilterLines returns the selected lines of text, with their line
+// endings.
+func (f *LineFilter) FilterLines(text []byte) ([]byte, error) {
+	var out bytes.Buffer
+	_, err := io.Copy(&out, f.Reader(bytes.NewReader(text)))
+	return out.Bytes(), err
+}
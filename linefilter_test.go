@@ -0,0 +1,44 @@
+package pcre
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLineFilter(t *testing.T) {
+	const input = "INFO start\nDEBUG x\nERROR disk\nERROR ignored: disk\nWARN slow\nlast"
+	errors := MustCompile(`^ERROR`, 0)
+	defer errors.FreeRegexp()
+	ignored := MustCompile(`ignored`, 0)
+	defer ignored.FreeRegexp()
+	debug := MustCompile(`^DEBUG`, 0)
+	defer debug.FreeRegexp()
+
+	var all LineFilter
+	if got, err := all.FilterLines([]byte(input)); err != nil || string(got) != input {
+		t.Errorf("zero filter: %q, %v", got, err)
+	}
+
+	var f LineFilter
+	f.Exclude(ignored)
+	f.Include(errors)
+	if got, _ := f.FilterLines([]byte(input)); string(got) != "ERROR disk\n" {
+		t.Errorf("include: %q", got)
+	}
+	f.Invert = true
+	if got, _ := f.FilterLines([]byte(input)); string(got) != "INFO start\nDEBUG x\nERROR ignored: disk\nWARN slow\nlast" {
+		t.Errorf("inverted: %q", got)
+	}
+
+	var g LineFilter
+	g.Exclude(debug)
+	r := g.Reader(strings.NewReader(input))
+	got, err := io.ReadAll(r)
+	if err != nil || string(got) != "INFO start\nERROR disk\nERROR ignored: disk\nWARN slow\nlast" {
+		t.Errorf("exclude: %q, %v", got, err)
+	}
+	if ok, err := g.Select([]byte("DEBUG y")); ok || err != nil {
+		t.Errorf("Select: %v, %v", ok, err)
+	}
+}
@@ -0,0 +1,65 @@
+package logformat
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/gijsbers/go-pcre"
+)
+
+// jsonValuePattern matches a JSON string, number or literal.  Objects
+// and arrays are not matched.
+const jsonValuePattern = `"(?:[^"\\]|\\.)*"|-?\d+(?:\.\d+)?(?:[eE][+-]?\d+)?|true|false|null`
+
+// JSONProbe extracts the values of some keys from JSON lines by
+// pattern matching, which is much faster than decoding lines to find
+// a few fields in them.  It is a probe, not a parser: keys are found
+// at any depth of nesting, the first occurrence of a key wins, and
+// values which are objects or arrays are not extracted.  A JSONProbe
+// may be used concurrently.  Close frees its pattern.
+type JSONProbe struct {
+	re *pcre.Regexp
+}
+
+// NewJSONProbe returns a probe for the keys.
+func NewJSONProbe(keys ...string) (*JSONProbe, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("logformat.NewJSONProbe: no keys")
+	}
+	quoted := make([]string, len(keys))
+	for i, key := range keys {
+		// Match the key as json.Marshal writes it.
+		b, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		quoted[i] = pcre.QuoteMeta(string(b[1 : len(b)-1]))
+	}
+	re, err := pcre.Compile(`"(?<key>`+strings.Join(quoted, "|")+`)"\s*:\s*(?<value>`+jsonValuePattern+`)`, 0)
+	if err != nil {
+		return nil, err
+	}
+	re.Study(0)
+	return &JSONProbe{re: re}, nil
+}
+
+// Probe returns the raw values of the keys found in line, which can
+// be decoded with json.Unmarshal.  Keys are those of NewJSONProbe,
+// escaped as in JSON.  An error, such as an exceeded match limit,
+// ends the search.
+func (p *JSONProbe) Probe(line string) (map[string]json.RawMessage, error) {
+	values := make(map[string]json.RawMessage)
+	matches, err := p.re.FindAllNamed(line, 0)
+	for _, m := range matches {
+		if _, ok := values[m["key"]]; !ok {
+			values[m["key"]] = json.RawMessage(m["value"])
+		}
+	}
+	return values, err
+}
+
+// Close frees the pattern of the probe.
+func (p *JSONProbe) Close() {
+	p.re.FreeRegexp()
+}
@@ -0,0 +1,188 @@
+// Package logformat provides tested patterns for common log formats,
+// with named groups for their fields, and functions converting a
+// line to a struct with typed fields, as well as a JSONProbe for
+// picking fields out of JSON lines:
+//
+//	entry, err := logformat.ParseCombined(line)
+//	if err != nil {
+//		return err // pcre.ErrNoMatch if line is not in the format
+//	}
+//	if entry.Status >= 500 { ... }
+//
+// Each pattern is available as a constant, for use with other
+// functions of the pcre package, and through a function returning a
+// Regexp that is compiled and studied on first use and shared by all
+// callers.  The shared Regexp objects must not be freed.  The
+// patterns match a whole line, without its line ending.
+package logformat
+
+import (
+	"time"
+
+	"github.com/gijsbers/go-pcre"
+	"github.com/gijsbers/go-pcre/internal/lazy"
+)
+
+// Patterns for the formats.  All of them may be compiled without
+// flags.
+const (
+	// CombinedPattern matches lines of the combined log format of
+	// Apache and Nginx, and of the common log format, which lacks
+	// the referer and user agent.  The groups are host, ident,
+	// user, time, request, with method, path and protocol if the
+	// request is well-formed, status, size, absent if it is "-",
+	// referer and agent.
+	CombinedPattern = `\A(?<host>\S+) (?<ident>\S+) (?<user>\S+) \[(?<time>[^\]]+)\] ` +
+		`"(?<request>(?<method>[A-Z]+) (?<path>\S+) (?<protocol>[^"\s]+)|(?:[^"\\]|\\.)*)" ` +
+		`(?<status>\d{3}) (?:(?<size>\d+)|-)` +
+		`(?: "(?<referer>(?:[^"\\]|\\.)*)" "(?<agent>(?:[^"\\]|\\.)*)")?\z`
+
+	// Syslog3164Pattern matches BSD syslog lines as described by RFC
+	// 3164, with or without the priority, as in /var/log/syslog.
+	// The groups are priority, time, host, tag, pid and message.
+	Syslog3164Pattern = `\A(?:<(?<priority>\d{1,3})>)?` +
+		`(?<time>[A-Z][a-z]{2} [ \d]\d \d\d:\d\d:\d\d) (?<host>\S+) ` +
+		`(?<tag>[^\s:\[]+)(?:\[(?<pid>\d+)\])?: ?(?<message>.*)\z`
+
+	// Syslog5424Pattern matches syslog lines in the format of RFC
+	// 5424.  The groups are priority, version, time, host, app,
+	// procid, msgid, data, the structured data, and message.
+	// Fields with the nil value "-" are absent.
+	Syslog5424Pattern = `\A<(?<priority>\d{1,3})>(?<version>[1-9]\d{0,2}) ` +
+		`(?:-|(?<time>\S+)) (?:-|(?<host>\S+)) (?:-|(?<app>\S+)) ` +
+		`(?:-|(?<procid>\S+)) (?:-|(?<msgid>\S+)) ` +
+		`(?:-|(?<data>(?:\[(?:[^\]\\"]|\\.|"(?:[^"\\]|\\.)*")*\])+))` +
+		`(?: (?:\xEF\xBB\xBF)?(?<message>.*))?\z`
+
+	// GoTestPattern matches the result lines of go test -v, such as
+	// "--- PASS: TestName (0.01s)", indented for subtests.  The
+	// groups are result, PASS, FAIL or SKIP, test and elapsed.
+	GoTestPattern = `\A\s*--- (?<result>PASS|FAIL|SKIP): (?<test>\S+) \((?<elapsed>\d+(?:\.\d+)?s)\)\z`
+
+	// GoTestPackagePattern matches the summary lines of go test for
+	// a package, such as "ok  	example.com/pkg	0.012s".  The groups
+	// are result, ok or FAIL, package, and elapsed, absent for
+	// cached results and packages that failed to build.
+	GoTestPackagePattern = `\A(?<result>ok|FAIL)\s+(?<package>\S+)` +
+		`(?:\s+(?:(?<elapsed>\d+(?:\.\d+)?s)|\(cached\)|\[[^\]]*\]))?(?:\s.*)?\z`
+)
+
+var (
+	combined      = lazy.New(CombinedPattern)
+	syslog3164    = lazy.New(Syslog3164Pattern)
+	syslog5424    = lazy.New(Syslog5424Pattern)
+	goTest        = lazy.New(GoTestPattern)
+	goTestPackage = lazy.New(GoTestPackagePattern)
+)
+
+// Combined returns the shared Regexp of CombinedPattern.
+func Combined() *pcre.Regexp { return combined.Get() }
+
+// Syslog3164 returns the shared Regexp of Syslog3164Pattern.
+func Syslog3164() *pcre.Regexp { return syslog3164.Get() }
+
+// Syslog5424 returns the shared Regexp of Syslog5424Pattern.
+func Syslog5424() *pcre.Regexp { return syslog5424.Get() }
+
+// GoTest returns the shared Regexp of GoTestPattern.
+func GoTest() *pcre.Regexp { return goTest.Get() }
+
+// GoTestPackage returns the shared Regexp of GoTestPackagePattern.
+func GoTestPackage() *pcre.Regexp { return goTestPackage.Get() }
+
+// CombinedEntry is a line of the combined log format.
+type CombinedEntry struct {
+	Host      string    `pcre:"host"`
+	Ident     string    `pcre:"ident"`
+	User      string    `pcre:"user"`
+	Time      time.Time `pcre:"time,layout=02/Jan/2006:15:04:05 -0700"`
+	Request   string    `pcre:"request"`
+	Method    string    `pcre:"method"`
+	Path      string    `pcre:"path"`
+	Protocol  string    `pcre:"protocol"`
+	Status    int       `pcre:"status"`
+	Size      int64     `pcre:"size"`
+	Referer   string    `pcre:"referer"`
+	UserAgent string    `pcre:"agent"`
+}
+
+// ParseCombined parses a line of the combined or common log format.
+// It fails with pcre.ErrNoMatch if the line is not in the format, or
+// with an error naming the field that could not be converted.
+func ParseCombined(line string) (e CombinedEntry, err error) {
+	err = pcre.Unmarshal(Combined(), line, &e)
+	return e, err
+}
+
+// Syslog3164Entry is a line of the RFC 3164 syslog format.  The
+// timestamp has no year; Time is in year 0 and UTC.
+type Syslog3164Entry struct {
+	Priority *int      `pcre:"priority"` // nil if the line has none
+	Time     time.Time `pcre:"time,layout=Jan _2 15:04:05"`
+	Host     string    `pcre:"host"`
+	Tag      string    `pcre:"tag"`
+	PID      *int      `pcre:"pid"`
+	Message  string    `pcre:"message"`
+}
+
+// ParseSyslog3164 parses a line of the RFC 3164 syslog format, as
+// ParseCombined does.
+func ParseSyslog3164(line string) (e Syslog3164Entry, err error) {
+	err = pcre.Unmarshal(Syslog3164(), line, &e)
+	return e, err
+}
+
+// Syslog5424Entry is a line of the RFC 5424 syslog format.  Fields
+// with the nil value "-" are left empty.
+type Syslog5424Entry struct {
+	Priority       int       `pcre:"priority"`
+	Version        int       `pcre:"version"`
+	Time           time.Time `pcre:"time"`
+	Host           string    `pcre:"host"`
+	App            string    `pcre:"app"`
+	ProcID         string    `pcre:"procid"`
+	MsgID          string    `pcre:"msgid"`
+	StructuredData string    `pcre:"data"`
+	Message        string    `pcre:"message"`
+}
+
+// Facility returns the facility encoded in the priority.
+func (e Syslog5424Entry) Facility() int { return e.Priority / 8 }
+
+// Severity returns the severity encoded in the priority.
+func (e Syslog5424Entry) Severity() int { return e.Priority % 8 }
+
+// ParseSyslog5424 parses a line of the RFC 5424 syslog format, as
+// ParseCombined does.
+func ParseSyslog5424(line string) (e Syslog5424Entry, err error) {
+	err = pcre.Unmarshal(Syslog5424(), line, &e)
+	return e, err
+}
+
+// GoTestResult is a result line of go test -v.
+type GoTestResult struct {
+	Result  string        `pcre:"result"` // PASS, FAIL or SKIP
+	Test    string        `pcre:"test"`   // Name, with subtests after slashes
+	Elapsed time.Duration `pcre:"elapsed"`
+}
+
+// ParseGoTest parses a result line of go test -v, as ParseCombined
+// does.
+func ParseGoTest(line string) (r GoTestResult, err error) {
+	err = pcre.Unmarshal(GoTest(), line, &r)
+	return r, err
+}
+
+// GoTestPackageResult is a summary line of go test for a package.
+type GoTestPackageResult struct {
+	Result  string        `pcre:"result"` // ok or FAIL
+	Package string        `pcre:"package"`
+	Elapsed time.Duration `pcre:"elapsed"` // 0 if cached or not run
+}
+
+// ParseGoTestPackage parses a summary line of go test for a package,
+// as ParseCombined does.
+func ParseGoTestPackage(line string) (r GoTestPackageResult, err error) {
+	err = pcre.Unmarshal(GoTestPackage(), line, &r)
+	return r, err
+}
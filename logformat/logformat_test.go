@@ -0,0 +1,122 @@
+package logformat
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gijsbers/go-pcre"
+)
+
+func TestParseCombined(t *testing.T) {
+	e, err := ParseCombined(`127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" ` +
+		`200 2326 "http://www.example.com/start.html" "Mozilla/4.08 [en] (Win98; I ;Nav)"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := CombinedEntry{
+		Host: "127.0.0.1", Ident: "-", User: "frank",
+		Time:    time.Date(2000, 10, 10, 20, 55, 36, 0, time.UTC),
+		Request: "GET /apache_pb.gif HTTP/1.0", Method: "GET", Path: "/apache_pb.gif", Protocol: "HTTP/1.0",
+		Status: 200, Size: 2326,
+		Referer: "http://www.example.com/start.html", UserAgent: "Mozilla/4.08 [en] (Win98; I ;Nav)",
+	}
+	if !e.Time.Equal(want.Time) {
+		t.Errorf("time: got %v, want %v", e.Time, want.Time)
+	}
+	e.Time = want.Time
+	if e != want {
+		t.Errorf("got %+v, want %+v", e, want)
+	}
+
+	// Common log format, no size, malformed request.
+	e, err = ParseCombined(`10.0.0.1 - - [10/Oct/2000:13:55:36 +0000] "\x16\x03" 400 -`)
+	if err != nil || e.Request != `\x16\x03` || e.Method != "" || e.Status != 400 || e.Size != 0 || e.UserAgent != "" {
+		t.Errorf("common log format: got %+v, %v", e, err)
+	}
+	if _, err := ParseCombined("garbage"); !errors.Is(err, pcre.ErrNoMatch) {
+		t.Errorf("expected ErrNoMatch, got %v", err)
+	}
+}
+
+func TestParseSyslog(t *testing.T) {
+	e, err := ParseSyslog3164("Feb  5 17:32:18 host sshd[1234]: Accepted publickey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Priority != nil || e.PID == nil || *e.PID != 1234 || e.Tag != "sshd" || e.Host != "host" ||
+		e.Message != "Accepted publickey" || e.Time.Month() != time.February || e.Time.Day() != 5 {
+		t.Errorf("RFC 3164: got %+v", e)
+	}
+	e, err = ParseSyslog3164("<34>Oct 11 22:14:15 mymachine su: 'su root' failed")
+	if err != nil || e.Priority == nil || *e.Priority != 34 || e.PID != nil || e.Message != "'su root' failed" {
+		t.Errorf("RFC 3164 with priority: got %+v, %v", e, err)
+	}
+
+	f, err := ParseSyslog5424(`<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 ` +
+		`[exampleSDID@32473 iut="3" eventSource="Application"] An application event`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Facility() != 20 || f.Severity() != 5 || f.Version != 1 || f.App != "evntslog" || f.ProcID != "" ||
+		f.MsgID != "ID47" || f.StructuredData != `[exampleSDID@32473 iut="3" eventSource="Application"]` ||
+		f.Message != "An application event" || f.Time.Nanosecond() != 3000000 {
+		t.Errorf("RFC 5424: got %+v", f)
+	}
+	f, err = ParseSyslog5424("<13>1 - - - - - -")
+	if err != nil || !f.Time.IsZero() || f.Host != "" || f.Message != "" {
+		t.Errorf("RFC 5424 nil values: got %+v, %v", f, err)
+	}
+}
+
+func TestParseGoTest(t *testing.T) {
+	r, err := ParseGoTest("    --- FAIL: TestFoo/sub_case (1.50s)")
+	if err != nil || r != (GoTestResult{"FAIL", "TestFoo/sub_case", 1500 * time.Millisecond}) {
+		t.Errorf("got %+v, %v", r, err)
+	}
+	if _, err := ParseGoTest("=== RUN   TestFoo"); !errors.Is(err, pcre.ErrNoMatch) {
+		t.Errorf("expected ErrNoMatch, got %v", err)
+	}
+	for line, want := range map[string]GoTestPackageResult{
+		"ok  \texample.com/pkg\t0.012s":        {"ok", "example.com/pkg", 12 * time.Millisecond},
+		"ok  \texample.com/pkg\t(cached)":      {"ok", "example.com/pkg", 0},
+		"FAIL\texample.com/pkg [build failed]": {"FAIL", "example.com/pkg", 0},
+	} {
+		if r, err := ParseGoTestPackage(line); err != nil || r != want {
+			t.Errorf("%q: got %+v, %v", line, r, err)
+		}
+	}
+}
+
+func TestJSONProbe(t *testing.T) {
+	p, err := NewJSONProbe("level", "status", "msg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	values, err := p.Probe(`{"time":"x","level" : "warn","msg":"say \"level\": 1","status":503,"extra":{"status":1}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var level, msg string
+	var status int
+	if json.Unmarshal(values["level"], &level) != nil || level != "warn" {
+		t.Errorf("level: got %s", values["level"])
+	}
+	if json.Unmarshal(values["msg"], &msg) != nil || msg != `say "level": 1` {
+		t.Errorf("msg: got %s", values["msg"])
+	}
+	if json.Unmarshal(values["status"], &status) != nil || status != 503 {
+		t.Errorf("status: got %s", values["status"])
+	}
+	if len(values) != 3 {
+		t.Errorf("got %d values", len(values))
+	}
+}
+
+func TestShared(t *testing.T) {
+	if Combined() != Combined() {
+		t.Error("Combined is compiled more than once")
+	}
+}
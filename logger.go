@@ -0,0 +1,64 @@
+package pcre
+
+import (
+	"context"
+	"hash/fnv"
+	"log/slog"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+var logger atomic.Pointer[slog.Logger]
+
+// SetLogger makes the package log its activity to l:
+//
+//   - "pcre compile", "pcre study" and "pcre free" at debug level,
+//     with the duration and the error, if any,
+//   - "pcre match error" and "pcre limit hit" at warning level, with
+//     the pcre_exec return code.
+//
+// Events carry a "fingerprint" attribute which identifies the pattern
+// without revealing it; see Fingerprint.  A nil logger, the default,
+// turns logging off.
+func SetLogger(l *slog.Logger) {
+	logger.Store(l)
+}
+
+// Fingerprint returns a short hash of the pattern, as used in log
+// events.
+func Fingerprint(pattern string) string {
+	h := fnv.New64a()
+	h.Write([]byte(pattern))
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+func logEvent(level slog.Level, msg, pattern string, attrs ...slog.Attr) {
+	l := logger.Load()
+	if l == nil || !l.Enabled(context.Background(), level) {
+		return
+	}
+	attrs = append(attrs, slog.String("fingerprint", Fingerprint(pattern)))
+	l.LogAttrs(context.Background(), level, msg, attrs...)
+}
+
+func logTimed(msg, pattern string, flags int, d time.Duration, err error) {
+	attrs := []slog.Attr{slog.Int("flags", flags), slog.Duration("duration", d)}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	logEvent(slog.LevelDebug, msg, pattern, attrs...)
+}
+
+// logExec logs and counts pcre_exec return codes other than a match
+// or no match.
+func logExec(pattern string, rc int) {
+	switch {
+	case rc >= 0 || rc == ERROR_NOMATCH || rc == ERROR_PARTIAL:
+	case rc == ERROR_MATCHLIMIT || rc == ERROR_RECURSIONLIMIT:
+		count(&countLimit)
+		logEvent(slog.LevelWarn, "pcre limit hit", pattern, slog.Int("rc", rc))
+	default:
+		logEvent(slog.LevelWarn, "pcre match error", pattern, slog.Int("rc", rc))
+	}
+}
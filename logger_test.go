@@ -0,0 +1,42 @@
+package pcre
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSetLogger(t *testing.T) {
+	var buf bytes.Buffer
+	SetLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	defer SetLogger(nil)
+
+	re := MustCompile("(a+)+$", 0)
+	re.Study(0)
+	re.SetLimits(100, 0)
+	re.MatcherString("aaaaaaaaaaaaaaaaaaaaaaaab", 0)
+	re.FreeRegexp()
+	Compile("(", 0)
+
+	out := buf.String()
+	for _, want := range []string{
+		`msg="pcre compile"`, `msg="pcre study"`, `msg="pcre limit hit"`,
+		`msg="pcre free"`, "fingerprint=" + Fingerprint("(a+)+$"),
+		`rc=-8`, `error=`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log lacks %s:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "(a+)+$") {
+		t.Error("log reveals the pattern")
+	}
+
+	buf.Reset()
+	SetLogger(nil)
+	MustCompile("a", 0)
+	if buf.Len() != 0 {
+		t.Error("logged after SetLogger(nil)")
+	}
+}
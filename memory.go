@@ -0,0 +1,88 @@
+package pcre
+
+// #include <stdlib.h>
+// #include "./pcre.h"
+//
+// // Bytes allocated through pcre_malloc and not yet freed, and the
+// // most that may be, or 0 for no limit.
+// static size_t pcre_mem_in_use, pcre_mem_limit;
+//
+// // Each block starts with its size, in a header which keeps the
+// // alignment of malloc.
+// #define PCRE_MEM_HEADER 16
+//
+// static void *pcre_counting_malloc(size_t n) {
+//     size_t limit = __atomic_load_n(&pcre_mem_limit, __ATOMIC_RELAXED);
+//     size_t used = __atomic_add_fetch(&pcre_mem_in_use, n, __ATOMIC_RELAXED);
+//     char *p = NULL;
+//     if (limit == 0 || used <= limit)
+//         p = malloc(PCRE_MEM_HEADER + n);
+//     if (p == NULL) {
+//         __atomic_sub_fetch(&pcre_mem_in_use, n, __ATOMIC_RELAXED);
+//         return NULL;
+//     }
+//     *(size_t *)p = n;
+//     return p + PCRE_MEM_HEADER;
+// }
+//
+// static void pcre_counting_free(void *block) {
+//     char *p;
+//     if (block == NULL)
+//         return;
+//     p = (char *)block - PCRE_MEM_HEADER;
+//     __atomic_sub_fetch(&pcre_mem_in_use, *(size_t *)p, __ATOMIC_RELAXED);
+//     free(p);
+// }
+//
+// #ifdef PCRE_DLL_SHIM
+// void pcre_dll_set_allocator(void *(*)(size_t), void (*)(void *));
+// #endif
+//
+// static void pcre_install_allocator(void) {
+// #ifdef PCRE_DLL_SHIM
+//     pcre_dll_set_allocator(pcre_counting_malloc, pcre_counting_free);
+// #else
+//     pcre_malloc = pcre_counting_malloc;
+//     pcre_free = pcre_counting_free;
+// #endif
+// }
+//
+// static size_t pcre_mem_get(void) {
+//     return __atomic_load_n(&pcre_mem_in_use, __ATOMIC_RELAXED);
+// }
+//
+// static size_t pcre_mem_set_limit(size_t limit) {
+//     return __atomic_exchange_n(&pcre_mem_limit, limit, __ATOMIC_RELAXED);
+// }
+import "C"
+
+// The allocator is installed before any pattern is compiled, so that
+// every block it frees is one it allocated.
+func init() {
+	C.pcre_install_allocator()
+}
+
+// MemoryInUse returns the number of bytes PCRE has allocated and not
+// freed, for compiled patterns, study data and the working memory of
+// matches in progress.  The package installs its own pcre_malloc and
+// pcre_free to count them.  The executable memory of JIT code, which
+// PCRE allocates separately, is not included; Regexp.Size counts
+// it for each pattern.  Patterns compiled with the pcre16 and pcre32
+// build tags are not counted either.
+func MemoryInUse() int64 {
+	return int64(C.pcre_mem_get())
+}
+
+// SetMemoryLimit sets the most bytes that PCRE may have allocated, as
+// reported by MemoryInUse, and returns the previous limit.  A limit of
+// zero or less, the default, removes it.  An allocation which would
+// exceed the limit fails: Compile then fails with a *CompileError for
+// which IsTooLarge reports true, Study fails, and a match which needs
+// working memory fails with ERROR_NOMEMORY.  Memory already allocated
+// is not affected by lowering the limit.
+func SetMemoryLimit(limit int64) int64 {
+	if limit < 0 {
+		limit = 0
+	}
+	return int64(C.pcre_mem_set_limit(C.size_t(limit)))
+}
@@ -0,0 +1,29 @@
+package pcre
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMemoryInUse(t *testing.T) {
+	re := MustCompile(`(\w+)@(\w+)\.com`, 0)
+	defer re.FreeRegexp()
+	if got := MemoryInUse(); got < int64(pcreSize(re.ptr)) {
+		t.Errorf("MemoryInUse: %d bytes, less than the pattern", got)
+	}
+}
+
+func TestSetMemoryLimit(t *testing.T) {
+	defer SetMemoryLimit(SetMemoryLimit(1))
+	_, err := Compile(`(\w+)@(\w+)\.com`, 0)
+	var ce *CompileError
+	if !errors.As(err, &ce) || !IsTooLarge(err) {
+		t.Errorf("expected the limit to fail compilation, got %v", err)
+	}
+	SetMemoryLimit(0)
+	re, err := Compile(`(\w+)@(\w+)\.com`, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	re.FreeRegexp()
+}
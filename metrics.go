@@ -0,0 +1,85 @@
+package pcre
+
+// #include "./pcre.h"
+import "C"
+
+import (
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// ExecResult classifies the outcome of a pcre_exec call for metrics.
+type ExecResult int
+
+// Outcomes reported to MetricsSink.ExecDone
+const (
+	EXEC_MATCH   ExecResult = iota // The subject matched
+	EXEC_NOMATCH                   // The subject did not match
+	EXEC_PARTIAL                   // The subject matched partially
+	EXEC_LIMIT                     // The match or recursion limit was hit
+	EXEC_ERROR                     // Any other error
+)
+
+// MetricsSink receives measurements of the package's activity, for
+// export to a monitoring system such as Prometheus or OpenTelemetry.
+// Its methods are called synchronously from Compile, Study and the
+// Match and Exec functions, possibly concurrently, so they should be
+// fast and safe for concurrent use.
+type MetricsSink interface {
+	// CompileDone is called after each Compile.
+	CompileDone(d time.Duration, err error)
+	// StudyDone is called after each Study.
+	StudyDone(d time.Duration, err error)
+	// ExecDone is called after each pcre_exec call.
+	ExecDone(d time.Duration, result ExecResult)
+	// JITFallback is called when Study was asked for JIT
+	// compilation, but the pattern will be interpreted instead.
+	JITFallback()
+}
+
+var metrics atomic.Pointer[MetricsSink]
+
+// SetMetricsSink makes the package report measurements to sink.  A nil
+// sink, the default, turns metrics off.
+func SetMetricsSink(sink MetricsSink) {
+	if sink == nil {
+		metrics.Store(nil)
+		return
+	}
+	metrics.Store(&sink)
+}
+
+// metricsSink returns the current sink, or nil.
+func metricsSink() MetricsSink {
+	if sink := metrics.Load(); sink != nil {
+		return *sink
+	}
+	return nil
+}
+
+// execResult classifies a pcre_exec return code.
+func execResult(rc int) ExecResult {
+	switch {
+	case rc >= 0:
+		return EXEC_MATCH
+	case rc == ERROR_NOMATCH:
+		return EXEC_NOMATCH
+	case rc == ERROR_PARTIAL:
+		return EXEC_PARTIAL
+	case rc == ERROR_MATCHLIMIT || rc == ERROR_RECURSIONLIMIT:
+		return EXEC_LIMIT
+	}
+	return EXEC_ERROR
+}
+
+// jitCompiled reports whether the study data holds JIT code.
+// The caller holds re.mu.
+func (re *Regexp) jitCompiled() bool {
+	if re.ptr == nil || re.extra == nil {
+		return false
+	}
+	var jit C.int
+	C.pcre_fullinfo(re.ptr, re.extra, C.PCRE_INFO_JIT, unsafe.Pointer(&jit))
+	return jit != 0
+}
@@ -0,0 +1,93 @@
+package pcre
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+type testSink struct {
+	mu       sync.Mutex
+	compiles int
+	errors   int
+	studies  int
+	results  map[ExecResult]int
+	fallback int
+}
+
+func (s *testSink) CompileDone(d time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.compiles++
+	if err != nil {
+		s.errors++
+	}
+}
+
+func (s *testSink) StudyDone(d time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.studies++
+}
+
+func (s *testSink) ExecDone(d time.Duration, result ExecResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results[result]++
+}
+
+func (s *testSink) JITFallback() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fallback++
+}
+
+func TestMetricsSink(t *testing.T) {
+	sink := &testSink{results: make(map[ExecResult]int)}
+	SetMetricsSink(sink)
+	defer SetMetricsSink(nil)
+
+	re := MustCompileJIT("(a+)+$", 0, STUDY_JIT_COMPILE)
+	Compile("(", 0)
+	m := re.MatcherString("aaa", 0)
+	m.MatchString("b", 0)
+	m.MatchString("aa", PARTIAL_SOFT)
+	re2 := MustCompile("(a+)+$", 0)
+	re2.SetLimits(100, 0)
+	re2.MatcherString("aaaaaaaaaaaaaaaaaaaaaaaab", 0)
+
+	if sink.compiles != 3 || sink.errors != 1 || sink.studies != 1 {
+		t.Errorf("unexpected counts %+v", sink)
+	}
+	if sink.results[EXEC_MATCH] != 2 || sink.results[EXEC_NOMATCH] != 1 ||
+		sink.results[EXEC_LIMIT] != 1 {
+		t.Error("unexpected results", sink.results)
+	}
+	if (sink.fallback == 0) != Capabilities().JIT {
+		t.Error("JIT fallbacks", sink.fallback)
+	}
+}
+
+func TestStudyWithoutJIT(t *testing.T) {
+	saved := features
+	features.JIT = false
+	defer func() { features = saved }()
+	sink := &testSink{results: make(map[ExecResult]int)}
+	SetMetricsSink(sink)
+	defer SetMetricsSink(nil)
+
+	re, err := CompileJIT("a+b", 0, STUDY_JIT_COMPILE)
+	if err != nil {
+		t.Fatal("CompileJIT failed without JIT support:", err)
+	}
+	defer re.FreeRegexp()
+	if info := re.StudyInfo(); info.JIT {
+		t.Error("JIT compiled although not supported", info)
+	}
+	if !re.MatcherString("xaab", 0).Matches() {
+		t.Error("no match")
+	}
+	if sink.fallback != 1 {
+		t.Error("JIT fallbacks", sink.fallback)
+	}
+}
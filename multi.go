@@ -0,0 +1,220 @@
+package pcre
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MultiPattern combines several patterns into one alternation, so that
+// a single match attempt tries all of them, instead of one pcre_exec
+// call per pattern.  Each pattern becomes a capture group of the
+// combined Regexp, followed by its own groups, and the methods of the
+// MultiPattern map the groups of a match back to the pattern that
+// matched.
+//
+// A match of the combined Regexp is the leftmost match of any of the
+// patterns; of the patterns matching at the same position, the first
+// one wins, as in any alternation.  Settings such as (*UTF8) at the
+// start of a pattern apply to the combined Regexp.
+type MultiPattern struct {
+	Regexp *Regexp // The combined alternation
+	bases  []int   // number of the group enclosing each pattern
+	groups []int   // number of capture groups of each pattern
+}
+
+// CompileMulti compiles the patterns into a MultiPattern.  The flags
+// apply to all patterns; DUPNAMES is added, so different patterns may
+// use the same group names.  Numbered references and recursions in a
+// pattern are renumbered to its groups in the combined pattern, and
+// named recursions are made numbered, so they call the group of their
+// own pattern.  If a pattern does not compile, the error wraps its
+// *CompileError.
+func CompileMulti(patterns []string, flags int) (*MultiPattern, error) {
+	if len(patterns) == 0 {
+		return nil, errors.New("pcre.CompileMulti: no patterns")
+	}
+	if (flags|DefaultCompileFlags())&NO_AUTO_CAPTURE != 0 {
+		return nil, errors.New("pcre.CompileMulti: NO_AUTO_CAPTURE is not supported")
+	}
+	flags |= DUPNAMES
+	mp := &MultiPattern{}
+	var prefixes, groups []string
+	seen := make(map[string]bool)
+	base := 1
+	for i, pattern := range patterns {
+		re, err := Compile(pattern, flags)
+		if err != nil {
+			return nil, fmt.Errorf("pcre.CompileMulti: pattern %d: %w", i, err)
+		}
+		n := re.Groups()
+		re.FreeRegexp()
+		renumbered, err := renumberPattern(pattern, flags, base)
+		if err != nil {
+			return nil, fmt.Errorf("pcre.CompileMulti: pattern %d: %w", i, err)
+		}
+		prefix, group := splitPattern(renumbered, flags)
+		for _, v := range splitVerbs(prefix) {
+			if !seen[v] {
+				seen[v] = true
+				prefixes = append(prefixes, v)
+			}
+		}
+		groups = append(groups, "("+group+")")
+		mp.bases = append(mp.bases, base)
+		mp.groups = append(mp.groups, n)
+		base += 1 + n
+	}
+	re, err := Compile(strings.Join(prefixes, "")+strings.Join(groups, "|"), flags)
+	if err != nil {
+		return nil, fmt.Errorf("pcre.CompileMulti: %w", err)
+	}
+	mp.Regexp = re
+	return mp, nil
+}
+
+// splitVerbs splits a sequence of start-of-pattern settings.
+func splitVerbs(prefix string) []string {
+	var verbs []string
+	for prefix != "" {
+		end := strings.IndexByte(prefix, ')') + 1
+		verbs = append(verbs, prefix[:end])
+		prefix = prefix[end:]
+	}
+	return verbs
+}
+
+// renumberPattern returns pattern with its group references made
+// absolute and moved up to base, the number of the group that will
+// enclose it.  Named recursions become numbered.
+func renumberPattern(pattern string, flags, base int) (string, error) {
+	tree, p, err := parse(pattern, flags)
+	if err != nil {
+		return "", err
+	}
+	type edit struct {
+		pos, end int
+		text     string
+	}
+	var edits []edit
+	number := func(name string) int {
+		for i, n := range p.names {
+			if n == name {
+				return i + 1
+			}
+		}
+		return -1
+	}
+	var walk func(n *node) error
+	walk = func(n *node) error {
+		if n == nil {
+			return nil
+		}
+		switch n.op {
+		case opBackref:
+			if n.name == "" {
+				edits = append(edits, edit{n.pos, n.end, `\g{` + strconv.Itoa(base+n.index) + `}`})
+			}
+		case opRecurse:
+			index := n.index
+			if _, err := strconv.Atoi(n.name); n.name == "R" {
+				index = 0
+			} else if n.name != "" && err != nil {
+				if index = number(n.name); index < 0 {
+					return errors.New("reference to missing group " + strconv.Quote(n.name))
+				}
+			}
+			edits = append(edits, edit{n.pos, n.end, "(?" + strconv.Itoa(base+index) + ")"})
+		case opConditional:
+			cond := n.name
+			start := n.pos + len("(?(")
+			switch {
+			case cond == "" || cond[0] == '(' || cond == "R" || cond == "DEFINE":
+			case cond[0] == '+' || cond[0] == '-':
+				return errors.New("relative condition (?(" + cond + ") is not supported")
+			case isDigit(cond[0]):
+				i, _ := strconv.Atoi(cond)
+				edits = append(edits, edit{start, start + len(cond), strconv.Itoa(base + i)})
+			case strings.HasPrefix(cond, "R&"):
+				if i := number(cond[2:]); i > 0 {
+					edits = append(edits, edit{start, start + len(cond), "R" + strconv.Itoa(base+i)})
+				}
+			case cond[0] == 'R' && len(cond) > 1 && isDigit(cond[1]):
+				i, _ := strconv.Atoi(cond[1:])
+				edits = append(edits, edit{start, start + len(cond), "R" + strconv.Itoa(base+i)})
+			}
+		}
+		for _, sub := range n.subs {
+			if err := walk(sub); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(tree); err != nil {
+		return "", err
+	}
+	sort.Slice(edits, func(i, j int) bool { return edits[i].pos < edits[j].pos })
+	var b strings.Builder
+	last := 0
+	for _, e := range edits {
+		b.WriteString(pattern[last:e.pos])
+		b.WriteString(e.text)
+		last = e.end
+	}
+	b.WriteString(pattern[last:])
+	return b.String(), nil
+}
+
+// Len returns the number of patterns.
+func (mp *MultiPattern) Len() int {
+	return len(mp.bases)
+}
+
+// Which returns the index of the pattern that produced the last match
+// of m, a Matcher of mp.Regexp, or -1 if the match failed.
+func (mp *MultiPattern) Which(m *Matcher) int {
+	if !m.Matches() {
+		return -1
+	}
+	for i, base := range mp.bases {
+		if m.Present(base) {
+			return i
+		}
+	}
+	return -1
+}
+
+// GroupIndex returns the number in mp.Regexp of a capture group of the
+// pattern with the given index, or -1 if there is no such group.
+// Group 0 is the group enclosing the pattern.
+func (mp *MultiPattern) GroupIndex(pattern, group int) int {
+	if pattern < 0 || pattern >= len(mp.bases) || group < 0 || group > mp.groups[pattern] {
+		return -1
+	}
+	return mp.bases[pattern] + group
+}
+
+// SubmatchIndex returns the index of the pattern that produced the
+// last match of m, and the start and end offsets of the match and of
+// the capture groups of that pattern, numbered as in the pattern.
+// Groups that are not present have offsets -1.  If the match failed,
+// it returns -1 and nil.
+func (mp *MultiPattern) SubmatchIndex(m *Matcher) (pattern int, loc []int) {
+	pattern = mp.Which(m)
+	if pattern < 0 {
+		return -1, nil
+	}
+	loc = make([]int, 2*(1+mp.groups[pattern]))
+	for i := 0; i <= mp.groups[pattern]; i++ {
+		loc[2*i], loc[2*i+1] = m.span(mp.bases[pattern] + i)
+	}
+	return pattern, loc
+}
+
+// Close frees the combined Regexp.
+func (mp *MultiPattern) Close() {
+	mp.Regexp.FreeRegexp()
+}
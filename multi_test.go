@@ -0,0 +1,61 @@
+package pcre
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMultiPattern(t *testing.T) {
+	mp, err := CompileMulti([]string{
+		`id=(\d+)`,
+		`(\w)\1(?<tail>\w)?`,
+		`<(?:[^<>]|(?R))*>`,
+		`(?i)(?<tail>end)(?(1)!|\.)`,
+		`x(?<p>y|\(\g<p>\))`,
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mp.Close()
+	if mp.Len() != 5 {
+		t.Error("Len", mp.Len())
+	}
+	for _, test := range []struct {
+		subject string
+		pattern int
+		loc     []int
+	}{
+		{"id=42", 0, []int{0, 5, 3, 5}},
+		{"-xxy", 1, []int{1, 4, 1, 2, 3, 4}},
+		{"-xx", 1, []int{1, 3, 1, 2, -1, -1}},
+		{"a<b<c>>", 2, []int{1, 7}},
+		{"END!", 3, []int{0, 4, 0, 3}},
+		{"x((y))", 4, []int{0, 6, 1, 6}},
+		{"none", -1, nil},
+	} {
+		m := mp.Regexp.MatcherString(test.subject, 0)
+		pattern, loc := mp.SubmatchIndex(m)
+		if pattern != test.pattern || !reflect.DeepEqual(loc, test.loc) {
+			t.Errorf("%q: expected %d %v, got %d %v", test.subject, test.pattern, test.loc, pattern, loc)
+		}
+		if which := mp.Which(m); which != test.pattern {
+			t.Errorf("%q: Which returned %d", test.subject, which)
+		}
+	}
+	if g := mp.GroupIndex(1, 2); g != 5 {
+		t.Error("GroupIndex", g)
+	}
+	if g := mp.GroupIndex(1, 3); g != -1 {
+		t.Error("GroupIndex of missing group", g)
+	}
+
+	_, err = CompileMulti([]string{`a`, `(b`}, 0)
+	var cerr *CompileError
+	if !errors.As(err, &cerr) || cerr.Pattern != `(b` {
+		t.Error("expected compile error of pattern 1, got", err)
+	}
+	if _, err := CompileMulti([]string{`(a)(?(-1)b)`}, 0); err == nil {
+		t.Error("expected error for relative condition")
+	}
+}
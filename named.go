@@ -0,0 +1,41 @@
+package pcre
+
+// NamedGroup is a named capture group of a match.
+type NamedGroup struct {
+	Name    string // Group name
+	Index   int    // Group number
+	Value   string // Text of the group, or "" if not present
+	Present bool   // Whether the group took part in the match
+}
+
+// NamedGroups returns the named capture groups of the last match in
+// the order they are declared in the pattern, which is the order of
+// their numbers, whether they took part in the match or not.  Unlike
+// a map, the result is stable, as needed for diffs and golden files.
+// With DUPNAMES, each group of a name has its own entry.  If the last
+// match failed, it returns nil.
+func (m *Matcher) NamedGroups() []NamedGroup {
+	if !m.Matches() {
+		return nil
+	}
+	return namedGroupList(m.re.SubexpNames(), m.Present, m.GroupString)
+}
+
+// NamedGroups returns the named capture groups of the match in
+// declaration order, like Matcher.NamedGroups.
+func (r MatchResult) NamedGroups() []NamedGroup {
+	if r.Regexp == nil || len(r.loc) == 0 {
+		return nil
+	}
+	return namedGroupList(r.Regexp.SubexpNames(), r.Present, r.GroupString)
+}
+
+func namedGroupList(names []string, present func(int) bool, value func(int) string) []NamedGroup {
+	groups := []NamedGroup{}
+	for i, name := range names {
+		if name != "" {
+			groups = append(groups, NamedGroup{name, i, value(i), present(i)})
+		}
+	}
+	return groups
+}
@@ -0,0 +1,33 @@
+package pcre
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNamedGroups(t *testing.T) {
+	re := MustCompile(`(?<year>\d+)-(\d+)(?:-(?<day>\d+))?(?<zone>Z)?`, 0)
+	defer re.FreeRegexp()
+	want := []NamedGroup{
+		{"year", 1, "2024", true},
+		{"day", 3, "15", true},
+		{"zone", 4, "", false},
+	}
+	m := re.MatcherString("on 2024-06-15", 0)
+	if got := m.NamedGroups(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Matcher: got %v, want %v", got, want)
+	}
+	r, _ := m.Result()
+	if got := r.NamedGroups(); !reflect.DeepEqual(got, want) {
+		t.Errorf("MatchResult: got %v, want %v", got, want)
+	}
+	if got := re.MatcherString("none", 0).NamedGroups(); got != nil {
+		t.Errorf("no match: got %v", got)
+	}
+
+	plain := MustCompile(`(a)`, 0)
+	defer plain.FreeRegexp()
+	if got := plain.MatcherString("a", 0).NamedGroups(); got == nil || len(got) != 0 {
+		t.Errorf("unnamed groups: got %#v, want empty", got)
+	}
+}
@@ -0,0 +1,27 @@
+package pcre
+
+import "unsafe"
+
+// noCopy is embedded in structs which must not be copied after first
+// use, so that the copylocks check of go vet reports copies.  A Matcher
+// has no runtime check besides: it may live on a goroutine stack, which
+// moves as it grows, so its address does not identify it.
+type noCopy struct{}
+
+// Lock is a no-op used by go vet.
+func (*noCopy) Lock() {}
+
+// Unlock is a no-op used by go vet.
+func (*noCopy) Unlock() {}
+
+// copyCheck panics if the Regexp is a copy of a compiled Regexp.  A
+// copy shares the C memory of the original, which is freed by either
+// of them, so it cannot be used safely.  The address of the original
+// is kept as a uintptr, as a pointer to itself would keep a Regexp
+// with a finalizer from being collected.  Compile allocates every
+// Regexp on the heap, where it does not move.
+func (re *Regexp) copyCheck() {
+	if re.self != 0 && re.self != uintptr(unsafe.Pointer(re)) {
+		panic("pcre: illegal use of Regexp copied by value")
+	}
+}
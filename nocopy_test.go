@@ -0,0 +1,69 @@
+package pcre
+
+import (
+	"reflect"
+	"testing"
+)
+
+// copyValue copies *src to *dst without go vet noticing.
+func copyValue(dst, src interface{}) {
+	reflect.ValueOf(dst).Elem().Set(reflect.ValueOf(src).Elem())
+}
+
+func expectPanic(t *testing.T, what string, f func()) {
+	t.Helper()
+	defer func() {
+		if recover() == nil {
+			t.Errorf("%s did not panic", what)
+		}
+	}()
+	f()
+}
+
+func TestCopyCheck(t *testing.T) {
+	re := MustCompile("a", 0)
+	defer re.FreeRegexp()
+	m := re.MatcherString("a", 0)
+	if !m.Matches() {
+		t.Fatal("no match")
+	}
+	var copiedRe Regexp
+	copyValue(&copiedRe, re)
+	expectPanic(t, "copied Regexp", func() { copiedRe.MatcherString("a", 0) })
+	expectPanic(t, "freed copy of a Regexp", copiedRe.FreeRegexp)
+	if !m.MatchString("a", 0) {
+		t.Error("Regexp unusable after freeing a copy")
+	}
+
+	// Nor may a Matcher reach the C memory through a copy.
+	other := re.NewMatcher()
+	other.re = &copiedRe
+	expectPanic(t, "exec on a copied Regexp", func() { other.ExecString("a", 0) })
+}
+
+// growStack calls f after using n frames of stack, so that the stack
+// of the goroutine has been copied to grow it.
+func growStack(n int, f func()) {
+	var pad [256]byte
+	if n > 0 {
+		growStack(n-1, f)
+	} else {
+		f()
+	}
+	_ = pad
+}
+
+func TestMatcherOnMovingStack(t *testing.T) {
+	re := MustCompile("a", 0)
+	defer re.FreeRegexp()
+	var m Matcher
+	m.Init(re)
+	if !m.MatchString("a", 0) {
+		t.Fatal("no match")
+	}
+	growStack(1000, func() {
+		if !m.MatchString("a", 0) {
+			t.Error("no match after the stack moved")
+		}
+	})
+}
@@ -0,0 +1,97 @@
+// Package patterns provides tested patterns for common entities, such
+// as e-mail addresses, IP addresses and timestamps, so that they need
+// not be rewritten for every program.
+//
+// Each pattern is available as a constant, for combining it with
+// others, and through a function returning a Regexp that is compiled
+// and studied on first use and shared by all callers.  The shared
+// Regexp objects must not be freed.
+//
+// The patterns find entities in running text: they are not anchored,
+// but do not match inside longer words or numbers.  To check that a
+// whole string is an entity, use Regexp.FullMatchString.
+package patterns
+
+import (
+	"github.com/gijsbers/go-pcre"
+	"github.com/gijsbers/go-pcre/internal/lazy"
+)
+
+// Patterns for the entities.  All of them may be compiled without
+// flags.
+const (
+	// EmailPattern matches e-mail addresses of the usual form
+	// local@domain, without quoted local parts or address literals.
+	EmailPattern = `(?<![\w.%+-])[\w.%+-]++@[A-Za-z0-9](?:[A-Za-z0-9-]{0,61}[A-Za-z0-9])?` +
+		`(?:\.[A-Za-z0-9](?:[A-Za-z0-9-]{0,61}[A-Za-z0-9])?)+(?![\w-])`
+
+	// IPv4Pattern matches IPv4 addresses in dotted decimal notation,
+	// without leading zeros.
+	IPv4Pattern = `(?<![\w.])(?:(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\.){3}` +
+		`(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)(?!\w|\.\d)`
+
+	// IPv6Pattern matches IPv6 addresses in full or compressed
+	// notation, without embedded IPv4 addresses or zone indices.
+	IPv6Pattern = `(?<![\w:.])(?:` +
+		`(?:[0-9A-Fa-f]{1,4}:){7}[0-9A-Fa-f]{1,4}|` +
+		`(?:[0-9A-Fa-f]{1,4}:){1,7}:|` +
+		`(?:[0-9A-Fa-f]{1,4}:){1,6}:[0-9A-Fa-f]{1,4}|` +
+		`(?:[0-9A-Fa-f]{1,4}:){1,5}(?::[0-9A-Fa-f]{1,4}){1,2}|` +
+		`(?:[0-9A-Fa-f]{1,4}:){1,4}(?::[0-9A-Fa-f]{1,4}){1,3}|` +
+		`(?:[0-9A-Fa-f]{1,4}:){1,3}(?::[0-9A-Fa-f]{1,4}){1,4}|` +
+		`(?:[0-9A-Fa-f]{1,4}:){1,2}(?::[0-9A-Fa-f]{1,4}){1,5}|` +
+		`[0-9A-Fa-f]{1,4}:(?::[0-9A-Fa-f]{1,4}){1,6}|` +
+		`:(?:(?::[0-9A-Fa-f]{1,4}){1,7}|:)` +
+		`)(?![\w:]|\.\d)`
+
+	// UUIDPattern matches UUIDs in the canonical 8-4-4-4-12 form.
+	UUIDPattern = `(?<![\w-])[0-9A-Fa-f]{8}(?:-[0-9A-Fa-f]{4}){3}-[0-9A-Fa-f]{12}(?![\w-])`
+
+	// URLPattern matches http, https and ftp URLs.  Punctuation at
+	// the end, such as the full stop of a sentence, is not included.
+	URLPattern = `\b(?:https?|ftp)://[\w-]+(?:\.[\w-]+)*(?::\d{1,5})?` +
+		`(?:[/?#](?:[^\s<>"]*[^\s<>".,;:!?'")\]])?)?`
+
+	// ISOTimestampPattern matches ISO 8601 and RFC 3339 timestamps,
+	// such as 2006-01-02T15:04:05.999Z, with T or a space between
+	// date and time, optional seconds, fraction and time zone.
+	ISOTimestampPattern = `(?<![\w-])\d{4}-(?:0[1-9]|1[0-2])-(?:0[1-9]|[12]\d|3[01])` +
+		`[T ](?:[01]\d|2[0-3]):[0-5]\d(?::(?:[0-5]\d|60)(?:[.,]\d+)?)?` +
+		`(?:Z|[+-](?:[01]\d|2[0-3]):?[0-5]\d)?(?![\w:])`
+
+	// CardNumberPattern matches numbers which look like payment card
+	// numbers: 13 to 19 digits, possibly grouped by single spaces or
+	// dashes.  It does not verify the check digit.
+	CardNumberPattern = `(?<![\d-])\d(?:[ -]?\d){12,18}(?![\d-])`
+)
+
+var (
+	email        = lazy.New(EmailPattern)
+	ipv4         = lazy.New(IPv4Pattern)
+	ipv6         = lazy.New(IPv6Pattern)
+	uuid         = lazy.New(UUIDPattern)
+	url          = lazy.New(URLPattern)
+	isoTimestamp = lazy.New(ISOTimestampPattern)
+	cardNumber   = lazy.New(CardNumberPattern)
+)
+
+// Email returns the shared Regexp of EmailPattern.
+func Email() *pcre.Regexp { return email.Get() }
+
+// IPv4 returns the shared Regexp of IPv4Pattern.
+func IPv4() *pcre.Regexp { return ipv4.Get() }
+
+// IPv6 returns the shared Regexp of IPv6Pattern.
+func IPv6() *pcre.Regexp { return ipv6.Get() }
+
+// UUID returns the shared Regexp of UUIDPattern.
+func UUID() *pcre.Regexp { return uuid.Get() }
+
+// URL returns the shared Regexp of URLPattern.
+func URL() *pcre.Regexp { return url.Get() }
+
+// ISOTimestamp returns the shared Regexp of ISOTimestampPattern.
+func ISOTimestamp() *pcre.Regexp { return isoTimestamp.Get() }
+
+// CardNumber returns the shared Regexp of CardNumberPattern.
+func CardNumber() *pcre.Regexp { return cardNumber.Get() }
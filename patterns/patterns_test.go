@@ -0,0 +1,55 @@
+package patterns
+
+import (
+	"testing"
+
+	"github.com/gijsbers/go-pcre"
+)
+
+func TestPatterns(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		re   func() *pcre.Regexp
+		text string
+		want string
+	}{
+		{"Email", Email, "mail john.doe+tag@mail.example.com.", "john.doe+tag@mail.example.com"},
+		{"Email", Email, "user@-bad.com", ""},
+		{"Email", Email, "no at sign", ""},
+		{"IPv4", IPv4, "from 192.168.0.1:80", "192.168.0.1"},
+		{"IPv4", IPv4, "host 10.0.0.1.", "10.0.0.1"},
+		{"IPv4", IPv4, "256.1.1.1", ""},
+		{"IPv4", IPv4, "1.2.3.4.5", ""},
+		{"IPv4", IPv4, "v1.2.3.4", ""},
+		{"IPv6", IPv6, "addr 2001:db8::ff00:42:8329 up", "2001:db8::ff00:42:8329"},
+		{"IPv6", IPv6, "[::1]:80", "::1"},
+		{"IPv6", IPv6, "fe80:0:0:0:202:b3ff:fe1e:8329", "fe80:0:0:0:202:b3ff:fe1e:8329"},
+		{"IPv6", IPv6, "1:2:3:4:5:6:7:8:9", ""},
+		{"IPv6", IPv6, "12:30", ""},
+		{"UUID", UUID, "id=123e4567-e89b-12d3-a456-426614174000;", "123e4567-e89b-12d3-a456-426614174000"},
+		{"UUID", UUID, "123e4567-e89b-12d3-a456-4266141740001", ""},
+		{"URL", URL, "see https://example.com/a?b=c#d.", "https://example.com/a?b=c#d"},
+		{"URL", URL, "(http://example.com:8080/path)", "http://example.com:8080/path"},
+		{"URL", URL, "at http://example.com.", "http://example.com"},
+		{"URL", URL, "mailto:x@example.com", ""},
+		{"ISOTimestamp", ISOTimestamp, "at 2006-01-02T15:04:05.999Z.", "2006-01-02T15:04:05.999Z"},
+		{"ISOTimestamp", ISOTimestamp, "2006-01-02 15:04+0700", "2006-01-02 15:04+0700"},
+		{"ISOTimestamp", ISOTimestamp, "2006-13-02T15:04", ""},
+		{"ISOTimestamp", ISOTimestamp, "2006-01-02", ""},
+		{"CardNumber", CardNumber, "card 4111 1111 1111 1111 exp", "4111 1111 1111 1111"},
+		{"CardNumber", CardNumber, "4111-1111-1111-1111", "4111-1111-1111-1111"},
+		{"CardNumber", CardNumber, "123456789012", ""},
+		{"CardNumber", CardNumber, "12345678901234567890", ""},
+	} {
+		got := ""
+		if loc := test.re().FindIndex([]byte(test.text), 0); loc != nil {
+			got = test.text[loc[0]:loc[1]]
+		}
+		if got != test.want {
+			t.Errorf("%s in %q: expected %q, got %q", test.name, test.text, test.want, got)
+		}
+	}
+	if Email() != Email() {
+		t.Error("Email is compiled more than once")
+	}
+}
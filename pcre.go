@@ -59,13 +59,34 @@ package pcre
 // static inline void pcre_free_stub(void *re) {
 //     pcre_free(re);
 // }
+// // Queried in C, so that no Go variable escapes to the heap for
+// // the result.
+// static inline int pcre_info_capturecount(const pcre *re) {
+//     int count = 0;
+//     pcre_fullinfo(re, NULL, PCRE_INFO_CAPTURECOUNT, &count);
+//     return count;
+// }
+// static inline unsigned long pcre_info_options(const pcre *re) {
+//     unsigned long options = 0;
+//     pcre_fullinfo(re, NULL, PCRE_INFO_OPTIONS, &options);
+//     return options;
+// }
 import "C"
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
-	"runtime"
+	"log/slog"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
 	"unsafe"
 )
 
@@ -138,14 +159,179 @@ const (
 	ERROR_INTERNAL       = C.PCRE_ERROR_INTERNAL
 	ERROR_BADCOUNT       = C.PCRE_ERROR_BADCOUNT
 	ERROR_JIT_STACKLIMIT = C.PCRE_ERROR_JIT_STACKLIMIT
+	ERROR_BADLENGTH      = C.PCRE_ERROR_BADLENGTH
+	ERROR_BADOFFSET      = C.PCRE_ERROR_BADOFFSET
 )
 
+// ErrSubjectTooLarge is reported by Match and MatchString for subjects
+// whose length does not fit into a C int, the limit of pcre_exec.
+var ErrSubjectTooLarge = errors.New("pcre: subject too large")
+
+// maxLength is the largest value of a C int, and so the longest
+// subject pcre_exec accepts.  Lengths are compared as uint64, which
+// holds both it and any Go int, whatever the width of the platform int.
+const maxLength = 1<<(8*unsafe.Sizeof(C.int(0))-1) - 1
+
+// matchFlags are the flags accepted by the Match functions.
+const matchFlags = ANCHORED | BSR_ANYCRLF | BSR_UNICODE |
+	NEWLINE_ANY | NEWLINE_ANYCRLF | NEWLINE_CR | NEWLINE_CRLF |
+	NEWLINE_LF | NO_START_OPTIMIZE | NO_UTF8_CHECK |
+	NOTBOL | NOTEOL | NOTEMPTY | NOTEMPTY_ATSTART |
+	PARTIAL_HARD | PARTIAL_SOFT
+
+// compileFlagNames names the compile-only flags, for error messages.
+var compileFlagNames = []struct {
+	flag int
+	name string
+}{
+	{CASELESS, "CASELESS"},
+	{DOLLAR_ENDONLY, "DOLLAR_ENDONLY"},
+	{DOTALL, "DOTALL"},
+	{DUPNAMES, "DUPNAMES"},
+	{EXTENDED, "EXTENDED"},
+	{EXTRA, "EXTRA"},
+	{FIRSTLINE, "FIRSTLINE"},
+	{JAVASCRIPT_COMPAT, "JAVASCRIPT_COMPAT"},
+	{MULTILINE, "MULTILINE"},
+	{NEVER_UTF, "NEVER_UTF"},
+	{NO_AUTO_CAPTURE, "NO_AUTO_CAPTURE"},
+	{UNGREEDY, "UNGREEDY"},
+	{UTF8, "UTF8"},
+	{UCP, "UCP"},
+}
+
+// checkMatchFlags returns an error naming the flags which are not
+// valid for a match, or nil if there are none.
+func checkMatchFlags(op string, flags int) error {
+	bad := flags &^ matchFlags
+	if bad == 0 {
+		return nil
+	}
+	var names []string
+	for _, f := range compileFlagNames {
+		if f.flag != 0 && bad&f.flag == f.flag {
+			names = append(names, f.name)
+			bad &^= f.flag
+		}
+	}
+	if bad != 0 {
+		names = append(names, fmt.Sprintf("%#x", bad))
+	}
+	msg := " is not a match flag"
+	if len(names) > 1 {
+		msg = " are not match flags"
+	}
+	return fmt.Errorf("%s: %s%s", op, strings.Join(names, "|"), msg)
+}
+
+// ErrUninitialized is wrapped by the errors reported in safe mode when
+// an uninitialized or freed Regexp or Matcher is used.
+var ErrUninitialized = errors.New("uninitialized")
+
+var safeMode atomic.Bool
+
+// SetSafeMode selects how the package handles the use of uninitialized
+// or freed Regexp and Matcher objects.  By default, such calls panic.
+// In safe mode, they report an error wrapping ErrUninitialized instead:
+// Matcher methods record it for Err, Exec and ExecString return
+// ERROR_NULL, and Regexp.Groups returns -1.  A nil *Regexp or *Matcher
+// counts as uninitialized, so a pattern which is optional in a
+// configuration can be left nil; the accessors of a nil Matcher, such
+// as Matches and Group, report no match without panicking.
+func SetSafeMode(on bool) {
+	safeMode.Store(on)
+}
+
+// uninitialized panics on misuse of an uninitialized object in op,
+// or returns the error to report in safe mode.
+func uninitialized(op string) error {
+	if !safeMode.Load() {
+		panic(op + ": uninitialized")
+	}
+	return fmt.Errorf("%s: %w", op, ErrUninitialized)
+}
+
 // Regexp holds a reference to a compiled regular expression.
 // Use Compile or MustCompile to create such objects.
 // Use FreeRegexp to free memory when done with the struct.
 type Regexp struct {
-	ptr   *C.pcre
-	extra *C.pcre_extra
+	noCopy         noCopy
+	self           uintptr      // address of the original, see copyCheck
+	mu             sync.RWMutex // held for writing while freeing or studying
+	ptr            *C.pcre
+	extra          *C.pcre_extra
+	pattern        string
+	names          map[string][]int // group numbers by name, see groupOf
+	full           *Regexp          // pattern enclosed in \A and \z, see FullMatch
+	fullOnce       sync.Once
+	shadow         *regexp.Regexp // see SetShadowHandler
+	invalidUTF8    InvalidUTF8Mode
+	encoding       EncodingMode
+	matchLimit     uint32       // zero selects defaultMatchLimit
+	recursionLimit uint32       // zero selects defaultRecursionLimit
+	untrusted      bool         // compiled by CompileUntrusted
+	autoStudy      atomic.Int64 // executions left before studying, see SetAutoStudy
+	autoStudyFlags int
+	jitModes       int           // STUDY_JIT flags of the JIT code, see execExtra
+	dfaInitial     int           // see SetDFAWorkspace
+	dfaMax         int           // see SetDFAWorkspace
+	dfaNeeded      atomic.Int64  // DFA workspace ints needed so far
+	replaceRatio   atomic.Uint32 // see replaceCap
+	compileTime    time.Duration
+	studyTime      atomic.Int64                // time.Duration
+	stats          atomic.Pointer[regexpStats] // see EnableStats
+	trackID        uint64                      // registry key, see TrackRegexps
+	trace          atomic.Pointer[tracer]      // see SetTrace
+	autoFree       autoFree                    // frees the Regexp when unreachable
+}
+
+// Package-wide match limits, see SetDefaultLimits.
+var defaultMatchLimit, defaultRecursionLimit atomic.Uint32
+
+// SetDefaultLimits sets the match and recursion limits used by every
+// Regexp that does not override them with SetLimits.  The limits bound
+// the work done by a single match attempt, which protects against
+// catastrophic backtracking on hostile patterns or subjects.  A zero
+// limit keeps the default compiled into the PCRE library.
+func SetDefaultLimits(matchLimit, recursionLimit uint32) {
+	defaultMatchLimit.Store(matchLimit)
+	defaultRecursionLimit.Store(recursionLimit)
+}
+
+// InvalidUTF8Mode selects how matches treat subjects containing
+// invalid UTF-8 when the pattern was compiled with UTF8, see
+// SetInvalidUTF8.
+type InvalidUTF8Mode int
+
+// Modes for SetInvalidUTF8
+const (
+	INVALID_UTF8_ERROR   InvalidUTF8Mode = iota // fail with a *UTF8Error
+	INVALID_UTF8_REPLACE                        // replace with U+FFFD and retry
+	INVALID_UTF8_SKIP                           // drop invalid bytes and retry
+)
+
+// valid reports whether the Regexp holds a compiled pattern.
+func (re *Regexp) valid() bool {
+	if !re.rlock() {
+		return false
+	}
+	re.mu.RUnlock()
+	return true
+}
+
+// rlock read-locks the Regexp and reports true if it holds a compiled
+// pattern.  A nil or freed Regexp is left unlocked.
+func (re *Regexp) rlock() bool {
+	if re == nil {
+		return false
+	}
+	re.copyCheck()
+	re.mu.RLock()
+	if re.ptr == nil {
+		re.mu.RUnlock()
+		return false
+	}
+	return true
 }
 
 // Number of bytes in the compiled pattern
@@ -155,29 +341,88 @@ func pcreSize(ptr *C.pcre) (size C.size_t) {
 }
 
 // Number of capture groups
-func pcreGroups(ptr *C.pcre) (count C.int) {
-	C.pcre_fullinfo(ptr, nil,
-		C.PCRE_INFO_CAPTURECOUNT, unsafe.Pointer(&count))
-	return
+func pcreGroups(ptr *C.pcre) C.int {
+	return C.pcre_info_capturecount(ptr)
+}
+
+// Options the pattern was compiled with, including inline settings
+func (re *Regexp) pcreOptions() int {
+	re.mu.RLock()
+	defer re.mu.RUnlock()
+	return int(C.pcre_info_options(re.ptr))
+}
+
+// maxLookbehind returns the number of characters before the start of
+// a match that the pattern may inspect, or a generous bound if the
+// library cannot tell.
+func (re *Regexp) maxLookbehind() int {
+	re.mu.RLock()
+	defer re.mu.RUnlock()
+	var n C.int
+	if C.pcre_fullinfo(re.ptr, nil, C.PCRE_INFO_MAXLOOKBEHIND, unsafe.Pointer(&n)) < 0 {
+		return 255
+	}
+	return int(n)
 }
 
 // Free c allocated memory related to regexp.
+// FreeRegexp may be called more than once, and concurrently with
+// matches against the Regexp: the memory is released once the
+// matches in progress have finished, and later matches fail as if
+// the Regexp were uninitialized.
+//
+// A Regexp which is not freed is freed by the garbage collector once
+// it is unreachable, see AUTO_FREE_CLEANUP.  Freeing it explicitly
+// releases the memory sooner.
 func (re *Regexp) FreeRegexp() {
+	if re == nil {
+		return
+	}
+	re.copyCheck()
+	re.mu.Lock()
+	defer re.mu.Unlock()
+	re.stopAutoFree()
+	freePattern(re.ptr, re.extra, re.pattern, re.trackID)
+	re.ptr, re.extra, re.trackID = nil, nil, 0
+	if re.full != nil {
+		re.full.FreeRegexp()
+	}
+}
+
+// freePattern releases the C memory of a compiled pattern.  The ptr
+// is nil if it has already been released.
+func freePattern(ptr *C.pcre, extra *C.pcre_extra, pattern string, trackID uint64) {
 	// pcre_free is a function pointer, call a stub that calls it.
-	if re.ptr != nil {
-		C.pcre_free_stub(unsafe.Pointer(re.ptr))
-		re.ptr = nil
+	if ptr != nil {
+		C.pcre_free_stub(unsafe.Pointer(ptr))
+		untrack(trackID)
+		count(&countFreed)
+		logEvent(slog.LevelDebug, "pcre free", pattern)
 	}
-	if re.extra != nil {
-		C.pcre_free_study(re.extra)
-		re.extra = nil
+	if extra != nil {
+		C.pcre_free_study(extra)
 	}
-	runtime.SetFinalizer(re, nil)
 }
 
 // Compile the pattern and return a compiled regexp.
 // If compilation fails, the second return value holds a *CompileError.
 func Compile(pattern string, flags int) (re *Regexp, err error) {
+	return compile(pattern, flags|DefaultCompileFlags(), ENCODING_DEFAULT)
+}
+
+// compile compiles the pattern with exactly the given flags.
+func compile(pattern string, flags int, enc EncodingMode) (re *Regexp, err error) {
+	start := time.Now()
+	defer func() {
+		d := time.Since(start)
+		logTimed("pcre compile", pattern, flags, d, err)
+		if sink := metricsSink(); sink != nil {
+			sink.CompileDone(d, err)
+		}
+		if err == nil {
+			re.compileTime = d
+		}
+	}()
 	pattern1 := C.CString(pattern)
 	defer C.free(unsafe.Pointer(pattern1))
 	if clen := int(C.strlen(pattern1)); clen != len(pattern) {
@@ -189,18 +434,24 @@ func Compile(pattern string, flags int) (re *Regexp, err error) {
 		return
 	}
 	var errptr *C.char
-	var erroffset C.int
-	re = &Regexp{}
-	re.ptr = C.pcre_compile(pattern1, C.int(flags), &errptr, &erroffset, nil)
+	var errcode, erroffset C.int
+	re = &Regexp{pattern: pattern, encoding: enc}
+	re.self = uintptr(unsafe.Pointer(re))
+	re.ptr = C.pcre_compile2(pattern1, C.int(flags), &errcode, &errptr, &erroffset, nil)
 	if re.ptr == nil {
 		err = &CompileError{
 			Pattern: pattern,
 			Message: C.GoString(errptr),
 			Offset:  int(erroffset),
+			Code:    int(errcode),
 		}
 		return
 	}
-	runtime.SetFinalizer(re, (*Regexp).FreeRegexp)
+	re.names = pcreNames(re.ptr)
+	re.shadow = shadowCompile(pattern, flags)
+	re.track()
+	count(&countCompiled)
+	re.startAutoFree()
 	return
 }
 
@@ -237,39 +488,500 @@ func MustCompileJIT(pattern string, comFlags, jitFlags int) (re *Regexp) {
 // Study adds Just-In-Time compilation to a Regexp. This may give a huge
 // speed boost when matching. If an error occurs, return value is non-nil.
 // Flags optionally specifies JIT compilation options for partial matches.
-func (re *Regexp) Study(flags int) error {
+// If the library was built without JIT support, the JIT options are
+// dropped, with a warning logged and reported to the MetricsSink, and
+// the pattern is studied for the interpreter, so that the same program
+// runs with any build of the library; use RequireJIT to insist on JIT.
+func (re *Regexp) Study(flags int) (err error) {
+	start := time.Now()
+	if re == nil {
+		return uninitialized("Regexp.Study")
+	}
+	defer func() { re.studyDone(flags, start, err) }()
+	re.mu.Lock()
+	defer re.mu.Unlock()
+	if re.ptr == nil {
+		return uninitialized("Regexp.Study")
+	}
 	if re.extra != nil {
 		return fmt.Errorf("Study: Regexp has already been optimized")
 	}
+	return re.study(flags)
+}
+
+// jitWarned is set once the missing JIT support has been logged.
+var jitWarned atomic.Bool
+
+// jitUnavailable reports that a pattern is studied without the JIT
+// options it asked for, as the library lacks JIT support.  The warning
+// is logged once; the sink is told every time.
+func jitUnavailable(pattern string) {
+	if !jitWarned.Swap(true) {
+		logEvent(slog.LevelWarn, "pcre JIT not supported by library, studying for the interpreter", pattern,
+			slog.String("version", features.Version))
+	}
+	if sink := metricsSink(); sink != nil {
+		sink.JITFallback()
+	}
+}
+
+// studyDone logs and reports a call of Study which began at start.
+func (re *Regexp) studyDone(flags int, start time.Time, err error) {
+	d := time.Since(start)
+	if err == nil {
+		re.studyTime.Store(int64(d))
+	}
+	logTimed("pcre study", re.pattern, flags, d, err)
+	if sink := metricsSink(); sink != nil {
+		sink.StudyDone(d, err)
+	}
+}
+
+// study runs pcre_study.  The caller holds re.mu for writing.
+func (re *Regexp) study(flags int) error {
 	if flags == 0 {
 		flags = STUDY_JIT_COMPILE
 	}
 
-	var err *C.char
-	re.extra = C.pcre_study(re.ptr, C.int(flags), &err)
-	if err != nil {
-		return fmt.Errorf("%s", C.GoString(err))
+	if flags&jitFlags != 0 && !features.JIT {
+		flags &^= jitFlags
+		jitUnavailable(re.pattern)
+	}
+	var errptr *C.char
+	re.extra = C.pcre_study(re.ptr, C.int(flags), &errptr)
+	re.autoFreeExtra()
+	re.retrack()
+	if errptr != nil {
+		return fmt.Errorf("%s", C.GoString(errptr))
+	}
+	// Studying the pattern may not produce useful information, and
+	// JIT compilation can fail, leaving the pattern interpreted.
+	if flags&jitFlags != 0 && !re.jitCompiled() {
+		if sink := metricsSink(); sink != nil {
+			sink.JITFallback()
+		}
+	} else if re.jitCompiled() {
+		re.jitModes = flags & jitFlags
+	}
+	return nil
+}
+
+// The STUDY_JIT flags.
+const jitFlags = STUDY_JIT_COMPILE | STUDY_JIT_PARTIAL_SOFT_COMPILE |
+	STUDY_JIT_PARTIAL_HARD_COMPILE
+
+// StudyPartial studies the Regexp with JIT compilation for complete
+// matches and for partial matches with PARTIAL_HARD, if hard is true,
+// or PARTIAL_SOFT.  Matches in a mode the JIT code was not compiled
+// for use the interpreter.
+func (re *Regexp) StudyPartial(hard bool) error {
+	if hard {
+		return re.Study(STUDY_JIT_COMPILE | STUDY_JIT_PARTIAL_HARD_COMPILE)
+	}
+	return re.Study(STUDY_JIT_COMPILE | STUDY_JIT_PARTIAL_SOFT_COMPILE)
+}
+
+// jitMode returns the STUDY_JIT flag of the JIT code used for a match
+// with the given flags.
+func jitMode(flags int) int {
+	switch {
+	case flags&PARTIAL_HARD != 0:
+		return STUDY_JIT_PARTIAL_HARD_COMPILE
+	case flags&PARTIAL_SOFT != 0:
+		return STUDY_JIT_PARTIAL_SOFT_COMPILE
+	}
+	return STUDY_JIT_COMPILE
+}
+
+// StudyInfo describes the result of Study, as returned by
+// Regexp.StudyInfo.
+type StudyInfo struct {
+	Studied bool // Study produced study data or JIT code
+	Size    int  // Bytes of study data, PCRE_INFO_STUDYSIZE
+	JIT     bool // The pattern was compiled to machine code
+	JITSize int  // Bytes of JIT code, PCRE_INFO_JITSIZE
+}
+
+// StudyInfo reports what studying the pattern produced.  Study returns
+// no data for patterns that it cannot optimize, and leaves the pattern
+// interpreted if JIT compilation fails, so this tells whether Study
+// actually helped.
+func (re *Regexp) StudyInfo() StudyInfo {
+	if !re.rlock() {
+		uninitialized("Regexp.StudyInfo")
+		return StudyInfo{}
 	}
+	defer re.mu.RUnlock()
 	if re.extra == nil {
-		// Studying the pattern may not produce useful information.
+		return StudyInfo{}
+	}
+	var size, jitSize C.size_t
+	C.pcre_fullinfo(re.ptr, re.extra, C.PCRE_INFO_STUDYSIZE, unsafe.Pointer(&size))
+	C.pcre_fullinfo(re.ptr, re.extra, C.PCRE_INFO_JITSIZE, unsafe.Pointer(&jitSize))
+	return StudyInfo{
+		Studied: true,
+		Size:    int(size),
+		JIT:     re.jitCompiled(),
+		JITSize: int(jitSize),
+	}
+}
+
+// Size returns the number of bytes of C memory the Regexp holds: the
+// compiled pattern (PCRE_INFO_SIZE), its study data (STUDYSIZE) and
+// its JIT code (JITSIZE).  StudyInfo breaks down the latter two.  It
+// returns 0 if the Regexp is uninitialized.
+func (re *Regexp) Size() int {
+	if !re.rlock() {
+		return 0
+	}
+	defer re.mu.RUnlock()
+	return re.sizeLocked()
+}
+
+// sizeLocked is Size for a caller holding re.mu.
+func (re *Regexp) sizeLocked() int {
+	size := int(pcreSize(re.ptr))
+	if re.extra != nil {
+		var studySize, jitSize C.size_t
+		C.pcre_fullinfo(re.ptr, re.extra, C.PCRE_INFO_STUDYSIZE, unsafe.Pointer(&studySize))
+		C.pcre_fullinfo(re.ptr, re.extra, C.PCRE_INFO_JITSIZE, unsafe.Pointer(&jitSize))
+		size += int(studySize) + int(jitSize)
+	}
+	return size
+}
+
+// SetAutoStudy makes the Regexp study itself with the given Study
+// flags once it has been executed threshold times, so that only
+// patterns which turn out to be used often pay for JIT compilation.
+// Studying runs in the background; matches wait for it only while the
+// study data is stored.  A threshold of zero or less disables it.
+// Call SetAutoStudy before the Regexp is used concurrently.
+func (re *Regexp) SetAutoStudy(threshold, flags int) {
+	if re == nil {
+		uninitialized("Regexp.SetAutoStudy")
+		return
+	}
+	re.autoStudyFlags = flags
+	re.autoStudy.Store(int64(max(threshold, 0)))
+}
+
+// studyInBackground studies the Regexp for SetAutoStudy, unless it
+// has been freed or studied in the meantime.
+func (re *Regexp) studyInBackground() {
+	re.studyOnce(re.autoStudyFlags)
+}
+
+// studyOnce studies the Regexp unless it has been studied already.  It
+// fails with ErrUninitialized if the Regexp has been freed.
+func (re *Regexp) studyOnce(flags int) error {
+	start := time.Now()
+	re.mu.Lock()
+	if re.ptr == nil || re.extra != nil {
+		freed := re.ptr == nil
+		re.mu.Unlock()
+		if freed {
+			return ErrUninitialized
+		}
 		return nil
 	}
-	return nil
+	err := re.study(flags)
+	re.mu.Unlock()
+	re.studyDone(flags, start, err)
+	return err
+}
+
+// SetInvalidUTF8 selects how matches handle invalid UTF-8 in subjects.
+// With INVALID_UTF8_REPLACE or INVALID_UTF8_SKIP, a subject rejected by
+// pcre_exec is sanitized and matched again.  This applies to the Match
+// and Find methods, ReplaceAll, SplitAfter and Substitution, but not to
+// Exec, ExecString and ExecRange.  Offsets in the sanitized copy are
+// mapped back to the subject, so that groups refer to the subject as
+// passed, including any invalid bytes they span.
+func (re *Regexp) SetInvalidUTF8(mode InvalidUTF8Mode) {
+	if re == nil {
+		uninitialized("Regexp.SetInvalidUTF8")
+		return
+	}
+	re.invalidUTF8 = mode
+}
+
+// sanitized is a copy of a subject with its invalid UTF-8 replaced or
+// dropped, which records where, to map offsets between the two.
+type sanitized struct {
+	subject string
+	repl    int          // length of the replacement of each run
+	runs    []invalidRun // in order
+}
+
+// invalidRun is a run of invalid bytes, subject[from:to] in the
+// original, replaced at offset at in the sanitized copy.
+type invalidRun struct {
+	from, to, at int
+}
+
+// sanitizeUTF8 replaces or drops invalid UTF-8 sequences in subject.
+// As with bytes.ToValidUTF8, each run of invalid bytes is replaced
+// once.
+func sanitizeUTF8(subject string, mode InvalidUTF8Mode) *sanitized {
+	s := new(sanitized)
+	if mode != INVALID_UTF8_SKIP {
+		s.repl = utf8.RuneLen(utf8.RuneError)
+	}
+	var b strings.Builder
+	b.Grow(len(subject))
+	invalid := func(i int) bool {
+		r, size := utf8.DecodeRuneInString(subject[i:])
+		return r == utf8.RuneError && size == 1
+	}
+	for i := 0; i < len(subject); {
+		if !invalid(i) {
+			_, size := utf8.DecodeRuneInString(subject[i:])
+			b.WriteString(subject[i : i+size])
+			i += size
+			continue
+		}
+		run := invalidRun{from: i, at: b.Len()}
+		for i < len(subject) && invalid(i) {
+			i++
+		}
+		run.to = i
+		if s.repl > 0 {
+			b.WriteRune(utf8.RuneError)
+		}
+		s.runs = append(s.runs, run)
+	}
+	s.subject = b.String()
+	return s
+}
+
+// toClean maps an offset in the original subject to the sanitized
+// copy.  Offsets inside a run map to the end of its replacement.
+func (s *sanitized) toClean(offset int) int {
+	i := sort.Search(len(s.runs), func(i int) bool { return s.runs[i].from > offset })
+	if i == 0 {
+		return offset
+	}
+	r := s.runs[i-1]
+	switch {
+	case offset == r.from:
+		return r.at
+	case offset < r.to:
+		return r.at + s.repl
+	}
+	return r.at + s.repl + offset - r.to
+}
+
+// toOrig maps an offset in the sanitized copy back to the original
+// subject.  A match which starts or ends at a replacement includes the
+// run, but one next to a dropped run does not.
+func (s *sanitized) toOrig(offset int, end bool) int {
+	i := sort.Search(len(s.runs), func(i int) bool { return s.runs[i].at > offset })
+	if i == 0 {
+		return offset
+	}
+	r := s.runs[i-1]
+	switch rend := r.at + s.repl; {
+	case offset >= rend && !(end && offset == r.at):
+		return r.to + offset - rend
+	case offset == r.at:
+		return r.from
+	}
+	return r.to
+}
+
+// mapBack maps the offsets of the first n pairs in ovector back to the
+// original subject.
+func (s *sanitized) mapBack(ovector []C.int, n int) {
+	for i := 0; i < n; i++ {
+		start, end := int(ovector[2*i]), int(ovector[2*i+1])
+		if start < 0 {
+			continue
+		}
+		start = s.toOrig(start, false)
+		ovector[2*i] = C.int(start)
+		ovector[2*i+1] = C.int(max(s.toOrig(end, true), start))
+	}
+}
+
+// SetLimits overrides the package default match and recursion limits
+// for matches against this Regexp.  A zero limit selects the package
+// default set by SetDefaultLimits.
+func (re *Regexp) SetLimits(matchLimit, recursionLimit uint32) {
+	if re == nil {
+		uninitialized("Regexp.SetLimits")
+		return
+	}
+	re.matchLimit = matchLimit
+	re.recursionLimit = recursionLimit
+}
+
+// limits returns the effective match and recursion limits for this
+// Regexp.  Zero means the default compiled into the PCRE library.
+func (re *Regexp) limits() (matchLimit, recursionLimit uint32) {
+	matchLimit = re.matchLimit
+	if matchLimit == 0 {
+		matchLimit = defaultMatchLimit.Load()
+	}
+	recursionLimit = re.recursionLimit
+	if recursionLimit == 0 {
+		recursionLimit = defaultRecursionLimit.Load()
+	}
+	return
+}
+
+// execExtra returns the pcre_extra block for pcre_exec, carrying the
+// study data (if any) and the given match limits.  If there is no JIT
+// code for the partial matching mode selected by flags, the JIT code
+// is left out, so that the interpreter is used.
+func (re *Regexp) execExtra(flags int, matchLimit, recursionLimit uint32) *C.pcre_extra {
+	interpret := re.jitModes != 0 && re.jitModes&jitMode(flags) == 0
+	if matchLimit == 0 && recursionLimit == 0 && !interpret {
+		return re.extra
+	}
+	// Work on a copy, so the study data stays shared and immutable.
+	var extra C.pcre_extra
+	if re.extra != nil {
+		extra = *re.extra
+	}
+	if interpret {
+		extra.flags &^= C.PCRE_EXTRA_EXECUTABLE_JIT
+	}
+	if matchLimit != 0 {
+		extra.flags |= C.PCRE_EXTRA_MATCH_LIMIT
+		extra.match_limit = C.ulong(matchLimit)
+	}
+	if recursionLimit != 0 {
+		extra.flags |= C.PCRE_EXTRA_MATCH_LIMIT_RECURSION
+		extra.match_limit_recursion = C.ulong(recursionLimit)
+	}
+	return &extra
+}
+
+// libraryMatchLimit returns the match limit compiled into libpcre.
+func libraryMatchLimit() uint32 {
+	var limit C.ulong
+	C.pcre_config(C.PCRE_CONFIG_MATCH_LIMIT, unsafe.Pointer(&limit))
+	return uint32(limit)
+}
+
+// libraryRecursionLimit returns the recursion limit compiled into libpcre.
+func libraryRecursionLimit() uint32 {
+	var limit C.ulong
+	C.pcre_config(C.PCRE_CONFIG_MATCH_LIMIT_RECURSION, unsafe.Pointer(&limit))
+	return uint32(limit)
 }
 
 // Groups returns the number of capture groups in the compiled pattern.
+// In safe mode, it returns -1 if the Regexp is uninitialized.
 func (re *Regexp) Groups() int {
-	if re.ptr == nil {
-		panic("Regexp.Groups: uninitialized")
+	if !re.rlock() {
+		uninitialized("Regexp.Groups")
+		return -1
 	}
+	defer re.mu.RUnlock()
 	out := int(pcreGroups(re.ptr))
 	return out
 }
 
+// SubexpNames returns the names of the capture groups, indexed by
+// group number, like regexp.Regexp.SubexpNames.  Group 0 and unnamed
+// groups have the name "".  In safe mode, it returns nil if the
+// Regexp is uninitialized.
+func (re *Regexp) SubexpNames() []string {
+	if !re.rlock() {
+		uninitialized("Regexp.SubexpNames")
+		return nil
+	}
+	defer re.mu.RUnlock()
+	names := make([]string, 1+pcreGroups(re.ptr))
+	pcreNameTable(re.ptr, func(n int, name string) {
+		if names[n] == "" {
+			names[n] = name
+		}
+	})
+	return names
+}
+
+// SubexpIndex returns the number of the capture group with the given
+// name, or -1 if there is no such group, like
+// regexp.Regexp.SubexpIndex.  With DUPNAMES, it returns the lowest
+// numbered group of that name.  In safe mode, it returns -1 if the
+// Regexp is uninitialized.
+func (re *Regexp) SubexpIndex(name string) int {
+	if !re.rlock() {
+		uninitialized("Regexp.SubexpIndex")
+		return -1
+	}
+	defer re.mu.RUnlock()
+	if groups := re.names[name]; groups != nil {
+		return groups[0]
+	}
+	return -1
+}
+
+// groupOf returns the group a name refers to in a match, given the
+// groups present in it, or -1 if there is no group of that name.  Of
+// several groups with the same name, allowed by DUPNAMES, this is the
+// first one present, as for pcre_get_named_substring, or else the
+// lowest numbered one.
+func (re *Regexp) groupOf(name string, present func(int) bool) int {
+	groups := re.names[name]
+	for _, n := range groups {
+		if present(n) {
+			return n
+		}
+	}
+	if groups == nil {
+		return -1
+	}
+	return groups[0]
+}
+
+// pcreNameTable calls f for each entry of the name table of a
+// compiled pattern.
+func pcreNameTable(ptr *C.pcre, f func(n int, name string)) {
+	var count, size C.int
+	var table *C.uchar
+	C.pcre_fullinfo(ptr, nil, C.PCRE_INFO_NAMECOUNT, unsafe.Pointer(&count))
+	C.pcre_fullinfo(ptr, nil, C.PCRE_INFO_NAMEENTRYSIZE, unsafe.Pointer(&size))
+	C.pcre_fullinfo(ptr, nil, C.PCRE_INFO_NAMETABLE, unsafe.Pointer(&table))
+	if count == 0 {
+		return
+	}
+	// Each entry holds the group number in two bytes, most
+	// significant first, followed by the NUL-terminated name.
+	entries := unsafe.Slice((*byte)(unsafe.Pointer(table)), int(count*size))
+	for i := 0; i < int(count); i++ {
+		entry := entries[i*int(size) : (i+1)*int(size)]
+		name := entry[2:]
+		if end := bytes.IndexByte(name, 0); end >= 0 {
+			name = name[:end]
+		}
+		f(int(entry[0])<<8|int(entry[1]), string(name))
+	}
+}
+
+// pcreNames maps the group names of a compiled pattern to their group
+// numbers in ascending order, or returns nil if there are none.
+func pcreNames(ptr *C.pcre) map[string][]int {
+	var names map[string][]int
+	pcreNameTable(ptr, func(n int, name string) {
+		if names == nil {
+			names = make(map[string][]int)
+		}
+		names[name] = append(names[name], n)
+	})
+	for _, groups := range names {
+		sort.Ints(groups)
+	}
+	return names
+}
+
 // Matcher objects provide a place for storing match results.
 // They can be created by the Matcher and MatcherString functions,
 // or they can be initialized with Reset or ResetString.
 type Matcher struct {
+	noCopy   noCopy
 	re       *Regexp
 	groups   int
 	ovector  []C.int // scratch space for capture offsets
@@ -278,6 +990,12 @@ type Matcher struct {
 	subjects string  // one of these fields is set to record the subject,
 	subjectb []byte  // so that Group/GroupString can return slices
 	err      error
+	deadline time.Time       // set during MatchTimeout, see exec
+	ctx      context.Context // set during FindAllContext, see execLimited
+	timedOut bool            // the deadline passed or ctx was done during the last exec
+	checked  bool            // subjects passed the UTF-8 check, see exec
+	offset   int             // start offset of the last exec, see LimitError
+	scratch  []byte          // output of ReplaceAll, reused by the next call
 }
 
 // NewMatcher creates a new matcher object for the given Regexp.
@@ -321,12 +1039,20 @@ func (m *Matcher) ResetString(re *Regexp, subject string, flags int) bool {
 
 // Init binds an existing Matcher object to the given Regexp.
 func (m *Matcher) Init(re *Regexp) {
-	if re.ptr == nil {
-		panic("Matcher.Init: uninitialized")
+	if m == nil {
+		uninitialized("Matcher.Init")
+		return
+	}
+	if !re.valid() {
+		m.re = nil
+		m.matches = false
+		m.err = uninitialized("Matcher.Init")
+		return
 	}
 	m.matches = false
 	m.err = nil
-	if m.re != nil && m.re.ptr != nil && m.re.ptr == re.ptr {
+	m.checked = false
+	if m.re == re {
 		// Skip group count extraction if the matcher has
 		// already been initialized with the same regular
 		// expression.
@@ -339,8 +1065,12 @@ func (m *Matcher) Init(re *Regexp) {
 	}
 }
 
-// Err returns first error encountered by Matcher.
+// Err returns first error encountered by Matcher.  For a nil Matcher,
+// it returns an error wrapping ErrUninitialized.
 func (m *Matcher) Err() error {
+	if m == nil {
+		return fmt.Errorf("Matcher.Err: nil Matcher: %w", ErrUninitialized)
+	}
 	return m.err
 }
 
@@ -350,16 +1080,29 @@ var nullbyte = []byte{0}
 // the current pattern by calling Exec and collects the result.
 // Returns true if the match succeeds.
 // Match is a no-op if err is not nil.
+// Match panics if the Matcher is uninitialized, see SetSafeMode.
 func (m *Matcher) Match(subject []byte, flags int) bool {
+	if m == nil {
+		uninitialized("Matcher.Match")
+		return false
+	}
 	if m.err != nil {
 		return false
 	}
-	if m.re == nil || m.re.ptr == nil {
-		panic("Matcher.Match: uninitialized")
+	if m.re == nil || !m.re.valid() {
+		m.err = uninitialized("Matcher.Match")
+		return false
 	}
-	rc := m.Exec(subject, flags)
-	m.matches, m.err = matched(rc)
+	if m.err = checkMatchFlags("Matcher.Match", flags); m.err != nil {
+		m.matches = false
+		return false
+	}
+	rc := m.execSanitizedBytes(subject, 0, flags, nil)
+	m.matches, m.err = m.matched(rc)
 	m.partial = (rc == ERROR_PARTIAL)
+	if m.re.shadow != nil && flags == 0 {
+		m.shadowCheck()
+	}
 	return m.matches
 }
 
@@ -367,60 +1110,424 @@ func (m *Matcher) Match(subject []byte, flags int) bool {
 // the current pattern by calling ExecString and collects the result.
 // Returns true if the match succeeds.
 func (m *Matcher) MatchString(subject string, flags int) bool {
+	if m == nil {
+		uninitialized("Matcher.MatchString")
+		return false
+	}
 	if m.err != nil {
 		return false
 	}
-	if m.re == nil || m.re.ptr == nil {
-		panic("Matcher.MatchString: uninitialized")
+	if m.re == nil || !m.re.valid() {
+		m.err = uninitialized("Matcher.MatchString")
+		return false
+	}
+	if m.err = checkMatchFlags("Matcher.MatchString", flags); m.err != nil {
+		m.matches = false
+		return false
+	}
+	rc := m.execSanitized(subject, 0, flags, nil)
+	m.matches, m.err = m.matched(rc)
+	m.partial = (rc == ERROR_PARTIAL)
+	if m.re.shadow != nil && flags == 0 {
+		m.shadowCheck()
+	}
+	return m.matches
+}
+
+// MatchAt reports whether the pattern matches subject starting
+// exactly at offset, as if it were compiled with ANCHORED.  The text
+// before offset remains visible to lookbehind assertions and \b, so
+// this tests whether a token starts at offset without slicing the
+// subject.  Group offsets are relative to subject.  An offset outside
+// subject is an error.
+func (m *Matcher) MatchAt(subject []byte, offset, flags int) bool {
+	if !m.matchAtCheck("Matcher.MatchAt", len(subject), offset, flags) {
+		return false
+	}
+	rc := m.execSanitizedBytes(subject, offset, flags|ANCHORED, nil)
+	m.matches, m.err = m.matched(rc)
+	m.partial = (rc == ERROR_PARTIAL)
+	return m.matches
+}
+
+// MatchStringAt is equivalent to MatchAt with a string subject.
+func (m *Matcher) MatchStringAt(subject string, offset, flags int) bool {
+	if !m.matchAtCheck("Matcher.MatchStringAt", len(subject), offset, flags) {
+		return false
 	}
-	rc := m.ExecString(subject, flags)
-	m.matches, m.err = matched(rc)
+	rc := m.execSanitized(subject, offset, flags|ANCHORED, nil)
+	m.matches, m.err = m.matched(rc)
 	m.partial = (rc == ERROR_PARTIAL)
 	return m.matches
 }
 
+// matchAtCheck reports whether a match at offset can be attempted, and
+// records the error otherwise.
+func (m *Matcher) matchAtCheck(op string, length, offset, flags int) bool {
+	if m == nil {
+		uninitialized(op)
+		return false
+	}
+	if m.err != nil {
+		return false
+	}
+	m.matches = false
+	if m.re == nil || !m.re.valid() {
+		m.err = uninitialized(op)
+		return false
+	}
+	if m.err = checkMatchFlags(op, flags); m.err != nil {
+		return false
+	}
+	if offset < 0 || offset > length {
+		m.err = errors.New(op + ": offset " + strconv.Itoa(offset) + " out of range")
+		return false
+	}
+	return true
+}
+
 // Exec tries to match the specified byte slice to
 // the current pattern. Returns the raw pcre_exec error code.
+// Subjects longer than a C int return ERROR_BADLENGTH.  As after
+// Match, Matches, Index and Group report the result; Err does not.
 func (m *Matcher) Exec(subject []byte, flags int) int {
-	if m.re == nil || m.re.ptr == nil {
-		panic("Matcher.Exec: uninitialized")
+	if m == nil || m.re == nil || !m.re.valid() {
+		uninitialized("Matcher.Exec")
+		return m.execDone(ERROR_NULL)
 	}
 	length := len(subject)
+	if uint64(length) > maxLength {
+		return m.execDone(ERROR_BADLENGTH)
+	}
 	m.subjects = ""
 	m.subjectb = subject
+	m.checked = false
 	if length == 0 {
 		subject = nullbyte // make first character adressable
 	}
 	subjectptr := (*C.char)(unsafe.Pointer(&subject[0]))
-	return m.exec(subjectptr, length, flags)
+	return m.exec(subjectptr, length, 0, flags)
 }
 
 // ExecString tries to match the specified subject string to
 // the current pattern. It returns the raw pcre_exec error code.
+// Subjects longer than a C int return ERROR_BADLENGTH.
 func (m *Matcher) ExecString(subject string, flags int) int {
-	if m.re == nil || m.re.ptr == nil {
-		panic("Matcher.ExecString: uninitialized")
+	if m == nil || m.re == nil || !m.re.valid() {
+		uninitialized("Matcher.ExecString")
+		return m.execDone(ERROR_NULL)
 	}
 	length := len(subject)
-	m.subjects = subject
-	m.subjectb = nil
+	if uint64(length) > maxLength {
+		return m.execDone(ERROR_BADLENGTH)
+	}
+	m.setSubjectString(subject)
 	if length == 0 {
 		subject = "\000" // make first character addressable
 	}
 	// The following is a non-portable kludge to avoid a copy
 	subjectptr := *(**C.char)(unsafe.Pointer(&subject))
-	return m.exec(subjectptr, length, flags)
+	return m.exec(subjectptr, length, 0, flags)
+}
+
+// ExecRange is like Exec, but matches only within subject[start:end].
+// The bytes before start remain visible to lookbehind assertions and
+// \b, and ^ and \A do not match at start unless it is 0.  The subject
+// is taken to end at end, where $, \z and lookahead assertions see the
+// end of the text; pass NOTEOL to keep $ from matching there.  The
+// offsets of the match, as reported by Index and Group, are relative
+// to subject.  An invalid range returns ERROR_BADOFFSET.
+func (m *Matcher) ExecRange(subject []byte, start, end, flags int) int {
+	if start < 0 || start > end || end > len(subject) {
+		return m.execDone(ERROR_BADOFFSET)
+	}
+	return m.execOffset(subject[:end], start, flags)
+}
+
+// ExecRangeString is equivalent to ExecRange with a string subject.
+func (m *Matcher) ExecRangeString(subject string, start, end, flags int) int {
+	if start < 0 || start > end || end > len(subject) {
+		return m.execDone(ERROR_BADOFFSET)
+	}
+	return m.execOffsetString(subject[:end], start, flags)
+}
+
+// execOffset is like Exec, but starts matching at offset, with the
+// bytes before it available to lookbehind assertions.
+func (m *Matcher) execOffset(subject []byte, offset, flags int) int {
+	if m == nil || m.re == nil || !m.re.valid() {
+		uninitialized("Matcher.Exec")
+		return m.execDone(ERROR_NULL)
+	}
+	length := len(subject)
+	if uint64(length) > maxLength {
+		return m.execDone(ERROR_BADLENGTH)
+	}
+	m.subjects = ""
+	m.subjectb = subject
+	m.checked = false
+	if length == 0 {
+		subject = nullbyte // make first character adressable
+	}
+	subjectptr := (*C.char)(unsafe.Pointer(&subject[0]))
+	return m.exec(subjectptr, length, offset, flags)
+}
+
+// execOffsetString is equivalent to execOffset with a string subject.
+func (m *Matcher) execOffsetString(subject string, offset, flags int) int {
+	if m == nil || m.re == nil || !m.re.valid() {
+		uninitialized("Matcher.ExecString")
+		return m.execDone(ERROR_NULL)
+	}
+	length := len(subject)
+	if uint64(length) > maxLength {
+		return m.execDone(ERROR_BADLENGTH)
+	}
+	m.setSubjectString(subject)
+	if length == 0 {
+		subject = "\000" // make first character addressable
+	}
+	subjectptr := *(**C.char)(unsafe.Pointer(&subject))
+	return m.exec(subjectptr, length, offset, flags)
+}
+
+// execSanitized is like execOffsetString, but applies the invalid UTF-8
+// mode of the Regexp: a subject rejected as invalid UTF-8 is sanitized
+// and matched again, and the offsets of the match are mapped back to
+// subject.  Loops over the matches in subject pass clean, which keeps
+// the sanitized copy for the following calls; it may be nil.
+func (m *Matcher) execSanitized(subject string, offset, flags int, clean **sanitized) int {
+	var s *sanitized
+	if clean != nil {
+		s = *clean
+	}
+	if s == nil {
+		rc := m.execOffsetString(subject, offset, flags)
+		if rc != C.PCRE_ERROR_BADUTF8 || m.re.invalidUTF8 == INVALID_UTF8_ERROR {
+			return rc
+		}
+		s = sanitizeUTF8(subject, m.re.invalidUTF8)
+		if clean != nil {
+			*clean = s
+		}
+	}
+	rc := m.execOffsetString(s.subject, s.toClean(offset), flags)
+	m.setSubjectString(subject)
+	m.offset = offset
+	switch {
+	case rc > 0:
+		s.mapBack(m.ovector, rc)
+	case rc == C.PCRE_ERROR_PARTIAL:
+		s.mapBack(m.ovector, 1)
+	}
+	return rc
+}
+
+// execSanitizedBytes is execSanitized for a byte slice subject.
+func (m *Matcher) execSanitizedBytes(subject []byte, offset, flags int, clean **sanitized) int {
+	rc := m.execSanitized(bytesString(subject), offset, flags, clean)
+	m.subjects, m.subjectb, m.checked = "", subject, false
+	return rc
+}
+
+// setSubjectString records the string subject of the next exec.  The
+// UTF-8 check of the previous subject still holds if it is the same
+// string: as m.subjects kept it alive, its memory was not reused.
+func (m *Matcher) setSubjectString(subject string) {
+	if m.subjectb != nil || len(subject) != len(m.subjects) ||
+		unsafe.StringData(subject) != unsafe.StringData(m.subjects) {
+		m.checked = false
+	}
+	m.subjects = subject
+	m.subjectb = nil
+}
+
+// timeoutStep is the initial match limit used while a deadline is set.
+const timeoutStep = 10000
+
+// exec runs pcre_exec on the subject recorded in m.  A string subject
+// is checked for valid UTF-8 only once: repeated matches against it,
+// as when iterating over its matches, pass NO_UTF8_CHECK, unless the
+// offset is inside a character.  Byte slice subjects may change
+// between calls, so they are always checked.
+func (m *Matcher) exec(subjectptr *C.char, length, offset, flags int) int {
+	count(&countExec)
+	if m.re.autoStudy.Load() > 0 && m.re.autoStudy.Add(-1) == 0 {
+		go m.re.studyInBackground()
+	}
+	m.offset = offset
+	flags |= DefaultExecFlags()
+	check := flags&NO_UTF8_CHECK == 0
+	if m.checked && (offset >= len(m.subjects) || utf8.RuneStart(m.subjects[offset])) {
+		flags |= NO_UTF8_CHECK
+	}
+	sink := metricsSink()
+	stats := m.re.stats.Load()
+	trace := m.re.trace.Load()
+	var rc int
+	if sink == nil && stats == nil && trace == nil {
+		rc = m.execLimited(subjectptr, length, offset, flags)
+	} else {
+		start := time.Now()
+		rc = m.execLimited(subjectptr, length, offset, flags)
+		d := time.Since(start)
+		if sink != nil {
+			sink.ExecDone(d, execResult(rc))
+		}
+		if stats != nil {
+			stats.add(d, rc)
+		}
+		if trace != nil {
+			trace.write(m, length, offset, flags, rc, d)
+		}
+	}
+	if check && m.subjectb == nil &&
+		(rc >= 0 || rc == C.PCRE_ERROR_NOMATCH || rc == C.PCRE_ERROR_PARTIAL) {
+		m.checked = true
+	}
+	logExec(m.re.pattern, rc)
+	return m.execDone(rc)
+}
+
+// execDone records whether an exec matched, for Matches, Partial and
+// the group accessors, and returns its rc.  Errors are left to the
+// callers which record them, as Exec returns the rc as is.  m may be
+// nil.
+func (m *Matcher) execDone(rc int) int {
+	if m == nil {
+		return rc
+	}
+	m.matches = rc >= 0 || rc == C.PCRE_ERROR_PARTIAL
+	m.partial = rc == C.PCRE_ERROR_PARTIAL
+	return rc
+}
+
+// execLimited runs exec1 with the match limits of the Regexp, and
+// enforces the deadline and context of the Matcher.
+func (m *Matcher) execLimited(subjectptr *C.char, length, offset, flags int) int {
+	if m.re.untrusted {
+		// Skipping the check on invalid UTF-8 is undefined behavior.
+		flags &^= NO_UTF8_CHECK
+	}
+	matchLimit, recursionLimit := m.re.limits()
+	if m.deadline.IsZero() && m.ctx == nil {
+		return m.exec1(subjectptr, length, offset, flags, matchLimit, recursionLimit)
+	}
+	// Raise the match limit step by step, so that a runaway match
+	// returns control often enough to check the deadline and context.
+	if matchLimit == 0 {
+		matchLimit = libraryMatchLimit()
+	}
+	deadline := m.deadline
+	if m.ctx != nil {
+		if d, ok := m.ctx.Deadline(); ok && (deadline.IsZero() || d.Before(deadline)) {
+			deadline = d
+		}
+	}
+	limit := min(uint32(timeoutStep), matchLimit)
+	for {
+		if m.expired(deadline, time.Now()) {
+			m.timedOut = true
+			return C.PCRE_ERROR_MATCHLIMIT
+		}
+		start := time.Now()
+		rc := m.exec1(subjectptr, length, offset, flags, limit, recursionLimit)
+		if rc != C.PCRE_ERROR_MATCHLIMIT || limit == matchLimit {
+			return rc
+		}
+		now := time.Now()
+		if m.expired(deadline, now) {
+			m.timedOut = true
+			return rc
+		}
+		next := 4 * uint64(limit)
+		if elapsed := now.Sub(start); !deadline.IsZero() && elapsed > 0 {
+			// Each step starts over, so it takes about as long as
+			// its limit: give it no more than the time left, rather
+			// than four times the last step.
+			left := deadline.Sub(now)
+			next = min(next, uint64(float64(limit)*float64(left)/float64(elapsed)))
+			if next <= uint64(limit) {
+				// Even a step as short as the last would overrun.
+				m.timedOut = true
+				return rc
+			}
+		}
+		limit = uint32(min(next, uint64(matchLimit)))
+	}
+}
+
+// expired reports whether the deadline, if any, has passed at now, or
+// the context of the Matcher is done.
+func (m *Matcher) expired(deadline, now time.Time) bool {
+	return !deadline.IsZero() && !now.Before(deadline) ||
+		m.ctx != nil && m.ctx.Err() != nil
 }
 
-func (m *Matcher) exec(subjectptr *C.char, length, flags int) int {
-	rc := C.pcre_exec(m.re.ptr, m.re.extra,
-		subjectptr, C.int(length),
-		0, C.int(flags), &m.ovector[0], C.int(len(m.ovector)))
+func (m *Matcher) exec1(subjectptr *C.char, length, offset, flags int,
+	matchLimit, recursionLimit uint32) int {
+	// Hold the read lock, so that FreeRegexp waits for us.
+	if !m.re.rlock() {
+		return C.PCRE_ERROR_NULL
+	}
+	defer m.re.mu.RUnlock()
+	extra := m.re.execExtra(flags, matchLimit, recursionLimit)
+	rc := C.pcre_exec(m.re.ptr, extra, subjectptr, C.int(length),
+		C.int(offset), C.int(flags), &m.ovector[0], C.int(len(m.ovector)))
+	if rc == 0 {
+		// The ovector is too small for all captures.  Make room
+		// for every group and retry, rather than drop some.
+		m.ovector = make([]C.int, 3*(1+int(pcreGroups(m.re.ptr))))
+		rc = C.pcre_exec(m.re.ptr, extra, subjectptr, C.int(length),
+			C.int(offset), C.int(flags), &m.ovector[0], C.int(len(m.ovector)))
+	}
 	return int(rc)
 }
 
+// MatchTimeout is like Match, but gives up once the match has run for
+// longer than d.  The time limit is enforced by running pcre_exec with
+// increasing match limits, each sized to the time left, and checking
+// the clock before and after every run, so it is approximate.  On
+// timeout, it returns false and Err returns a *TimeoutError.  The match
+// and recursion limits still apply.
+func (m *Matcher) MatchTimeout(subject []byte, flags int, d time.Duration) bool {
+	if m == nil {
+		uninitialized("Matcher.MatchTimeout")
+		return false
+	}
+	m.startTimeout(d)
+	defer m.stopTimeout(d)
+	return m.Match(subject, flags)
+}
+
+// MatchStringTimeout is like MatchString, but gives up once the match
+// has run for longer than d.  See MatchTimeout.
+func (m *Matcher) MatchStringTimeout(subject string, flags int, d time.Duration) bool {
+	if m == nil {
+		uninitialized("Matcher.MatchStringTimeout")
+		return false
+	}
+	m.startTimeout(d)
+	defer m.stopTimeout(d)
+	return m.MatchString(subject, flags)
+}
+
+func (m *Matcher) startTimeout(d time.Duration) {
+	m.deadline = time.Now().Add(d)
+	m.timedOut = false
+}
+
+func (m *Matcher) stopTimeout(d time.Duration) {
+	if m.timedOut {
+		m.matches = false
+		m.err = &TimeoutError{Timeout: d}
+	}
+	m.deadline = time.Time{}
+	m.timedOut = false
+}
+
 // matched checks the return code of a pattern match for success.
-func matched(rc int) (bool, error) {
+func (m *Matcher) matched(rc int) (bool, error) {
 	switch {
 	case rc >= 0 || rc == C.PCRE_ERROR_PARTIAL:
 		return true, nil
@@ -428,6 +1535,17 @@ func matched(rc int) (bool, error) {
 		return false, nil
 	case rc == C.PCRE_ERROR_BADOPTION:
 		return false, errors.New("PCRE.Match: invalid option flag")
+	case rc == C.PCRE_ERROR_BADLENGTH:
+		return false, ErrSubjectTooLarge
+	case rc == C.PCRE_ERROR_MATCHLIMIT || rc == C.PCRE_ERROR_RECURSIONLIMIT:
+		return false, m.limitError(rc)
+	case rc == C.PCRE_ERROR_BADUTF8:
+		// pcre_exec stores the offset of the invalid character
+		// and the reason code in the first two ovector slots.
+		return false, &UTF8Error{
+			Offset: int(m.ovector[0]),
+			Reason: int(m.ovector[1]),
+		}
 	}
 	err := errors.New(
 		"unexpected return code from pcre_exec: " + strconv.Itoa(rc),
@@ -438,36 +1556,51 @@ func matched(rc int) (bool, error) {
 // Matches returns true if a previous call to Matcher, MatcherString, Reset,
 // ResetString, Match or MatchString succeeded.
 func (m *Matcher) Matches() bool {
-	return m.matches
+	return m != nil && m.matches
 }
 
 // Partial returns true if a previous call to Matcher, MatcherString, Reset,
 // ResetString, Match or MatchString found a partial match.
 func (m *Matcher) Partial() bool {
-	return m.partial
+	return m != nil && m.partial
 }
 
 // Groups returns the number of groups in the current pattern.
 func (m *Matcher) Groups() int {
+	if m == nil {
+		return 0
+	}
 	return m.groups
 }
 
 // Present returns true if the numbered capture group is present in the last
 // match (performed by Matcher, MatcherString, Reset, ResetString,
 // Match, or MatchString).  Group numbers start at 1.  A capture group
-// can be present and match the empty string.
+// can be present and match the empty string.  Group numbers out of
+// range are never present.
 func (m *Matcher) Present(group int) bool {
-	return m.ovector[2*group] >= 0
+	start, _ := m.span(group)
+	return start >= 0
+}
+
+// span returns the offsets of the numbered capture group in the last
+// match, or -1 if the group is not present or out of range.  After a
+// failed match, no group is present.
+func (m *Matcher) span(group int) (start, end int) {
+	if !m.Matches() || group < 0 || group > m.groups ||
+		2*group+1 >= len(m.ovector) {
+		return -1, -1
+	}
+	return int(m.ovector[2*group]), int(m.ovector[2*group+1])
 }
 
 // Group returns the numbered capture group of the last match (performed by
 // Matcher, MatcherString, Reset, ResetString, Match, or MatchString).
 // Group 0 is the part of the subject which matches the whole pattern;
 // the first actual capture group is numbered 1.  Capture groups which
-// are not present return a nil slice.
+// are not present or out of range return a nil slice.
 func (m *Matcher) Group(group int) []byte {
-	start := m.ovector[2*group]
-	end := m.ovector[2*group+1]
+	start, end := m.span(group)
 	if start >= 0 {
 		if m.subjectb != nil {
 			return m.subjectb[start:end]
@@ -479,36 +1612,38 @@ func (m *Matcher) Group(group int) []byte {
 
 // Extract returns a slice of byte slices for a single match.
 // The first byte slice contains the complete match.
-// Subsequent byte slices contain the captured groups.
+// Subsequent byte slices contain the captured groups, or nil for
+// groups which are not present.
 // If there was no match then nil is returned.
 func (m *Matcher) Extract() [][]byte {
-	if !m.matches {
+	if !m.Matches() {
 		return nil
 	}
 	extract := make([][]byte, m.groups+1)
 	extract[0] = m.subjectb
 	for i := 1; i <= m.groups; i++ {
-		x0 := m.ovector[2*i]
-		x1 := m.ovector[2*i+1]
-		extract[i] = m.subjectb[x0:x1]
+		if x0, x1 := m.span(i); x0 >= 0 {
+			extract[i] = m.subjectb[x0:x1]
+		}
 	}
 	return extract
 }
 
 // ExtractString returns a slice of strings for a single match.
 // The first string contains the complete match.
-// Subsequent strings in the slice contain the captured groups.
+// Subsequent strings in the slice contain the captured groups, or ""
+// for groups which are not present.
 // If there was no match then nil is returned.
 func (m *Matcher) ExtractString() []string {
-	if !m.matches {
+	if !m.Matches() {
 		return nil
 	}
 	extract := make([]string, m.groups+1)
 	extract[0] = m.subjects
 	for i := 1; i <= m.groups; i++ {
-		x0 := m.ovector[2*i]
-		x1 := m.ovector[2*i+1]
-		extract[i] = m.subjects[x0:x1]
+		if x0, x1 := m.span(i); x0 >= 0 {
+			extract[i] = m.subjects[x0:x1]
+		}
 	}
 	return extract
 }
@@ -517,12 +1652,11 @@ func (m *Matcher) ExtractString() []string {
 // match (performed by Matcher, MatcherString, Reset, ResetString, Match,
 // or MatchString). Group 0 is the part of the subject which matches
 // the whole pattern; the first actual capture group is numbered 1.
-// Capture groups which are not present return a nil slice.
+// Capture groups which are not present or out of range return a nil slice.
 func (m *Matcher) GroupIndices(group int) []int {
-	start := m.ovector[2*group]
-	end := m.ovector[2*group+1]
+	start, end := m.span(group)
 	if start >= 0 {
-		return []int{int(start), int(end)}
+		return []int{start, end}
 	}
 	return nil
 }
@@ -530,10 +1664,9 @@ func (m *Matcher) GroupIndices(group int) []int {
 // GroupString returns the numbered capture group as a string.  Group 0
 // is the part of the subject which matches the whole pattern; the first
 // actual capture group is numbered 1.  Capture groups which are not
-// present return an empty string.
+// present or out of range return an empty string.
 func (m *Matcher) GroupString(group int) string {
-	start := m.ovector[2*group]
-	end := m.ovector[2*group+1]
+	start, end := m.span(group)
 	if start >= 0 {
 		if m.subjectb != nil {
 			return string(m.subjectb[start:end])
@@ -547,23 +1680,27 @@ func (m *Matcher) GroupString(group int) string {
 // call to Matcher, MatcherString, Reset, ResetString, Match or
 // MatchString succeeded. loc[0] is the start and loc[1] is the end.
 func (m *Matcher) Index() (loc []int) {
-	if !m.matches {
+	if !m.Matches() {
 		return nil
 	}
 	loc = []int{int(m.ovector[0]), int(m.ovector[1])}
 	return
 }
 
-// name2index converts a group name to its group index number.
+// name2index converts a group name to its group index number in the
+// last match, see Regexp.groupOf.
 func (m *Matcher) name2index(name string) (int, error) {
-	if m.re == nil || m.re.ptr == nil {
-		return 0, fmt.Errorf("Matcher.Named: uninitialized")
+	if m == nil || m.re == nil {
+		return 0, errors.New("Matcher.Named: uninitialized")
+	}
+	m.re.mu.RLock()
+	defer m.re.mu.RUnlock()
+	if m.re.ptr == nil {
+		return 0, errors.New("Matcher.Named: uninitialized")
 	}
-	name1 := C.CString(name)
-	defer C.free(unsafe.Pointer(name1))
-	group := int(C.pcre_get_stringnumber(m.re.ptr, name1))
+	group := m.re.groupOf(name, m.Present)
 	if group < 0 {
-		return group, fmt.Errorf("Matcher.Named: unknown name: " + name)
+		return ERROR_NOSUBSTRING, errors.New("Matcher.Named: unknown name: " + name)
 	}
 	return group, nil
 }
@@ -603,25 +1740,24 @@ func (m *Matcher) NamedPresent(group string) (bool, error) {
 // FindIndex returns the start and end of the first match,
 // or nil if no match.  loc[0] is the start and loc[1] is the end.
 func (re *Regexp) FindIndex(bytes []byte, flags int) (loc []int) {
-	m := re.Matcher(bytes, flags)
-	if m.Matches() {
-		loc = []int{int(m.ovector[0]), int(m.ovector[1])}
-		return
-	}
-	return nil
+	return re.AppendIndex(nil, bytes, flags)
 }
 
 // ReplaceAll returns a copy of a byte slice
 // where all pattern matches are replaced by repl.
+// Matches are found as by FindAllIndex, in the whole subject, so
+// that ^ and lookbehind assertions see the text before each match.
+// The output is allocated at once in the size that earlier calls
+// on re suggest.
 func (re *Regexp) ReplaceAll(bytes, repl []byte, flags int) ([]byte, error) {
-	m := re.Matcher(bytes, flags)
-	r := []byte{}
-	for m.matches {
-		r = append(append(r, bytes[:m.ovector[0]]...), repl...)
-		bytes = bytes[m.ovector[1]:]
-		m.Match(bytes, flags)
+	m := re.AcquireMatcher()
+	defer m.Release()
+	if m.err != nil {
+		return append([]byte{}, bytes...), m.err
 	}
-	return append(r, bytes...), m.err
+	out, err := m.appendReplaceAll(make([]byte, 0, re.replaceCap(len(bytes))), bytes, repl, flags)
+	re.noteReplace(len(bytes), len(out))
+	return out, err
 }
 
 // ReplaceAllString is equivalent to ReplaceAll with string return type.
@@ -638,19 +1774,21 @@ type Match struct {
 
 // FindAll finds all instances that match the regex.
 func (re *Regexp) FindAll(subject string, flags int) ([]Match, error) {
-	matches := make([]Match, 0)
-	m := re.MatcherString(subject, flags)
+	locs, err := re.FindAllLoc(subject, flags)
+	return locs.Matches(), err
+}
+
+// FindAllLoc finds the same matches as FindAll, but only records
+// their offsets, in a single slice, leaving the Match values to be
+// made on demand by the methods of Locs.
+func (re *Regexp) FindAllLoc(subject string, flags int) (Locs, error) {
+	locs := Locs{Subject: subject}
+	m := re.AcquireMatcher()
+	defer m.Release()
+	m.MatchString(subject, flags)
 	offset := 0
 	for m.Matches() {
-		leftIdx := int(m.ovector[0]) + offset
-		rightIdx := int(m.ovector[1]) + offset
-		matches = append(
-			matches,
-			Match{
-				subject[leftIdx:rightIdx],
-				[]int{leftIdx, rightIdx},
-			},
-		)
+		locs.Loc = append(locs.Loc, int(m.ovector[0])+offset, int(m.ovector[1])+offset)
 		offset += maxInt(1, int(m.ovector[1]))
 		if offset < len(subject) {
 			m.MatchString(subject[offset:], flags)
@@ -658,17 +1796,22 @@ func (re *Regexp) FindAll(subject string, flags int) ([]Match, error) {
 			break
 		}
 	}
-	return matches, m.err
+	return locs, m.err
 }
 
 // CompileError holds details about a compilation error,
 // as returned by the Compile function.  The offset is
 // the byte position in the pattern string at which the
-// error was detected.
+// error was detected.  The code is the PCRE error number,
+// see the pcreapi documentation; errors detected by this
+// package have code 0, or the code of the matching PCRE
+// error.  Use IsSyntax, IsUnsupported and IsTooLarge to
+// classify errors.
 type CompileError struct {
 	Pattern string // The failed pattern
 	Message string // The error message
 	Offset  int    // Byte position of error
+	Code    int    // PCRE error number, or 0
 }
 
 // Error converts a compile error to a string
@@ -676,6 +1819,96 @@ func (e *CompileError) Error() string {
 	return e.Pattern + " (" + strconv.Itoa(e.Offset) + "): " + e.Message
 }
 
+// LimitError is reported by Err when a match was aborted because it
+// exceeded the match or recursion limit.  The limit tells whether
+// raising it is reasonable: a pattern that exceeds the library
+// default on a short subject needs fixing instead.
+type LimitError struct {
+	Recursion bool   // The recursion limit, rather than the match limit, was exceeded
+	Limit     uint32 // The limit in effect, see SetLimits
+	Offset    int    // Byte position in the subject where the match attempt started
+}
+
+// Error converts a limit error to a string
+func (e *LimitError) Error() string {
+	kind := "match"
+	if e.Recursion {
+		kind = "recursion"
+	}
+	return "pcre: " + kind + " limit " + strconv.FormatUint(uint64(e.Limit), 10) +
+		" exceeded by match attempt at offset " + strconv.Itoa(e.Offset)
+}
+
+// limitError returns the *LimitError for the return code of the last
+// exec.  Limits of zero are replaced by the library defaults.
+func (m *Matcher) limitError(rc int) *LimitError {
+	matchLimit, recursionLimit := m.re.limits()
+	e := &LimitError{Limit: matchLimit, Offset: m.offset}
+	if rc == C.PCRE_ERROR_RECURSIONLIMIT {
+		e.Recursion, e.Limit = true, recursionLimit
+		if e.Limit == 0 {
+			e.Limit = libraryRecursionLimit()
+		}
+	} else if e.Limit == 0 {
+		e.Limit = libraryMatchLimit()
+	}
+	return e
+}
+
+// TimeoutError is reported by Err when MatchTimeout or
+// MatchStringTimeout ran out of time.
+type TimeoutError struct {
+	Timeout time.Duration // The time limit which was exceeded
+}
+
+// Error converts a timeout error to a string
+func (e *TimeoutError) Error() string {
+	return "Matcher.MatchTimeout: timed out after " + e.Timeout.String()
+}
+
+// UTF8Error holds details about invalid UTF-8 in a subject, as
+// reported by Match and MatchString for patterns compiled with UTF8.
+// The offset is the byte position of the start of the invalid
+// character in the subject.
+type UTF8Error struct {
+	Offset int // Byte position of the invalid character
+	Reason int // PCRE_UTF8_ERR* reason code
+}
+
+// utf8Reasons describes the PCRE_UTF8_ERR* reason codes.
+var utf8Reasons = [...]string{
+	C.PCRE_UTF8_ERR1:  "truncated character, 1 byte missing",
+	C.PCRE_UTF8_ERR2:  "truncated character, 2 bytes missing",
+	C.PCRE_UTF8_ERR3:  "truncated character, 3 bytes missing",
+	C.PCRE_UTF8_ERR4:  "truncated character, 4 bytes missing",
+	C.PCRE_UTF8_ERR5:  "truncated character, 5 bytes missing",
+	C.PCRE_UTF8_ERR6:  "byte 2 top bits not 0x80",
+	C.PCRE_UTF8_ERR7:  "byte 3 top bits not 0x80",
+	C.PCRE_UTF8_ERR8:  "byte 4 top bits not 0x80",
+	C.PCRE_UTF8_ERR9:  "byte 5 top bits not 0x80",
+	C.PCRE_UTF8_ERR10: "byte 6 top bits not 0x80",
+	C.PCRE_UTF8_ERR11: "5-byte character is not allowed",
+	C.PCRE_UTF8_ERR12: "6-byte character is not allowed",
+	C.PCRE_UTF8_ERR13: "code point greater than 0x10ffff",
+	C.PCRE_UTF8_ERR14: "code point is a surrogate (0xd800-0xdfff)",
+	C.PCRE_UTF8_ERR15: "overlong 2-byte sequence",
+	C.PCRE_UTF8_ERR16: "overlong 3-byte sequence",
+	C.PCRE_UTF8_ERR17: "overlong 4-byte sequence",
+	C.PCRE_UTF8_ERR18: "overlong 5-byte sequence",
+	C.PCRE_UTF8_ERR19: "overlong 6-byte sequence",
+	C.PCRE_UTF8_ERR20: "isolated continuation byte",
+	C.PCRE_UTF8_ERR21: "invalid byte (0xfe or 0xff)",
+}
+
+// Error converts a UTF-8 error to a string
+func (e *UTF8Error) Error() string {
+	msg := "invalid UTF-8"
+	if e.Reason > 0 && e.Reason < len(utf8Reasons) && utf8Reasons[e.Reason] != "" {
+		msg += ": " + utf8Reasons[e.Reason]
+	}
+	return msg + " at offset " + strconv.Itoa(e.Offset)
+}
+
 func maxInt(a, b int) int {
 	if a > b {
 		return a
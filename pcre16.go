@@ -0,0 +1,346 @@
+//go:build pcre16
+
+package pcre
+
+// #cgo LDFLAGS: -lpcre16
+// #include <stdlib.h>
+// #include "./pcre.h"
+// static inline void pcre16_free_stub(void *re) {
+//     pcre16_free(re);
+// }
+import "C"
+
+import (
+	"errors"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf16"
+	"unsafe"
+)
+
+// UTF16 makes Compile16 treat patterns and subjects as UTF-16
+// rather than as sequences of 16-bit units.
+const UTF16 = C.PCRE_UTF16
+
+// Regexp16 holds a reference to a regular expression compiled with
+// the 16-bit PCRE library, which matches []uint16 subjects such as
+// UTF-16 strings from Windows APIs or JavaScript.  It is only
+// available with the pcre16 build tag, and links against the system
+// libpcre16.
+type Regexp16 struct {
+	mu    sync.RWMutex // held for writing while freeing or studying
+	ptr   *C.pcre16
+	extra *C.pcre16_extra
+}
+
+// encode16 converts a Go string to NUL-terminated UTF-16.
+func encode16(s string) []uint16 {
+	return append(utf16.Encode([]rune(s)), 0)
+}
+
+// Compile16 compiles the pattern for the 16-bit library.  Flags are
+// the Compile flags, with UTF16 in place of UTF8.  If compilation
+// fails, the second return value holds a *CompileError whose offset
+// is a byte position in pattern.
+func Compile16(pattern string, flags int) (re *Regexp16, err error) {
+	if i := strings.IndexByte(pattern, 0); i >= 0 {
+		err = &CompileError{
+			Pattern: pattern,
+			Message: "NUL byte in pattern",
+			Offset:  i,
+		}
+		return
+	}
+	pattern1 := encode16(pattern)
+	var errptr *C.char
+	var errcode, erroffset C.int
+	re = &Regexp16{}
+	re.ptr = C.pcre16_compile2((*C.ushort)(unsafe.Pointer(&pattern1[0])),
+		C.int(flags), &errcode, &errptr, &erroffset, nil)
+	if re.ptr == nil {
+		err = &CompileError{
+			Pattern: pattern,
+			Message: C.GoString(errptr),
+			Offset:  len(string(utf16.Decode(pattern1[:erroffset]))),
+			Code:    int(errcode),
+		}
+		return
+	}
+	runtime.SetFinalizer(re, (*Regexp16).FreeRegexp)
+	return
+}
+
+// MustCompile16 compiles the pattern.  If compilation fails, panic.
+func MustCompile16(pattern string, flags int) (re *Regexp16) {
+	re, err := Compile16(pattern, flags)
+	if err != nil {
+		panic(err)
+	}
+	return
+}
+
+// Free c allocated memory related to regexp.
+// FreeRegexp may be called more than once.
+func (re *Regexp16) FreeRegexp() {
+	re.mu.Lock()
+	defer re.mu.Unlock()
+	if re.ptr != nil {
+		C.pcre16_free_stub(unsafe.Pointer(re.ptr))
+		re.ptr = nil
+	}
+	if re.extra != nil {
+		C.pcre16_free_study(re.extra)
+		re.extra = nil
+	}
+	runtime.SetFinalizer(re, nil)
+}
+
+// Study adds Just-In-Time compilation to a Regexp16, see
+// Regexp.Study.
+func (re *Regexp16) Study(flags int) error {
+	re.mu.Lock()
+	defer re.mu.Unlock()
+	if re.ptr == nil {
+		return errors.New("Regexp16.Study: uninitialized")
+	}
+	if re.extra != nil {
+		return errors.New("Study: Regexp has already been optimized")
+	}
+	if flags == 0 {
+		flags = STUDY_JIT_COMPILE
+	}
+	var err *C.char
+	re.extra = C.pcre16_study(re.ptr, C.int(flags), &err)
+	if err != nil {
+		return errors.New(C.GoString(err))
+	}
+	return nil
+}
+
+// valid reports whether re has been compiled and not freed.
+func (re *Regexp16) valid() bool {
+	if re == nil {
+		return false
+	}
+	re.mu.RLock()
+	defer re.mu.RUnlock()
+	return re.ptr != nil
+}
+
+// Groups returns the number of capture groups in the compiled pattern.
+func (re *Regexp16) Groups() int {
+	re.mu.RLock()
+	defer re.mu.RUnlock()
+	if re.ptr == nil {
+		panic("Regexp16.Groups: uninitialized")
+	}
+	var count C.int
+	C.pcre16_fullinfo(re.ptr, nil, C.PCRE_INFO_CAPTURECOUNT, unsafe.Pointer(&count))
+	return int(count)
+}
+
+// Matcher16 stores the result of matching a Regexp16 against a
+// []uint16 subject.  Group offsets are in 16-bit units.
+type Matcher16 struct {
+	re      *Regexp16
+	groups  int
+	ovector []C.int
+	matches bool
+	partial bool
+	subject []uint16
+	err     error
+}
+
+// NewMatcher creates a new matcher object for the given Regexp16.
+func (re *Regexp16) NewMatcher() (m *Matcher16) {
+	m = new(Matcher16)
+	m.Init(re)
+	return
+}
+
+// Matcher creates a new matcher object and starts a first match on
+// subject.  Test for success with Matches().
+func (re *Regexp16) Matcher(subject []uint16, flags int) (m *Matcher16) {
+	m = re.NewMatcher()
+	m.Match(subject, flags)
+	return
+}
+
+// MatcherString creates a new matcher, with the UTF-16 encoding of
+// subject as subject.
+func (re *Regexp16) MatcherString(subject string, flags int) (m *Matcher16) {
+	return re.Matcher(utf16.Encode([]rune(subject)), flags)
+}
+
+// Init binds an existing Matcher16 object to the given Regexp16.
+func (m *Matcher16) Init(re *Regexp16) {
+	if m == nil {
+		uninitialized("Matcher16.Init")
+		return
+	}
+	if !re.valid() {
+		m.re = nil
+		m.matches = false
+		m.err = uninitialized("Matcher16.Init")
+		return
+	}
+	m.matches = false
+	m.err = nil
+	if m.re == re {
+		return
+	}
+	m.re = re
+	m.groups = re.Groups()
+	if ovectorlen := 3 * (1 + m.groups); len(m.ovector) < ovectorlen {
+		m.ovector = make([]C.int, ovectorlen)
+	}
+}
+
+// Err returns first error encountered by Matcher16.
+func (m *Matcher16) Err() error {
+	return m.err
+}
+
+var nullunit = []uint16{0}
+
+// Match tries to match subject to the current pattern and collects
+// the result.  Returns true if the match succeeds.
+// Match is a no-op if err is not nil.
+func (m *Matcher16) Match(subject []uint16, flags int) bool {
+	if m.err != nil {
+		return false
+	}
+	if m.err = checkMatchFlags("Matcher16.Match", flags); m.err != nil {
+		m.matches = false
+		return false
+	}
+	rc := m.Exec(subject, flags)
+	switch {
+	case rc >= 0 || rc == ERROR_PARTIAL:
+		m.matches = true
+	case rc == ERROR_NOMATCH:
+		m.matches = false
+	case rc == ERROR_BADLENGTH:
+		m.matches = false
+		m.err = ErrSubjectTooLarge
+	case rc == ERROR_BADUTF8:
+		m.matches = false
+		m.err = &UTF8Error{Offset: int(m.ovector[0]), Reason: int(m.ovector[1])}
+	default:
+		m.matches = false
+		m.err = errors.New("Matcher16.Match: pcre16_exec returned " + strconv.Itoa(rc))
+	}
+	m.partial = (rc == ERROR_PARTIAL)
+	return m.matches
+}
+
+// Exec tries to match subject to the current pattern.  It returns the
+// raw pcre16_exec error code.  Subjects longer than a C int return
+// ERROR_BADLENGTH.
+func (m *Matcher16) Exec(subject []uint16, flags int) int {
+	if m.re == nil {
+		panic("Matcher16.Exec: uninitialized")
+	}
+	length := len(subject)
+	if uint64(length) > maxLength {
+		return ERROR_BADLENGTH
+	}
+	m.subject = subject
+	if length == 0 {
+		subject = nullunit // make first unit adressable
+	}
+	m.re.mu.RLock()
+	defer m.re.mu.RUnlock()
+	if m.re.ptr == nil {
+		return ERROR_NULL
+	}
+	rc := C.pcre16_exec(m.re.ptr, m.re.extra,
+		(*C.ushort)(unsafe.Pointer(&subject[0])), C.int(length),
+		0, C.int(flags), &m.ovector[0], C.int(len(m.ovector)))
+	return int(rc)
+}
+
+// Matches returns true if the last match succeeded.
+func (m *Matcher16) Matches() bool {
+	return m.matches
+}
+
+// Partial returns true if the last match was a partial match.
+func (m *Matcher16) Partial() bool {
+	return m.partial
+}
+
+// Groups returns the number of groups in the current pattern.
+func (m *Matcher16) Groups() int {
+	return m.groups
+}
+
+// span returns the offsets of the numbered capture group in the last
+// match, or -1 if the group is not present or out of range.
+func (m *Matcher16) span(group int) (start, end int) {
+	if !m.matches || group < 0 || group > m.groups {
+		return -1, -1
+	}
+	return int(m.ovector[2*group]), int(m.ovector[2*group+1])
+}
+
+// Present returns true if the numbered capture group is present in
+// the last match.
+func (m *Matcher16) Present(group int) bool {
+	start, _ := m.span(group)
+	return start >= 0
+}
+
+// Group returns the numbered capture group of the last match, or nil
+// if it is not present or out of range.
+func (m *Matcher16) Group(group int) []uint16 {
+	start, end := m.span(group)
+	if start >= 0 {
+		return m.subject[start:end]
+	}
+	return nil
+}
+
+// GroupString returns the numbered capture group decoded from UTF-16.
+func (m *Matcher16) GroupString(group int) string {
+	return string(utf16.Decode(m.Group(group)))
+}
+
+// GroupIndices returns the start and end of the numbered capture group
+// in 16-bit units, or nil if it is not present or out of range.
+func (m *Matcher16) GroupIndices(group int) []int {
+	start, end := m.span(group)
+	if start >= 0 {
+		return []int{start, end}
+	}
+	return nil
+}
+
+// Index returns the start and end of the match in 16-bit units.
+func (m *Matcher16) Index() []int {
+	return m.GroupIndices(0)
+}
+
+// Named returns the value of the named capture group.
+// If the name does not refer to a group then error is non-nil.
+func (m *Matcher16) Named(group string) ([]uint16, error) {
+	if m.re == nil {
+		return nil, errors.New("Matcher16.Named: uninitialized")
+	}
+	if strings.IndexByte(group, 0) >= 0 {
+		return nil, errors.New("Matcher16.Named: NUL byte in name")
+	}
+	name := encode16(group)
+	m.re.mu.RLock()
+	defer m.re.mu.RUnlock()
+	if m.re.ptr == nil {
+		return nil, errors.New("Matcher16.Named: uninitialized")
+	}
+	n := int(C.pcre16_get_stringnumber(m.re.ptr, (*C.ushort)(unsafe.Pointer(&name[0]))))
+	if n < 0 {
+		return nil, errors.New("Matcher16.Named: unknown name: " + group)
+	}
+	return m.Group(n), nil
+}
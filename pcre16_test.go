@@ -0,0 +1,87 @@
+//go:build pcre16
+
+package pcre
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"unicode/utf16"
+	"unsafe"
+)
+
+func TestMatcher16(t *testing.T) {
+	re := MustCompile16(`(?<word>\w+)(x)?`, UTF16|UCP)
+	m := re.MatcherString("  héllo ☺", 0)
+	if !m.Matches() {
+		t.Fatal("no match")
+	}
+	if s := m.GroupString(1); s != "héllo" {
+		t.Error("group 1", s)
+	}
+	if loc := m.Index(); loc[0] != 2 || loc[1] != 7 {
+		t.Error("index", loc)
+	}
+	if m.Present(2) || m.Group(3) != nil {
+		t.Error("absent group present")
+	}
+	if g, err := m.Named("word"); string(utf16.Decode(g)) != "héllo" || err != nil {
+		t.Error("named", g, err)
+	}
+	if _, err := m.Named("nope"); err == nil {
+		t.Error("expected unknown name error")
+	}
+	if _, err := m.Named("word\x00x"); err == nil {
+		t.Error("expected NUL byte error")
+	}
+	// A lone surrogate is invalid UTF-16.
+	if m.Match([]uint16{'a', 0xd800}, 0) {
+		t.Error("matched invalid UTF-16")
+	} else if _, ok := m.Err().(*UTF8Error); !ok {
+		t.Error("expected *UTF8Error, got", m.Err())
+	}
+}
+
+func TestCompile16(t *testing.T) {
+	if _, err := Compile16("☺(", UTF16); err == nil {
+		t.Error("expected CompileError")
+	} else if ce := err.(*CompileError); ce.Offset != 4 {
+		t.Error("offset", ce.Offset)
+	}
+	if _, err := Compile16("a\x00", 0); err == nil {
+		t.Error("expected NUL byte error")
+	}
+}
+
+func TestSubjectTooLarge16(t *testing.T) {
+	n := uint64(maxLength) + 1
+	if n > math.MaxInt {
+		t.Skip("int is no wider than a C int")
+	}
+	m := MustCompile16("a", 0).NewMatcher()
+	// The length is rejected before the subject is read.
+	var u uint16
+	huge := unsafe.Slice(&u, int(n))
+	if rc := m.Exec(huge, 0); rc != ERROR_BADLENGTH {
+		t.Error("Exec", rc)
+	}
+	if m.Match(huge, 0) || m.Err() != ErrSubjectTooLarge {
+		t.Error("Match", m.Err())
+	}
+}
+
+func TestUninitialized16(t *testing.T) {
+	SetSafeMode(true)
+	defer SetSafeMode(false)
+	var nilre *Regexp16
+	m := nilre.NewMatcher()
+	if m.Match([]uint16{'a'}, 0) || !errors.Is(m.Err(), ErrUninitialized) {
+		t.Error("nil Regexp16", m.Err())
+	}
+	re := MustCompile16("a", 0)
+	re.FreeRegexp()
+	m.Init(re)
+	if m.Match([]uint16{'a'}, 0) || !errors.Is(m.Err(), ErrUninitialized) {
+		t.Error("freed Regexp16", m.Err())
+	}
+}
@@ -0,0 +1,734 @@
+// Package pcre2 provides the Regexp and Matcher API of package pcre on
+// top of the PCRE2 library, the maintained successor of PCRE.
+//
+// Code written against package pcre can usually be migrated by
+// changing the import path: the flag names, Compile, CompileJIT,
+// Study, Matcher, Group, Named, FindIndex, ReplaceAll and FindAll
+// behave as documented there.  PCRE2 renames a few options, so
+// flags without a PCRE2 equivalent, such as EXTRA, are not defined,
+// and UTF8 selects PCRE2_UTF.  Subjects and patterns may contain NUL
+// bytes.  In addition, Substitute exposes pcre2_substitute, which
+// expands $1, ${name} and, with SUBSTITUTE_EXTENDED, \U and \L in
+// the replacement.
+//
+// The package links against the system libpcre2-8 through
+// pkg-config; install the PCRE2 development package to build it.
+package pcre2
+
+// #cgo pkg-config: libpcre2-8
+// #define PCRE2_CODE_UNIT_WIDTH 8
+// #include <stdlib.h>
+// #include <pcre2.h>
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"sync"
+	"unsafe"
+)
+
+// Flags for Compile and Match functions.
+const (
+	ANCHORED          = C.PCRE2_ANCHORED
+	NO_START_OPTIMIZE = C.PCRE2_NO_START_OPTIMIZE
+	NO_UTF8_CHECK     = C.PCRE2_NO_UTF_CHECK
+)
+
+// Flags for Compile functions
+const (
+	CASELESS        = C.PCRE2_CASELESS
+	DOLLAR_ENDONLY  = C.PCRE2_DOLLAR_ENDONLY
+	DOTALL          = C.PCRE2_DOTALL
+	DUPNAMES        = C.PCRE2_DUPNAMES
+	EXTENDED        = C.PCRE2_EXTENDED
+	FIRSTLINE       = C.PCRE2_FIRSTLINE
+	MULTILINE       = C.PCRE2_MULTILINE
+	NEVER_UTF       = C.PCRE2_NEVER_UTF
+	NO_AUTO_CAPTURE = C.PCRE2_NO_AUTO_CAPTURE
+	UNGREEDY        = C.PCRE2_UNGREEDY
+	UTF8            = C.PCRE2_UTF
+	UCP             = C.PCRE2_UCP
+)
+
+// Newline selects the line ending convention of a pattern, see
+// CompileOptions.
+type Newline uint32
+
+// Newline conventions
+const (
+	NEWLINE_CR      Newline = C.PCRE2_NEWLINE_CR
+	NEWLINE_LF      Newline = C.PCRE2_NEWLINE_LF
+	NEWLINE_CRLF    Newline = C.PCRE2_NEWLINE_CRLF
+	NEWLINE_ANY     Newline = C.PCRE2_NEWLINE_ANY
+	NEWLINE_ANYCRLF Newline = C.PCRE2_NEWLINE_ANYCRLF
+)
+
+// BSR selects the line endings which \R matches, see CompileOptions.
+type BSR uint32
+
+// \R conventions
+const (
+	BSR_UNICODE BSR = C.PCRE2_BSR_UNICODE
+	BSR_ANYCRLF BSR = C.PCRE2_BSR_ANYCRLF
+)
+
+// CompileOptions holds the settings of the PCRE2 compile context.
+// Unlike PCRE, PCRE2 has no option bits for them, and does not accept
+// them at match time.  Zero values keep the defaults of the library.
+type CompileOptions struct {
+	Newline Newline // Line ending convention
+	BSR     BSR     // Line endings matched by \R
+}
+
+// Flags for Match functions
+const (
+	NOTBOL           = C.PCRE2_NOTBOL
+	NOTEOL           = C.PCRE2_NOTEOL
+	NOTEMPTY         = C.PCRE2_NOTEMPTY
+	NOTEMPTY_ATSTART = C.PCRE2_NOTEMPTY_ATSTART
+	PARTIAL_HARD     = C.PCRE2_PARTIAL_HARD
+	PARTIAL_SOFT     = C.PCRE2_PARTIAL_SOFT
+)
+
+// Flags for Study function
+const (
+	STUDY_JIT_COMPILE              = C.PCRE2_JIT_COMPLETE
+	STUDY_JIT_PARTIAL_SOFT_COMPILE = C.PCRE2_JIT_PARTIAL_SOFT
+	STUDY_JIT_PARTIAL_HARD_COMPILE = C.PCRE2_JIT_PARTIAL_HARD
+)
+
+// Flags for Substitute, in addition to the Match flags
+const (
+	SUBSTITUTE_GLOBAL        = C.PCRE2_SUBSTITUTE_GLOBAL
+	SUBSTITUTE_EXTENDED      = C.PCRE2_SUBSTITUTE_EXTENDED
+	SUBSTITUTE_UNSET_EMPTY   = C.PCRE2_SUBSTITUTE_UNSET_EMPTY
+	SUBSTITUTE_UNKNOWN_UNSET = C.PCRE2_SUBSTITUTE_UNKNOWN_UNSET
+)
+
+// Match-time error codes
+const (
+	ERROR_NOMATCH     = C.PCRE2_ERROR_NOMATCH
+	ERROR_PARTIAL     = C.PCRE2_ERROR_PARTIAL
+	ERROR_NOMEMORY    = C.PCRE2_ERROR_NOMEMORY
+	ERROR_NOSUBSTRING = C.PCRE2_ERROR_NOSUBSTRING
+)
+
+// unset marks a capture group which is not present in the ovector.
+const unset = ^C.PCRE2_SIZE(0)
+
+// Regexp holds a reference to a compiled regular expression.
+// Use Compile or MustCompile to create such objects.
+type Regexp struct {
+	mu  sync.RWMutex // held for writing while freeing or studying
+	ptr *C.pcre2_code_8
+}
+
+// errorMessage returns the PCRE2 message for an error code.
+func errorMessage(code C.int) string {
+	var buf [256]C.PCRE2_UCHAR8
+	n := C.pcre2_get_error_message_8(code, &buf[0], C.PCRE2_SIZE(len(buf)))
+	if n < 0 {
+		return "unknown error " + strconv.Itoa(int(code))
+	}
+	return C.GoStringN((*C.char)(unsafe.Pointer(&buf[0])), n)
+}
+
+// Free c allocated memory related to regexp.
+// FreeRegexp may be called more than once.
+func (re *Regexp) FreeRegexp() {
+	re.mu.Lock()
+	defer re.mu.Unlock()
+	if re.ptr != nil {
+		C.pcre2_code_free_8(re.ptr)
+		re.ptr = nil
+	}
+	runtime.SetFinalizer(re, nil)
+}
+
+// Compile the pattern and return a compiled regexp.
+// If compilation fails, the second return value holds a *CompileError.
+func Compile(pattern string, flags int) (re *Regexp, err error) {
+	return CompileOptions{}.Compile(pattern, flags)
+}
+
+// Compile compiles the pattern as the package function Compile does,
+// with the newline and \R conventions of opts.
+func (opts CompileOptions) Compile(pattern string, flags int) (re *Regexp, err error) {
+	pattern1 := C.CString(pattern)
+	defer C.free(unsafe.Pointer(pattern1))
+	var ctx *C.pcre2_compile_context_8
+	if opts != (CompileOptions{}) {
+		ctx = C.pcre2_compile_context_create_8(nil)
+		if ctx == nil {
+			return nil, errors.New("Compile: out of memory")
+		}
+		defer C.pcre2_compile_context_free_8(ctx)
+		if opts.Newline != 0 && C.pcre2_set_newline_8(ctx, C.uint32_t(opts.Newline)) != 0 {
+			return nil, errors.New("Compile: invalid Newline " + strconv.Itoa(int(opts.Newline)))
+		}
+		if opts.BSR != 0 && C.pcre2_set_bsr_8(ctx, C.uint32_t(opts.BSR)) != 0 {
+			return nil, errors.New("Compile: invalid BSR " + strconv.Itoa(int(opts.BSR)))
+		}
+	}
+	var errcode C.int
+	var erroffset C.PCRE2_SIZE
+	re = &Regexp{}
+	re.ptr = C.pcre2_compile_8((C.PCRE2_SPTR8)(unsafe.Pointer(pattern1)),
+		C.PCRE2_SIZE(len(pattern)), C.uint32_t(flags),
+		&errcode, &erroffset, ctx)
+	if re.ptr == nil {
+		err = &CompileError{
+			Pattern: pattern,
+			Message: errorMessage(errcode),
+			Offset:  int(erroffset),
+		}
+		return
+	}
+	runtime.SetFinalizer(re, (*Regexp).FreeRegexp)
+	return
+}
+
+// CompileJIT is a combination of Compile and Study. It first compiles
+// the pattern and if this succeeds calls Study on the compiled pattern.
+// comFlags are Compile flags, jitFlags are study flags.
+// If compilation fails, the second return value holds a *CompileError.
+func CompileJIT(pattern string, comFlags, jitFlags int) (*Regexp, error) {
+	re, err := Compile(pattern, comFlags)
+	if err == nil {
+		err = re.Study(jitFlags)
+	}
+	return re, err
+}
+
+// MustCompile compiles the pattern.  If compilation fails, panic.
+func MustCompile(pattern string, flags int) (re *Regexp) {
+	re, err := Compile(pattern, flags)
+	if err != nil {
+		panic(err)
+	}
+	return
+}
+
+// MustCompileJIT compiles and studies the pattern.  On failure it panics.
+func MustCompileJIT(pattern string, comFlags, jitFlags int) (re *Regexp) {
+	re, err := CompileJIT(pattern, comFlags, jitFlags)
+	if err != nil {
+		panic(err)
+	}
+	return
+}
+
+// Study adds Just-In-Time compilation to a Regexp, using
+// pcre2_jit_compile.  Flags optionally specifies JIT compilation
+// options for partial matches.
+func (re *Regexp) Study(flags int) error {
+	re.mu.Lock()
+	defer re.mu.Unlock()
+	if re.ptr == nil {
+		return errors.New("Regexp.Study: uninitialized")
+	}
+	if flags == 0 {
+		flags = STUDY_JIT_COMPILE
+	}
+	if rc := C.pcre2_jit_compile_8(re.ptr, C.uint32_t(flags)); rc < 0 {
+		return fmt.Errorf("Study: %s", errorMessage(rc))
+	}
+	return nil
+}
+
+// Groups returns the number of capture groups in the compiled pattern.
+func (re *Regexp) Groups() int {
+	re.mu.RLock()
+	defer re.mu.RUnlock()
+	if re.ptr == nil {
+		panic("Regexp.Groups: uninitialized")
+	}
+	var count C.uint32_t
+	C.pcre2_pattern_info_8(re.ptr, C.PCRE2_INFO_CAPTURECOUNT, unsafe.Pointer(&count))
+	return int(count)
+}
+
+// Matcher objects provide a place for storing match results.
+// They can be created by the Matcher and MatcherString functions,
+// or they can be initialized with Reset or ResetString.
+//
+// The match data of a Matcher is allocated by PCRE2 and released
+// by a finalizer, or earlier by Free.
+type Matcher struct {
+	re       *Regexp
+	groups   int
+	data     *matchData
+	ovector  []C.PCRE2_SIZE // view of the match data's ovector
+	matches  bool           // last match was successful
+	partial  bool           // was the last match a partial match?
+	subjects string         // one of these fields is set to record the subject,
+	subjectb []byte         // so that Group/GroupString can return slices
+	err      error
+}
+
+// matchData owns the match data of a Matcher.  It is allocated on its
+// own, so that its finalizer is set on a heap object of its own, also
+// for Matchers embedded in other structs or not on the heap.
+type matchData struct {
+	ptr *C.pcre2_match_data_8
+}
+
+func (md *matchData) free() {
+	C.pcre2_match_data_free_8(md.ptr)
+	md.ptr = nil
+	runtime.SetFinalizer(md, nil)
+}
+
+// NewMatcher creates a new matcher object for the given Regexp.
+func (re *Regexp) NewMatcher() (m *Matcher) {
+	m = new(Matcher)
+	m.Init(re)
+	return
+}
+
+// Matcher creates a new matcher object, with the byte slice as subject.
+// It also starts a first match on subject. Test for success with Matches().
+func (re *Regexp) Matcher(subject []byte, flags int) (m *Matcher) {
+	m = re.NewMatcher()
+	m.Match(subject, flags)
+	return
+}
+
+// MatcherString creates a new matcher, with the specified subject string.
+// It also starts a first match on subject. Test for success with Matches().
+func (re *Regexp) MatcherString(subject string, flags int) (m *Matcher) {
+	m = re.NewMatcher()
+	m.MatchString(subject, flags)
+	return
+}
+
+// Reset switches the matcher object to the specified regexp and subject.
+// It also starts a first match on subject.
+func (m *Matcher) Reset(re *Regexp, subject []byte, flags int) bool {
+	m.Init(re)
+	return m.Match(subject, flags)
+}
+
+// ResetString switches the matcher object to the given regexp and subject.
+// It also starts a first match on subject.
+func (m *Matcher) ResetString(re *Regexp, subject string, flags int) bool {
+	m.Init(re)
+	return m.MatchString(subject, flags)
+}
+
+// Init binds an existing Matcher object to the given Regexp, and
+// allocates match data sized for its capture groups.
+func (m *Matcher) Init(re *Regexp) {
+	m.matches = false
+	m.err = nil
+	if m.re == re && m.data != nil {
+		return
+	}
+	m.Free()
+	re.mu.RLock()
+	defer re.mu.RUnlock()
+	if re.ptr == nil {
+		panic("Matcher.Init: uninitialized")
+	}
+	ptr := C.pcre2_match_data_create_from_pattern_8(re.ptr, nil)
+	if ptr == nil {
+		panic("Matcher.Init: out of memory")
+	}
+	m.re = re
+	m.data = &matchData{ptr}
+	runtime.SetFinalizer(m.data, (*matchData).free)
+	n := int(C.pcre2_get_ovector_count_8(ptr))
+	m.groups = n - 1
+	m.ovector = unsafe.Slice(C.pcre2_get_ovector_pointer_8(ptr), 2*n)
+}
+
+// Free releases the match data of the Matcher.  The Matcher must be
+// bound to a Regexp again with Init, Reset or ResetString before it
+// is reused.
+func (m *Matcher) Free() {
+	if m.data != nil {
+		m.data.free()
+		m.data = nil
+		m.ovector = nil
+		m.re = nil
+		m.matches = false
+	}
+}
+
+// Err returns first error encountered by Matcher.
+func (m *Matcher) Err() error {
+	return m.err
+}
+
+var nullbyte = []byte{0}
+
+// Match tries to match the specified byte slice to
+// the current pattern by calling Exec and collects the result.
+// Returns true if the match succeeds.
+// Match is a no-op if err is not nil.
+func (m *Matcher) Match(subject []byte, flags int) bool {
+	if m.err != nil {
+		return false
+	}
+	rc := m.Exec(subject, flags)
+	m.matches, m.err = matched(rc)
+	m.partial = (rc == ERROR_PARTIAL)
+	return m.matches
+}
+
+// MatchString tries to match the specified subject string to
+// the current pattern by calling ExecString and collects the result.
+// Returns true if the match succeeds.
+func (m *Matcher) MatchString(subject string, flags int) bool {
+	if m.err != nil {
+		return false
+	}
+	rc := m.ExecString(subject, flags)
+	m.matches, m.err = matched(rc)
+	m.partial = (rc == ERROR_PARTIAL)
+	return m.matches
+}
+
+// Exec tries to match the specified byte slice to
+// the current pattern. Returns the raw pcre2_match error code.
+func (m *Matcher) Exec(subject []byte, flags int) int {
+	if m.data == nil {
+		panic("Matcher.Exec: uninitialized")
+	}
+	m.subjects = ""
+	m.subjectb = subject
+	length := len(subject)
+	if length == 0 {
+		subject = nullbyte // make first character adressable
+	}
+	return m.exec(unsafe.Pointer(&subject[0]), length, flags)
+}
+
+// ExecString tries to match the specified subject string to
+// the current pattern. It returns the raw pcre2_match error code.
+func (m *Matcher) ExecString(subject string, flags int) int {
+	if m.data == nil {
+		panic("Matcher.ExecString: uninitialized")
+	}
+	m.subjects = subject
+	m.subjectb = nil
+	if len(subject) == 0 {
+		return m.exec(unsafe.Pointer(&nullbyte[0]), 0, flags)
+	}
+	return m.exec(unsafe.Pointer(unsafe.StringData(subject)), len(subject), flags)
+}
+
+func (m *Matcher) exec(subjectptr unsafe.Pointer, length, flags int) int {
+	m.re.mu.RLock()
+	defer m.re.mu.RUnlock()
+	if m.re.ptr == nil {
+		panic("Matcher.Exec: uninitialized")
+	}
+	rc := C.pcre2_match_8(m.re.ptr, (C.PCRE2_SPTR8)(subjectptr),
+		C.PCRE2_SIZE(length), 0, C.uint32_t(flags), m.data.ptr, nil)
+	runtime.KeepAlive(m.data)
+	return int(rc)
+}
+
+// matched checks the return code of a pattern match for success.
+func matched(rc int) (bool, error) {
+	switch {
+	case rc >= 0 || rc == ERROR_PARTIAL:
+		return true, nil
+	case rc == ERROR_NOMATCH:
+		return false, nil
+	}
+	return false, errors.New("pcre2_match: " + errorMessage(C.int(rc)))
+}
+
+// Matches returns true if a previous call to Matcher, MatcherString, Reset,
+// ResetString, Match or MatchString succeeded.
+func (m *Matcher) Matches() bool {
+	return m.matches
+}
+
+// Partial returns true if a previous call to Matcher, MatcherString, Reset,
+// ResetString, Match or MatchString found a partial match.
+func (m *Matcher) Partial() bool {
+	return m.partial
+}
+
+// Groups returns the number of groups in the current pattern.
+func (m *Matcher) Groups() int {
+	return m.groups
+}
+
+// Present returns true if the numbered capture group is present in the
+// last match.  Group numbers start at 1.  A capture group can be present
+// and match the empty string.  Group numbers out of range are never
+// present.
+func (m *Matcher) Present(group int) bool {
+	start, _ := m.span(group)
+	return start >= 0
+}
+
+// span returns the offsets of the numbered capture group in the last
+// match, or -1 if the group is not present or out of range.  After a
+// failed match, no group is present.
+func (m *Matcher) span(group int) (start, end int) {
+	if !m.matches || group < 0 || group > m.groups ||
+		m.ovector[2*group] == unset {
+		return -1, -1
+	}
+	return int(m.ovector[2*group]), int(m.ovector[2*group+1])
+}
+
+// Group returns the numbered capture group of the last match.  Group 0
+// is the part of the subject which matches the whole pattern; the first
+// actual capture group is numbered 1.  Capture groups which are not
+// present or out of range return a nil slice.
+func (m *Matcher) Group(group int) []byte {
+	start, end := m.span(group)
+	if start >= 0 {
+		if m.subjectb != nil {
+			return m.subjectb[start:end]
+		}
+		return []byte(m.subjects[start:end])
+	}
+	return nil
+}
+
+// Extract returns a slice of byte slices for a single match.
+// The first byte slice contains the complete match.
+// Subsequent byte slices contain the captured groups, or nil for
+// groups which are not present.
+// If there was no match then nil is returned.
+func (m *Matcher) Extract() [][]byte {
+	if !m.matches {
+		return nil
+	}
+	extract := make([][]byte, m.groups+1)
+	for i := 0; i <= m.groups; i++ {
+		extract[i] = m.Group(i)
+	}
+	return extract
+}
+
+// ExtractString returns a slice of strings for a single match.
+// The first string contains the complete match.
+// Subsequent strings in the slice contain the captured groups, or ""
+// for groups which are not present.
+// If there was no match then nil is returned.
+func (m *Matcher) ExtractString() []string {
+	if !m.matches {
+		return nil
+	}
+	extract := make([]string, m.groups+1)
+	for i := 0; i <= m.groups; i++ {
+		extract[i] = m.GroupString(i)
+	}
+	return extract
+}
+
+// GroupIndices returns the numbered capture group positions of the last
+// match.  Capture groups which are not present or out of range return a
+// nil slice.
+func (m *Matcher) GroupIndices(group int) []int {
+	start, end := m.span(group)
+	if start >= 0 {
+		return []int{start, end}
+	}
+	return nil
+}
+
+// GroupString returns the numbered capture group as a string.  Capture
+// groups which are not present or out of range return an empty string.
+func (m *Matcher) GroupString(group int) string {
+	start, end := m.span(group)
+	if start >= 0 {
+		if m.subjectb != nil {
+			return string(m.subjectb[start:end])
+		}
+		return m.subjects[start:end]
+	}
+	return ""
+}
+
+// Index returns the start and end of the first match, if a previous
+// call to Matcher, MatcherString, Reset, ResetString, Match or
+// MatchString succeeded. loc[0] is the start and loc[1] is the end.
+func (m *Matcher) Index() (loc []int) {
+	return m.GroupIndices(0)
+}
+
+// name2index converts a group name to its group index number.
+func (m *Matcher) name2index(name string) (int, error) {
+	if m.re == nil {
+		return 0, errors.New("Matcher.Named: uninitialized")
+	}
+	m.re.mu.RLock()
+	defer m.re.mu.RUnlock()
+	if m.re.ptr == nil {
+		return 0, errors.New("Matcher.Named: uninitialized")
+	}
+	name1 := C.CString(name)
+	defer C.free(unsafe.Pointer(name1))
+	group := int(C.pcre2_substring_number_from_name_8(m.re.ptr,
+		(C.PCRE2_SPTR8)(unsafe.Pointer(name1))))
+	if group < 0 {
+		return group, errors.New("Matcher.Named: unknown name: " + name)
+	}
+	return group, nil
+}
+
+// Named returns the value of the named capture group.
+// This is a nil slice if the capture group is not present.
+// If the name does not refer to a group then error is non-nil.
+func (m *Matcher) Named(group string) ([]byte, error) {
+	groupNum, err := m.name2index(group)
+	if err != nil {
+		return []byte{}, err
+	}
+	return m.Group(groupNum), nil
+}
+
+// NamedString returns the value of the named capture group,
+// or an empty string if the capture group is not present.
+// If the name does not refer to a group then error is non-nil.
+func (m *Matcher) NamedString(group string) (string, error) {
+	groupNum, err := m.name2index(group)
+	if err != nil {
+		return "", err
+	}
+	return m.GroupString(groupNum), nil
+}
+
+// NamedPresent returns true if the named capture group is present.
+// If the name does not refer to a group then error is non-nil.
+func (m *Matcher) NamedPresent(group string) (bool, error) {
+	groupNum, err := m.name2index(group)
+	if err != nil {
+		return false, err
+	}
+	return m.Present(groupNum), nil
+}
+
+// FindIndex returns the start and end of the first match,
+// or nil if no match.  loc[0] is the start and loc[1] is the end.
+func (re *Regexp) FindIndex(bytes []byte, flags int) (loc []int) {
+	m := re.Matcher(bytes, flags)
+	defer m.Free()
+	return m.Index()
+}
+
+// ReplaceAll returns a copy of a byte slice
+// where all pattern matches are replaced by repl.
+// Unlike Substitute, repl is inserted literally.
+func (re *Regexp) ReplaceAll(bytes, repl []byte, flags int) ([]byte, error) {
+	m := re.Matcher(bytes, flags)
+	defer m.Free()
+	r := []byte{}
+	for m.matches {
+		r = append(append(r, bytes[:m.ovector[0]]...), repl...)
+		bytes = bytes[m.ovector[1]:]
+		m.Match(bytes, flags)
+	}
+	return append(r, bytes...), m.err
+}
+
+// ReplaceAllString is equivalent to ReplaceAll with string return type.
+func (re *Regexp) ReplaceAllString(in, repl string, flags int) (string, error) {
+	str, err := re.ReplaceAll([]byte(in), []byte(repl), flags)
+	return string(str), err
+}
+
+// Match holds details about a single successful regex match.
+type Match struct {
+	Finding string // Text that was found.
+	Loc     []int  // Index bounds for location of finding.
+}
+
+// FindAll finds all instances that match the regex.
+func (re *Regexp) FindAll(subject string, flags int) ([]Match, error) {
+	matches := make([]Match, 0)
+	m := re.MatcherString(subject, flags)
+	defer m.Free()
+	offset := 0
+	for m.Matches() {
+		leftIdx := int(m.ovector[0]) + offset
+		rightIdx := int(m.ovector[1]) + offset
+		matches = append(
+			matches,
+			Match{
+				subject[leftIdx:rightIdx],
+				[]int{leftIdx, rightIdx},
+			},
+		)
+		offset += max(1, int(m.ovector[1]))
+		if offset < len(subject) {
+			m.MatchString(subject[offset:], flags)
+		} else {
+			break
+		}
+	}
+	return matches, m.err
+}
+
+// Substitute returns a copy of subject in which the first match, or
+// every match with SUBSTITUTE_GLOBAL, is replaced by the expansion of
+// repl, using pcre2_substitute.  In repl, $n and ${n} refer to numbered
+// groups, ${name} to named groups and $$ to a literal dollar sign.
+// Flags may combine Match flags with the SUBSTITUTE flags.  If nothing
+// matches, Substitute returns a copy of subject.
+func (re *Regexp) Substitute(subject, repl []byte, flags int) ([]byte, error) {
+	re.mu.RLock()
+	defer re.mu.RUnlock()
+	if re.ptr == nil {
+		return nil, errors.New("Regexp.Substitute: uninitialized")
+	}
+	subjectptr, replptr := nullbyte, nullbyte
+	if len(subject) > 0 {
+		subjectptr = subject
+	}
+	if len(repl) > 0 {
+		replptr = repl
+	}
+	// With OVERFLOW_LENGTH, a short buffer makes pcre2_substitute
+	// report the size it needs, so at most one retry is necessary.
+	out := make([]byte, len(subject)+len(repl)+1)
+	for {
+		outlen := C.PCRE2_SIZE(len(out))
+		rc := C.pcre2_substitute_8(re.ptr,
+			(C.PCRE2_SPTR8)(unsafe.Pointer(&subjectptr[0])), C.PCRE2_SIZE(len(subject)),
+			0, C.uint32_t(flags)|C.PCRE2_SUBSTITUTE_OVERFLOW_LENGTH, nil, nil,
+			(C.PCRE2_SPTR8)(unsafe.Pointer(&replptr[0])), C.PCRE2_SIZE(len(repl)),
+			(*C.PCRE2_UCHAR8)(unsafe.Pointer(&out[0])), &outlen)
+		switch {
+		case rc >= 0:
+			return out[:outlen], nil
+		case rc == ERROR_NOMEMORY && int(outlen) > len(out):
+			out = make([]byte, outlen)
+		default:
+			return nil, errors.New("Regexp.Substitute: " + errorMessage(rc))
+		}
+	}
+}
+
+// SubstituteString is equivalent to Substitute with string arguments.
+func (re *Regexp) SubstituteString(subject, repl string, flags int) (string, error) {
+	out, err := re.Substitute([]byte(subject), []byte(repl), flags)
+	return string(out), err
+}
+
+// CompileError holds details about a compilation error,
+// as returned by the Compile function.  The offset is
+// the byte position in the pattern string at which the
+// error was detected.
+type CompileError struct {
+	Pattern string // The failed pattern
+	Message string // The error message
+	Offset  int    // Byte position of error
+}
+
+// Error converts a compile error to a string
+func (e *CompileError) Error() string {
+	return e.Pattern + " (" + strconv.Itoa(e.Offset) + "): " + e.Message
+}
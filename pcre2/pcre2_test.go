@@ -0,0 +1,142 @@
+package pcre2
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	var check = func(p string, groups int) {
+		re, err := Compile(p, 0)
+		if err != nil {
+			t.Error(p, err)
+		} else if g := re.Groups(); g != groups {
+			t.Error(p, g)
+		}
+	}
+	check("", 0)
+	check("^", 0)
+	check("^$", 0)
+	check("()", 1)
+	check("(())", 2)
+	check("((?:))", 1)
+	if _, err := Compile("(", 0); err == nil {
+		t.Error("expected CompileError")
+	} else if ce := err.(*CompileError); ce.Offset != 1 {
+		t.Error("offset", ce.Offset)
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	re := MustCompile(`(?<word>\w+)(x)?`, 0)
+	m := re.MatcherString("  hello", 0)
+	if !m.Matches() {
+		t.Fatal("no match")
+	}
+	if s := m.GroupString(1); s != "hello" {
+		t.Error("group 1", s)
+	}
+	if m.Present(2) || m.Group(2) != nil || m.Present(3) {
+		t.Error("absent group present")
+	}
+	if s, err := m.NamedString("word"); s != "hello" || err != nil {
+		t.Error("named", s, err)
+	}
+	if _, err := m.Named("nope"); err == nil {
+		t.Error("expected unknown name error")
+	}
+	if !m.Match([]byte("a\x00b"), 0) || string(m.Group(0)) != "a" {
+		t.Error("NUL subject", m.Index())
+	}
+	if m.MatchString("", 0) {
+		t.Error("empty subject matched")
+	}
+	m.Free()
+}
+
+func TestPartial(t *testing.T) {
+	re := MustCompile("hello", 0)
+	m := re.MatcherString("hel", PARTIAL_SOFT)
+	if !m.Matches() || !m.Partial() {
+		t.Error("expected partial match")
+	}
+}
+
+func TestReplaceAll(t *testing.T) {
+	re := MustCompile("foo", 0)
+	if s, err := re.ReplaceAllString("afoobfooc", "$1", 0); s != "a$1b$1c" || err != nil {
+		t.Error(s, err)
+	}
+	if loc := re.FindIndex([]byte("xfoo"), 0); len(loc) != 2 || loc[0] != 1 || loc[1] != 4 {
+		t.Error(loc)
+	}
+	all, err := re.FindAll("foofoo", 0)
+	if len(all) != 2 || all[1].Loc[0] != 3 || err != nil {
+		t.Error(all, err)
+	}
+}
+
+func TestSubstitute(t *testing.T) {
+	re := MustCompile(`(?<k>\w+)=(\w+)`, 0)
+	var check = func(subject, repl string, flags int, want string) {
+		got, err := re.SubstituteString(subject, repl, flags)
+		if err != nil {
+			t.Error(subject, repl, err)
+		} else if got != want {
+			t.Errorf("%q %q: expected %q, got %q", subject, repl, want, got)
+		}
+	}
+	check("a=1 b=2", "$2:${k}", 0, "1:a b=2")
+	check("a=1 b=2", "$2:${k}", SUBSTITUTE_GLOBAL, "1:a 2:b")
+	check("a=1", `\U$k`, SUBSTITUTE_EXTENDED, "A")
+	check("none", "x", 0, "none")
+	check("", "x", 0, "")
+	long := make([]byte, 1000)
+	for i := range long {
+		long[i] = 'x'
+	}
+	check("a=1", string(long), 0, string(long))
+	if _, err := re.SubstituteString("a=1", "$9", 0); err == nil {
+		t.Error("expected error for unknown group")
+	}
+}
+
+func TestNewline(t *testing.T) {
+	re, err := CompileOptions{Newline: NEWLINE_CR}.Compile("^b", MULTILINE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer re.FreeRegexp()
+	if !re.MatcherString("a\rb", 0).Matches() || re.MatcherString("a\nb", 0).Matches() {
+		t.Error("NEWLINE_CR not applied")
+	}
+	re, err = CompileOptions{BSR: BSR_ANYCRLF}.Compile(`a\Rb`, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer re.FreeRegexp()
+	if re.MatcherString("a\vb", 0).Matches() || !re.MatcherString("a\r\nb", 0).Matches() {
+		t.Error("BSR_ANYCRLF not applied")
+	}
+	if _, err := (CompileOptions{Newline: 99}).Compile("a", 0); err == nil {
+		t.Error("expected error for invalid Newline")
+	}
+}
+
+var globalMatcher Matcher
+
+func TestMatcherNotOnHeap(t *testing.T) {
+	re := MustCompile("a", 0)
+	defer re.FreeRegexp()
+	var embedded struct {
+		n int
+		m Matcher
+	}
+	for _, m := range []*Matcher{&embedded.m, &globalMatcher} {
+		if !m.ResetString(re, "a", 0) {
+			t.Error("no match")
+		}
+		m.Free()
+	}
+	runtime.GC()
+}
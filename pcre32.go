@@ -0,0 +1,345 @@
+//go:build pcre32
+
+package pcre
+
+// #cgo LDFLAGS: -lpcre32
+// #include <stdlib.h>
+// #include "./pcre.h"
+// static inline void pcre32_free_stub(void *re) {
+//     pcre32_free(re);
+// }
+import "C"
+
+import (
+	"errors"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// UTF32 makes Compile32 treat patterns and subjects as UTF-32, so
+// that surrogates and values above U+10FFFF are rejected.
+const UTF32 = C.PCRE_UTF32
+
+// Regexp32 holds a reference to a regular expression compiled with
+// the 32-bit PCRE library, which matches []rune subjects without
+// encoding them to UTF-8 first.  It is only
+// available with the pcre32 build tag, and links against the system
+// libpcre32.
+type Regexp32 struct {
+	mu    sync.RWMutex // held for writing while freeing or studying
+	ptr   *C.pcre32
+	extra *C.pcre32_extra
+}
+
+// encode32 converts a Go string to NUL-terminated UTF-32.
+func encode32(s string) []rune {
+	return append([]rune(s), 0)
+}
+
+// Compile32 compiles the pattern for the 32-bit library.  Flags are
+// the Compile flags, with UTF32 in place of UTF8.  If compilation
+// fails, the second return value holds a *CompileError whose offset
+// is a byte position in pattern.
+func Compile32(pattern string, flags int) (re *Regexp32, err error) {
+	if i := strings.IndexByte(pattern, 0); i >= 0 {
+		err = &CompileError{
+			Pattern: pattern,
+			Message: "NUL byte in pattern",
+			Offset:  i,
+		}
+		return
+	}
+	pattern1 := encode32(pattern)
+	var errptr *C.char
+	var errcode, erroffset C.int
+	re = &Regexp32{}
+	re.ptr = C.pcre32_compile2((*C.uint)(unsafe.Pointer(&pattern1[0])),
+		C.int(flags), &errcode, &errptr, &erroffset, nil)
+	if re.ptr == nil {
+		err = &CompileError{
+			Pattern: pattern,
+			Message: C.GoString(errptr),
+			Offset:  len(string(pattern1[:erroffset])),
+			Code:    int(errcode),
+		}
+		return
+	}
+	runtime.SetFinalizer(re, (*Regexp32).FreeRegexp)
+	return
+}
+
+// MustCompile32 compiles the pattern.  If compilation fails, panic.
+func MustCompile32(pattern string, flags int) (re *Regexp32) {
+	re, err := Compile32(pattern, flags)
+	if err != nil {
+		panic(err)
+	}
+	return
+}
+
+// Free c allocated memory related to regexp.
+// FreeRegexp may be called more than once.
+func (re *Regexp32) FreeRegexp() {
+	re.mu.Lock()
+	defer re.mu.Unlock()
+	if re.ptr != nil {
+		C.pcre32_free_stub(unsafe.Pointer(re.ptr))
+		re.ptr = nil
+	}
+	if re.extra != nil {
+		C.pcre32_free_study(re.extra)
+		re.extra = nil
+	}
+	runtime.SetFinalizer(re, nil)
+}
+
+// Study adds Just-In-Time compilation to a Regexp32, see
+// Regexp.Study.
+func (re *Regexp32) Study(flags int) error {
+	re.mu.Lock()
+	defer re.mu.Unlock()
+	if re.ptr == nil {
+		return errors.New("Regexp32.Study: uninitialized")
+	}
+	if re.extra != nil {
+		return errors.New("Study: Regexp has already been optimized")
+	}
+	if flags == 0 {
+		flags = STUDY_JIT_COMPILE
+	}
+	var err *C.char
+	re.extra = C.pcre32_study(re.ptr, C.int(flags), &err)
+	if err != nil {
+		return errors.New(C.GoString(err))
+	}
+	return nil
+}
+
+// valid reports whether re has been compiled and not freed.
+func (re *Regexp32) valid() bool {
+	if re == nil {
+		return false
+	}
+	re.mu.RLock()
+	defer re.mu.RUnlock()
+	return re.ptr != nil
+}
+
+// Groups returns the number of capture groups in the compiled pattern.
+func (re *Regexp32) Groups() int {
+	re.mu.RLock()
+	defer re.mu.RUnlock()
+	if re.ptr == nil {
+		panic("Regexp32.Groups: uninitialized")
+	}
+	var count C.int
+	C.pcre32_fullinfo(re.ptr, nil, C.PCRE_INFO_CAPTURECOUNT, unsafe.Pointer(&count))
+	return int(count)
+}
+
+// Matcher32 stores the result of matching a Regexp32 against a
+// []rune subject.  Group offsets are rune positions.
+type Matcher32 struct {
+	re      *Regexp32
+	groups  int
+	ovector []C.int
+	matches bool
+	partial bool
+	subject []rune
+	err     error
+}
+
+// NewMatcher creates a new matcher object for the given Regexp32.
+func (re *Regexp32) NewMatcher() (m *Matcher32) {
+	m = new(Matcher32)
+	m.Init(re)
+	return
+}
+
+// Matcher creates a new matcher object and starts a first match on
+// subject.  Test for success with Matches().
+func (re *Regexp32) Matcher(subject []rune, flags int) (m *Matcher32) {
+	m = re.NewMatcher()
+	m.Match(subject, flags)
+	return
+}
+
+// MatcherString creates a new matcher, with the runes of subject as
+// subject.
+func (re *Regexp32) MatcherString(subject string, flags int) (m *Matcher32) {
+	return re.Matcher([]rune(subject), flags)
+}
+
+// Init binds an existing Matcher32 object to the given Regexp32.
+func (m *Matcher32) Init(re *Regexp32) {
+	if m == nil {
+		uninitialized("Matcher32.Init")
+		return
+	}
+	if !re.valid() {
+		m.re = nil
+		m.matches = false
+		m.err = uninitialized("Matcher32.Init")
+		return
+	}
+	m.matches = false
+	m.err = nil
+	if m.re == re {
+		return
+	}
+	m.re = re
+	m.groups = re.Groups()
+	if ovectorlen := 3 * (1 + m.groups); len(m.ovector) < ovectorlen {
+		m.ovector = make([]C.int, ovectorlen)
+	}
+}
+
+// Err returns first error encountered by Matcher32.
+func (m *Matcher32) Err() error {
+	return m.err
+}
+
+var nullrune = []rune{0}
+
+// Match tries to match subject to the current pattern and collects
+// the result.  Returns true if the match succeeds.
+// Match is a no-op if err is not nil.
+func (m *Matcher32) Match(subject []rune, flags int) bool {
+	if m.err != nil {
+		return false
+	}
+	if m.err = checkMatchFlags("Matcher32.Match", flags); m.err != nil {
+		m.matches = false
+		return false
+	}
+	rc := m.Exec(subject, flags)
+	switch {
+	case rc >= 0 || rc == ERROR_PARTIAL:
+		m.matches = true
+	case rc == ERROR_NOMATCH:
+		m.matches = false
+	case rc == ERROR_BADLENGTH:
+		m.matches = false
+		m.err = ErrSubjectTooLarge
+	case rc == ERROR_BADUTF8:
+		m.matches = false
+		m.err = &UTF8Error{Offset: int(m.ovector[0]), Reason: int(m.ovector[1])}
+	default:
+		m.matches = false
+		m.err = errors.New("Matcher32.Match: pcre32_exec returned " + strconv.Itoa(rc))
+	}
+	m.partial = (rc == ERROR_PARTIAL)
+	return m.matches
+}
+
+// Exec tries to match subject to the current pattern.  It returns the
+// raw pcre32_exec error code.  Subjects longer than a C int return
+// ERROR_BADLENGTH.
+func (m *Matcher32) Exec(subject []rune, flags int) int {
+	if m.re == nil {
+		panic("Matcher32.Exec: uninitialized")
+	}
+	length := len(subject)
+	if uint64(length) > maxLength {
+		return ERROR_BADLENGTH
+	}
+	m.subject = subject
+	if length == 0 {
+		subject = nullrune // make first rune adressable
+	}
+	m.re.mu.RLock()
+	defer m.re.mu.RUnlock()
+	if m.re.ptr == nil {
+		return ERROR_NULL
+	}
+	rc := C.pcre32_exec(m.re.ptr, m.re.extra,
+		(*C.uint)(unsafe.Pointer(&subject[0])), C.int(length),
+		0, C.int(flags), &m.ovector[0], C.int(len(m.ovector)))
+	return int(rc)
+}
+
+// Matches returns true if the last match succeeded.
+func (m *Matcher32) Matches() bool {
+	return m.matches
+}
+
+// Partial returns true if the last match was a partial match.
+func (m *Matcher32) Partial() bool {
+	return m.partial
+}
+
+// Groups returns the number of groups in the current pattern.
+func (m *Matcher32) Groups() int {
+	return m.groups
+}
+
+// span returns the offsets of the numbered capture group in the last
+// match, or -1 if the group is not present or out of range.
+func (m *Matcher32) span(group int) (start, end int) {
+	if !m.matches || group < 0 || group > m.groups {
+		return -1, -1
+	}
+	return int(m.ovector[2*group]), int(m.ovector[2*group+1])
+}
+
+// Present returns true if the numbered capture group is present in
+// the last match.
+func (m *Matcher32) Present(group int) bool {
+	start, _ := m.span(group)
+	return start >= 0
+}
+
+// Group returns the numbered capture group of the last match, or nil
+// if it is not present or out of range.
+func (m *Matcher32) Group(group int) []rune {
+	start, end := m.span(group)
+	if start >= 0 {
+		return m.subject[start:end]
+	}
+	return nil
+}
+
+// GroupString returns the numbered capture group as a string.
+func (m *Matcher32) GroupString(group int) string {
+	return string(m.Group(group))
+}
+
+// GroupIndices returns the start and end of the numbered capture group
+// in runes, or nil if it is not present or out of range.
+func (m *Matcher32) GroupIndices(group int) []int {
+	start, end := m.span(group)
+	if start >= 0 {
+		return []int{start, end}
+	}
+	return nil
+}
+
+// Index returns the start and end of the match in runes.
+func (m *Matcher32) Index() []int {
+	return m.GroupIndices(0)
+}
+
+// Named returns the value of the named capture group.
+// If the name does not refer to a group then error is non-nil.
+func (m *Matcher32) Named(group string) ([]rune, error) {
+	if m.re == nil {
+		return nil, errors.New("Matcher32.Named: uninitialized")
+	}
+	if strings.IndexByte(group, 0) >= 0 {
+		return nil, errors.New("Matcher32.Named: NUL byte in name")
+	}
+	name := encode32(group)
+	m.re.mu.RLock()
+	defer m.re.mu.RUnlock()
+	if m.re.ptr == nil {
+		return nil, errors.New("Matcher32.Named: uninitialized")
+	}
+	n := int(C.pcre32_get_stringnumber(m.re.ptr, (*C.uint)(unsafe.Pointer(&name[0]))))
+	if n < 0 {
+		return nil, errors.New("Matcher32.Named: unknown name: " + group)
+	}
+	return m.Group(n), nil
+}
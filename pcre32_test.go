@@ -0,0 +1,84 @@
+//go:build pcre32
+
+package pcre
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"unsafe"
+)
+
+func TestMatcher32(t *testing.T) {
+	re := MustCompile32(`(?<word>\w+)(x)?`, UTF32|UCP)
+	m := re.MatcherString("  héllo ☺", 0)
+	if !m.Matches() {
+		t.Fatal("no match")
+	}
+	if s := m.GroupString(1); s != "héllo" {
+		t.Error("group 1", s)
+	}
+	if loc := m.Index(); loc[0] != 2 || loc[1] != 7 {
+		t.Error("index", loc)
+	}
+	if m.Present(2) || m.Group(3) != nil {
+		t.Error("absent group present")
+	}
+	if g, err := m.Named("word"); string(g) != "héllo" || err != nil {
+		t.Error("named", g, err)
+	}
+	if _, err := m.Named("word\x00x"); err == nil {
+		t.Error("expected NUL byte error")
+	}
+	re = MustCompile32("☺+", UTF32)
+	if loc := re.Matcher([]rune("ab☺☺c"), 0).Index(); loc[0] != 2 || loc[1] != 4 {
+		t.Error("rune index", loc)
+	}
+	// Surrogates are invalid UTF-32.
+	if m := re.Matcher([]rune{'a', 0xd800}, 0); m.Matches() {
+		t.Error("matched invalid UTF-32")
+	} else if _, ok := m.Err().(*UTF8Error); !ok {
+		t.Error("expected *UTF8Error, got", m.Err())
+	}
+}
+
+func TestCompile32(t *testing.T) {
+	if _, err := Compile32("☺(", UTF32); err == nil {
+		t.Error("expected CompileError")
+	} else if ce := err.(*CompileError); ce.Offset != 4 {
+		t.Error("offset", ce.Offset)
+	}
+}
+
+func TestSubjectTooLarge32(t *testing.T) {
+	n := uint64(maxLength) + 1
+	if n > math.MaxInt {
+		t.Skip("int is no wider than a C int")
+	}
+	m := MustCompile32("a", 0).NewMatcher()
+	// The length is rejected before the subject is read.
+	var r rune
+	huge := unsafe.Slice(&r, int(n))
+	if rc := m.Exec(huge, 0); rc != ERROR_BADLENGTH {
+		t.Error("Exec", rc)
+	}
+	if m.Match(huge, 0) || m.Err() != ErrSubjectTooLarge {
+		t.Error("Match", m.Err())
+	}
+}
+
+func TestUninitialized32(t *testing.T) {
+	SetSafeMode(true)
+	defer SetSafeMode(false)
+	var nilre *Regexp32
+	m := nilre.NewMatcher()
+	if m.Match([]rune{'a'}, 0) || !errors.Is(m.Err(), ErrUninitialized) {
+		t.Error("nil Regexp32", m.Err())
+	}
+	re := MustCompile32("a", 0)
+	re.FreeRegexp()
+	m.Init(re)
+	if m.Match([]rune{'a'}, 0) || !errors.Is(m.Err(), ErrUninitialized) {
+		t.Error("freed Regexp32", m.Err())
+	}
+}
@@ -0,0 +1,125 @@
+//go:build pcre_dll && !pcre_vendor
+
+/* PCRE functions used by the package, forwarding to a PCRE DLL which
+   is loaded at run time by pcre_dll_load.  Functions newly called from
+   Go must be added here too, or pcre_dll builds fail to link. */
+
+#include <windows.h>
+#include "pcre.h"
+
+static pcre *(*dll_compile)(const char *, int, const char **, int *,
+                            const unsigned char *);
+static pcre *(*dll_compile2)(const char *, int, int *, const char **, int *,
+                             const unsigned char *);
+static int (*dll_config)(int, void *);
+static int (*dll_dfa_exec)(const pcre *, const pcre_extra *, const char *, int,
+                           int, int, int *, int, int *, int);
+static int (*dll_exec)(const pcre *, const pcre_extra *, PCRE_SPTR, int,
+                       int, int, int *, int);
+static void (**dll_free)(void *);
+static void (*dll_free_study)(pcre_extra *);
+static int (*dll_fullinfo)(const pcre *, const pcre_extra *, int, void *);
+static int (*dll_get_stringnumber)(const pcre *, const char *);
+static void *(**dll_malloc)(size_t);
+static pcre_extra *(*dll_study)(const pcre *, int, const char **);
+static const char *(*dll_version)(void);
+
+static const struct {
+  const char *name;
+  void **fn;
+} symbols[] = {
+  {"pcre_compile", (void **)&dll_compile},
+  {"pcre_compile2", (void **)&dll_compile2},
+  {"pcre_config", (void **)&dll_config},
+  {"pcre_dfa_exec", (void **)&dll_dfa_exec},
+  {"pcre_exec", (void **)&dll_exec},
+  {"pcre_free", (void **)&dll_free},
+  {"pcre_free_study", (void **)&dll_free_study},
+  {"pcre_fullinfo", (void **)&dll_fullinfo},
+  {"pcre_get_stringnumber", (void **)&dll_get_stringnumber},
+  {"pcre_malloc", (void **)&dll_malloc},
+  {"pcre_study", (void **)&dll_study},
+  {"pcre_version", (void **)&dll_version},
+};
+
+/* pcre_dll_load loads the DLL at path and resolves all symbols.  It
+   returns 0 if the DLL cannot be loaded or lacks one of them. */
+int pcre_dll_load(const char *path) {
+  size_t i;
+  HMODULE lib = LoadLibraryA(path);
+  if (lib == NULL)
+    return 0;
+  for (i = 0; i < sizeof(symbols) / sizeof(symbols[0]); i++) {
+    *symbols[i].fn = (void *)GetProcAddress(lib, symbols[i].name);
+    if (*symbols[i].fn == NULL) {
+      FreeLibrary(lib);
+      return 0;
+    }
+  }
+  return 1;
+}
+
+pcre *pcre_compile(const char *pattern, int options, const char **errptr,
+                   int *erroffset, const unsigned char *tables) {
+  return dll_compile(pattern, options, errptr, erroffset, tables);
+}
+
+pcre *pcre_compile2(const char *pattern, int options, int *errorcode,
+                    const char **errptr, int *erroffset,
+                    const unsigned char *tables) {
+  return dll_compile2(pattern, options, errorcode, errptr, erroffset, tables);
+}
+
+int pcre_config(int what, void *where) {
+  return dll_config(what, where);
+}
+
+int pcre_dfa_exec(const pcre *code, const pcre_extra *extra,
+                  const char *subject, int length, int startoffset,
+                  int options, int *ovector, int ovecsize, int *workspace,
+                  int wscount) {
+  return dll_dfa_exec(code, extra, subject, length, startoffset, options,
+                      ovector, ovecsize, workspace, wscount);
+}
+
+int pcre_exec(const pcre *code, const pcre_extra *extra, PCRE_SPTR subject,
+              int length, int startoffset, int options, int *ovector,
+              int ovecsize) {
+  return dll_exec(code, extra, subject, length, startoffset, options,
+                  ovector, ovecsize);
+}
+
+/* The DLL exports pcre_free as a variable holding a function pointer. */
+static void free_stub(void *p) {
+  (*dll_free)(p);
+}
+
+void (*pcre_free)(void *) = free_stub;
+
+/* pcre_dll_set_allocator sets the pcre_malloc and pcre_free of the
+   DLL, which pcre_free above forwards to. */
+void pcre_dll_set_allocator(void *(*m)(size_t), void (*f)(void *)) {
+  *dll_malloc = m;
+  *dll_free = f;
+}
+
+void pcre_free_study(pcre_extra *extra) {
+  dll_free_study(extra);
+}
+
+int pcre_fullinfo(const pcre *code, const pcre_extra *extra, int what,
+                  void *where) {
+  return dll_fullinfo(code, extra, what, where);
+}
+
+int pcre_get_stringnumber(const pcre *code, const char *name) {
+  return dll_get_stringnumber(code, name);
+}
+
+pcre_extra *pcre_study(const pcre *code, int options, const char **errptr) {
+  return dll_study(code, options, errptr);
+}
+
+const char *pcre_version(void) {
+  return dll_version();
+}
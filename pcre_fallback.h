@@ -1,3 +1,6 @@
 #ifndef PCRE_NEVER_UTF
 #define PCRE_NEVER_UTF 0x0
 #endif
+#ifndef PCRE_INFO_MAXLOOKBEHIND
+#define PCRE_INFO_MAXLOOKBEHIND 18
+#endif
@@ -3,8 +3,13 @@
 package pcre
 
 import (
+	"errors"
+	"math"
 	"reflect"
+	"sync"
 	"testing"
+	"time"
+	"unsafe"
 )
 
 func TestCompile(t *testing.T) {
@@ -47,7 +52,7 @@ func TestCompileFail(t *testing.T) {
 	check("a\000bc", "NUL byte in pattern", 1)
 }
 
-func strings(b [][]byte) (r []string) {
+func byteStrings(b [][]byte) (r []string) {
 	r = make([]string, len(b))
 	for i, v := range b {
 		r[i] = string(v)
@@ -214,6 +219,49 @@ func TestNamed(t *testing.T) {
 	}
 }
 
+func TestSubexpNames(t *testing.T) {
+	re := MustCompile("(?<year>\\d+)-(\\d+)-(?<a_long_name>\\d+)", 0)
+	defer re.FreeRegexp()
+	want := []string{"", "year", "", "a_long_name"}
+	if got := re.SubexpNames(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	re2 := MustCompile("a(b)", 0)
+	defer re2.FreeRegexp()
+	if got := re2.SubexpNames(); !reflect.DeepEqual(got, []string{"", ""}) {
+		t.Errorf("unnamed groups: %q", got)
+	}
+}
+
+func TestSubexpIndex(t *testing.T) {
+	re := MustCompile("(?<year>\\d+)-(?<day>\\d+)|(?<day>x)", DUPNAMES)
+	defer re.FreeRegexp()
+	for name, want := range map[string]int{"year": 1, "day": 2, "month": -1, "": -1} {
+		if got := re.SubexpIndex(name); got != want {
+			t.Errorf("%q: expected %d, got %d", name, want, got)
+		}
+	}
+	m := re.MatcherString("2024-15", 0)
+	if day, err := m.NamedString("day"); err != nil || day != "15" {
+		t.Errorf("NamedString: %q, %v", day, err)
+	}
+	if _, err := m.NamedString("month"); err == nil {
+		t.Error("expected error for unknown name")
+	}
+	// The name refers to the duplicate which is set, as in
+	// FindAllNamed.
+	m = re.MatcherString("x", 0)
+	if day, err := m.NamedString("day"); err != nil || day != "x" {
+		t.Errorf("NamedString of a higher duplicate: %q, %v", day, err)
+	}
+	if ok, _ := m.NamedPresent("day"); !ok {
+		t.Error("NamedPresent of a higher duplicate")
+	}
+	if r, _ := m.Result(); r.namedGroup("day") != 3 {
+		t.Error("MatchResult group", r.namedGroup("day"))
+	}
+}
+
 func TestMatcherIndex(t *testing.T) {
 	re := MustCompile("bcd", 0)
 	defer re.FreeRegexp()
@@ -246,6 +294,53 @@ func TestFindIndex(t *testing.T) {
 	}
 }
 
+func TestExecRange(t *testing.T) {
+	subject := "foo bar baz"
+	for _, test := range []struct {
+		pattern    string
+		start, end int
+		flags      int
+		want       []int
+	}{
+		{`ba.`, 4, 11, 0, []int{4, 7}},
+		{`ba.`, 5, 11, 0, []int{8, 11}},
+		{`ba.`, 5, 10, 0, nil},
+		{`(?<=o )ba.`, 4, 7, 0, []int{4, 7}},
+		{`\bar`, 5, 11, 0, nil},
+		{`^bar`, 4, 11, 0, nil},
+		{`\w+$`, 4, 7, 0, []int{4, 7}},
+		{`\w+$`, 4, 7, NOTEOL, nil},
+		{`a(?=r)`, 4, 6, 0, nil},
+	} {
+		re := MustCompile(test.pattern, 0)
+		m := re.NewMatcher()
+		for _, rc := range []int{
+			m.ExecRange([]byte(subject), test.start, test.end, test.flags),
+			m.ExecRangeString(subject, test.start, test.end, test.flags),
+		} {
+			if rc < 0 && rc != ERROR_NOMATCH {
+				t.Errorf("%s: unexpected error %d", test.pattern, rc)
+			}
+			if got := m.Index(); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("%s in [%d,%d): expected %v, got %v", test.pattern, test.start, test.end, test.want, got)
+			}
+		}
+		re.FreeRegexp()
+	}
+	re := MustCompile(`b(a)(.)`, 0)
+	defer re.FreeRegexp()
+	m := re.NewMatcher()
+	if rc := m.ExecRange([]byte(subject), 5, 11, 0); rc < 0 || m.GroupString(2) != "z" {
+		t.Errorf("groups: rc %d, group 2 %q", rc, m.GroupString(2))
+	}
+	for _, r := range [][2]int{{-1, 2}, {3, 2}, {0, 12}} {
+		m.MatchString(subject, 0)
+		if rc := m.ExecRangeString(subject, r[0], r[1], 0); rc != ERROR_BADOFFSET || m.Matches() {
+			t.Errorf("range %v: expected ERROR_BADOFFSET, got %d", r, rc)
+		}
+	}
+}
+
 func TestExtract(t *testing.T) {
 	re := MustCompile("b(c)(d)", 0)
 	defer re.FreeRegexp()
@@ -291,6 +386,99 @@ func TestReplaceAll(t *testing.T) {
 	}
 }
 
+func TestStudyInfo(t *testing.T) {
+	re := MustCompile(`(?:foo|bar)\d+`, 0)
+	defer re.FreeRegexp()
+	if info := re.StudyInfo(); info != (StudyInfo{}) {
+		t.Error("unstudied pattern", info)
+	}
+	if err := re.Study(STUDY_JIT_COMPILE); err != nil {
+		t.Fatal(err)
+	}
+	info := re.StudyInfo()
+	if !info.Studied || info.Size <= 0 {
+		t.Error("expected study data", info)
+	}
+	if info.JIT != (info.JITSize > 0) {
+		t.Error("JIT size does not agree with JIT", info)
+	}
+}
+
+func TestSize(t *testing.T) {
+	re := MustCompile(`(?:foo|bar)\d+`, 0)
+	size := re.Size()
+	if size <= 0 || size != int(pcreSize(re.ptr)) {
+		t.Errorf("unstudied pattern: Size %d, pattern %d", size, pcreSize(re.ptr))
+	}
+	if err := re.Study(STUDY_JIT_COMPILE); err != nil {
+		t.Fatal(err)
+	}
+	info := re.StudyInfo()
+	if got := re.Size(); got != size+info.Size+info.JITSize {
+		t.Errorf("studied pattern: Size %d, expected %d+%d+%d", got, size, info.Size, info.JITSize)
+	}
+	re.FreeRegexp()
+	if got := re.Size(); got != 0 {
+		t.Errorf("freed pattern: Size %d", got)
+	}
+}
+
+func TestStudyPartial(t *testing.T) {
+	for _, study := range []func(re *Regexp) error{
+		func(re *Regexp) error { return re.Study(STUDY_JIT_COMPILE) },
+		func(re *Regexp) error { return re.StudyPartial(false) },
+		func(re *Regexp) error { return re.StudyPartial(true) },
+	} {
+		re := MustCompile("abc", 0)
+		if err := study(re); err != nil {
+			t.Fatal(err)
+		}
+		for _, flags := range []int{PARTIAL_SOFT, PARTIAL_HARD} {
+			m := re.MatcherString("xab", flags)
+			if !m.Partial() || m.Err() != nil {
+				t.Errorf("flags %#x: no partial match, %v", flags, m.Err())
+			}
+		}
+		if !re.MatcherString("abc", 0).Matches() {
+			t.Error("no complete match")
+		}
+		re.FreeRegexp()
+	}
+	if jitMode(PARTIAL_SOFT|PARTIAL_HARD) != STUDY_JIT_PARTIAL_HARD_COMPILE {
+		t.Error("PARTIAL_HARD does not take precedence")
+	}
+}
+
+func TestAutoStudy(t *testing.T) {
+	re := MustCompile(`\d+`, 0)
+	defer re.FreeRegexp()
+	re.SetAutoStudy(3, 0)
+	m := re.NewMatcher()
+	for i := 0; i < 2; i++ {
+		m.MatchString("a1", 0)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if re.StudyInfo().Studied {
+		t.Fatal("studied before the threshold")
+	}
+	m.MatchString("a1", 0)
+	for i := 0; !re.StudyInfo().Studied; i++ {
+		if i == 100 {
+			t.Fatal("not studied after the threshold")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !m.MatchString("a1", 0) {
+		t.Error("no match after studying")
+	}
+
+	freed := MustCompile(`\d+`, 0)
+	freed.SetAutoStudy(1, 0)
+	freed.MatcherString("1", 0)
+	freed.FreeRegexp()
+	time.Sleep(10 * time.Millisecond)
+}
+
 func TestFreeRegexp(t *testing.T) {
 	re := MustCompileJIT("\\d{3}", 0, STUDY_JIT_COMPILE)
 	data := []string{"15asd213", "sadi32fjoi"}
@@ -356,3 +544,376 @@ func verifyMatches(t *testing.T, expected []Match, matches []Match) {
 		}
 	}
 }
+
+func TestUTF8Error(t *testing.T) {
+	re := MustCompile("b", UTF8)
+	defer re.FreeRegexp()
+	var check = func(subject string, off, reason int) {
+		m := re.MatcherString(subject, 0)
+		if m.Matches() {
+			t.Error(subject, "Matches")
+		}
+		uerr, ok := m.Err().(*UTF8Error)
+		switch {
+		case !ok:
+			t.Error(subject, "Err", m.Err())
+		case uerr.Offset != off:
+			t.Error(subject, "Offset", uerr.Offset)
+		case uerr.Reason != reason:
+			t.Error(subject, "Reason", uerr.Reason)
+		}
+	}
+	check("a\xffb", 1, 21)
+	check("ab\xc3", 2, 1)
+	check("\xc0\x80", 0, 15)
+}
+
+func TestUTF8CheckOnce(t *testing.T) {
+	re := MustCompile("b", UTF8)
+	defer re.FreeRegexp()
+	m := re.NewMatcher()
+	subject := "äbäb"
+	if rc := m.ExecString(subject, 0); rc < 0 || !m.checked {
+		t.Fatal("expected match and checked subject", rc, m.checked)
+	}
+	if rc := m.ExecRangeString(subject, 5, len(subject), 0); rc < 0 {
+		t.Error("second match", rc)
+	}
+	if rc := m.ExecRangeString(subject, 1, len(subject), 0); rc != ERROR_BADUTF8_OFFSET {
+		t.Error("offset inside a character", rc)
+	}
+	if rc := m.ExecString("\xffb", 0); rc != ERROR_BADUTF8 {
+		t.Error("new string subject", rc)
+	}
+	b := []byte("ab")
+	if !m.Match(b, 0) {
+		t.Fatal("byte subject", m.Err())
+	}
+	b[0] = 0xff
+	if m.Match(b, 0) || m.Err() == nil {
+		t.Error("modified byte subject was not checked")
+	}
+}
+
+func TestInvalidUTF8Mode(t *testing.T) {
+	re := MustCompile("a(.)c", UTF8)
+	defer re.FreeRegexp()
+	re.SetInvalidUTF8(INVALID_UTF8_REPLACE)
+	m := re.MatcherString("xa\xffc", 0)
+	if !m.Matches() || m.Err() != nil {
+		t.Fatal("INVALID_UTF8_REPLACE", m.Err())
+	}
+	// Offsets are mapped back to the subject as passed.
+	if g := m.GroupString(1); g != "\xff" {
+		t.Errorf("INVALID_UTF8_REPLACE group %q", g)
+	}
+	re.SetInvalidUTF8(INVALID_UTF8_SKIP)
+	m = re.Matcher([]byte("xa\xffbc"), 0)
+	if !m.Matches() || m.Err() != nil {
+		t.Fatal("INVALID_UTF8_SKIP", m.Err())
+	}
+	if g := m.GroupString(1); g != "b" {
+		t.Error("INVALID_UTF8_SKIP group", g)
+	}
+	if loc := m.Index(); !reflect.DeepEqual(loc, []int{1, 5}) {
+		t.Error("INVALID_UTF8_SKIP index", loc)
+	}
+
+	// A replaced byte before a match shifts the sanitized copy.
+	re = MustCompile("b", UTF8)
+	defer re.FreeRegexp()
+	for _, mode := range []InvalidUTF8Mode{INVALID_UTF8_REPLACE, INVALID_UTF8_SKIP} {
+		re.SetInvalidUTF8(mode)
+		locs, err := re.FindAllLoc("\xffb", 0)
+		if err != nil || !reflect.DeepEqual(locs.Loc, []int{1, 2}) {
+			t.Fatal("FindAllLoc", mode, locs.Loc, err)
+		}
+		if f := locs.Finding(0); f != "b" {
+			t.Errorf("Finding %d %q", mode, f)
+		}
+		out, err := re.ReplaceAllString("\xffb\xfe\xfdb", "x", 0)
+		if err != nil || out != "\xffx\xfe\xfdx" {
+			t.Errorf("ReplaceAllString %d %q %v", mode, out, err)
+		}
+		parts := re.SplitAfter("a\xffbc", 0)
+		if !reflect.DeepEqual(parts, []string{"a\xffb", "c"}) {
+			t.Errorf("SplitAfter %d %q", mode, parts)
+		}
+	}
+}
+
+func TestLimits(t *testing.T) {
+	re := MustCompile("(a+)+$", 0)
+	defer re.FreeRegexp()
+	subject := "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaab"
+	re.SetLimits(1000, 0)
+	m := re.MatcherString(subject, 0)
+	if m.Matches() || m.Err() == nil {
+		t.Error("SetLimits: expected match limit error")
+	}
+
+	re.SetLimits(0, 0)
+	SetDefaultLimits(1000, 0)
+	defer SetDefaultLimits(0, 0)
+	m = re.MatcherString(subject, 0)
+	if m.Matches() || m.Err() == nil {
+		t.Error("SetDefaultLimits: expected match limit error")
+	}
+	m = re.MatcherString("aaa", 0)
+	if !m.Matches() || m.Err() != nil {
+		t.Error("SetDefaultLimits: expected match", m.Err())
+	}
+}
+
+func TestLimitError(t *testing.T) {
+	re := MustCompile("(a+)+$", 0)
+	defer re.FreeRegexp()
+	re.SetLimits(1000, 0)
+	m := re.NewMatcher()
+	subject := "xyz aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaab"
+	if m.execOffsetString(subject, 4, 0) != ERROR_MATCHLIMIT {
+		t.Fatal("expected match limit error")
+	}
+	_, err := m.matched(ERROR_MATCHLIMIT)
+	var lerr *LimitError
+	if !errors.As(err, &lerr) || lerr.Recursion || lerr.Limit != 1000 || lerr.Offset != 4 {
+		t.Errorf("unexpected error %#v", err)
+	}
+	re.SetLimits(0, 10)
+	m = re.MatcherString(subject, 0)
+	if !errors.As(m.Err(), &lerr) || !lerr.Recursion || lerr.Limit != 10 {
+		t.Errorf("unexpected error %#v", m.Err())
+	}
+}
+
+func TestMatchTimeout(t *testing.T) {
+	re := MustCompile("(a+)+$", 0)
+	defer re.FreeRegexp()
+	re.SetLimits(1<<31, 0)
+	m := re.NewMatcher()
+	if m.MatchStringTimeout("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaab", 0, 10*time.Millisecond) {
+		t.Error("MatchStringTimeout: unexpected match")
+	}
+	if _, ok := m.Err().(*TimeoutError); !ok {
+		t.Error("MatchStringTimeout: expected *TimeoutError, got", m.Err())
+	}
+	m = re.NewMatcher()
+	if !m.MatchTimeout([]byte("aaa"), 0, time.Second) || m.Err() != nil {
+		t.Error("MatchTimeout: expected match", m.Err())
+	}
+	// The deadline is checked before the first step.
+	m = re.NewMatcher()
+	if m.MatchTimeout([]byte("aaa"), 0, -time.Second) {
+		t.Error("MatchTimeout: matched past the deadline")
+	}
+	if _, ok := m.Err().(*TimeoutError); !ok {
+		t.Error("MatchTimeout: expected *TimeoutError, got", m.Err())
+	}
+}
+
+func TestSafeMode(t *testing.T) {
+	SetSafeMode(true)
+	defer SetSafeMode(false)
+	re := MustCompile("a", 0)
+	re.FreeRegexp()
+	if g := re.Groups(); g != -1 {
+		t.Error("Groups", g)
+	}
+	m := re.NewMatcher()
+	if !errors.Is(m.Err(), ErrUninitialized) {
+		t.Error("NewMatcher", m.Err())
+	}
+	var m2 Matcher
+	if m2.MatchString("a", 0) || !errors.Is(m2.Err(), ErrUninitialized) {
+		t.Error("MatchString", m2.Err())
+	}
+	if rc := m2.ExecString("a", 0); rc != ERROR_NULL {
+		t.Error("ExecString", rc)
+	}
+}
+
+func TestNilReceivers(t *testing.T) {
+	SetSafeMode(true)
+	defer SetSafeMode(false)
+	var re *Regexp
+	if g := re.Groups(); g != -1 {
+		t.Error("Groups", g)
+	}
+	if err := re.Study(0); !errors.Is(err, ErrUninitialized) {
+		t.Error("Study", err)
+	}
+	re.SetLimits(1, 1)
+	re.FreeRegexp()
+	m := re.MatcherString("a", 0)
+	if m.Matches() || !errors.Is(m.Err(), ErrUninitialized) {
+		t.Error("MatcherString", m.Err())
+	}
+	if _, err := re.ReplaceAllString("a", "b", 0); !errors.Is(err, ErrUninitialized) {
+		t.Error("ReplaceAllString", err)
+	}
+	var nm *Matcher
+	if nm.MatchString("a", 0) || nm.Matches() || nm.Group(0) != nil || nm.Index() != nil {
+		t.Error("nil Matcher matched")
+	}
+	if !errors.Is(nm.Err(), ErrUninitialized) {
+		t.Error("Err", nm.Err())
+	}
+	if rc := nm.ExecString("a", 0); rc != ERROR_NULL {
+		t.Error("ExecString", rc)
+	}
+	if _, err := nm.NamedString("x"); err == nil {
+		t.Error("NamedString: no error")
+	}
+}
+
+func TestConcurrentFree(t *testing.T) {
+	SetSafeMode(true)
+	defer SetSafeMode(false)
+	re := MustCompileJIT("a+b", 0, STUDY_JIT_COMPILE)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m := re.NewMatcher()
+			for j := 0; j < 1000; j++ {
+				m.MatchString("xxaaab", 0)
+			}
+		}()
+	}
+	re.FreeRegexp()
+	re.FreeRegexp()
+	wg.Wait()
+}
+
+func TestMatchFlags(t *testing.T) {
+	re := MustCompile("abc", 0)
+	defer re.FreeRegexp()
+	m := re.MatcherString("abc", CASELESS)
+	if m.Matches() || m.Err() == nil {
+		t.Fatal("CASELESS accepted as match flag")
+	}
+	if msg := m.Err().Error(); msg != "Matcher.MatchString: CASELESS is not a match flag" {
+		t.Error("Err", msg)
+	}
+	m = re.Matcher([]byte("abc"), NOTEMPTY|MULTILINE|DOTALL)
+	if msg := m.Err().Error(); msg != "Matcher.Match: DOTALL|MULTILINE are not match flags" {
+		t.Error("Err", msg)
+	}
+	m = re.MatcherString("abc", NOTEMPTY|NOTBOL)
+	if !m.Matches() || m.Err() != nil {
+		t.Error("valid match flags rejected", m.Err())
+	}
+}
+
+func TestSubjectTooLarge(t *testing.T) {
+	re := MustCompile("a", 0)
+	defer re.FreeRegexp()
+	m := re.NewMatcher()
+	if ok, err := m.matched(ERROR_BADLENGTH); ok || err != ErrSubjectTooLarge {
+		t.Error("matched(ERROR_BADLENGTH)", ok, err)
+	}
+	n := uint64(maxLength) + 1
+	if n > math.MaxInt {
+		t.Skip("int is no wider than a C int")
+	}
+	// The length is rejected before the subject is read, so it need
+	// not be backed by memory.
+	var b byte
+	huge := unsafe.Slice(&b, int(n))
+	hugeString := unsafe.String(&b, int(n))
+	if rc := m.Exec(huge, 0); rc != ERROR_BADLENGTH {
+		t.Error("Exec", rc)
+	}
+	if rc := m.ExecString(hugeString, 0); rc != ERROR_BADLENGTH {
+		t.Error("ExecString", rc)
+	}
+	if rc := m.ExecRange(huge, 0, len(huge), 0); rc != ERROR_BADLENGTH {
+		t.Error("ExecRange", rc)
+	}
+	if m.Match(huge, 0) || m.Err() != ErrSubjectTooLarge {
+		t.Error("Match", m.Err())
+	}
+	m = re.NewMatcher()
+	if m.MatchStringAt(hugeString, 0, 0) || m.Err() != ErrSubjectTooLarge {
+		t.Error("MatchStringAt", m.Err())
+	}
+	if _, err := re.MatchDFA(huge, 0); err != ErrSubjectTooLarge {
+		t.Error("MatchDFA", err)
+	}
+	if _, err := re.FindLastStringIndex(hugeString, 0); err != ErrSubjectTooLarge {
+		t.Error("FindLastStringIndex", err)
+	}
+}
+
+func TestCaptureOverflow(t *testing.T) {
+	re := MustCompile("(a)(b)(c)", 0)
+	defer re.FreeRegexp()
+	m := re.NewMatcher()
+	m.ovector = m.ovector[:3] // room for group 0 only
+	if !m.MatchString("abc", 0) {
+		t.Fatal("MatchString", m.Err())
+	}
+	if g := m.GroupString(3); g != "c" {
+		t.Error("GroupString(3)", g)
+	}
+
+	// Stale groups of a failed match are not reported.
+	m.MatchString("xyz", 0)
+	if g := m.Group(1); g != nil {
+		t.Error("Group(1) after failed match", g)
+	}
+
+	re2 := MustCompile("(a)|(b)", 0)
+	defer re2.FreeRegexp()
+	m = re2.MatcherString("b", 0)
+	if x := m.ExtractString(); x[1] != "" || x[2] != "b" {
+		t.Error("ExtractString", x)
+	}
+}
+
+func TestExecGroups(t *testing.T) {
+	re := MustCompile(`(a)(b)?`, 0)
+	defer re.FreeRegexp()
+	m := re.NewMatcher()
+	if rc := m.ExecString("xa", 0); rc < 0 || !m.Matches() {
+		t.Fatal("ExecString", rc)
+	}
+	if m.GroupString(1) != "a" || !reflect.DeepEqual(m.GroupIndices(1), []int{1, 2}) || m.Present(2) {
+		t.Error("groups after ExecString", m.GroupString(1), m.GroupIndices(1), m.Present(2))
+	}
+	if rc := m.Exec([]byte("ab"), 0); rc < 0 || string(m.Group(2)) != "b" {
+		t.Error("Group(2) after Exec", rc, m.Group(2))
+	}
+
+	// A failed Exec does not leave the groups of an earlier match.
+	m.MatchString("a", 0)
+	if rc := m.ExecString("xyz", 0); rc != ERROR_NOMATCH || m.Matches() || m.Group(1) != nil {
+		t.Error("stale groups after failed ExecString", rc, m.Group(1))
+	}
+	if m.ExecString("a", PARTIAL_HARD); !m.Partial() {
+		t.Error("Partial after ExecString with PARTIAL_HARD")
+	}
+}
+
+func TestMatchAt(t *testing.T) {
+	re := MustCompile(`\b(\d+)`, 0)
+	defer re.FreeRegexp()
+	m := re.NewMatcher()
+	if !m.MatchStringAt("ab 12 34", 3, 0) || m.GroupString(1) != "12" {
+		t.Error("no match at token start")
+	}
+	if loc := m.Index(); loc[0] != 3 || loc[1] != 5 {
+		t.Error("offsets not relative to subject", loc)
+	}
+	if m.MatchStringAt("ab 12 34", 2, 0) {
+		t.Error("match not anchored at offset")
+	}
+	// \b sees the digit before the offset.
+	if m.MatchAt([]byte("ab 12 34"), 4, 0) {
+		t.Error("lookbehind does not see text before offset")
+	}
+	if m.MatchStringAt("ab", 3, 0) || m.Err() == nil {
+		t.Error("offset out of range accepted")
+	}
+}
@@ -0,0 +1,515 @@
+// Package pcretest runs the test files of the PCRE distribution,
+// testdata/testinputN with their expected testoutputN, against the
+// pcre package, and reports where the results diverge.  It lets
+// programs linked with their own PCRE build check that it behaves
+// like upstream PCRE 8.x:
+//
+//	report, err := pcretest.RunFiles("testdata/testinput1", "testdata/testoutput1")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	for _, d := range report.Divergences {
+//		fmt.Println(d)
+//	}
+//
+// The files use the format of the pcretest program: a pattern between
+// delimiters with modifier letters, followed by indented subject
+// lines, up to a blank line.  The expected output echoes every input
+// line, followed by the results for it.
+//
+// Only part of what pcretest can do is available through the pcre
+// package.  Patterns with modifiers that print compile-time
+// information, or that the package cannot reproduce, such as /g or
+// /I, and subjects with unsupported escapes, such as \C or \D, are
+// skipped and listed in the report.
+package pcretest
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gijsbers/go-pcre"
+)
+
+// Test is a pattern of a test file with its subjects.
+type Test struct {
+	Line      int      // Line of the pattern in the input
+	Pattern   string   // The pattern, without delimiters
+	Modifiers string   // The modifier letters following the pattern
+	Output    []string // Expected compile output, see ParseWithOutput
+	Subjects  []*Subject
+}
+
+// Subject is a subject line of a test.
+type Subject struct {
+	Line   int      // Line in the input
+	Input  string   // The subject as written, without indentation
+	Output []string // Expected match output, see ParseWithOutput
+}
+
+// Parse reads a pcretest input file.
+func Parse(r io.Reader) ([]*Test, error) {
+	lines, err := readLines(r)
+	if err != nil {
+		return nil, err
+	}
+	tests, _, err := parse(lines)
+	return tests, err
+}
+
+// ParseWithOutput reads a pcretest input file and the matching output
+// file, and sets the expected Output of every test and subject.
+func ParseWithOutput(input, output io.Reader) ([]*Test, error) {
+	in, err := readLines(input)
+	if err != nil {
+		return nil, err
+	}
+	out, err := readLines(output)
+	if err != nil {
+		return nil, err
+	}
+	tests, owners, err := parse(in)
+	if err != nil {
+		return nil, err
+	}
+	// Every input line is echoed in the output; the lines between
+	// two echoes are the results for the first of them.
+	j := 0
+	for i, line := range in {
+		start := j
+		for j < len(out) && out[j] != line {
+			j++
+		}
+		if j == len(out) {
+			return nil, fmt.Errorf("pcretest: line %d of the input is missing from the output", i+1)
+		}
+		if i > 0 && owners[i-1] != nil {
+			*owners[i-1] = out[start:j]
+		}
+		j++
+	}
+	if len(in) > 0 && owners[len(in)-1] != nil {
+		*owners[len(in)-1] = out[j:]
+	}
+	return tests, nil
+}
+
+func readLines(r io.Reader) ([]string, error) {
+	var lines []string
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		lines = append(lines, strings.TrimRight(sc.Text(), "\r"))
+	}
+	return lines, sc.Err()
+}
+
+// parse splits input lines into tests.  For each line that ends a
+// pattern or is a subject, owners holds the Output it is followed by.
+func parse(lines []string) (tests []*Test, owners []*[]string, err error) {
+	owners = make([]*[]string, len(lines))
+	var test *Test
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		switch {
+		case line == "":
+			test = nil
+		case test != nil:
+			s := &Subject{Line: i + 1, Input: line}
+			test.Subjects = append(test.Subjects, s)
+			owners[i] = &s.Output
+		case line[0] == '#':
+			// Comment.
+		default:
+			test = &Test{Line: i + 1}
+			delim := line[0]
+			if delim == '\\' || isAlnum(delim) {
+				return nil, nil, fmt.Errorf("pcretest: line %d: invalid pattern delimiter", i+1)
+			}
+			// The pattern may continue over several lines.
+			text := line[1:]
+			for {
+				if end := closing(text, delim); end >= 0 {
+					test.Pattern += text[:end]
+					test.Modifiers = strings.TrimSpace(text[end+1:])
+					break
+				}
+				if i++; i == len(lines) {
+					return nil, nil, fmt.Errorf("pcretest: line %d: unterminated pattern", test.Line)
+				}
+				test.Pattern += text + "\n"
+				text = lines[i]
+			}
+			tests = append(tests, test)
+			owners[i] = &test.Output
+		}
+	}
+	return tests, owners, nil
+}
+
+// closing returns the index of the first unescaped delim in s, or -1.
+func closing(s string, delim byte) int {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case delim:
+			return i
+		}
+	}
+	return -1
+}
+
+func isAlnum(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// Divergence is a test or subject whose results differ from the
+// expected output.
+type Divergence struct {
+	Test    *Test
+	Subject *Subject // nil if the compile results differ
+	Want    []string
+	Got     []string
+}
+
+// String describes the divergence in a few lines.
+func (d *Divergence) String() string {
+	var b strings.Builder
+	if d.Subject == nil {
+		fmt.Fprintf(&b, "line %d: /%s/%s\n", d.Test.Line, d.Test.Pattern, d.Test.Modifiers)
+	} else {
+		fmt.Fprintf(&b, "line %d: /%s/%s on %s\n", d.Subject.Line, d.Test.Pattern, d.Test.Modifiers, d.Subject.Input)
+	}
+	for _, line := range d.Want {
+		b.WriteString("-" + line + "\n")
+	}
+	for _, line := range d.Got {
+		b.WriteString("+" + line + "\n")
+	}
+	return b.String()
+}
+
+// Skip is a test or subject which could not be run.
+type Skip struct {
+	Line   int
+	Reason string
+}
+
+// Report summarizes a test run.
+type Report struct {
+	Tests       int // Patterns run
+	Subjects    int // Subjects run
+	Divergences []*Divergence
+	Skipped     []Skip
+}
+
+// RunFiles reads and runs a pcretest input file and its output file.
+func RunFiles(input, output string) (*Report, error) {
+	in, err := os.Open(input)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	out, err := os.Open(output)
+	if err != nil {
+		return nil, err
+	}
+	defer out.Close()
+	tests, err := ParseWithOutput(in, out)
+	if err != nil {
+		return nil, err
+	}
+	return Run(tests), nil
+}
+
+// Run runs the tests, and compares the results with their Output.
+func Run(tests []*Test) *Report {
+	report := new(Report)
+	for _, test := range tests {
+		report.run(test)
+	}
+	return report
+}
+
+// modifiers maps pcretest pattern modifiers to compile flags.
+var modifiers = map[byte]int{
+	'i': pcre.CASELESS,
+	'm': pcre.MULTILINE,
+	's': pcre.DOTALL,
+	'x': pcre.EXTENDED,
+	'A': pcre.ANCHORED,
+	'E': pcre.DOLLAR_ENDONLY,
+	'f': pcre.FIRSTLINE,
+	'J': pcre.DUPNAMES,
+	'N': pcre.NO_AUTO_CAPTURE,
+	'U': pcre.UNGREEDY,
+	'W': pcre.UCP,
+	'X': pcre.EXTRA,
+	'Y': pcre.NO_START_OPTIMIZE,
+	'8': pcre.UTF8,
+}
+
+// newlines maps the <...> modifiers to compile flags.
+var newlines = map[string]int{
+	"<cr>":      pcre.NEWLINE_CR,
+	"<lf>":      pcre.NEWLINE_LF,
+	"<crlf>":    pcre.NEWLINE_CRLF,
+	"<any>":     pcre.NEWLINE_ANY,
+	"<anycrlf>": pcre.NEWLINE_ANYCRLF,
+}
+
+// compileFlags parses the modifiers of a test, and reports whether
+// the rest of the match should be shown, as with /+.
+func compileFlags(mods string) (flags int, rest bool, err error) {
+	for i := 0; i < len(mods); i++ {
+		c := mods[i]
+		switch {
+		case c == ' ' || c == '\t':
+		case c == '+':
+			rest = true
+		case c == 'S':
+			// Studying, with or without JIT, does not change results.
+			for i+1 < len(mods) && (mods[i+1] == '+' || mods[i+1] == '-') {
+				i++
+			}
+		case c == '<':
+			end := strings.IndexByte(mods[i:], '>')
+			if end < 0 {
+				return 0, false, errors.New("unterminated modifier " + mods[i:])
+			}
+			nl, ok := newlines[strings.ToLower(mods[i:i+end+1])]
+			if !ok {
+				return 0, false, errors.New("unsupported modifier " + mods[i:i+end+1])
+			}
+			flags |= nl
+			i += end
+		case modifiers[c] != 0:
+			flags |= modifiers[c]
+		default:
+			return 0, false, fmt.Errorf("unsupported modifier %c", c)
+		}
+	}
+	return flags, rest, nil
+}
+
+func (report *Report) run(test *Test) {
+	flags, rest, err := compileFlags(test.Modifiers)
+	if err != nil {
+		report.Skipped = append(report.Skipped, Skip{test.Line, err.Error()})
+		return
+	}
+	report.Tests++
+	var got []string
+	re, err := pcre.Compile(test.Pattern, flags)
+	if err != nil {
+		var cerr *pcre.CompileError
+		if !errors.As(err, &cerr) {
+			got = []string{"Failed: " + err.Error()}
+		} else {
+			got = []string{fmt.Sprintf("Failed: %s at offset %d", cerr.Message, cerr.Offset)}
+		}
+	}
+	if !equal(got, test.Output) {
+		report.Divergences = append(report.Divergences, &Divergence{Test: test, Want: test.Output, Got: got})
+	}
+	if err != nil {
+		return
+	}
+	defer re.FreeRegexp()
+	m := re.NewMatcher()
+	utf := flags&pcre.UTF8 != 0
+	for _, s := range test.Subjects {
+		subject, matchFlags, err := decodeSubject(s.Input, utf)
+		if err != nil {
+			report.Skipped = append(report.Skipped, Skip{s.Line, err.Error()})
+			continue
+		}
+		report.Subjects++
+		got := matchOutput(m, subject, matchFlags, utf, rest)
+		if !equal(got, s.Output) {
+			report.Divergences = append(report.Divergences,
+				&Divergence{Test: test, Subject: s, Want: s.Output, Got: got})
+		}
+	}
+}
+
+// matchOutput matches subject, and returns the results as pcretest
+// prints them.
+func matchOutput(m *pcre.Matcher, subject []byte, flags int, utf, rest bool) []string {
+	m.Match(subject, flags)
+	var uerr *pcre.UTF8Error
+	switch err := m.Err(); {
+	case errors.As(err, &uerr):
+		return []string{fmt.Sprintf("Error -10 (bad UTF-8 string) offset=%d reason=%d", uerr.Offset, uerr.Reason)}
+	case err != nil:
+		return []string{"Error: " + err.Error()}
+	case m.Partial():
+		return []string{"Partial match: " + printable(m.Group(0), utf)}
+	case !m.Matches():
+		return []string{"No match"}
+	}
+	// Like pcretest, print groups up to the last one that is set.
+	last := 0
+	for i := 1; i <= m.Groups(); i++ {
+		if m.Present(i) {
+			last = i
+		}
+	}
+	var out []string
+	for i := 0; i <= last; i++ {
+		if !m.Present(i) {
+			out = append(out, fmt.Sprintf("%2d: <unset>", i))
+			continue
+		}
+		out = append(out, fmt.Sprintf("%2d: %s", i, printable(m.Group(i), utf)))
+		if i == 0 && rest {
+			out = append(out, " 0+ "+printable(subject[m.Index()[1]:], utf))
+		}
+	}
+	return out
+}
+
+// printable escapes characters outside printable ASCII as pcretest
+// does.
+func printable(b []byte, utf bool) string {
+	var s strings.Builder
+	for len(b) > 0 {
+		c, n := rune(b[0]), 1
+		if utf && c >= utf8.RuneSelf {
+			if r, size := utf8.DecodeRune(b); r != utf8.RuneError || size > 1 {
+				c, n = r, size
+			}
+		}
+		b = b[n:]
+		switch {
+		case c >= ' ' && c < 0x7f:
+			s.WriteRune(c)
+		case utf:
+			fmt.Fprintf(&s, "\\x{%02x}", c)
+		default:
+			fmt.Fprintf(&s, "\\x%02x", c)
+		}
+	}
+	return s.String()
+}
+
+// decodeSubject interprets the escapes of a subject line, and
+// returns the subject and the match flags it selects.
+func decodeSubject(line string, utf bool) (subject []byte, flags int, err error) {
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if c != '\\' {
+			subject = append(subject, c)
+			continue
+		}
+		if i++; i == len(line) {
+			break // a trailing backslash ends the subject
+		}
+		switch c = line[i]; c {
+		case 'a':
+			subject = append(subject, 7)
+		case 'b':
+			subject = append(subject, '\b')
+		case 'e':
+			subject = append(subject, 27)
+		case 'f':
+			subject = append(subject, '\f')
+		case 'n':
+			subject = append(subject, '\n')
+		case 'r':
+			subject = append(subject, '\r')
+		case 't':
+			subject = append(subject, '\t')
+		case 'v':
+			subject = append(subject, '\v')
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			n := 0
+			j := i
+			for j < len(line) && j < i+3 && '0' <= line[j] && line[j] <= '7' {
+				n = n*8 + int(line[j]-'0')
+				j++
+			}
+			subject = appendChar(subject, n, utf && n > 0xff)
+			i = j - 1
+		case 'x':
+			var digits string
+			braces := strings.HasPrefix(line[i+1:], "{")
+			if braces {
+				end := strings.IndexByte(line[i:], '}')
+				if end < 0 {
+					return nil, 0, errors.New("unterminated \\x{")
+				}
+				digits = line[i+2 : i+end]
+				i += end
+			} else {
+				j := i + 1
+				for j < len(line) && j < i+3 && isHex(line[j]) {
+					j++
+				}
+				digits = line[i+1 : j]
+				i = j - 1
+			}
+			n, _ := strconv.ParseUint("0"+digits, 16, 32)
+			// Only \x{...} is encoded in UTF-8 mode; \xhh is a byte.
+			subject = appendChar(subject, int(n), utf && braces)
+		case 'A':
+			flags |= pcre.ANCHORED
+		case 'B':
+			flags |= pcre.NOTBOL
+		case 'Z':
+			flags |= pcre.NOTEOL
+		case 'N':
+			if flags&pcre.NOTEMPTY != 0 {
+				flags |= pcre.NOTEMPTY_ATSTART
+			}
+			flags |= pcre.NOTEMPTY
+		case 'P':
+			if flags&pcre.PARTIAL_SOFT != 0 {
+				flags = flags&^pcre.PARTIAL_SOFT | pcre.PARTIAL_HARD
+			} else {
+				flags |= pcre.PARTIAL_SOFT
+			}
+		case 'Y':
+			flags |= pcre.NO_START_OPTIMIZE
+		case '?':
+			flags |= pcre.NO_UTF8_CHECK
+		default:
+			if isAlnum(c) || c == '<' || c == '>' || c == '=' || c == '$' {
+				return nil, 0, fmt.Errorf("unsupported subject escape \\%c", c)
+			}
+			subject = append(subject, c)
+		}
+	}
+	return subject, flags, nil
+}
+
+// appendChar appends a character code, UTF-8 encoded if utf is set.
+func appendChar(b []byte, c int, utf bool) []byte {
+	if utf {
+		return utf8.AppendRune(b, rune(c))
+	}
+	return append(b, byte(c))
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,150 @@
+package pcretest
+
+import (
+	"strings"
+	"testing"
+)
+
+const testInput = `/-- A comment pattern --/
+
+/the (quick) (brown )?fox/+
+    the quick fox
+    *** Failers
+    the slow fox
+
+/a(b/
+
+/caf\x{e9}/8i
+    CAF\x{e9}
+    caf\x{e9}\?
+
+/abc/
+    ab\P
+    xabc\B
+    abd
+    abc\C1
+
+/multi
+line/x
+    multiline
+
+/x/g
+    x
+`
+
+const testOutput = `PCRE version 8.45 2021-06-15
+
+/-- A comment pattern --/
+
+/the (quick) (brown )?fox/+
+    the quick fox
+ 0: the quick fox
+ 0+ 
+ 1: quick
+    *** Failers
+No match
+    the slow fox
+No match
+
+/a(b/
+Failed: missing ) at offset 3
+
+/caf\x{e9}/8i
+    CAF\x{e9}
+ 0: CAF\x{e9}
+    caf\x{e9}\?
+ 0: caf\x{e9}
+
+/abc/
+    ab\P
+Partial match: ab
+    xabc\B
+ 0: abc
+    abd
+ 0: abd
+    abc\C1
+ 0: abc
+ 1: <unset>
+
+/multi
+line/x
+    multiline
+ 0: multiline
+
+/x/g
+    x
+ 0: x
+`
+
+func TestParse(t *testing.T) {
+	tests, err := ParseWithOutput(strings.NewReader(testInput), strings.NewReader(testOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tests) != 7 {
+		t.Fatalf("expected 7 tests, got %d", len(tests))
+	}
+	fox := tests[1]
+	if fox.Line != 3 || fox.Pattern != "the (quick) (brown )?fox" || fox.Modifiers != "+" || len(fox.Subjects) != 3 {
+		t.Errorf("unexpected test %+v", fox)
+	}
+	if s := fox.Subjects[0]; s.Line != 4 || s.Input != "the quick fox" || len(s.Output) != 3 || s.Output[2] != " 1: quick" {
+		t.Errorf("unexpected subject %+v", s)
+	}
+	if out := tests[2].Output; len(out) != 1 || out[0] != "Failed: missing ) at offset 3" {
+		t.Errorf("unexpected compile output %q", out)
+	}
+	if multi := tests[5]; multi.Pattern != "multi\nline" || multi.Modifiers != "x" || multi.Subjects[0].Line != 22 {
+		t.Errorf("unexpected multi-line test %+v", multi)
+	}
+
+	if _, err := ParseWithOutput(strings.NewReader(testInput), strings.NewReader("")); err == nil {
+		t.Error("expected error for missing output")
+	}
+	if _, err := Parse(strings.NewReader("/abc\n")); err == nil {
+		t.Error("expected error for unterminated pattern")
+	}
+}
+
+func TestRun(t *testing.T) {
+	tests, err := ParseWithOutput(strings.NewReader(testInput), strings.NewReader(testOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := Run(tests)
+	if report.Tests != 6 || report.Subjects != 9 {
+		t.Errorf("ran %d tests and %d subjects", report.Tests, report.Subjects)
+	}
+	if len(report.Skipped) != 2 || report.Skipped[0].Line != 18 || report.Skipped[1].Line != 24 {
+		t.Errorf("unexpected skips %+v", report.Skipped)
+	}
+	if len(report.Divergences) != 1 {
+		for _, d := range report.Divergences {
+			t.Log(d)
+		}
+		t.Fatalf("expected 1 divergence, got %d", len(report.Divergences))
+	}
+	// The expected output for abd above is wrong on purpose.
+	if d := report.Divergences[0]; d.Subject == nil || d.Subject.Input != "abd" ||
+		d.String() != "line 17: /abc/ on abd\n- 0: abd\n+No match\n" {
+		t.Errorf("unexpected divergence %s", d)
+	}
+}
+
+func TestDecodeSubject(t *testing.T) {
+	for _, test := range []struct {
+		line, want string
+		utf        bool
+	}{
+		{`a\tb\\c`, "a\tb\\c", false},
+		{`\101\x41\x{41}\x`, "AAA\x00", false},
+		{`\x{e9}\xe9`, "é\xe9", true},
+		{`\x{e9}`, "\xe9", false},
+		{`abc\`, "abc", false},
+	} {
+		got, _, err := decodeSubject(test.line, test.utf)
+		if err != nil || string(got) != test.want {
+			t.Errorf("%s: expected %q, got %q, %v", test.line, test.want, got, err)
+		}
+	}
+}
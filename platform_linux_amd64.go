@@ -1,4 +1,4 @@
-//go:build linux && amd64
+//go:build linux && amd64 && !pcre_musl && !pcre_vendor
 
 package pcre
 
@@ -1,4 +1,4 @@
-//go:build linux && arm64
+//go:build linux && arm64 && !pcre_musl && !pcre_vendor
 
 package pcre
 
@@ -0,0 +1,6 @@
+//go:build linux && amd64 && pcre_musl && !pcre_vendor
+
+package pcre
+
+// #cgo LDFLAGS: ${SRCDIR}/libpcre_linux_musl_amd64.a
+import "C"
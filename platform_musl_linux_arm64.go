@@ -0,0 +1,6 @@
+//go:build linux && arm64 && pcre_musl && !pcre_vendor
+
+package pcre
+
+// #cgo LDFLAGS: ${SRCDIR}/libpcre_linux_musl_arm64.a
+import "C"
@@ -0,0 +1,14 @@
+//go:build pcre_vendor
+
+package pcre
+
+// Compile the PCRE sources fetched by vendor_lib.sh as part of the cgo
+// build, with the options used for the static archives.  JIT support
+// is limited to the architectures the archives are built for.
+// NEWLINE=~0 is -1, which selects NEWLINE_ANY; cgo rejects a minus
+// sign in macro values.
+
+// #cgo CFLAGS: -DHAVE_CONFIG_H -DPCRE_STATIC -DSUPPORT_UTF -DNEWLINE=~0
+// #cgo CFLAGS: -DMATCH_LIMIT=500000 -DMATCH_LIMIT_RECURSION=50000
+// #cgo 386 amd64 arm arm64 CFLAGS: -DSUPPORT_JIT
+import "C"
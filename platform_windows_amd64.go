@@ -1,4 +1,4 @@
-//go:build windows && amd64
+//go:build windows && amd64 && !pcre_dll && !pcre_vendor
 
 package pcre
 
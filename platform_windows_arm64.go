@@ -1,4 +1,4 @@
-//go:build windows && arm64
+//go:build windows && arm64 && !pcre_dll && !pcre_vendor
 
 package pcre
 
@@ -0,0 +1,48 @@
+//go:build windows && pcre_dll && !pcre_vendor
+
+package pcre
+
+// #cgo CFLAGS: -DPCRE_STATIC -DPCRE_DLL_SHIM
+// #include <stdlib.h>
+// int pcre_dll_load(const char *path);
+import "C"
+
+import (
+	"os"
+	"strings"
+	"unsafe"
+)
+
+// dllNames are the PCRE DLLs tried, in order, when PCRE_DLL is not set.
+var dllNames = []string{"pcre.dll", "pcre3.dll", "libpcre-1.dll", "libpcre.dll"}
+
+// dllPath is set by a variable initializer, so the DLL is loaded
+// before any init function calls into it.
+var dllPath = loadDLL()
+
+// DLLPath returns the name of the PCRE DLL loaded at startup.  It is
+// only available with the pcre_dll build tag, which loads PCRE from a
+// DLL at run time instead of linking the static archive.  The DLL is
+// located through the standard DLL search order, or named by the
+// PCRE_DLL environment variable.  The pcre16 and pcre32 tags are not
+// supported in this mode.
+func DLLPath() string {
+	return dllPath
+}
+
+func loadDLL() string {
+	names := dllNames
+	if path := os.Getenv("PCRE_DLL"); path != "" {
+		names = []string{path}
+	}
+	for _, name := range names {
+		name1 := C.CString(name)
+		ok := C.pcre_dll_load(name1) != 0
+		C.free(unsafe.Pointer(name1))
+		if ok {
+			return name
+		}
+	}
+	panic("pcre: cannot load " + strings.Join(names, " or ") +
+		"; set PCRE_DLL to the path of the PCRE DLL")
+}
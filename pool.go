@@ -0,0 +1,108 @@
+package pcre
+
+import (
+	"sync"
+	"time"
+)
+
+// matcherPool holds released Matchers of any Regexp.  Init reuses
+// their ovector when it is large enough.
+var matcherPool sync.Pool
+
+// maxPooledScratch is the largest ReplaceAll buffer a pooled Matcher
+// keeps.
+const maxPooledScratch = 64 << 10
+
+// AcquireMatcher returns a Matcher for re from a pool shared by all
+// Regexp objects, instead of allocating one as NewMatcher does.  Call
+// Release when done with it, so that matching in a hot path does not
+// allocate once the pool is warm.
+func (re *Regexp) AcquireMatcher() *Matcher {
+	m, _ := matcherPool.Get().(*Matcher)
+	if m == nil {
+		m = new(Matcher)
+	}
+	m.Init(re)
+	return m
+}
+
+// Release returns a Matcher obtained from AcquireMatcher to the pool.
+// Neither the Matcher nor the slices returned by its Group methods
+// may be used afterwards.
+func (m *Matcher) Release() {
+	if m == nil {
+		return
+	}
+	m.subjects, m.subjectb = "", nil
+	m.matches, m.partial, m.err = false, false, nil
+	m.deadline, m.ctx, m.timedOut = time.Time{}, nil, false
+	if cap(m.scratch) > maxPooledScratch {
+		m.scratch = nil
+	}
+	matcherPool.Put(m)
+}
+
+// AppendIndex appends the start and end of the first match of the
+// pattern in subject to dst, and returns the extended slice, or dst
+// if there is no match.  With a dst of sufficient capacity, it does
+// not allocate.
+func (re *Regexp) AppendIndex(dst []int, subject []byte, flags int) []int {
+	m := re.AcquireMatcher()
+	defer m.Release()
+	if m.Match(subject, flags) {
+		dst = append(dst, int(m.ovector[0]), int(m.ovector[1]))
+	}
+	return dst
+}
+
+// AppendIndexString is equivalent to AppendIndex with a string
+// subject.
+func (re *Regexp) AppendIndexString(dst []int, subject string, flags int) []int {
+	m := re.AcquireMatcher()
+	defer m.Release()
+	if m.MatchString(subject, flags) {
+		dst = append(dst, int(m.ovector[0]), int(m.ovector[1]))
+	}
+	return dst
+}
+
+// AppendAllIndex appends the start and end of each successive match
+// of the pattern in subject to dst, as consecutive pairs, and returns
+// the extended slice.  Matches are found as by FindAllIndex, and n
+// limits their number in the same way.  With a dst of sufficient
+// capacity, it does not allocate.
+func (re *Regexp) AppendAllIndex(dst []int, subject []byte, n, flags int) ([]int, error) {
+	return re.AppendAllIndexString(dst, bytesString(subject), n, flags)
+}
+
+// AppendAllIndexString is equivalent to AppendAllIndex with a string
+// subject.
+func (re *Regexp) AppendAllIndexString(dst []int, subject string, n, flags int) ([]int, error) {
+	if n == 0 {
+		return dst, nil
+	}
+	m := re.AcquireMatcher()
+	defer m.Release()
+	if err := m.Err(); err != nil {
+		return dst, err
+	}
+	err := forEachMatchString(m, subject, 0, flags, func() bool {
+		dst = append(dst, int(m.ovector[0]), int(m.ovector[1]))
+		n--
+		return n != 0
+	})
+	return dst, err
+}
+
+// AppendReplaceAll appends a copy of src with all matches of the
+// pattern replaced by repl to dst, and returns the extended slice.
+// Matches are found as by ReplaceAll.  With a dst of sufficient
+// capacity, it does not allocate.
+func (re *Regexp) AppendReplaceAll(dst, src, repl []byte, flags int) ([]byte, error) {
+	m := re.AcquireMatcher()
+	defer m.Release()
+	if m.err != nil {
+		return append(dst, src...), m.err
+	}
+	return m.appendReplaceAll(dst, src, repl, flags)
+}
@@ -0,0 +1,159 @@
+package pcre
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// raceEnabled is set by race_test.go.
+var raceEnabled bool
+
+func TestAppendHelpers(t *testing.T) {
+	re := MustCompile(`a+`, 0)
+	defer re.FreeRegexp()
+	subject := []byte("xaay a")
+	if got := re.AppendIndex([]int{9}, subject, 0); !reflect.DeepEqual(got, []int{9, 1, 3}) {
+		t.Errorf("AppendIndex: got %v", got)
+	}
+	if got := re.AppendIndexString(nil, "xyz", 0); got != nil {
+		t.Errorf("AppendIndexString without match: got %v", got)
+	}
+	got, err := re.AppendAllIndex(nil, subject, -1, 0)
+	if err != nil || !reflect.DeepEqual(got, []int{1, 3, 5, 6}) {
+		t.Errorf("AppendAllIndex: got %v, %v", got, err)
+	}
+	got, err = re.AppendAllIndexString(got[:0], "aba", 1, 0)
+	if err != nil || !reflect.DeepEqual(got, []int{0, 1}) {
+		t.Errorf("AppendAllIndexString with n: got %v, %v", got, err)
+	}
+	out, err := re.AppendReplaceAll([]byte(">"), subject, []byte("b"), 0)
+	if err != nil || string(out) != ">xby b" {
+		t.Errorf("AppendReplaceAll: got %q, %v", out, err)
+	}
+}
+
+func TestMatcherPool(t *testing.T) {
+	re1 := MustCompile(`(a)(b)(c)(d)`, 0)
+	defer re1.FreeRegexp()
+	re2 := MustCompile(`x`, 0)
+	defer re2.FreeRegexp()
+	m := re1.AcquireMatcher()
+	if !m.MatchString("abcd", 0) || m.GroupString(4) != "d" {
+		t.Fatal("no match")
+	}
+	m.Release()
+	m = re2.AcquireMatcher()
+	defer m.Release()
+	if m.Matches() || m.Groups() != 0 {
+		t.Error("released Matcher kept its state")
+	}
+	if !m.MatchString("x", 0) {
+		t.Error("pooled Matcher does not match")
+	}
+}
+
+func TestHelperAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("sync.Pool does not keep items with the race detector")
+	}
+	re := MustCompile(`\w+@\w+\.com`, 0)
+	defer re.FreeRegexp()
+	subject := []byte("mail bob@example.com or ann@example.com today")
+	loc := make([]int, 0, 8)
+	buf := make([]byte, 0, 2*len(subject))
+	for name, fn := range map[string]func(){
+		"AppendIndex":       func() { loc = re.AppendIndex(loc[:0], subject, 0) },
+		"AppendIndexString": func() { loc = re.AppendIndexString(loc[:0], "to bob@example.com", 0) },
+		"AppendAllIndex":    func() { loc, _ = re.AppendAllIndex(loc[:0], subject, -1, 0) },
+		"AppendReplaceAll":  func() { buf, _ = re.AppendReplaceAll(buf[:0], subject, []byte("x"), 0) },
+		"AcquireMatcher": func() {
+			m := re.AcquireMatcher()
+			m.Match(subject, 0)
+			m.Release()
+		},
+	} {
+		fn() // warm up the pool
+		if n := testing.AllocsPerRun(100, fn); n != 0 {
+			t.Errorf("%s: %v allocations per call, want 0", name, n)
+		}
+	}
+}
+
+var benchSubject = bytes.Repeat([]byte("lorem ipsum dolor sit amet, bob@example.com "), 20)
+
+func BenchmarkFindIndex(b *testing.B) {
+	re := MustCompile(`\w+@\w+\.com`, 0)
+	defer re.FreeRegexp()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		re.FindIndex(benchSubject, 0)
+	}
+}
+
+func BenchmarkAppendIndex(b *testing.B) {
+	re := MustCompile(`\w+@\w+\.com`, 0)
+	defer re.FreeRegexp()
+	loc := make([]int, 0, 2)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		loc = re.AppendIndex(loc[:0], benchSubject, 0)
+	}
+}
+
+func BenchmarkFindAllIndex(b *testing.B) {
+	re := MustCompile(`\w+@\w+\.com`, 0)
+	defer re.FreeRegexp()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		re.FindAllIndex(benchSubject, -1, 0)
+	}
+}
+
+func BenchmarkAppendAllIndex(b *testing.B) {
+	re := MustCompile(`\w+@\w+\.com`, 0)
+	defer re.FreeRegexp()
+	var loc []int
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		loc, _ = re.AppendAllIndex(loc[:0], benchSubject, -1, 0)
+	}
+}
+
+func BenchmarkReplaceAll(b *testing.B) {
+	re := MustCompile(`\w+@\w+\.com`, 0)
+	defer re.FreeRegexp()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		re.ReplaceAll(benchSubject, []byte("<email>"), 0)
+	}
+}
+
+func BenchmarkAppendReplaceAll(b *testing.B) {
+	re := MustCompile(`\w+@\w+\.com`, 0)
+	defer re.FreeRegexp()
+	var buf []byte
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf, _ = re.AppendReplaceAll(buf[:0], benchSubject, []byte("<email>"), 0)
+	}
+}
+
+func BenchmarkMatcher(b *testing.B) {
+	re := MustCompile(`\w+@\w+\.com`, 0)
+	defer re.FreeRegexp()
+	b.Run("New", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			re.NewMatcher().Match(benchSubject, 0)
+		}
+	})
+	b.Run("Pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			m := re.AcquireMatcher()
+			m.Match(benchSubject, 0)
+			m.Release()
+		}
+	})
+}
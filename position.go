@@ -0,0 +1,66 @@
+package pcre
+
+import (
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Position is a location in a subject, for diagnostics.
+type Position struct {
+	Line   int // Line number, starting at 1
+	Column int // Column in characters, starting at 1
+}
+
+// String formats the position as line:column.
+func (p Position) String() string {
+	return strconv.Itoa(p.Line) + ":" + strconv.Itoa(p.Column)
+}
+
+// PositionMapper converts byte offsets in a subject, such as those of
+// Matcher.Index and GroupIndices, to line and column numbers.  Lines
+// end with "\n"; columns count UTF-8 characters, and bytes of invalid
+// UTF-8 as one character each.
+type PositionMapper struct {
+	subject string
+	starts  []int // offsets of the line starts
+}
+
+// NewPositionMapper returns a PositionMapper for subject.
+func NewPositionMapper(subject []byte) *PositionMapper {
+	return NewPositionMapperString(string(subject))
+}
+
+// NewPositionMapperString returns a PositionMapper for subject.
+func NewPositionMapperString(subject string) *PositionMapper {
+	starts := []int{0}
+	for i := 0; i < len(subject); i++ {
+		if subject[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return &PositionMapper{subject: subject, starts: starts}
+}
+
+// Position returns the position of the character at the byte offset.
+// An offset inside a character maps to that character, and offsets
+// past the end to the end of the subject.  A negative offset, as for
+// a group that is not present, returns the zero Position.
+func (pm *PositionMapper) Position(offset int) Position {
+	if offset < 0 {
+		return Position{}
+	}
+	offset = min(offset, len(pm.subject))
+	line := sort.SearchInts(pm.starts, offset+1) - 1
+	start := pm.starts[line]
+	// Count the characters which start before offset.
+	column := 1
+	for i := start; i < offset; {
+		_, size := utf8.DecodeRuneInString(pm.subject[i:])
+		i += size
+		if i <= offset {
+			column++
+		}
+	}
+	return Position{Line: line + 1, Column: column}
+}
@@ -0,0 +1,36 @@
+package pcre
+
+import (
+	"testing"
+)
+
+func TestPositionMapper(t *testing.T) {
+	subject := "ab\nñx\n\nend"
+	pm := NewPositionMapperString(subject)
+	for _, tc := range []struct {
+		offset int
+		want   Position
+	}{
+		{0, Position{1, 1}},
+		{2, Position{1, 3}},
+		{3, Position{2, 1}},
+		{4, Position{2, 1}}, // inside ñ
+		{5, Position{2, 2}},
+		{7, Position{3, 1}},
+		{8, Position{4, 1}},
+		{11, Position{4, 4}},
+		{99, Position{4, 4}},
+		{-1, Position{}},
+	} {
+		if got := pm.Position(tc.offset); got != tc.want {
+			t.Errorf("%d: expected %v, got %v", tc.offset, tc.want, got)
+		}
+	}
+
+	re := MustCompile(`x`, 0)
+	defer re.FreeRegexp()
+	m := re.MatcherString(subject, 0)
+	if got := NewPositionMapper([]byte(subject)).Position(m.Index()[0]).String(); got != "2:2" {
+		t.Error("match position", got)
+	}
+}
@@ -0,0 +1,95 @@
+package pcre
+
+import (
+	"io"
+	"unicode/utf8"
+)
+
+// readerChunk is the number of bytes read from a reader between match
+// attempts.
+const readerChunk = 4096
+
+// FindReaderIndex returns the start and end of the first match of the
+// text read from r, or nil if there is none, like
+// regexp.Regexp.FindReaderIndex.  The offsets count the bytes of the
+// UTF-8 encoding of the runes read.  The reader may be read past the
+// end of the match.
+//
+// Only the text that a match in progress may still need is buffered:
+// matching is attempted with PARTIAL_HARD as text arrives, and text
+// before a partial match, apart from what lookbehind assertions
+// inspect, is discarded.  A read error ends the text, like io.EOF.
+func (re *Regexp) FindReaderIndex(r io.RuneReader, flags int) []int {
+	loc := re.FindReaderSubmatchIndex(r, flags)
+	if loc == nil {
+		return nil
+	}
+	return loc[:2]
+}
+
+// FindReaderSubmatchIndex returns the offsets of the first match of
+// the text read from r and of its capture groups, as pairs of start
+// and end, like regexp.Regexp.FindReaderSubmatchIndex.  Groups that
+// are not present have offsets -1.  See FindReaderIndex.
+func (re *Regexp) FindReaderSubmatchIndex(r io.RuneReader, flags int) []int {
+	if !re.valid() {
+		uninitialized("Regexp.FindReaderSubmatchIndex")
+		return nil
+	}
+	utf := re.pcreOptions()&UTF8 != 0
+	lookbehind := max(re.maxLookbehind(), 1)
+	m := re.NewMatcher()
+	var buf []byte
+	base := 0  // stream offset of buf[0]
+	start := 0 // offset in buf where matching resumes
+	eof := false
+	for {
+		for n := len(buf); !eof && len(buf)-n < readerChunk; {
+			c, _, err := r.ReadRune()
+			if err != nil {
+				eof = true
+				break
+			}
+			buf = utf8.AppendRune(buf, c)
+		}
+		f := flags
+		if !eof {
+			f |= PARTIAL_HARD
+		}
+		if base > 0 {
+			f |= NOTBOL
+		}
+		rc := m.execOffset(buf, start, f)
+		switch {
+		case rc >= 0:
+			loc := make([]int, 2*(1+m.groups))
+			for i := range loc {
+				loc[i] = int(m.ovector[i])
+				if loc[i] >= 0 {
+					loc[i] += base
+				}
+			}
+			return loc
+		case rc == ERROR_PARTIAL:
+			start = int(m.ovector[0])
+		case rc == ERROR_NOMATCH && !eof:
+			start = len(buf)
+		default:
+			return nil
+		}
+		// Keep the characters which lookbehind assertions and ^ may
+		// inspect before the next match attempt.
+		keep := start
+		for i := 0; i < lookbehind && keep > 0; i++ {
+			if utf {
+				_, n := utf8.DecodeLastRune(buf[:keep])
+				keep -= n
+			} else {
+				keep--
+			}
+		}
+		base += keep
+		start -= keep
+		buf = append(buf[:0], buf[keep:]...)
+	}
+}
@@ -0,0 +1,46 @@
+package pcre
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindReaderIndex(t *testing.T) {
+	long := strings.Repeat("x", 3*readerChunk)
+	for _, tc := range []struct {
+		pattern string
+		flags   int
+		subject string
+		want    []int
+	}{
+		{`b+`, 0, "aabbbc", []int{2, 5}},
+		{`b+`, 0, "aaa", nil},
+		{`x+y`, 0, "a" + long + "y", []int{1, 2 + len(long)}},
+		{`(?<=x)y`, 0, long + "y", []int{len(long), len(long) + 1}},
+		{`(?<=axxx)y|(?<=xxx)y`, 0, long + "y", []int{len(long), len(long) + 1}},
+		{`^y`, 0, long + "y", nil},
+		{`^y`, MULTILINE, long + "\ny", []int{len(long) + 1, len(long) + 2}},
+		{`\bz\b`, 0, long + " zz z", []int{len(long) + 4, len(long) + 5}},
+		{`é+`, UTF8, "aéé", []int{1, 5}},
+	} {
+		re := MustCompile(tc.pattern, tc.flags)
+		got := re.FindReaderIndex(strings.NewReader(tc.subject), 0)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: expected %v, got %v", tc.pattern, tc.want, got)
+		}
+		re.FreeRegexp()
+	}
+}
+
+func TestFindReaderSubmatchIndex(t *testing.T) {
+	re := MustCompile(`(\d+)(x)?-(\d+)`, 0)
+	defer re.FreeRegexp()
+	subject := strings.Repeat(".", 2*readerChunk) + "12-345."
+	got := re.FindReaderSubmatchIndex(strings.NewReader(subject), 0)
+	n := 2 * readerChunk
+	want := []int{n, n + 6, n, n + 2, -1, -1, n + 3, n + 6}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
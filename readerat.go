@@ -0,0 +1,180 @@
+package pcre
+
+import (
+	"io"
+	"unicode/utf8"
+)
+
+// readerAtWindow is the number of bytes FindReaderAtFunc holds in
+// memory, unless a match in progress needs more.
+const readerAtWindow = 1 << 20
+
+// FindReaderAtFunc calls fn with the offsets of each successive
+// non-overlapping match of the pattern in the first size bytes of r,
+// and of its capture groups, as pairs of start and end, with -1 for
+// groups that are not present.  Offsets are from the start of r, so
+// files of any size can be searched.  Matches are found as by
+// FindAllIndex; fn returns false to stop.  The slice passed to fn is
+// reused by the next call.
+//
+// The text is read through a window of about a megabyte, which slides
+// forward as matching proceeds, keeping the characters that
+// lookbehind assertions may inspect.  Matching is attempted with
+// PARTIAL_HARD until the end is reached, so a match which extends past
+// the window is completed with the following text; the window grows
+// as such a match requires.  Patterns compiled with UTF8 need valid
+// UTF-8 throughout, and report a *UTF8Error at its offset in r
+// otherwise; use ENCODING_BYTES for binary files.  A read error ends
+// the search and is returned.
+func (re *Regexp) FindReaderAtFunc(r io.ReaderAt, size int64, flags int, fn func(loc []int64) bool) error {
+	return re.findReaderAt(r, size, flags, readerAtWindow, fn)
+}
+
+// FindAllReaderAtIndex returns the start and end offsets of the first
+// n matches of the pattern in the first size bytes of r, or of all
+// matches if n < 0.  See FindReaderAtFunc.
+func (re *Regexp) FindAllReaderAtIndex(r io.ReaderAt, size int64, n, flags int) ([][]int64, error) {
+	var all [][]int64
+	if n == 0 {
+		return all, nil
+	}
+	err := re.FindReaderAtFunc(r, size, flags, func(loc []int64) bool {
+		all = append(all, []int64{loc[0], loc[1]})
+		n--
+		return n != 0
+	})
+	return all, err
+}
+
+// findReaderAt is FindReaderAtFunc with a window of the given size.
+func (re *Regexp) findReaderAt(r io.ReaderAt, size int64, flags, window int, fn func(loc []int64) bool) error {
+	if !re.valid() {
+		return uninitialized("Regexp.FindReaderAtFunc")
+	}
+	if err := checkMatchFlags("Regexp.FindReaderAtFunc", flags); err != nil {
+		return err
+	}
+	utf := re.pcreOptions()&UTF8 != 0
+	lookbehind := max(re.maxLookbehind(), 1)
+	m := re.AcquireMatcher()
+	defer m.Release()
+	buf := make([]byte, 0, window)
+	var base int64    // offset in r of buf[0]
+	start := 0        // offset in buf where matching resumes
+	prev := int64(-1) // offset in r of the end of the last match
+	var loc []int64
+	checked := false // buf is known to be valid UTF-8
+	for {
+		if remaining := size - base - int64(len(buf)); remaining > 0 && len(buf) < cap(buf) {
+			want := len(buf) + int(min(int64(cap(buf)-len(buf)), remaining))
+			n, err := r.ReadAt(buf[len(buf):want], base+int64(len(buf)))
+			buf = buf[:len(buf)+n]
+			if err != nil && (err != io.EOF || len(buf) < want) {
+				if err != io.EOF {
+					return err
+				}
+				size = base + int64(len(buf))
+			}
+			checked = false
+		}
+		eof := base+int64(len(buf)) >= size
+		end := len(buf)
+		f := flags
+		if !eof {
+			f |= PARTIAL_HARD
+			if utf {
+				end -= partialRune(buf)
+			}
+		}
+		if base > 0 {
+			f |= NOTBOL
+		}
+		if utf && !checked {
+			checked = utf8.Valid(buf[:end])
+		}
+		if checked {
+			f |= NO_UTF8_CHECK
+		}
+		rc := m.execOffset(buf[:end], start, f)
+		switch {
+		case rc >= 0:
+			from, to := int(m.ovector[0]), int(m.ovector[1])
+			if from < to || base+int64(from) != prev {
+				loc = loc[:0]
+				for _, off := range m.ovector[:2*(1+m.groups)] {
+					if off >= 0 {
+						loc = append(loc, base+int64(off))
+					} else {
+						loc = append(loc, -1)
+					}
+				}
+				if !fn(loc) {
+					return nil
+				}
+			}
+			prev, start = base+int64(to), to
+			if from < to {
+				continue
+			}
+			if to < end {
+				// Continue one character past an empty match.
+				n := 1
+				if utf {
+					_, n = utf8.DecodeRune(buf[to:end])
+				}
+				start += n
+				continue
+			}
+			if eof {
+				return nil
+			}
+			// An empty match at the end of the window: continue
+			// past it once more text has been read.
+		case rc == ERROR_PARTIAL:
+			start = int(m.ovector[0])
+		case rc == ERROR_NOMATCH && !eof:
+			start = end
+		case rc == ERROR_NOMATCH:
+			return nil
+		default:
+			_, err := m.matched(rc)
+			if e, ok := err.(*UTF8Error); ok {
+				e.Offset += int(base)
+			}
+			return err
+		}
+		// Keep the characters which lookbehind assertions and ^ may
+		// inspect before the next match attempt.
+		keep := start
+		for i := 0; i < lookbehind && keep > 0; i++ {
+			if utf {
+				_, n := utf8.DecodeLastRune(buf[:keep])
+				keep -= n
+			} else {
+				keep--
+			}
+		}
+		if keep == 0 && len(buf) == cap(buf) {
+			// The match in progress fills the window.
+			buf = append(make([]byte, 0, 2*cap(buf)), buf...)
+			continue
+		}
+		base += int64(keep)
+		start -= keep
+		buf = buf[:copy(buf, buf[keep:])]
+	}
+}
+
+// partialRune returns the number of bytes at the end of buf which
+// begin a UTF-8 character without completing it.
+func partialRune(buf []byte) int {
+	for i := len(buf) - 1; i >= 0 && i >= len(buf)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(buf[i]) {
+			if utf8.FullRune(buf[i:]) {
+				return 0
+			}
+			return len(buf) - i
+		}
+	}
+	return 0
+}
@@ -0,0 +1,74 @@
+package pcre
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindReaderAt(t *testing.T) {
+	subject := strings.Repeat("xx aab word caad é€é wordy aaaaaaaaaaaaaaaaaaaaab ", 7)
+	for _, test := range []struct {
+		pattern string
+		flags   int
+	}{
+		{`a+b`, 0},
+		{`x*`, 0},
+		{`(?<=c)a+d`, 0},
+		{`\bword\b`, 0},
+		{`(?m)^xx`, 0},
+		{`é+`, UTF8},
+		{`[^ ]*`, UTF8},
+		{`a{10,}b`, 0},
+	} {
+		re := MustCompile(test.pattern, test.flags)
+		want := re.FindAllIndex([]byte(subject), -1, 0)
+		// Windows shorter than some matches and characters split
+		// between windows.
+		for _, window := range []int{4, 5, 13, readerAtWindow} {
+			var got [][]int
+			err := re.findReaderAt(strings.NewReader(subject), int64(len(subject)), 0, window, func(loc []int64) bool {
+				got = append(got, []int{int(loc[0]), int(loc[1])})
+				return true
+			})
+			if err != nil || !reflect.DeepEqual(got, want) {
+				t.Errorf("%s, window %d: %v\ngot  %v\nwant %v", test.pattern, window, err, got, want)
+			}
+		}
+		re.FreeRegexp()
+	}
+}
+
+func TestFindAllReaderAtIndex(t *testing.T) {
+	re := MustCompile(`\d+`, 0)
+	defer re.FreeRegexp()
+	r := strings.NewReader("a1b22c333")
+	got, err := re.FindAllReaderAtIndex(r, r.Size()+100, 2, 0)
+	if want := [][]int64{{1, 2}, {3, 5}}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v, %v", want, got, err)
+	}
+	got, err = re.FindAllReaderAtIndex(r, 5, -1, 0)
+	if want := [][]int64{{1, 2}, {3, 5}}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("limited size: expected %v, got %v, %v", want, got, err)
+	}
+
+	utf := MustCompile(`b`, UTF8)
+	defer utf.FreeRegexp()
+	r = strings.NewReader(strings.Repeat("a", 20) + "\xff")
+	var ue *UTF8Error
+	err = utf.findReaderAt(r, r.Size(), 0, 8, func([]int64) bool { return true })
+	if !errors.As(err, &ue) || ue.Offset != 20 {
+		t.Errorf("expected a UTF8Error at 20, got %v", err)
+	}
+
+	readErr := errors.New("read failed")
+	err = re.FindReaderAtFunc(failingReaderAt{readErr}, 10, 0, func([]int64) bool { return true })
+	if err != readErr {
+		t.Errorf("expected the read error, got %v", err)
+	}
+}
+
+type failingReaderAt struct{ err error }
+
+func (r failingReaderAt) ReadAt([]byte, int64) (int, error) { return 0, r.err }
@@ -0,0 +1,93 @@
+package pcre
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// Record is a multi-line record found by a RecordScanner.
+type Record struct {
+	Text       []byte // The lines of the record, with their line endings
+	Start, End int64  // Byte range of the record in the stream
+}
+
+// RecordScanner splits a stream into records which begin at the lines
+// matching a pattern, like awk with a regular expression as record
+// separator, for reading stack traces or multi-line log events.
+// Lines before the first matching line form a record of their own.
+//
+// Use it like bufio.Scanner: call Scan until it returns false, then
+// check Err.
+type RecordScanner struct {
+	r       *bufio.Reader
+	m       *Matcher
+	pending []byte // first line of the next record
+	offset  int64  // stream offset of the end of the lines read
+	rec     Record
+	err     error
+	done    bool
+}
+
+// NewRecordScanner returns a RecordScanner reading from r, which
+// starts a record at each line that start matches.  Lines are matched
+// without their line endings.
+func NewRecordScanner(r io.Reader, start *Regexp) *RecordScanner {
+	return &RecordScanner{r: bufio.NewReader(r), m: start.NewMatcher()}
+}
+
+// Scan advances to the next record, which is then available through
+// Record.  It returns false at the end of the stream or on an error.
+func (s *RecordScanner) Scan() bool {
+	if s.done {
+		return false
+	}
+	text := s.pending
+	s.pending = nil
+	start := s.offset - int64(len(text))
+	for {
+		line, err := s.r.ReadBytes('\n')
+		if len(line) > 0 {
+			s.offset += int64(len(line))
+			if s.m.Match(trimLineEnding(line), 0) && len(text) > 0 {
+				s.pending = line
+				break
+			}
+			if err := s.m.Err(); err != nil {
+				s.err, s.done = err, true
+				return false
+			}
+			text = append(text, line...)
+		}
+		if err != nil {
+			if err != io.EOF {
+				s.err = err
+			}
+			s.done = true
+			if len(text) == 0 || s.err != nil {
+				return false
+			}
+			break
+		}
+	}
+	s.rec = Record{Text: text, Start: start, End: start + int64(len(text))}
+	return true
+}
+
+// Record returns the record found by the last call to Scan.  Its Text
+// is not reused by later calls.
+func (s *RecordScanner) Record() Record {
+	return s.rec
+}
+
+// Err returns the first error other than io.EOF encountered while
+// reading or matching.
+func (s *RecordScanner) Err() error {
+	return s.err
+}
+
+// trimLineEnding removes a trailing "\n" or "\r\n".
+func trimLineEnding(line []byte) []byte {
+	line = bytes.TrimSuffix(line, []byte("\n"))
+	return bytes.TrimSuffix(line, []byte("\r"))
+}
@@ -0,0 +1,51 @@
+package pcre
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRecordScanner(t *testing.T) {
+	input := "preamble\n" +
+		"2024-01-01 ERROR boom\n" +
+		"\tat main.go:1\n" +
+		"\tat main.go:2\n" +
+		"2024-01-01 INFO ok\r\n" +
+		"2024-01-02 ERROR again"
+	re := MustCompile(`^\d{4}-\d\d-\d\d `, 0)
+	defer re.FreeRegexp()
+	s := NewRecordScanner(strings.NewReader(input), re)
+	want := []string{
+		"preamble\n",
+		"2024-01-01 ERROR boom\n\tat main.go:1\n\tat main.go:2\n",
+		"2024-01-01 INFO ok\r\n",
+		"2024-01-02 ERROR again",
+	}
+	var offset int64
+	for i := 0; s.Scan(); i++ {
+		r := s.Record()
+		if i >= len(want) {
+			t.Fatalf("unexpected record %q", r.Text)
+		}
+		if string(r.Text) != want[i] {
+			t.Errorf("%d: expected %q, got %q", i, want[i], r.Text)
+		}
+		if r.Start != offset || r.End != offset+int64(len(want[i])) {
+			t.Errorf("%d: unexpected range %d-%d", i, r.Start, r.End)
+		}
+		if input[r.Start:r.End] != string(r.Text) {
+			t.Errorf("%d: range does not match text", i)
+		}
+		offset = r.End
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if offset != int64(len(input)) {
+		t.Errorf("records end at %d", offset)
+	}
+
+	if s := NewRecordScanner(strings.NewReader(""), re); s.Scan() {
+		t.Error("unexpected record in empty input")
+	}
+}
@@ -0,0 +1,163 @@
+package pcre
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LiveRegexp describes a Regexp which has been compiled and not freed,
+// as returned by LiveRegexps.
+type LiveRegexp struct {
+	Pattern string
+	Size    int       // Bytes of C memory, see Regexp.Size
+	Created time.Time // When it was compiled
+	Stack   string    // Stack trace of the call to Compile
+}
+
+var (
+	trackingOn atomic.Bool
+	registryMu sync.Mutex
+	registry   = make(map[uint64]*liveEntry)
+	lastID     uint64
+)
+
+type liveEntry struct {
+	pattern string
+	size    int
+	created time.Time
+	pcs     []uintptr
+}
+
+// TrackRegexps turns the registry of live Regexps on or off.  While it
+// is on, every Regexp compiled is recorded with its size and the stack
+// trace of its compilation, until it is freed, explicitly or by the
+// finalizer.  Use it to find code that compiles patterns over and over
+// and does not free them.  Turning it off forgets the recorded Regexps.
+func TrackRegexps(on bool) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	trackingOn.Store(on)
+	if !on {
+		registry = make(map[uint64]*liveEntry)
+	}
+}
+
+// track records a newly compiled Regexp if tracking is on.
+func (re *Regexp) track() {
+	if !trackingOn.Load() {
+		return
+	}
+	pcs := make([]uintptr, 32)
+	// Skip runtime.Callers, track, compile and Compile.
+	pcs = pcs[:runtime.Callers(4, pcs)]
+	entry := &liveEntry{
+		pattern: re.pattern,
+		size:    int(pcreSize(re.ptr)),
+		created: time.Now(),
+		pcs:     pcs,
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if trackingOn.Load() {
+		lastID++
+		re.trackID = lastID
+		registry[re.trackID] = entry
+	}
+}
+
+// retrack updates the size of a tracked Regexp after studying it.
+// The caller holds re.mu.
+func (re *Regexp) retrack() {
+	if re.trackID == 0 {
+		return
+	}
+	size := re.sizeLocked()
+	registryMu.Lock()
+	if e := registry[re.trackID]; e != nil {
+		e.size = size
+	}
+	registryMu.Unlock()
+}
+
+// untrack removes a freed Regexp from the registry.
+func untrack(id uint64) {
+	if id == 0 {
+		return
+	}
+	registryMu.Lock()
+	delete(registry, id)
+	registryMu.Unlock()
+}
+
+// LiveRegexps returns the Regexps recorded since TrackRegexps was
+// turned on which have not been freed, oldest first.
+func LiveRegexps() []LiveRegexp {
+	registryMu.Lock()
+	entries := make([]*liveEntry, 0, len(registry))
+	for _, e := range registry {
+		entries = append(entries, e)
+	}
+	registryMu.Unlock()
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].created.Before(entries[j].created)
+	})
+	live := make([]LiveRegexp, len(entries))
+	for i, e := range entries {
+		live[i] = LiveRegexp{e.pattern, e.size, e.created, formatStack(e.pcs)}
+	}
+	return live
+}
+
+// DumpLiveRegexps writes a report of the live Regexps to w, grouped by
+// the stack trace of their compilation, largest groups first.  A site
+// compiling many Regexps that stay live is likely a leak.
+func DumpLiveRegexps(w io.Writer) error {
+	type site struct {
+		stack         string
+		count, size   int
+		first, latest string
+	}
+	sites := make(map[string]*site)
+	var order []*site
+	for _, r := range LiveRegexps() {
+		s := sites[r.Stack]
+		if s == nil {
+			s = &site{stack: r.Stack, first: r.Pattern}
+			sites[r.Stack] = s
+			order = append(order, s)
+		}
+		s.count++
+		s.size += r.Size
+		s.latest = r.Pattern
+	}
+	sort.SliceStable(order, func(i, j int) bool { return order[i].count > order[j].count })
+	for _, s := range order {
+		_, err := fmt.Fprintf(w, "%d live, %d bytes, first %q, latest %q\n%s\n",
+			s.count, s.size, s.first, s.latest, s.stack)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatStack formats program counters as a stack trace, one
+// indented function and position per line.
+func formatStack(pcs []uintptr) string {
+	var b strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for {
+		f, more := frames.Next()
+		fmt.Fprintf(&b, "\t%s\n\t\t%s:%d\n", f.Function, f.File, f.Line)
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
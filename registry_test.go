@@ -0,0 +1,37 @@
+package pcre
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTrackRegexps(t *testing.T) {
+	untracked := MustCompile("untracked", 0)
+	defer untracked.FreeRegexp()
+	TrackRegexps(true)
+	defer TrackRegexps(false)
+	a := MustCompile("a+", 0)
+	b := MustCompile("b+", 0)
+	defer b.FreeRegexp()
+	live := LiveRegexps()
+	if len(live) != 2 || live[0].Pattern != "a+" || live[1].Pattern != "b+" {
+		t.Fatalf("unexpected live regexps %+v", live)
+	}
+	if live[0].Size <= 0 || live[0].Created.IsZero() {
+		t.Errorf("unexpected size or time %+v", live[0])
+	}
+	if !strings.Contains(live[0].Stack, "TestTrackRegexps") {
+		t.Errorf("stack does not show the caller:\n%s", live[0].Stack)
+	}
+	a.FreeRegexp()
+	if live := LiveRegexps(); len(live) != 1 || live[0].Pattern != "b+" {
+		t.Errorf("freed regexp still live %+v", live)
+	}
+	var sb strings.Builder
+	if err := DumpLiveRegexps(&sb); err != nil {
+		t.Fatal(err)
+	}
+	if out := sb.String(); !strings.HasPrefix(out, `1 live, `) || !strings.Contains(out, `"b+"`) {
+		t.Errorf("unexpected dump:\n%s", out)
+	}
+}
@@ -0,0 +1,106 @@
+package pcre
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ReloadableRegexp holds the Regexp compiled from a pattern source,
+// such as a file, which may change while the program runs.  Reload
+// reads the source again and, if the pattern has changed, compiles it
+// and replaces the Regexp; Watch does so periodically.
+//
+// Callers get the current Regexp with Regexp each time they match.  A
+// replaced Regexp is not freed explicitly, since Matchers of it may
+// still be in use; it is freed by the garbage collector once they are
+// gone.
+type ReloadableRegexp struct {
+	load    func() (string, error)
+	flags   int
+	re      atomic.Pointer[Regexp]
+	mu      sync.Mutex // serializes reloads
+	pattern string     // pattern of the current Regexp
+}
+
+// NewReloadable returns a ReloadableRegexp compiling the pattern
+// returned by load with the given flags.  It fails if the first
+// pattern cannot be loaded or compiled.
+func NewReloadable(load func() (string, error), flags int) (*ReloadableRegexp, error) {
+	r := &ReloadableRegexp{load: load, flags: flags}
+	pattern, err := load()
+	if err != nil {
+		return nil, fmt.Errorf("pcre.NewReloadable: %w", err)
+	}
+	re, err := Compile(pattern, flags)
+	if err != nil {
+		return nil, fmt.Errorf("pcre.NewReloadable: %w", err)
+	}
+	r.re.Store(re)
+	r.pattern = pattern
+	return r, nil
+}
+
+// NewReloadableFile returns a ReloadableRegexp whose pattern is the
+// content of the named file, without a final line ending.
+func NewReloadableFile(name string, flags int) (*ReloadableRegexp, error) {
+	return NewReloadable(func() (string, error) {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSuffix(strings.TrimSuffix(string(data), "\n"), "\r"), nil
+	}, flags)
+}
+
+// Regexp returns the current Regexp.
+func (r *ReloadableRegexp) Regexp() *Regexp {
+	return r.re.Load()
+}
+
+// Reload loads the pattern again and reports whether it has changed.
+// A changed pattern is compiled before the current Regexp is replaced,
+// so matches never see a missing Regexp.  If the pattern cannot be
+// loaded or compiled, the current Regexp stays in place and the error
+// is returned.
+func (r *ReloadableRegexp) Reload() (changed bool, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	pattern, err := r.load()
+	if err != nil {
+		return false, fmt.Errorf("ReloadableRegexp.Reload: %w", err)
+	}
+	if pattern == r.pattern {
+		return false, nil
+	}
+	re, err := Compile(pattern, r.flags)
+	if err != nil {
+		return false, fmt.Errorf("ReloadableRegexp.Reload: %w", err)
+	}
+	r.re.Store(re)
+	r.pattern = pattern
+	logEvent(slog.LevelInfo, "pcre reload", pattern)
+	return true, nil
+}
+
+// Watch calls Reload every interval until ctx is done.  Errors are
+// passed to onError, if not nil, and do not stop the watch.
+func (r *ReloadableRegexp) Watch(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := r.Reload(); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
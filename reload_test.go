@@ -0,0 +1,54 @@
+package pcre
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReloadableFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "pattern")
+	write := func(pattern string) {
+		if err := os.WriteFile(name, []byte(pattern), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("foo+\n")
+	r, err := NewReloadableFile(name, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := r.Regexp()
+	m := old.NewMatcher()
+	if !m.MatchString("fooo", 0) {
+		t.Error("initial pattern does not match")
+	}
+	if changed, err := r.Reload(); changed || err != nil {
+		t.Error("unchanged pattern reloaded", changed, err)
+	}
+	write("bar(")
+	if changed, err := r.Reload(); changed || !IsSyntax(err) || r.Regexp() != old {
+		t.Error("invalid pattern replaced the Regexp", changed, err)
+	}
+	write("bar")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.Watch(ctx, time.Millisecond, nil)
+		close(done)
+	}()
+	for i := 0; i < 1000 && r.Regexp() == old; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+	if !r.Regexp().MatcherString("bar", 0).Matches() {
+		t.Error("watched pattern not reloaded")
+	}
+	// Matchers of the replaced Regexp keep working.
+	if !m.MatchString("foo", 0) {
+		t.Error("old matcher fails after reload")
+	}
+}
@@ -0,0 +1,141 @@
+package pcre
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+// ReplaceNth returns a copy of subject with only the nth match of the
+// pattern replaced by repl, counting from 1.  Matches are found as by
+// FindAllNamed.  If there are fewer than n matches, or n < 1, subject
+// is returned unchanged.
+func (re *Regexp) ReplaceNth(subject, repl string, n, flags int) (string, error) {
+	if !re.valid() {
+		return "", uninitialized("Regexp.ReplaceNth")
+	}
+	if n < 1 {
+		return subject, nil
+	}
+	i := 0
+	return re.replaceString(subject, repl, 0, flags, func(m *Matcher) (replace, more bool) {
+		i++
+		return i == n, i < n
+	})
+}
+
+// Replace returns a copy of subject with the matches of the pattern
+// for which keep returns true replaced by repl.  Matches are found as
+// by FindAllNamed; the subject is searched past a match even if it
+// is not replaced.
+func (re *Regexp) Replace(subject, repl string, flags int, keep func(MatchResult) bool) (string, error) {
+	if !re.valid() {
+		return "", uninitialized("Regexp.Replace")
+	}
+	return re.replaceString(subject, repl, 0, flags, func(m *Matcher) (replace, more bool) {
+		r, _ := m.Result()
+		return keep(r), true
+	})
+}
+
+// ReplaceWithin returns a copy of subject with the matches of the
+// pattern inside the byte range [start, end) replaced by repl.
+// Matching starts at start with the text before it available to
+// lookbehind assertions, so ^ and \b keep their meaning relative to
+// the whole subject.  Matches cannot extend past end, where $ does not
+// match unless end is the end of subject; \z and lookahead assertions
+// see end as the end of the text.
+func (re *Regexp) ReplaceWithin(subject, repl string, start, end, flags int) (string, error) {
+	if !re.valid() {
+		return "", uninitialized("Regexp.ReplaceWithin")
+	}
+	if start < 0 || start > end || end > len(subject) {
+		return "", errors.New("Regexp.ReplaceWithin: range out of bounds")
+	}
+	if end < len(subject) {
+		flags |= NOTEOL
+	}
+	out, err := re.replaceString(subject[:end], repl, start, flags, func(*Matcher) (replace, more bool) {
+		return true, true
+	})
+	if err != nil {
+		return "", err
+	}
+	return out + subject[end:], nil
+}
+
+// replaceString replaces the matches in subject from offset start for
+// which pick returns true with repl, until it returns false for more.
+func (re *Regexp) replaceString(subject, repl string, start, flags int, pick func(m *Matcher) (replace, more bool)) (string, error) {
+	var b strings.Builder
+	last := 0 // end of the text copied to b
+	m := re.AcquireMatcher()
+	defer m.Release()
+	err := forEachMatchString(m, subject, start, flags, func() bool {
+		replace, more := pick(m)
+		if replace {
+			start, end := int(m.ovector[0]), int(m.ovector[1])
+			b.WriteString(subject[last:start])
+			b.WriteString(repl)
+			last = end
+		}
+		return more
+	})
+	if err != nil {
+		return "", err
+	}
+	if last == 0 && b.Len() == 0 {
+		return subject, nil
+	}
+	b.WriteString(subject[last:])
+	return b.String(), nil
+}
+
+// ReplaceAll is like Regexp.ReplaceAll, but builds the output in a
+// buffer of m that the next call reuses, so that replacing in many
+// subjects does not allocate once the buffer has grown.  The result
+// is valid until the next call of ReplaceAll or Release.
+func (m *Matcher) ReplaceAll(subject, repl []byte, flags int) ([]byte, error) {
+	if m == nil || m.re == nil || !m.re.valid() {
+		return nil, uninitialized("Matcher.ReplaceAll")
+	}
+	if err := checkMatchFlags("Matcher.ReplaceAll", flags); err != nil {
+		return nil, err
+	}
+	out, err := m.appendReplaceAll(m.scratch[:0], subject, repl, flags)
+	m.scratch = out
+	return out, err
+}
+
+// appendReplaceAll appends src with the matches replaced by repl to
+// dst.
+func (m *Matcher) appendReplaceAll(dst, src, repl []byte, flags int) ([]byte, error) {
+	s := bytesString(src)
+	last := 0
+	err := forEachMatchString(m, s, 0, flags, func() bool {
+		from, end := int(m.ovector[0]), int(m.ovector[1])
+		dst = append(append(dst, src[last:from]...), repl...)
+		last = end
+		return true
+	})
+	m.subjects = ""
+	return append(dst, src[last:]...), err
+}
+
+// replaceCap returns the capacity to allocate for the output of
+// ReplaceAll on a subject of n bytes: n scaled by the ratio of output
+// to input size of the last call, or n plus an eighth at first.
+func (re *Regexp) replaceCap(n int) int {
+	ratio := int64(re.replaceRatio.Load())
+	if ratio == 0 {
+		return n + n/8
+	}
+	return int(min(int64(n)*ratio/1024+64, math.MaxInt32))
+}
+
+// noteReplace records the output size of ReplaceAll for replaceCap.
+func (re *Regexp) noteReplace(in, out int) {
+	if in > 0 {
+		re.replaceRatio.Store(uint32(max(min(int64(out)*1024/int64(in), math.MaxUint32), 1)))
+	}
+}
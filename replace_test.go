@@ -0,0 +1,128 @@
+package pcre
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReplaceNth(t *testing.T) {
+	re := MustCompile(`\d+`, 0)
+	defer re.FreeRegexp()
+	for _, test := range []struct {
+		n    int
+		want string
+	}{
+		{1, "a # b 22 c 333"},
+		{2, "a 1 b # c 333"},
+		{3, "a 1 b 22 c #"},
+		{4, "a 1 b 22 c 333"},
+		{0, "a 1 b 22 c 333"},
+	} {
+		got, err := re.ReplaceNth("a 1 b 22 c 333", "#", test.n, 0)
+		if got != test.want || err != nil {
+			t.Errorf("n=%d: expected %q, got %q, %v", test.n, test.want, got, err)
+		}
+	}
+	empty := MustCompile(`x*`, 0)
+	defer empty.FreeRegexp()
+	if got, _ := empty.ReplaceNth("xab", "-", 2, 0); got != "xa-b" {
+		t.Errorf("empty match: got %q", got)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	re := MustCompile(`(?<=\s|^)(\w+)`, 0)
+	defer re.FreeRegexp()
+	got, err := re.Replace("keep drop Keep dropped", "-", 0, func(r MatchResult) bool {
+		return strings.HasPrefix(r.GroupString(1), "drop")
+	})
+	if want := "keep - Keep -"; got != want || err != nil {
+		t.Errorf("expected %q, got %q, %v", want, got, err)
+	}
+	got, _ = re.Replace("a b", "-", 0, func(MatchResult) bool { return false })
+	if got != "a b" {
+		t.Errorf("nothing replaced: got %q", got)
+	}
+}
+
+func TestReplaceWithin(t *testing.T) {
+	for _, test := range []struct {
+		pattern    string
+		start, end int
+		want       string
+	}{
+		{`a`, 2, 6, "aa--- aa"},
+		{`^a`, 2, 6, "aaaaa aa"},
+		{`^a|a$`, 0, 8, "-aaaa a-"},
+		{`a$`, 0, 6, "aaaaa aa"},
+		{`(?<=a)a`, 1, 3, "a--aa aa"},
+		{`\ba+`, 3, 8, "aaaaa -"},
+		{`a+`, 3, 4, "aaa-a aa"},
+		{`a`, 4, 4, "aaaaa aa"},
+	} {
+		re := MustCompile(test.pattern, 0)
+		got, err := re.ReplaceWithin("aaaaa aa", "-", test.start, test.end, 0)
+		if got != test.want || err != nil {
+			t.Errorf("%s in [%d,%d): expected %q, got %q, %v", test.pattern, test.start, test.end, test.want, got, err)
+		}
+		re.FreeRegexp()
+	}
+	re := MustCompile(`a`, 0)
+	defer re.FreeRegexp()
+	if _, err := re.ReplaceWithin("abc", "-", 2, 1, 0); err == nil {
+		t.Error("expected error for invalid range")
+	}
+	if _, err := re.ReplaceWithin("abc", "-", 0, 4, 0); err == nil {
+		t.Error("expected error for range past the end")
+	}
+}
+
+func TestReplaceAllOffsets(t *testing.T) {
+	for _, test := range []struct {
+		pattern, subject, repl, want string
+	}{
+		{`x*`, "abc", "-", "-a-b-c-"},
+		{`a*`, "baaac", "-", "-b-c-"},
+		{`(?<=a)b`, "abab", "x", "axax"},
+		{`^a`, "aaa", "b", "baa"},
+	} {
+		re := MustCompile(test.pattern, 0)
+		got, err := re.ReplaceAll([]byte(test.subject), []byte(test.repl), 0)
+		if string(got) != test.want || err != nil {
+			t.Errorf("%s on %q: expected %q, got %q, %v", test.pattern, test.subject, test.want, got, err)
+		}
+		re.FreeRegexp()
+	}
+}
+
+func TestMatcherReplaceAll(t *testing.T) {
+	re := MustCompile(`\d+`, 0)
+	defer re.FreeRegexp()
+	m := re.NewMatcher()
+	got, err := m.ReplaceAll([]byte("a1b22c333"), []byte("#"), 0)
+	if string(got) != "a#b#c#" || err != nil {
+		t.Fatalf("got %q, %v", got, err)
+	}
+	first := &got[0]
+	got, _ = m.ReplaceAll([]byte("x9"), []byte("#"), 0)
+	if string(got) != "x#" || &got[0] != first {
+		t.Errorf("buffer not reused: got %q", got)
+	}
+	if n := testing.AllocsPerRun(10, func() {
+		m.ReplaceAll([]byte("7 and 8"), []byte("#"), 0)
+	}); n != 0 {
+		t.Errorf("%v allocations per call, want 0", n)
+	}
+}
+
+func TestReplaceCap(t *testing.T) {
+	re := MustCompile(`a`, 0)
+	defer re.FreeRegexp()
+	if got := re.replaceCap(800); got != 900 {
+		t.Errorf("initial capacity: got %d", got)
+	}
+	out, _ := re.ReplaceAll([]byte("aaaa"), []byte("bbbbbb"), 0)
+	if got := re.replaceCap(100); got < 6*100 {
+		t.Errorf("capacity after growth to %d bytes: got %d", len(out), got)
+	}
+}
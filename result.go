@@ -0,0 +1,164 @@
+package pcre
+
+import (
+	"errors"
+	"strconv"
+)
+
+// MatchResult holds a copy of a successful match.  Unlike the
+// Matcher it came from, it stays valid when the Matcher is reused,
+// so it can be passed to handlers or kept.
+type MatchResult struct {
+	Regexp  *Regexp
+	Subject string
+	loc     []int // start and end of each group, or -1 if not present
+}
+
+// Result returns a MatchResult holding the last match, and false if
+// the last match failed.
+func (m *Matcher) Result() (MatchResult, bool) {
+	if !m.Matches() {
+		return MatchResult{}, false
+	}
+	r := MatchResult{Regexp: m.re, loc: make([]int, 2*(1+m.groups))}
+	if m.subjectb != nil {
+		r.Subject = string(m.subjectb)
+	} else {
+		r.Subject = m.subjects
+	}
+	for i := 0; i <= m.groups; i++ {
+		r.loc[2*i], r.loc[2*i+1] = m.span(i)
+	}
+	return r, true
+}
+
+// Groups returns the number of capture groups of the pattern.
+func (r MatchResult) Groups() int {
+	return len(r.loc)/2 - 1
+}
+
+func (r MatchResult) span(group int) (start, end int) {
+	if group < 0 || 2*group+1 >= len(r.loc) {
+		return -1, -1
+	}
+	return r.loc[2*group], r.loc[2*group+1]
+}
+
+// Present reports whether the numbered capture group took part in
+// the match.
+func (r MatchResult) Present(group int) bool {
+	start, _ := r.span(group)
+	return start >= 0
+}
+
+// GroupString returns the numbered capture group, or "" if it is not
+// present or out of range.  Group 0 is the whole match.
+func (r MatchResult) GroupString(group int) string {
+	if start, end := r.span(group); start >= 0 {
+		return r.Subject[start:end]
+	}
+	return ""
+}
+
+// GroupIndices returns the start and end of the numbered capture
+// group, or nil if it is not present or out of range.
+func (r MatchResult) GroupIndices(group int) []int {
+	if start, end := r.span(group); start >= 0 {
+		return []int{start, end}
+	}
+	return nil
+}
+
+// Index returns the start and end of the whole match.
+func (r MatchResult) Index() []int {
+	return r.GroupIndices(0)
+}
+
+// NamedString returns the named capture group, and whether the
+// pattern has a group of that name which took part in the match.
+func (r MatchResult) NamedString(name string) (string, bool) {
+	if n := r.namedGroup(name); n >= 0 {
+		return r.GroupString(n), true
+	}
+	return "", false
+}
+
+// ErrGroupNotPresent is wrapped by the errors of the typed group
+// accessors of MatchResult for groups which did not take part in the
+// match, or do not exist.
+var ErrGroupNotPresent = errors.New("not present")
+
+// Int returns the numbered capture group converted to an int.  It
+// fails with a *ScanError.
+func (r MatchResult) Int(group int) (int, error) {
+	s, err := r.typedGroup(group, "")
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, &ScanError{Group: group, Err: err}
+	}
+	return n, nil
+}
+
+// Float returns the numbered capture group converted to a float64.
+// It fails with a *ScanError.
+func (r MatchResult) Float(group int) (float64, error) {
+	s, err := r.typedGroup(group, "")
+	if err != nil {
+		return 0, err
+	}
+	x, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, &ScanError{Group: group, Err: err}
+	}
+	return x, nil
+}
+
+// NamedInt is like Int for the named capture group.
+func (r MatchResult) NamedInt(name string) (int, error) {
+	s, err := r.typedGroup(r.namedGroup(name), name)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, &ScanError{Group: r.namedGroup(name), Name: name, Err: err}
+	}
+	return n, nil
+}
+
+// NamedFloat is like Float for the named capture group.
+func (r MatchResult) NamedFloat(name string) (float64, error) {
+	s, err := r.typedGroup(r.namedGroup(name), name)
+	if err != nil {
+		return 0, err
+	}
+	x, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, &ScanError{Group: r.namedGroup(name), Name: name, Err: err}
+	}
+	return x, nil
+}
+
+// namedGroup returns the number of the present group of the given
+// name, see Regexp.groupOf, or -1.
+func (r MatchResult) namedGroup(name string) int {
+	if r.Regexp == nil {
+		return -1
+	}
+	if n := r.Regexp.groupOf(name, r.Present); r.Present(n) {
+		return n
+	}
+	return -1
+}
+
+// typedGroup returns the text of a group to convert, or a *ScanError
+// wrapping ErrGroupNotPresent.
+func (r MatchResult) typedGroup(group int, name string) (string, error) {
+	if !r.Present(group) {
+		return "", &ScanError{Group: max(group, 0), Name: name, Err: ErrGroupNotPresent}
+	}
+	return r.GroupString(group), nil
+}
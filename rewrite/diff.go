@@ -0,0 +1,150 @@
+package rewrite
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// diffContext is the number of unchanged lines around the changes of
+// a hunk.
+const diffContext = 3
+
+// diffOp is a line of an edit script: kept (' '), deleted ('-') or
+// inserted ('+').
+type diffOp struct {
+	kind byte
+	line []byte
+}
+
+// writeDiff writes a unified diff from old to new, as the file name.
+func writeDiff(w io.Writer, name string, old, new []byte) error {
+	ops := diffLines(splitLines(old), splitLines(new))
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "--- a/%s\n+++ b/%s\n", name, name)
+	// oldLine and newLine count the lines before each op.
+	oldLine, newLine := make([]int, len(ops)+1), make([]int, len(ops)+1)
+	for i, op := range ops {
+		oldLine[i+1], newLine[i+1] = oldLine[i], newLine[i]
+		if op.kind != '+' {
+			oldLine[i+1]++
+		}
+		if op.kind != '-' {
+			newLine[i+1]++
+		}
+	}
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		// Extend the hunk over changes separated by at most twice
+		// the context.
+		start, end := max(0, i-diffContext), i
+		for j := i; j < len(ops) && j <= end+2*diffContext; j++ {
+			if ops[j].kind != ' ' {
+				end = j
+			}
+		}
+		end = min(len(ops), end+1+diffContext)
+		fmt.Fprintf(bw, "@@ -%s +%s @@\n",
+			hunkRange(oldLine[start], oldLine[end]), hunkRange(newLine[start], newLine[end]))
+		for _, op := range ops[start:end] {
+			bw.WriteByte(op.kind)
+			bw.Write(op.line)
+			if !bytes.HasSuffix(op.line, []byte("\n")) {
+				bw.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		i = end
+	}
+	return bw.Flush()
+}
+
+// hunkRange formats the lines [from, to) of a hunk header.
+func hunkRange(from, to int) string {
+	if from == to {
+		return fmt.Sprintf("%d,0", from)
+	}
+	return fmt.Sprintf("%d,%d", from+1, to-from)
+}
+
+// splitLines splits b after each newline.
+func splitLines(b []byte) [][]byte {
+	lines := bytes.SplitAfter(b, []byte("\n"))
+	if len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns a shortest edit script from a to b, computed with
+// Myers' algorithm after stripping common lines at both ends.
+func diffLines(a, b [][]byte) []diffOp {
+	var prefix, suffix []diffOp
+	for len(a) > 0 && len(b) > 0 && bytes.Equal(a[0], b[0]) {
+		prefix = append(prefix, diffOp{' ', a[0]})
+		a, b = a[1:], b[1:]
+	}
+	for len(a) > 0 && len(b) > 0 && bytes.Equal(a[len(a)-1], b[len(b)-1]) {
+		suffix = append(suffix, diffOp{' ', a[len(a)-1]})
+		a, b = a[:len(a)-1], b[:len(b)-1]
+	}
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1) // furthest x on each diagonal k = x-y
+	var trace [][]int
+search:
+	for d := 0; d <= n+m; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || k != d && v[offset+k-1] < v[offset+k+1] {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && bytes.Equal(a[x], b[y]) {
+				x, y = x+1, y+1
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+	// Walk back from the end, collecting the script in reverse.
+	var ops []diffOp
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		prevK := k - 1
+		if k == -d || k != d && v[offset+k-1] < v[offset+k+1] {
+			prevK = k + 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x, y = x-1, y-1
+			ops = append(ops, diffOp{' ', a[x]})
+		}
+		if d > 0 {
+			if x == prevX {
+				ops = append(ops, diffOp{'+', b[y-1]})
+			} else {
+				ops = append(ops, diffOp{'-', a[x-1]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	for i := len(suffix) - 1; i >= 0; i-- {
+		ops = append(ops, suffix[i])
+	}
+	return append(prefix, ops...)
+}
@@ -0,0 +1,197 @@
+// Package rewrite applies search-and-replace edits to the files of a
+// directory tree, such as a pcre.Substitution:
+//
+//	s, err := pcre.ParseSubstitution(`s/\bOldName\b/NewName/g`)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	rw := rewrite.Substitute(s)
+//	rw.Include = []string{"*.go"}
+//	rw.Exclude = []string{".git", "vendor"}
+//	rw.Diff = os.Stdout
+//	result, err := rw.Dir(".")
+//
+// Binary files, recognized by a NUL byte near the start as git does,
+// are left alone, as are symbolic links and other files which are not
+// regular.  Edited files are replaced atomically, by renaming a
+// temporary file written next to them, and keep their permissions;
+// files the edit does not change are not written.
+package rewrite
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/gijsbers/go-pcre"
+)
+
+// binaryPrefix is the length of the start of a file searched for a
+// NUL byte to detect binary files.
+const binaryPrefix = 8000
+
+// Rewriter edits the files of a tree.  Its fields must not change
+// while it is in use.
+type Rewriter struct {
+	// Edit returns the new content of a file.  An error skips the
+	// file, and is reported with its path.
+	Edit func(content []byte) ([]byte, error)
+
+	Include []string  // Globs for file names to edit; all files if empty
+	Exclude []string  // Globs for file and directory names to skip
+	DryRun  bool      // Report changes without writing them
+	Diff    io.Writer // If set, receives a unified diff of each change
+	MaxSize int64     // Skip files larger than this, if positive
+}
+
+// Result lists the files a Rewriter changed or skipped.
+type Result struct {
+	Changed  []string // Files whose content the edit changed
+	Binary   []string // Files skipped as binary
+	TooLarge []string // Files skipped for exceeding MaxSize
+}
+
+// New returns a Rewriter for the edit function.
+func New(edit func(content []byte) ([]byte, error)) *Rewriter {
+	return &Rewriter{Edit: edit}
+}
+
+// Substitute returns a Rewriter applying a substitution command, with
+// the semantics of Substitution.Apply: without the g flag, only the
+// first match in each file is replaced.
+func Substitute(s *pcre.Substitution) *Rewriter {
+	return New(s.Apply)
+}
+
+// Dir edits the files below the directory root.  The paths in the
+// result start with root.  Errors for single files do not stop the
+// walk; they are joined into the returned error.
+func (rw *Rewriter) Dir(root string) (*Result, error) {
+	fsys := os.DirFS(root)
+	return rw.walk(fsys, ".", func(name string) string {
+		return filepath.Join(root, filepath.FromSlash(name))
+	})
+}
+
+// FS reports the edits of the files of fsys below root, as Dir does
+// with DryRun set: an fs.FS cannot be written.  The paths in the
+// result are those in fsys.
+func (rw *Rewriter) FS(fsys fs.FS, root string) (*Result, error) {
+	dry := *rw
+	dry.DryRun = true
+	return dry.walk(fsys, root, nil)
+}
+
+// walk edits the files of fsys below root.  Files are written to the
+// path that osPath maps their name to; without osPath, nothing is
+// written.
+func (rw *Rewriter) walk(fsys fs.FS, root string, osPath func(string) string) (*Result, error) {
+	if rw.Edit == nil {
+		return nil, errors.New("rewrite: no Edit function")
+	}
+	result := &Result{}
+	var errs []error
+	name := func(p string) string {
+		if osPath != nil {
+			return osPath(p)
+		}
+		return p
+	}
+	err := fs.WalkDir(fsys, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+		if p != root && matchAny(rw.Exclude, d.Name()) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || len(rw.Include) > 0 && !matchAny(rw.Include, d.Name()) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+		if rw.MaxSize > 0 && info.Size() > rw.MaxSize {
+			result.TooLarge = append(result.TooLarge, name(p))
+			return nil
+		}
+		old, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+		if bytes.IndexByte(old[:min(len(old), binaryPrefix)], 0) >= 0 {
+			result.Binary = append(result.Binary, name(p))
+			return nil
+		}
+		edited, err := rw.Edit(old)
+		if err != nil {
+			errs = append(errs, &fs.PathError{Op: "edit", Path: name(p), Err: err})
+			return nil
+		}
+		if bytes.Equal(old, edited) {
+			return nil
+		}
+		if rw.Diff != nil {
+			if err := writeDiff(rw.Diff, path.Clean(p), old, edited); err != nil {
+				return err
+			}
+		}
+		if !rw.DryRun && osPath != nil {
+			if err := writeFile(name(p), edited, info.Mode().Perm()); err != nil {
+				errs = append(errs, err)
+				return nil
+			}
+		}
+		result.Changed = append(result.Changed, name(p))
+		return nil
+	})
+	return result, errors.Join(append(errs, err)...)
+}
+
+// matchAny reports whether name matches one of the globs.
+func matchAny(globs []string, name string) bool {
+	for _, glob := range globs {
+		if ok, _ := path.Match(glob, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// writeFile replaces the file name with data, by writing a temporary
+// file in the same directory and renaming it, so that readers see
+// either the old or the new content.
+func writeFile(name string, data []byte, perm fs.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".rewrite*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Chmod(perm)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, name)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
@@ -0,0 +1,180 @@
+package rewrite
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gijsbers/go-pcre"
+)
+
+func replacer(old, new string) *Rewriter {
+	return New(func(content []byte) ([]byte, error) {
+		return bytes.ReplaceAll(content, []byte(old), []byte(new)), nil
+	})
+}
+
+func writeTree(t *testing.T, files map[string]string) string {
+	root := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o640); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestDir(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.go":         "foo bar\n",
+		"b.txt":        "foo\n",
+		"same.go":      "nothing here\n",
+		"bin.go":       "foo\x00\n",
+		"big.go":       strings.Repeat("foo ", 100),
+		"vendor/v.go":  "foo\n",
+		"sub/c.go":     "x\nfoo\n",
+		"sub/.hide.go": "foo\n",
+	})
+	rw := replacer("foo", "baz")
+	rw.Include = []string{"*.go"}
+	rw.Exclude = []string{"vendor", ".*"}
+	rw.MaxSize = 100
+	result, err := rw.Dir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Result{
+		Changed:  []string{filepath.Join(root, "a.go"), filepath.Join(root, "sub", "c.go")},
+		Binary:   []string{filepath.Join(root, "bin.go")},
+		TooLarge: []string{filepath.Join(root, "big.go")},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("got %+v, want %+v", result, want)
+	}
+	for name, content := range map[string]string{
+		"a.go":         "baz bar\n",
+		"b.txt":        "foo\n",
+		"vendor/v.go":  "foo\n",
+		"sub/c.go":     "x\nbaz\n",
+		"sub/.hide.go": "foo\n",
+	} {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		data, _ := os.ReadFile(p)
+		if string(data) != content {
+			t.Errorf("%s: got %q, want %q", name, data, content)
+		}
+	}
+	info, err := os.Stat(filepath.Join(root, "a.go"))
+	if err != nil || info.Mode().Perm() != 0o640 {
+		t.Errorf("permissions not kept: %v, %v", info.Mode(), err)
+	}
+	entries, _ := os.ReadDir(root)
+	for _, e := range entries {
+		if strings.Contains(e.Name(), ".rewrite") {
+			t.Error("temporary file left behind:", e.Name())
+		}
+	}
+}
+
+func TestDryRunDiff(t *testing.T) {
+	fsys := fstest.MapFS{
+		"dir/a.txt": {Data: []byte("1\n2\n3\n4\nfoo\n6\n7\n8\n9\n10\n11\n12\nfoo")},
+	}
+	var diff strings.Builder
+	rw := replacer("foo", "bar\nbaz")
+	rw.Diff = &diff
+	result, err := rw.FS(fsys, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Changed, []string{"dir/a.txt"}) {
+		t.Errorf("changed: %v", result.Changed)
+	}
+	want := `--- a/dir/a.txt
++++ b/dir/a.txt
+@@ -2,7 +2,8 @@
+ 2
+ 3
+ 4
+-foo
++bar
++baz
+ 6
+ 7
+ 8
+@@ -10,4 +11,5 @@
+ 10
+ 11
+ 12
+-foo
+\ No newline at end of file
++bar
++baz
+\ No newline at end of file
+`
+	if diff.String() != want {
+		t.Errorf("got diff\n%s\nwant\n%s", diff.String(), want)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	for _, test := range []struct{ a, b, want string }{
+		{"", "", ""},
+		{"a\n", "", "-a\n"},
+		{"", "a\n", "+a\n"},
+		{"a\nb\nc\n", "a\nc\n", " a\n-b\n c\n"},
+		{"a\nb\nc\n", "c\nb\na\n", "-a\n-b\n c\n+b\n+a\n"},
+	} {
+		var b strings.Builder
+		for _, op := range diffLines(splitLines([]byte(test.a)), splitLines([]byte(test.b))) {
+			b.WriteByte(op.kind)
+			b.Write(op.line)
+		}
+		if b.String() != test.want {
+			t.Errorf("%q -> %q: got %q, want %q", test.a, test.b, b.String(), test.want)
+		}
+	}
+}
+
+func TestEditError(t *testing.T) {
+	root := writeTree(t, map[string]string{"a": "x", "b": "y"})
+	fail := errors.New("fail")
+	rw := New(func(content []byte) ([]byte, error) {
+		if string(content) == "x" {
+			return nil, fail
+		}
+		return []byte("z"), nil
+	})
+	result, err := rw.Dir(root)
+	if !errors.Is(err, fail) || !strings.Contains(err.Error(), filepath.Join(root, "a")) {
+		t.Errorf("expected error for a, got %v", err)
+	}
+	if len(result.Changed) != 1 {
+		t.Errorf("other files not edited: %v", result.Changed)
+	}
+}
+
+func TestSubstitute(t *testing.T) {
+	s, err := pcre.ParseSubstitution(`s/(\w+)@example\.com/$1@example.org/g`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Regexp.FreeRegexp()
+	root := writeTree(t, map[string]string{"users": "ann@example.com, bob@example.com\n"})
+	if _, err := Substitute(s).Dir(root); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(filepath.Join(root, "users"))
+	if string(data) != "ann@example.org, bob@example.org\n" {
+		t.Errorf("got %q", data)
+	}
+}
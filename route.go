@@ -0,0 +1,127 @@
+package pcre
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Route is a compiled route template, as returned by CompileRoute.
+type Route struct {
+	Template string
+	Regexp   *Regexp  // The anchored pattern
+	Params   []string // Parameter names, in template order
+}
+
+// CompileRoute compiles a route template such as
+// "/users/{id:\d+}/posts/{slug}" into an anchored pattern with a named
+// group per parameter.  The text outside braces matches literally.  A
+// parameter {name} matches one or more characters other than /, and
+// {name:pattern} matches the pattern, which may contain balanced
+// braces, as in \d{4}.  A final {name...} matches the rest of the
+// subject, including slashes, and may be empty.  Parameter names are
+// Go identifiers, and may not repeat.
+//
+// The flags apply to the whole pattern; with CASELESS, the literal
+// text matches in any case.
+func CompileRoute(template string, flags int) (*Route, error) {
+	fail := func(msg string) (*Route, error) {
+		return nil, errors.New("pcre.CompileRoute: " + msg + " in " + strconv.Quote(template))
+	}
+	r := &Route{Template: template}
+	var b strings.Builder
+	b.WriteString(`\A`)
+	seen := make(map[string]bool)
+	for rest := template; rest != ""; {
+		open := strings.IndexByte(rest, '{')
+		if open < 0 {
+			b.WriteString(QuoteMeta(rest))
+			break
+		}
+		b.WriteString(QuoteMeta(rest[:open]))
+		end := closingBrace(rest, open)
+		if end < 0 {
+			return fail("unclosed {")
+		}
+		param := rest[open+1 : end]
+		rest = rest[end+1:]
+		name, pattern, custom := strings.Cut(param, ":")
+		wildcard := false
+		if !custom {
+			name, wildcard = strings.CutSuffix(name, "...")
+			pattern = `[^/]+`
+			if wildcard {
+				if rest != "" {
+					return fail("{" + param + "} not at the end")
+				}
+				pattern = `(?s:.*)`
+			}
+		}
+		if !isIdentifier(name) {
+			return fail("invalid parameter name " + strconv.Quote(name))
+		}
+		if seen[name] {
+			return fail("duplicate parameter " + name)
+		}
+		if pattern == "" {
+			return fail("empty pattern for " + name)
+		}
+		seen[name] = true
+		r.Params = append(r.Params, name)
+		b.WriteString("(?<" + name + ">" + pattern + ")")
+	}
+	b.WriteString(`\z`)
+	re, err := Compile(b.String(), flags)
+	if err != nil {
+		return nil, err
+	}
+	r.Regexp = re
+	return r, nil
+}
+
+// closingBrace returns the index of the brace closing the one at
+// s[open], skipping escaped characters, or -1.
+func closingBrace(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// Match matches path against the route, and returns the values of
+// the parameters, and whether it matched.
+func (r *Route) Match(path string) (map[string]string, bool) {
+	m := r.Regexp.AcquireMatcher()
+	defer m.Release()
+	if !m.MatchString(path, 0) {
+		return nil, false
+	}
+	params := make(map[string]string, len(r.Params))
+	for _, name := range r.Params {
+		params[name], _ = m.NamedString(name)
+	}
+	return params, true
+}
+
+// Unmarshal matches path against the route, and stores the
+// parameters in the fields of the struct pointed to by dst, converted
+// to their types, as Unmarshal does with the pcre tags naming the
+// parameters.  If path does not match, it returns ErrNoMatch.
+func (r *Route) Unmarshal(path string, dst interface{}) error {
+	return Unmarshal(r.Regexp, path, dst)
+}
+
+// Close frees the compiled pattern.
+func (r *Route) Close() {
+	r.Regexp.FreeRegexp()
+}
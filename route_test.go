@@ -0,0 +1,78 @@
+package pcre
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRoute(t *testing.T) {
+	r, err := CompileRoute(`/users/{id:\d+}/posts/{slug}.{year:\d{4}}`, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if want := []string{"id", "slug", "year"}; !reflect.DeepEqual(r.Params, want) {
+		t.Errorf("Params: got %v, want %v", r.Params, want)
+	}
+	params, ok := r.Match("/users/42/posts/hello-world.2024")
+	want := map[string]string{"id": "42", "slug": "hello-world", "year": "2024"}
+	if !ok || !reflect.DeepEqual(params, want) {
+		t.Errorf("Match: got %v, %v", params, ok)
+	}
+	for _, path := range []string{
+		"/users/x/posts/a.2024",
+		"/users/1/posts/a/b.2024",
+		"/users/1/posts/a.2024/",
+		"/users/1/posts/.2024",
+		"/users/1/postsXa.2024",
+	} {
+		if _, ok := r.Match(path); ok {
+			t.Errorf("%q matched", path)
+		}
+	}
+
+	var dst struct {
+		ID   int    `pcre:"id"`
+		Slug string `pcre:"slug"`
+	}
+	if err := r.Unmarshal("/users/7/posts/x.1999", &dst); err != nil || dst.ID != 7 || dst.Slug != "x" {
+		t.Errorf("Unmarshal: got %+v, %v", dst, err)
+	}
+	if err := r.Unmarshal("/other", &dst); !errors.Is(err, ErrNoMatch) {
+		t.Errorf("Unmarshal without match: got %v", err)
+	}
+}
+
+func TestRouteWildcard(t *testing.T) {
+	r, err := CompileRoute("/static/{path...}", CASELESS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	for path, want := range map[string]string{
+		"/static/css/site.css": "css/site.css",
+		"/STATIC/":             "",
+	} {
+		if params, ok := r.Match(path); !ok || params["path"] != want {
+			t.Errorf("%q: got %v, %v", path, params, ok)
+		}
+	}
+}
+
+func TestRouteErrors(t *testing.T) {
+	for template, want := range map[string]string{
+		"/a/{id":          "unclosed {",
+		"/a/{1x}":         "invalid parameter name",
+		"/{a}/{a}":        "duplicate parameter a",
+		"/{rest...}/more": "not at the end",
+		"/{a:}":           "empty pattern",
+		"/{a:(}":          "missing",
+	} {
+		_, err := CompileRoute(template, 0)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: expected %q, got %v", template, want, err)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package pcre
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+// RuneIndex is like Index, but returns the offsets of the match in
+// characters rather than bytes, for user interfaces which index text
+// by character.  Offsets are meant for patterns compiled with UTF8;
+// bytes of invalid UTF-8 count as one character each.  For patterns
+// compiled with ENCODING_BYTES, every byte is a character.
+func (m *Matcher) RuneIndex() []int {
+	return m.GroupRuneIndices(0)
+}
+
+// GroupRuneIndices is like GroupIndices, but returns character
+// offsets.  See RuneIndex.
+func (m *Matcher) GroupRuneIndices(group int) []int {
+	start, end := m.span(group)
+	if start < 0 {
+		return nil
+	}
+	return m.runeOffsets([]int{start, end})
+}
+
+// SubmatchRuneIndex returns the character offsets of the match and of
+// every capture group, as pairs of start and end, with -1 for groups
+// which are not present, or nil if the last match failed.  See
+// RuneIndex.
+func (m *Matcher) SubmatchRuneIndex() []int {
+	if !m.Matches() {
+		return nil
+	}
+	loc := make([]int, 2*(1+m.groups))
+	for i := 0; i <= m.groups; i++ {
+		loc[2*i], loc[2*i+1] = m.span(i)
+	}
+	return m.runeOffsets(loc)
+}
+
+// runeOffsets converts byte offsets in the subject to character
+// offsets in place, counting each stretch of the subject once.
+func (m *Matcher) runeOffsets(loc []int) []int {
+	if m.re.Encoding() == ENCODING_BYTES {
+		return loc
+	}
+	if m.subjectb != nil {
+		return byteToRuneOffsets(loc, func(i, j int) int {
+			return utf8.RuneCount(m.subjectb[i:j])
+		})
+	}
+	return byteToRuneOffsets(loc, func(i, j int) int {
+		return utf8.RuneCountInString(m.subjects[i:j])
+	})
+}
+
+// GroupRuneIndices is like GroupIndices, but returns character
+// offsets.  See Matcher.RuneIndex.
+func (r MatchResult) GroupRuneIndices(group int) []int {
+	start, end := r.span(group)
+	if start < 0 {
+		return nil
+	}
+	if r.Regexp.Encoding() == ENCODING_BYTES {
+		return []int{start, end}
+	}
+	return byteToRuneOffsets([]int{start, end}, func(i, j int) int {
+		return utf8.RuneCountInString(r.Subject[i:j])
+	})
+}
+
+// byteToRuneOffsets replaces the byte offsets in loc, ignoring
+// negative ones, by the number of characters before them, as counted
+// by count.
+func byteToRuneOffsets(loc []int, count func(i, j int) int) []int {
+	order := make([]int, 0, len(loc))
+	for i, off := range loc {
+		if off >= 0 {
+			order = append(order, i)
+		}
+	}
+	sort.Slice(order, func(a, b int) bool { return loc[order[a]] < loc[order[b]] })
+	prev, runes := 0, 0
+	for _, i := range order {
+		off := loc[i]
+		runes += count(prev, off)
+		prev = off
+		loc[i] = runes
+	}
+	return loc
+}
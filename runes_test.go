@@ -0,0 +1,39 @@
+package pcre
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRuneIndex(t *testing.T) {
+	re := MustCompile(`(ü+)(x)?(.)`, UTF8)
+	defer re.FreeRegexp()
+	subject := "aé üüb"
+	m := re.MatcherString(subject, 0)
+	if got := m.Index(); !reflect.DeepEqual(got, []int{4, 9}) {
+		t.Fatal("byte offsets", got)
+	}
+	if got := m.RuneIndex(); !reflect.DeepEqual(got, []int{3, 6}) {
+		t.Error("RuneIndex", got)
+	}
+	if got := m.GroupRuneIndices(1); !reflect.DeepEqual(got, []int{3, 5}) {
+		t.Error("GroupRuneIndices", got)
+	}
+	if got := m.GroupRuneIndices(2); got != nil {
+		t.Error("absent group", got)
+	}
+	if got := m.SubmatchRuneIndex(); !reflect.DeepEqual(got, []int{3, 6, 3, 5, -1, -1, 5, 6}) {
+		t.Error("SubmatchRuneIndex", got)
+	}
+	m.Match([]byte(subject), 0)
+	if got := m.SubmatchRuneIndex(); !reflect.DeepEqual(got, []int{3, 6, 3, 5, -1, -1, 5, 6}) {
+		t.Error("SubmatchRuneIndex on bytes", got)
+	}
+	r, _ := m.Result()
+	if got := r.GroupRuneIndices(3); !reflect.DeepEqual(got, []int{5, 6}) {
+		t.Error("MatchResult.GroupRuneIndices", got)
+	}
+	if m.MatchString("none", 0) || m.RuneIndex() != nil || m.SubmatchRuneIndex() != nil {
+		t.Error("failed match")
+	}
+}
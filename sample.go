@@ -0,0 +1,300 @@
+package pcre
+
+import (
+	"errors"
+	"math/rand"
+	"strconv"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SampleOptions configures a Sampler.
+type SampleOptions struct {
+	// Seed initializes the random source, so that the same seed gives
+	// the same samples.  Zero selects a random seed.
+	Seed int64
+	// MaxRepeat bounds the repetitions a quantifier adds beyond its
+	// minimum, such as for * and +.  Zero selects 8.
+	MaxRepeat int
+}
+
+// Sampler produces random strings which match a pattern, for fuzzing
+// parsers and generating test fixtures.  It is not safe for
+// concurrent use.
+//
+// Characters are taken from printable ASCII where the pattern allows
+// it.  Assertions, such as lookahead or \b, are not taken into account
+// while generating; instead each sample is checked against the
+// compiled pattern, and samples which do not match are discarded.
+type Sampler struct {
+	re        *Regexp
+	tree      *node
+	utf       bool
+	rand      *rand.Rand
+	maxRepeat int
+	groups    map[int]*node
+	names     map[string]int
+	captures  map[int]string
+	depth     int
+}
+
+// sampleAttempts is the number of samples Sample generates before it
+// gives up on finding one that matches.
+const sampleAttempts = 100
+
+// maxSampleDepth bounds the nesting of recursive subpatterns.
+const maxSampleDepth = 10
+
+// errSampleFailed aborts a sample that cannot be completed.
+var errSampleFailed = errors.New("sample failed")
+
+// NewSampler returns a Sampler for the pattern of re.  The error is a
+// *CompileError if the pattern cannot be parsed.
+func NewSampler(re *Regexp, opts SampleOptions) (*Sampler, error) {
+	if !re.valid() {
+		return nil, uninitialized("NewSampler")
+	}
+	flags := re.pcreOptions()
+	tree, p, err := parse(re.pattern, flags)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Seed == 0 {
+		opts.Seed = time.Now().UnixNano()
+	}
+	if opts.MaxRepeat <= 0 {
+		opts.MaxRepeat = 8
+	}
+	s := &Sampler{
+		re:        re,
+		tree:      tree,
+		utf:       p.utf,
+		rand:      rand.New(rand.NewSource(opts.Seed)),
+		maxRepeat: opts.MaxRepeat,
+		groups:    make(map[int]*node),
+		names:     make(map[string]int),
+	}
+	s.index(tree)
+	return s, nil
+}
+
+// index records the capture groups of the tree, for back references
+// and subroutine calls.
+func (s *Sampler) index(n *node) {
+	if n.op == opGroup && n.group == groupCapture {
+		s.groups[n.index] = n
+		if n.name != "" {
+			s.names[n.name] = n.index
+		}
+	}
+	for _, sub := range n.subs {
+		s.index(sub)
+	}
+}
+
+// Sample returns a random string matching the pattern.  It fails if
+// none of a number of attempts produced a match, which happens for
+// patterns whose assertions rule out most generated strings.
+func (s *Sampler) Sample() (string, error) {
+	m := s.re.NewMatcher()
+	for i := 0; i < sampleAttempts; i++ {
+		s.captures = make(map[int]string)
+		s.depth = 0
+		b, err := s.generate(nil, s.tree)
+		if err != nil {
+			continue
+		}
+		if m.Match(b, 0) {
+			return string(b), nil
+		}
+		if err := m.Err(); err != nil {
+			return "", err
+		}
+	}
+	return "", errors.New("pcre.Sampler: no matching sample in " +
+		strconv.Itoa(sampleAttempts) + " attempts for " + strconv.Quote(s.re.pattern))
+}
+
+func (s *Sampler) generate(b []byte, n *node) ([]byte, error) {
+	var err error
+	switch n.op {
+	case opLiteral:
+		r := n.r
+		if n.caseless {
+			// Keep to ASCII variants of ASCII letters, so that the
+			// Kelvin sign does not replace k.
+			variants := []rune{r}
+			for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+				if (f < utf8.RuneSelf) == (r < utf8.RuneSelf) {
+					variants = append(variants, f)
+				}
+			}
+			r = variants[s.rand.Intn(len(variants))]
+		}
+		return s.appendRune(b, r), nil
+	case opClass:
+		ranges := n.ranges
+		if n.caseless {
+			ranges = foldRanges(ranges)
+		}
+		return s.appendFrom(b, ranges)
+	case opAny:
+		if n.dotall {
+			return s.appendFrom(b, allRanges)
+		}
+		return s.appendFrom(b, negateRanges(newlineRanges))
+	case opBackref:
+		index := n.index
+		if n.name != "" {
+			index = s.names[n.name]
+		}
+		return append(b, s.captures[index]...), nil
+	case opGroup:
+		switch n.group {
+		case groupLookahead, groupNegLookahead, groupLookbehind, groupNegLookbehind:
+			return b, nil
+		}
+		start := len(b)
+		if b, err = s.generate(b, n.subs[0]); err != nil {
+			return nil, err
+		}
+		if n.group == groupCapture {
+			s.captures[n.index] = string(b[start:])
+		}
+		return b, nil
+	case opConcat:
+		for _, sub := range n.subs {
+			if b, err = s.generate(b, sub); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case opAlternate:
+		return s.generate(b, n.subs[s.rand.Intn(len(n.subs))])
+	case opRepeat:
+		hi := n.min + s.maxRepeat
+		if n.max >= 0 {
+			hi = min(n.max, hi)
+		}
+		count := n.min + s.rand.Intn(hi-n.min+1)
+		for i := 0; i < count; i++ {
+			if b, err = s.generate(b, n.subs[0]); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case opRecurse:
+		target := s.tree
+		if n.index > 0 {
+			target = s.groups[n.index]
+		} else if i, ok := s.names[n.name]; ok {
+			target = s.groups[i]
+		}
+		if target == nil || s.depth >= maxSampleDepth {
+			return nil, errSampleFailed
+		}
+		s.depth++
+		b, err = s.generate(b, target)
+		s.depth--
+		return b, err
+	case opConditional:
+		if n.name == "DEFINE" {
+			return b, nil
+		}
+		// The condition, if it is an assertion, comes first.
+		branches := n.subs
+		if len(branches) > 0 && branches[0].op == opGroup && branches[0].pos == n.pos+2 {
+			branches = branches[1:]
+		}
+		yes := s.rand.Intn(2) == 0
+		if i, err := strconv.Atoi(n.name); err == nil {
+			_, yes = s.captures[i]
+		} else if i, ok := s.names[n.name]; ok {
+			_, yes = s.captures[i]
+		} else if len(n.name) > 2 && (n.name[0] == '<' || n.name[0] == '\'') {
+			_, yes = s.captures[s.names[n.name[1:len(n.name)-1]]]
+		}
+		switch {
+		case yes && len(branches) > 0:
+			return s.generate(b, branches[0])
+		case !yes && len(branches) > 1:
+			return s.generate(b, branches[1])
+		}
+		return b, nil
+	}
+	// Anchors, assertions, option settings, verbs and callouts
+	// match no characters.
+	return b, nil
+}
+
+// printableRanges are the characters samples prefer.
+var printableRanges = []rune{' ', '~'}
+
+// appendFrom appends a random character from ranges, preferring
+// printable ASCII.
+func (s *Sampler) appendFrom(b []byte, ranges []rune) ([]byte, error) {
+	limit := rune(0xff)
+	if s.utf {
+		limit = maxRune
+	}
+	set := clipRanges(ranges, printableRanges[0], printableRanges[1])
+	if len(set) == 0 {
+		set = clipRanges(ranges, 0, limit)
+		if s.utf {
+			set = subtractRanges(set, 0xd800, 0xdfff)
+		}
+	}
+	total := 0
+	for i := 0; i+1 < len(set); i += 2 {
+		total += int(set[i+1]-set[i]) + 1
+	}
+	if total == 0 {
+		return nil, errSampleFailed
+	}
+	k := rune(s.rand.Intn(total))
+	for i := 0; i+1 < len(set); i += 2 {
+		if size := set[i+1] - set[i] + 1; k >= size {
+			k -= size
+			continue
+		}
+		return s.appendRune(b, set[i]+k), nil
+	}
+	return nil, errSampleFailed
+}
+
+// appendRune appends r, UTF-8 encoded in UTF-8 mode, and as a byte
+// otherwise.
+func (s *Sampler) appendRune(b []byte, r rune) []byte {
+	if s.utf {
+		return utf8.AppendRune(b, r)
+	}
+	return append(b, byte(r))
+}
+
+// clipRanges returns the part of normalized ranges within [lo, hi].
+func clipRanges(ranges []rune, lo, hi rune) []rune {
+	var out []rune
+	for i := 0; i+1 < len(ranges); i += 2 {
+		a, b := max(ranges[i], lo), min(ranges[i+1], hi)
+		if a <= b {
+			out = append(out, a, b)
+		}
+	}
+	return out
+}
+
+// subtractRanges removes [lo, hi] from normalized ranges.
+func subtractRanges(ranges []rune, lo, hi rune) []rune {
+	var out []rune
+	for i := 0; i+1 < len(ranges); i += 2 {
+		a, b := ranges[i], ranges[i+1]
+		if a < lo {
+			out = append(out, a, min(b, lo-1))
+		}
+		if b > hi {
+			out = append(out, max(a, hi+1), b)
+		}
+	}
+	return out
+}
@@ -0,0 +1,74 @@
+package pcre
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSampler(t *testing.T) {
+	for _, test := range []struct {
+		pattern string
+		flags   int
+	}{
+		{`^\d{4}-\d\d-\d\d$`, 0},
+		{`^[a-z]+@[a-z]+\.(com|org)$`, 0},
+		{`^(?<word>\w+) \k<word>$`, 0},
+		{`^(a|b)(?(1)c|d)+$`, 0},
+		{`^[^\x00-\x7f]{3}$`, UTF8},
+		{`^\bfoo(?=\d)\w+$`, 0},
+		{`^(\((?1)*\))$`, 0},
+		{`^(?i)abc$`, 0},
+		{`^[^a-z]*\z`, DOTALL},
+	} {
+		re := MustCompile(test.pattern, test.flags)
+		s, err := NewSampler(re, SampleOptions{Seed: 1})
+		if err != nil {
+			t.Fatal(test.pattern, err)
+		}
+		for i := 0; i < 20; i++ {
+			sample, err := s.Sample()
+			if err != nil {
+				t.Errorf("%s: %v", test.pattern, err)
+				break
+			}
+			if !re.MatcherString(sample, 0).Matches() {
+				t.Errorf("%s: sample %q does not match", test.pattern, sample)
+			}
+			if test.flags&UTF8 != 0 && !utf8.ValidString(sample) {
+				t.Errorf("%s: invalid UTF-8 in %q", test.pattern, sample)
+			}
+		}
+		re.FreeRegexp()
+	}
+}
+
+func TestSamplerSeed(t *testing.T) {
+	re := MustCompile(`[a-z]{5,10}\d*`, 0)
+	defer re.FreeRegexp()
+	samples := func(seed int64) (out []string) {
+		s, _ := NewSampler(re, SampleOptions{Seed: seed, MaxRepeat: 3})
+		for i := 0; i < 5; i++ {
+			sample, _ := s.Sample()
+			if n := len(sample); n < 5 || n > 13 {
+				t.Errorf("sample %q exceeds the repeat bound", sample)
+			}
+			out = append(out, sample)
+		}
+		return
+	}
+	a, b := samples(42), samples(42)
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("same seed, different samples: %q and %q", a, b)
+		}
+	}
+}
+
+func TestSamplerImpossible(t *testing.T) {
+	re := MustCompile(`^a(?!b)b`, 0)
+	defer re.FreeRegexp()
+	s, _ := NewSampler(re, SampleOptions{Seed: 1})
+	if _, err := s.Sample(); err == nil {
+		t.Error("expected error")
+	}
+}
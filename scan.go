@@ -0,0 +1,129 @@
+package pcre
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ScanError reports a capture group which could not be stored by
+// Matcher.Scan or Matcher.ScanNamed.
+type ScanError struct {
+	Group int    // Group number, or 0 if Name is not a group
+	Name  string // Group name, if scanned by name
+	Err   error  // The conversion error
+}
+
+// Error converts a scan error to a string.
+func (e *ScanError) Error() string {
+	group := "group " + strconv.Itoa(e.Group)
+	if e.Name != "" {
+		group = "group " + strconv.Quote(e.Name)
+	}
+	return "pcre: " + group + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *ScanError) Unwrap() error {
+	return e.Err
+}
+
+// Scan stores the capture groups of the last match in the values
+// pointed to by dest, group 1 in dest[0] and so on, like
+// database/sql.Rows.Scan.  The number of values must equal the number
+// of capture groups.  A nil value skips its group.
+//
+// Values may point to strings, byte slices, integers, floats, bools,
+// time.Duration, time.Time, types implementing
+// encoding.TextUnmarshaler, pointers to these, or an empty interface,
+// which receives the string.  Times are parsed with time.RFC3339.
+// Values whose group is not present keep their value.  A group which
+// cannot be converted fails with a *ScanError; the values before it
+// have then been stored.  If the last match failed, Scan returns the
+// error of the match, or ErrNoMatch.
+func (m *Matcher) Scan(dest ...interface{}) error {
+	if err := m.scanMatched(); err != nil {
+		return err
+	}
+	if len(dest) != m.groups {
+		return errors.New("Matcher.Scan: expected " + strconv.Itoa(m.groups) +
+			" destination arguments, not " + strconv.Itoa(len(dest)))
+	}
+	for i, d := range dest {
+		if d == nil || !m.Present(i+1) {
+			continue
+		}
+		if err := scanValue(d, m.GroupString(i+1)); err != nil {
+			return &ScanError{Group: i + 1, Err: err}
+		}
+	}
+	return nil
+}
+
+// ScanNamed stores named capture groups of the last match in the
+// values pointed to by dest, which maps group names to pointers.  The
+// conversions are those of Scan.  An unknown name fails with a
+// *ScanError before any value is stored.  The groups are stored in
+// the order of their numbers, so when one cannot be converted, the
+// values of the groups before it have been stored, as with Scan.
+func (m *Matcher) ScanNamed(dest map[string]interface{}) error {
+	if err := m.scanMatched(); err != nil {
+		return err
+	}
+	type named struct {
+		name  string
+		group int
+	}
+	groups := make([]named, 0, len(dest))
+	for name := range dest {
+		group, err := m.name2index(name)
+		if err != nil {
+			return &ScanError{Name: name, Err: errors.New("unknown group")}
+		}
+		groups = append(groups, named{name, group})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].group != groups[j].group {
+			return groups[i].group < groups[j].group
+		}
+		return groups[i].name < groups[j].name
+	})
+	for _, g := range groups {
+		d := dest[g.name]
+		if d == nil || !m.Present(g.group) {
+			continue
+		}
+		if err := scanValue(d, m.GroupString(g.group)); err != nil {
+			return &ScanError{Group: g.group, Name: g.name, Err: err}
+		}
+	}
+	return nil
+}
+
+// scanMatched returns the error to report when scanning a matcher
+// without a match.
+func (m *Matcher) scanMatched() error {
+	if err := m.Err(); err != nil {
+		return err
+	}
+	if !m.matches {
+		return ErrNoMatch
+	}
+	return nil
+}
+
+// scanValue converts s and stores it where d points.
+func scanValue(d interface{}, s string) error {
+	v := reflect.ValueOf(d)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return errors.New("destination not a non-nil pointer")
+	}
+	v = v.Elem()
+	if v.Kind() == reflect.Interface && v.NumMethod() == 0 {
+		v.Set(reflect.ValueOf(s))
+		return nil
+	}
+	return setField(v, s, time.RFC3339)
+}
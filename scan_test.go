@@ -0,0 +1,77 @@
+package pcre
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMatcherScan(t *testing.T) {
+	re := MustCompile(`(?<n>-?\d+) (?<x>[\d.]+) (?<ok>\w+) (?<d>\w+) (?<t>\S+)(?: (?<opt>\w+))?`, 0)
+	defer re.FreeRegexp()
+	m := re.MatcherString("-12 2.5 true 1m30s 2000-10-10T13:55:36Z", 0)
+	var (
+		n   int
+		x   float64
+		ok  bool
+		d   time.Duration
+		tm  time.Time
+		opt = "keep"
+	)
+	if err := m.Scan(&n, &x, &ok, &d, &tm, &opt); err != nil {
+		t.Fatal(err)
+	}
+	if n != -12 || x != 2.5 || !ok || d != 90*time.Second || tm.Year() != 2000 || opt != "keep" {
+		t.Errorf("unexpected result %v %v %v %v %v %q", n, x, ok, d, tm, opt)
+	}
+	var s interface{}
+	var b []byte
+	if err := m.Scan(nil, nil, &s, &b, nil, nil); err != nil || s != "true" || string(b) != "1m30s" {
+		t.Errorf("unexpected result %v %q %v", s, b, err)
+	}
+	if err := m.Scan(&n); err == nil {
+		t.Error("expected error for wrong number of arguments")
+	}
+	var u uint8
+	err := m.Scan(&u, nil, nil, nil, nil, nil)
+	var se *ScanError
+	if !errors.As(err, &se) || se.Group != 1 || !errors.Is(err, strconv.ErrSyntax) {
+		t.Errorf("expected conversion error, got %v", err)
+	}
+	if err := m.Scan(n, nil, nil, nil, nil, nil); err == nil {
+		t.Error("expected error for non-pointer")
+	}
+
+	n, ok = 0, false
+	if err := m.ScanNamed(map[string]interface{}{"n": &n, "ok": &ok, "opt": &opt}); err != nil {
+		t.Fatal(err)
+	}
+	if n != -12 || !ok || opt != "keep" {
+		t.Errorf("unexpected result %v %v %q", n, ok, opt)
+	}
+	err = m.ScanNamed(map[string]interface{}{"ok": &n})
+	if !errors.As(err, &se) || se.Name != "ok" || se.Group != 3 {
+		t.Errorf("expected conversion error, got %v", err)
+	}
+	if err := m.ScanNamed(map[string]interface{}{"nope": &n}); !errors.As(err, &se) || se.Group != 0 {
+		t.Errorf("expected unknown group error, got %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		n, x, opt = 0, 0, "keep"
+		err = m.ScanNamed(map[string]interface{}{"n": &n, "ok": &u, "x": &x, "opt": &opt})
+		if !errors.As(err, &se) || se.Name != "ok" || n != -12 || x != 2.5 || opt != "keep" {
+			t.Fatalf("stored %v %v %q before error %v", n, x, opt, err)
+		}
+		n = 0
+		err = m.ScanNamed(map[string]interface{}{"n": &n, "nope": &x})
+		if !errors.As(err, &se) || se.Name != "nope" || n != 0 {
+			t.Fatalf("stored %v before unknown group error %v", n, err)
+		}
+	}
+
+	m.MatchString("none", 0)
+	if err := m.Scan(&n, &x, &ok, &d, &tm, &opt); err != ErrNoMatch {
+		t.Error("expected ErrNoMatch, got", err)
+	}
+}
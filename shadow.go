@@ -0,0 +1,118 @@
+package pcre
+
+import (
+	"regexp"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// ShadowMismatch describes a match whose result differs between PCRE
+// and the standard library's regexp package, see SetShadowHandler.
+type ShadowMismatch struct {
+	Pattern string // The pattern, as passed to Compile
+	Subject string // The subject of the match
+	PCRE    []int  // Location of the PCRE match, or nil
+	Std     []int  // Location of the regexp match, or nil
+}
+
+var shadowHandler atomic.Pointer[func(*ShadowMismatch)]
+
+// SetShadowHandler enables shadow mode, which helps with migrating
+// code from the regexp package.  Patterns compiled while a handler is
+// set are also compiled with regexp if their syntax and flags allow it.
+// Match and MatchString calls without flags on such a Regexp then run
+// the standard library matcher too, and call handler whenever the
+// results differ.  Subjects which are not plain ASCII are only checked
+// for patterns compiled with UTF8.  A nil handler disables shadow mode
+// for patterns compiled afterwards.
+//
+// Shadow mode roughly doubles the cost of matching, and handler is
+// called synchronously, so it should not block.
+func SetShadowHandler(handler func(*ShadowMismatch)) {
+	if handler == nil {
+		shadowHandler.Store(nil)
+		return
+	}
+	shadowHandler.Store(&handler)
+}
+
+// shadowFlags maps compile flags to the equivalent regexp flags.
+var shadowFlags = []struct {
+	flag int
+	std  string
+}{
+	{CASELESS, "i"},
+	{MULTILINE, "m"},
+	{DOTALL, "s"},
+	{UNGREEDY, "U"},
+	{UTF8, ""},
+}
+
+// shadowCompile compiles the shadow regexp for a pattern, or returns
+// nil if shadow mode is off or the pattern is not RE2-compatible.
+func shadowCompile(pattern string, flags int) *regexp.Regexp {
+	if shadowHandler.Load() == nil {
+		return nil
+	}
+	var std string
+	for _, f := range shadowFlags {
+		if flags&f.flag != 0 {
+			std += f.std
+			flags &^= f.flag
+		}
+	}
+	if flags != 0 {
+		return nil
+	}
+	if std != "" {
+		pattern = "(?" + std + ")" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil
+	}
+	return re
+}
+
+// shadowCheck compares the last match with the shadow regexp.
+func (m *Matcher) shadowCheck() {
+	handler := shadowHandler.Load()
+	if handler == nil || m.err != nil {
+		return
+	}
+	var std []int
+	if m.subjectb != nil {
+		if m.re.pcreOptions()&UTF8 == 0 && !isASCII(m.subjectb) {
+			return
+		}
+		std = m.re.shadow.FindIndex(m.subjectb)
+	} else {
+		if m.re.pcreOptions()&UTF8 == 0 && !isASCII(m.subjects) {
+			return
+		}
+		std = m.re.shadow.FindStringIndex(m.subjects)
+	}
+	loc := m.Index()
+	if len(loc) == len(std) && (loc == nil || loc[0] == std[0] && loc[1] == std[1]) {
+		return
+	}
+	subject := m.subjects
+	if m.subjectb != nil {
+		subject = string(m.subjectb)
+	}
+	(*handler)(&ShadowMismatch{
+		Pattern: m.re.pattern,
+		Subject: subject,
+		PCRE:    loc,
+		Std:     std,
+	})
+}
+
+func isASCII[T string | []byte](s T) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
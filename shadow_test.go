@@ -0,0 +1,40 @@
+package pcre
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestShadow(t *testing.T) {
+	var mismatches []*ShadowMismatch
+	SetShadowHandler(func(mm *ShadowMismatch) {
+		mismatches = append(mismatches, mm)
+	})
+	defer SetShadowHandler(nil)
+
+	re := MustCompile("a$", 0)
+	defer re.FreeRegexp()
+	re.MatcherString("a\n", 0)
+	if len(mismatches) != 1 {
+		t.Fatal("expected one mismatch, got", len(mismatches))
+	}
+	want := &ShadowMismatch{"a$", "a\n", []int{0, 1}, nil}
+	if !reflect.DeepEqual(mismatches[0], want) {
+		t.Errorf("Expected mismatch: %v, got: %v", want, mismatches[0])
+	}
+
+	re2 := MustCompile("b+c", CASELESS)
+	defer re2.FreeRegexp()
+	re2.Matcher([]byte("aBbCd"), 0)
+	re2.MatcherString("xyz", 0)
+	if len(mismatches) != 1 {
+		t.Error("unexpected mismatch", mismatches[1:])
+	}
+
+	// Lookbehind is not supported by regexp, so no shadow is compiled.
+	re3 := MustCompile("(?<=a)b", 0)
+	defer re3.FreeRegexp()
+	if re3.shadow != nil {
+		t.Error("shadow compiled for unsupported pattern")
+	}
+}
@@ -0,0 +1,52 @@
+package pcre
+
+import "unicode/utf8"
+
+// SplitAfter slices s after each match of the pattern, and returns the
+// substrings, which keep their delimiters, like strings.SplitAfter.
+// It is equivalent to SplitAfterN with n < 0.
+func (re *Regexp) SplitAfter(s string, flags int) []string {
+	return re.SplitAfterN(s, -1, flags)
+}
+
+// SplitAfterN slices s after each match of the pattern, and returns at
+// most n substrings, the last of which is the unsplit remainder.  If n
+// is zero, the result is nil; if n is negative, all substrings are
+// returned.  An empty match splits s between characters, except at
+// the start and end of s and right after another match.  Matching
+// stops at the first error, such as an exceeded match limit.
+func (re *Regexp) SplitAfterN(s string, n, flags int) []string {
+	if n == 0 {
+		return nil
+	}
+	if !re.valid() {
+		uninitialized("Regexp.SplitAfterN")
+		return nil
+	}
+	utf := re.pcreOptions()&UTF8 != 0
+	m := re.AcquireMatcher()
+	defer m.Release()
+	var out []string
+	var clean *sanitized
+	beg, pos, prev := 0, 0, -1
+	for pos <= len(s) && (n < 0 || len(out) < n-1) {
+		if m.execSanitized(s, pos, flags, &clean) < 0 {
+			break
+		}
+		start, end := int(m.ovector[0]), int(m.ovector[1])
+		if start < end || start != 0 && start != len(s) && start != prev {
+			out = append(out, s[beg:end])
+			beg, prev = end, end
+		}
+		pos = end
+		if start == end {
+			// Continue one character past an empty match.
+			size := 1
+			if utf && end < len(s) {
+				_, size = utf8.DecodeRuneInString(s[end:])
+			}
+			pos += size
+		}
+	}
+	return append(out, s[beg:])
+}
@@ -0,0 +1,38 @@
+package pcre
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitAfter(t *testing.T) {
+	for _, tc := range []struct {
+		pattern string
+		flags   int
+		s       string
+		n       int
+		want    []string
+	}{
+		{`[.!?]\s*`, 0, "One. Two! Three", -1, []string{"One. ", "Two! ", "Three"}},
+		{`,`, 0, "a,b,", -1, []string{"a,", "b,", ""}},
+		{`,`, 0, "a,b,c", 2, []string{"a,", "b,c"}},
+		{`,`, 0, "a,b,c", 0, nil},
+		{`,`, 0, "", -1, []string{""}},
+		{``, 0, "abc", -1, []string{"a", "b", "c"}},
+		{`x*`, 0, "axxb", -1, []string{"axx", "b"}},
+		{``, UTF8, "añb", -1, []string{"a", "ñ", "b"}},
+		{`^\w+:`, MULTILINE, "a:1\nb:2", -1, []string{"a:", "1\nb:", "2"}},
+	} {
+		re := MustCompile(tc.pattern, tc.flags)
+		if got := re.SplitAfterN(tc.s, tc.n, 0); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s on %q: expected %q, got %q", tc.pattern, tc.s, tc.want, got)
+		}
+		re.FreeRegexp()
+	}
+
+	re := MustCompile(`\s+`, 0)
+	defer re.FreeRegexp()
+	if got := re.SplitAfter("a b  c", 0); !reflect.DeepEqual(got, []string{"a ", "b  ", "c"}) {
+		t.Errorf("SplitAfter: %q", got)
+	}
+}
@@ -0,0 +1,76 @@
+package pcre
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// RegexpStats holds runtime statistics of a Regexp, as returned by
+// Regexp.Stats.
+type RegexpStats struct {
+	CompileTime time.Duration // Time taken by Compile
+	StudyTime   time.Duration // Time taken by Study, zero if not studied
+	Execs       int64         // Number of pcre_exec calls
+	TotalTime   time.Duration // Cumulative duration of the pcre_exec calls
+	MaxTime     time.Duration // Longest pcre_exec call
+	NoMatches   int64         // Calls which did not match
+	LimitErrors int64         // Calls which hit the match or recursion limit
+}
+
+// regexpStats are the execution statistics of a Regexp, updated
+// atomically by every exec.
+type regexpStats struct {
+	execs, total, max, noMatches, limitErrors atomic.Int64
+}
+
+// add records an exec which took d and returned rc.
+func (s *regexpStats) add(d time.Duration, rc int) {
+	s.execs.Add(1)
+	s.total.Add(int64(d))
+	for {
+		old := s.max.Load()
+		if int64(d) <= old || s.max.CompareAndSwap(old, int64(d)) {
+			break
+		}
+	}
+	switch execResult(rc) {
+	case EXEC_NOMATCH:
+		s.noMatches.Add(1)
+	case EXEC_LIMIT:
+		s.limitErrors.Add(1)
+	}
+}
+
+// EnableStats starts collecting the execution statistics returned by
+// Stats.  Collecting them costs two clock readings and a few atomic
+// operations per pcre_exec call, so it is off by default.  Calling
+// EnableStats again resets them.
+func (re *Regexp) EnableStats() {
+	if re == nil {
+		uninitialized("Regexp.EnableStats")
+		return
+	}
+	re.stats.Store(new(regexpStats))
+}
+
+// Stats returns the statistics of the Regexp.  Compile and study
+// times are always available; the execution statistics count the
+// calls since EnableStats, and are zero if it has not been called.
+// Stats may be called concurrently with matching.
+func (re *Regexp) Stats() RegexpStats {
+	if re == nil {
+		return RegexpStats{}
+	}
+	st := RegexpStats{
+		CompileTime: re.compileTime,
+		StudyTime:   time.Duration(re.studyTime.Load()),
+	}
+	if s := re.stats.Load(); s != nil {
+		st.Execs = s.execs.Load()
+		st.TotalTime = time.Duration(s.total.Load())
+		st.MaxTime = time.Duration(s.max.Load())
+		st.NoMatches = s.noMatches.Load()
+		st.LimitErrors = s.limitErrors.Load()
+	}
+	return st
+}
@@ -0,0 +1,33 @@
+package pcre
+
+import "testing"
+
+func TestRegexpStats(t *testing.T) {
+	re := MustCompile("(a+)+$", 0)
+	defer re.FreeRegexp()
+	if st := re.Stats(); st.CompileTime <= 0 || st.StudyTime != 0 || st.Execs != 0 {
+		t.Errorf("unexpected stats before matching %+v", st)
+	}
+	m := re.MatcherString("aa", 0)
+	if st := re.Stats(); st.Execs != 0 {
+		t.Error("statistics collected before EnableStats", st.Execs)
+	}
+	re.EnableStats()
+	m.MatchString("aa", 0)
+	m.MatchString("b", 0)
+	re.SetLimits(100, 0)
+	m.MatchString("aaaaaaaaaaaaaaaaaaaaaaaab", 0)
+	st := re.Stats()
+	if st.Execs != 3 || st.NoMatches != 1 || st.LimitErrors != 1 {
+		t.Errorf("unexpected counts %+v", st)
+	}
+	if st.TotalTime <= 0 || st.MaxTime <= 0 || st.MaxTime > st.TotalTime {
+		t.Errorf("unexpected times %+v", st)
+	}
+	if err := re.Study(0); err != nil {
+		t.Fatal(err)
+	}
+	if st := re.Stats(); st.StudyTime <= 0 {
+		t.Error("study time not recorded")
+	}
+}
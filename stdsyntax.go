@@ -0,0 +1,355 @@
+package pcre
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// UnsupportedConstruct is a part of a pattern which has no equivalent
+// in the syntax of the regexp package.
+type UnsupportedConstruct struct {
+	Offset int    // Byte position in the pattern, -1 for compile flags
+	Text   string // Source text of the construct
+	Reason string
+}
+
+// StdSyntaxError is returned by ToStdSyntax for a pattern which
+// cannot be converted.
+type StdSyntaxError struct {
+	Pattern    string
+	Constructs []UnsupportedConstruct
+}
+
+// Error lists the unsupported constructs.
+func (e *StdSyntaxError) Error() string {
+	parts := make([]string, len(e.Constructs))
+	for i, c := range e.Constructs {
+		if c.Offset < 0 {
+			parts[i] = c.Text + ": " + c.Reason
+		} else {
+			parts[i] = strconv.Quote(c.Text) + " (" + strconv.Itoa(c.Offset) + "): " + c.Reason
+		}
+	}
+	return "pcre.ToStdSyntax: " + e.Pattern + ": " + strings.Join(parts, "; ")
+}
+
+// stdFlags are the compile flags ToStdSyntax can convert.
+const stdFlags = CASELESS | DOLLAR_ENDONLY | DOTALL | DUPNAMES | EXTENDED |
+	MULTILINE | NEVER_UTF | UNGREEDY | UTF8 | UCP | ANCHORED |
+	NEWLINE_LF | NO_START_OPTIMIZE | NO_UTF8_CHECK
+
+// maxStdRepeat is the largest repetition count the regexp package
+// accepts.
+const maxStdRepeat = 1000
+
+// ToStdSyntax converts a pattern compiled with flags into the syntax
+// of the regexp package, which matches in linear time, so that
+// patterns can be run by either engine with the same results.  It
+// fails with a *StdSyntaxError listing every construct that relies on
+// backtracking or has different semantics, such as back references,
+// lookaround, atomic groups, possessive quantifiers and recursion.
+//
+// Outside UTF-8 mode, characters above 0x7f are matched as bytes by
+// PCRE but as runes by the regexp package, so they are unsupported,
+// and the conversion assumes ASCII subjects.
+// As $ also matches before a final newline, it is only supported in
+// multiline mode or with DOLLAR_ENDONLY.  A pattern that fails to
+// compile returns a *CompileError.
+func ToStdSyntax(pattern string, flags int) (string, error) {
+	re, err := Compile(pattern, flags)
+	if err != nil {
+		return "", err
+	}
+	re.FreeRegexp()
+	tree, p, err := parse(pattern, flags)
+	if err != nil {
+		return "", err
+	}
+	c := &stdConverter{p: p, flags: flags, names: make(map[string]bool)}
+	if flags&^stdFlags != 0 {
+		c.unsupported(-1, "flags 0x"+strconv.FormatInt(int64(flags&^stdFlags), 16),
+			"compile flags without equivalent")
+	}
+	var b strings.Builder
+	if flags&ANCHORED != 0 {
+		b.WriteString(`\A(?:`)
+	}
+	c.convert(&b, tree)
+	if flags&ANCHORED != 0 {
+		b.WriteString(`)`)
+	}
+	if len(c.errs) > 0 {
+		return "", &StdSyntaxError{Pattern: pattern, Constructs: c.errs}
+	}
+	if _, err := regexp.Compile(b.String()); err != nil {
+		return "", &StdSyntaxError{Pattern: pattern, Constructs: []UnsupportedConstruct{
+			{Offset: 0, Text: pattern, Reason: err.Error()},
+		}}
+	}
+	return b.String(), nil
+}
+
+type stdConverter struct {
+	p     *parser
+	flags int
+	names map[string]bool
+	errs  []UnsupportedConstruct
+}
+
+func (c *stdConverter) unsupported(offset int, text, reason string) {
+	c.errs = append(c.errs, UnsupportedConstruct{Offset: offset, Text: text, Reason: reason})
+}
+
+func (c *stdConverter) reject(n *node, reason string) {
+	c.unsupported(n.pos, c.p.text(n), reason)
+}
+
+func (c *stdConverter) convert(b *strings.Builder, n *node) {
+	switch n.op {
+	case opEmpty, opOptions:
+		// Options are recorded in the nodes they apply to.
+	case opLiteral:
+		if n.r >= utf8.RuneSelf && !c.p.utf {
+			c.reject(n, "byte above 0x7f outside UTF-8 mode")
+			return
+		}
+		c.literal(b, []rune{n.r}, n.caseless)
+	case opClass:
+		c.class(b, n)
+	case opAny:
+		if n.dotall {
+			b.WriteString(`(?s:.)`)
+		} else {
+			b.WriteString(`.`)
+		}
+	case opAssert:
+		c.assertion(b, n)
+	case opBackref:
+		c.reject(n, "back reference")
+	case opRecurse:
+		c.reject(n, "recursion")
+	case opCallout:
+		c.reject(n, "callout")
+	case opConditional:
+		c.reject(n, "conditional group")
+	case opVerb:
+		switch n.name {
+		case "UTF8", "UTF", "UCP":
+		default:
+			c.reject(n, "backtracking control verb")
+		}
+	case opGroup:
+		switch n.group {
+		case groupCapture:
+			if n.name != "" {
+				if c.names[n.name] {
+					c.reject(n, "duplicate group name")
+				}
+				c.names[n.name] = true
+				b.WriteString(`(?P<` + n.name + `>`)
+			} else {
+				b.WriteString(`(`)
+			}
+		case groupNonCapture:
+			b.WriteString(`(?:`)
+		case groupAtomic:
+			c.reject(n, "atomic group")
+			return
+		case groupBranchReset:
+			c.reject(n, "branch reset group")
+			return
+		default:
+			c.reject(n, "lookaround assertion")
+			return
+		}
+		c.convert(b, n.subs[0])
+		b.WriteString(`)`)
+	case opConcat:
+		for i := 0; i < len(n.subs); i++ {
+			// Write runs of literals with the same case folding
+			// together.
+			sub := n.subs[i]
+			if sub.op != opLiteral || sub.r >= utf8.RuneSelf && !c.p.utf {
+				c.convert(b, sub)
+				continue
+			}
+			run := []rune{sub.r}
+			for i+1 < len(n.subs) && n.subs[i+1].op == opLiteral &&
+				n.subs[i+1].caseless == sub.caseless &&
+				(n.subs[i+1].r < utf8.RuneSelf || c.p.utf) {
+				i++
+				run = append(run, n.subs[i].r)
+			}
+			c.literal(b, run, sub.caseless)
+		}
+	case opAlternate:
+		for i, sub := range n.subs {
+			if i > 0 {
+				b.WriteByte('|')
+			}
+			c.convert(b, sub)
+		}
+	case opRepeat:
+		c.repeat(b, n)
+	}
+}
+
+func (c *stdConverter) literal(b *strings.Builder, run []rune, caseless bool) {
+	text := regexp.QuoteMeta(string(run))
+	folds := false
+	for _, r := range run {
+		folds = folds || unicode.SimpleFold(r) != r
+	}
+	if caseless && folds {
+		text = `(?i:` + text + `)`
+	}
+	b.WriteString(text)
+}
+
+// stdEscapes are the character type escapes which have the same
+// meaning in both syntaxes, outside UCP mode.
+var stdEscapes = map[string]bool{`\d`: true, `\D`: true, `\w`: true, `\W`: true}
+
+func (c *stdConverter) class(b *strings.Builder, n *node) {
+	switch n.name {
+	case `\R`, `\X`, `\C`:
+		c.reject(n, "escape without equivalent")
+		return
+	}
+	if stdEscapes[n.name] && !c.p.ucp {
+		b.WriteString(n.name)
+		return
+	}
+	ranges := n.ranges
+	if n.caseless {
+		ranges = foldRanges(ranges)
+	}
+	if !c.p.utf {
+		// Classes which cover all bytes above 0x7f, as negated classes
+		// do, can cover all non-ASCII runes instead.
+		high := clipRanges(ranges, utf8.RuneSelf, 0xff)
+		switch {
+		case len(high) == 0:
+			ranges = clipRanges(ranges, 0, utf8.RuneSelf-1)
+		case len(high) == 2 && high[0] == utf8.RuneSelf && high[1] == 0xff:
+			ranges = append(clipRanges(ranges, 0, utf8.RuneSelf-1), utf8.RuneSelf, maxRune)
+		default:
+			c.reject(n, "bytes above 0x7f outside UTF-8 mode")
+			return
+		}
+	}
+	b.WriteString(stdClass(ranges))
+}
+
+// stdClass formats normalized ranges as a bracketed class, negated
+// if that is shorter.
+func stdClass(ranges []rune) string {
+	if len(ranges) == 0 {
+		return `[^\x00-\x{10FFFF}]`
+	}
+	var b strings.Builder
+	b.WriteByte('[')
+	if ranges[0] == 0 && ranges[len(ranges)-1] == maxRune {
+		ranges = negateRanges(ranges)
+		b.WriteByte('^')
+		if len(ranges) == 0 {
+			return `[\x00-\x{10FFFF}]`
+		}
+	}
+	for i := 0; i+1 < len(ranges); i += 2 {
+		b.WriteString(stdClassRune(ranges[i]))
+		if ranges[i+1] != ranges[i] {
+			b.WriteByte('-')
+			b.WriteString(stdClassRune(ranges[i+1]))
+		}
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+func stdClassRune(r rune) string {
+	switch {
+	case r >= '0' && r <= '9' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r == '_':
+		return string(r)
+	case r > ' ' && r < 0x7f:
+		return `\` + string(r)
+	case r == '\t':
+		return `\t`
+	case r == '\n':
+		return `\n`
+	case r == '\r':
+		return `\r`
+	}
+	return `\x{` + strconv.FormatInt(int64(r), 16) + `}`
+}
+
+func (c *stdConverter) assertion(b *strings.Builder, n *node) {
+	switch n.name {
+	case "^":
+		if n.multiline {
+			b.WriteString(`(?m:^)`)
+		} else {
+			b.WriteString(`^`)
+		}
+	case "$":
+		switch {
+		case n.multiline:
+			b.WriteString(`(?m:$)`)
+		case c.flags&DOLLAR_ENDONLY != 0:
+			b.WriteString(`\z`)
+		default:
+			c.reject(n, "$ matches before a final newline, use DOLLAR_ENDONLY")
+		}
+	case `\b`, `\B`:
+		if c.p.ucp {
+			c.reject(n, "word boundary in UCP mode")
+			return
+		}
+		b.WriteString(n.name)
+	case `\A`, `\z`:
+		b.WriteString(n.name)
+	case `\Z`:
+		c.reject(n, `\Z matches before a final newline`)
+	default:
+		c.reject(n, "assertion without equivalent")
+	}
+}
+
+func (c *stdConverter) repeat(b *strings.Builder, n *node) {
+	if n.possessive {
+		c.reject(n, "possessive quantifier")
+		return
+	}
+	if n.min > maxStdRepeat || n.max > maxStdRepeat {
+		c.reject(n, "repetition count above "+strconv.Itoa(maxStdRepeat))
+		return
+	}
+	sub := n.subs[0]
+	var atom strings.Builder
+	c.convert(&atom, sub)
+	text := atom.String()
+	if sub.op == opConcat || sub.op == opAlternate {
+		text = `(?:` + text + `)`
+	}
+	b.WriteString(text)
+	switch {
+	case n.min == 0 && n.max == 1:
+		b.WriteString(`?`)
+	case n.min == 0 && n.max < 0:
+		b.WriteString(`*`)
+	case n.min == 1 && n.max < 0:
+		b.WriteString(`+`)
+	case n.max < 0:
+		b.WriteString(`{` + strconv.Itoa(n.min) + `,}`)
+	case n.min == n.max:
+		b.WriteString(`{` + strconv.Itoa(n.min) + `}`)
+	default:
+		b.WriteString(`{` + strconv.Itoa(n.min) + `,` + strconv.Itoa(n.max) + `}`)
+	}
+	if n.lazy && n.min != n.max {
+		b.WriteString(`?`)
+	}
+}
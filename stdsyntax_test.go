@@ -0,0 +1,80 @@
+package pcre
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestToStdSyntax(t *testing.T) {
+	for _, tc := range []struct {
+		pattern string
+		flags   int
+		want    string
+	}{
+		{`^(\d+)-(?<name>[a-f]+)x{2,3}?$`, DOLLAR_ENDONLY, `^(\d+)-(?P<name>[a-f]+)x{2,3}?\z`},
+		{`ab c. # comment`, CASELESS | EXTENDED, `(?i:abc).`},
+		{`(?s)a.(?-s).`, 0, `a(?s:.).`},
+		{`(?m)^foo$`, 0, `(?m:^)foo(?m:$)`},
+		{`(?:ab)*|[^a]\s`, 0, `(?:ab)*|[^a][\t-\r\x{20}]`},
+		{`\Qa.b\E+`, ANCHORED, `\A(?:a\.b+)`},
+		{`a(?#c)+b\E*`, 0, `a+b*`},
+		{`\p{Greek}\b`, UTF8, ``},
+	} {
+		got, err := ToStdSyntax(tc.pattern, tc.flags)
+		if err != nil {
+			t.Errorf("%s: %v", tc.pattern, err)
+			continue
+		}
+		if tc.want != "" && got != tc.want {
+			t.Errorf("%s: expected %s, got %s", tc.pattern, tc.want, got)
+		}
+		if _, err := regexp.Compile(got); err != nil {
+			t.Errorf("%s: %v", tc.pattern, err)
+		}
+	}
+
+	std, err := ToStdSyntax(`\bcolou?r\b`, CASELESS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	re := MustCompile(`\bcolou?r\b`, CASELESS)
+	defer re.FreeRegexp()
+	stdRe := regexp.MustCompile(std)
+	for _, subject := range []string{"Color", "the COLOUR red", "colors", "xcolor"} {
+		if got, want := stdRe.MatchString(subject), re.MatcherString(subject, 0).Matches(); got != want {
+			t.Errorf("%q: expected %v, got %v", subject, want, got)
+		}
+	}
+}
+
+func TestToStdSyntaxUnsupported(t *testing.T) {
+	_, err := ToStdSyntax(`(a)\1(?=b)x++$`, 0)
+	serr, ok := err.(*StdSyntaxError)
+	if !ok {
+		t.Fatalf("expected StdSyntaxError, got %v", err)
+	}
+	want := []UnsupportedConstruct{
+		{3, `\1`, "back reference"},
+		{5, `(?=b)`, "lookaround assertion"},
+		{10, `x++`, "possessive quantifier"},
+		{13, `$`, "$ matches before a final newline, use DOLLAR_ENDONLY"},
+	}
+	if len(serr.Constructs) != len(want) {
+		t.Fatalf("expected %v, got %v", want, serr.Constructs)
+	}
+	for i := range want {
+		if serr.Constructs[i] != want[i] {
+			t.Errorf("%d: expected %v, got %v", i, want[i], serr.Constructs[i])
+		}
+	}
+
+	if _, err := ToStdSyntax(`caf\xe9`, 0); err == nil {
+		t.Error("expected error for byte above 0x7f")
+	}
+	if _, err := ToStdSyntax(`a`, NO_AUTO_CAPTURE); err == nil {
+		t.Error("expected error for NO_AUTO_CAPTURE")
+	}
+	if _, err := ToStdSyntax(`a(`, 0); err == nil {
+		t.Error("expected CompileError")
+	}
+}
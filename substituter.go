@@ -0,0 +1,111 @@
+package pcre
+
+import "strings"
+
+// SubstituteFunc computes a replacement from a match and the
+// replacement computed so far, which for the first function of a
+// Substituter is the text of the match.
+type SubstituteFunc func(r MatchResult, repl string) (string, error)
+
+// Substituter replaces matches with text computed by Go functions, as
+// the e flag of Perl's s/// operator evaluates the replacement as an
+// expression:
+//
+//	double := pcre.NewSubstituter(pcre.MustCompile(`\d+`, 0),
+//		func(r pcre.MatchResult, _ string) (string, error) {
+//			n, err := r.Int(0)
+//			return strconv.Itoa(2 * n), err
+//		})
+//	out, err := double.ReplaceAll("3 apples, 4 pears", 0) // "6 apples, 8 pears"
+//
+// The functions run in a chain, each receiving the result of the one
+// before, so transformations can be composed with Then.  A Substituter
+// may be used concurrently if its functions may.
+type Substituter struct {
+	Regexp *Regexp
+	funcs  []SubstituteFunc
+}
+
+// NewSubstituter returns a Substituter for re which computes
+// replacements with the chain of funcs.  Without funcs, matches are
+// replaced by themselves.
+func NewSubstituter(re *Regexp, funcs ...SubstituteFunc) *Substituter {
+	return &Substituter{Regexp: re, funcs: funcs}
+}
+
+// Then returns a Substituter with fn appended to the chain of s.
+// s is not changed.
+func (s *Substituter) Then(fn SubstituteFunc) *Substituter {
+	funcs := append(s.funcs[:len(s.funcs):len(s.funcs)], fn)
+	return &Substituter{Regexp: s.Regexp, funcs: funcs}
+}
+
+// Replacement runs the chain on a match, and returns the replacement.
+func (s *Substituter) Replacement(r MatchResult) (string, error) {
+	repl := r.GroupString(0)
+	for _, fn := range s.funcs {
+		var err error
+		if repl, err = fn(r, repl); err != nil {
+			return "", err
+		}
+	}
+	return repl, nil
+}
+
+// ReplaceAll returns a copy of subject with every match replaced by
+// the result of the chain.  Matches are found as by FindAllNamed.  An
+// error of a function, or of a match, ends the replacement.
+func (s *Substituter) ReplaceAll(subject string, flags int) (string, error) {
+	return s.replace("Substituter.ReplaceAll", subject, -1, flags)
+}
+
+// ReplaceFirst is like ReplaceAll, but replaces only the first match.
+func (s *Substituter) ReplaceFirst(subject string, flags int) (string, error) {
+	return s.replace("Substituter.ReplaceFirst", subject, 1, flags)
+}
+
+func (s *Substituter) replace(op, subject string, n, flags int) (string, error) {
+	if !s.Regexp.valid() {
+		return "", uninitialized(op)
+	}
+	var b strings.Builder
+	var err error
+	last := 0 // end of the text copied to b
+	m := s.Regexp.AcquireMatcher()
+	defer m.Release()
+	matchErr := forEachMatchString(m, subject, 0, flags, func() bool {
+		r, _ := m.Result()
+		var repl string
+		if repl, err = s.Replacement(r); err != nil {
+			return false
+		}
+		start, end := int(m.ovector[0]), int(m.ovector[1])
+		b.WriteString(subject[last:start])
+		b.WriteString(repl)
+		last = end
+		n--
+		return n != 0
+	})
+	if err == nil {
+		err = matchErr
+	}
+	if err != nil {
+		return "", err
+	}
+	if last == 0 && b.Len() == 0 {
+		return subject, nil
+	}
+	b.WriteString(subject[last:])
+	return b.String(), nil
+}
+
+// Evaluate runs the chain on the match of m.  It has the signature of
+// Substitution.Evaluate, so that a Substituter can compute the
+// replacements of an s///e command; the expression is ignored.
+func (s *Substituter) Evaluate(expr string, m *Matcher) (string, error) {
+	r, ok := m.Result()
+	if !ok {
+		return "", ErrNoMatch
+	}
+	return s.Replacement(r)
+}
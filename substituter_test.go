@@ -0,0 +1,90 @@
+package pcre
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSubstituter(t *testing.T) {
+	re := MustCompile(`(?<n>\d+)(?<unit>[kM])?`, 0)
+	defer re.FreeRegexp()
+	scale := NewSubstituter(re, func(r MatchResult, _ string) (string, error) {
+		n, err := r.NamedInt("n")
+		if err != nil {
+			return "", err
+		}
+		switch unit, _ := r.NamedString("unit"); unit {
+		case "k":
+			n *= 1000
+		case "M":
+			n *= 1000000
+		}
+		return strconv.Itoa(n), nil
+	})
+	got, err := scale.ReplaceAll("2k of 3M and 7", 0)
+	if err != nil || got != "2000 of 3000000 and 7" {
+		t.Errorf("ReplaceAll: got %q, %v", got, err)
+	}
+	got, err = scale.ReplaceFirst("2k of 3M", 0)
+	if err != nil || got != "2000 of 3M" {
+		t.Errorf("ReplaceFirst: got %q, %v", got, err)
+	}
+
+	bracket := scale.Then(func(_ MatchResult, repl string) (string, error) {
+		return "[" + repl + "]", nil
+	})
+	if got, _ := bracket.ReplaceAll("1k 2", 0); got != "[1000] [2]" {
+		t.Errorf("Then: got %q", got)
+	}
+	if got, _ := scale.ReplaceAll("1k", 0); got != "1000" {
+		t.Errorf("Then changed the original chain: got %q", got)
+	}
+	if got, _ := NewSubstituter(re).ReplaceAll("a1b", 0); got != "a1b" {
+		t.Errorf("empty chain: got %q", got)
+	}
+
+	fail := errors.New("fail")
+	failing := NewSubstituter(re, func(MatchResult, string) (string, error) { return "", fail })
+	if _, err := failing.ReplaceAll("1", 0); err != fail {
+		t.Errorf("expected function error, got %v", err)
+	}
+}
+
+func TestSubstituterEvaluate(t *testing.T) {
+	s, err := ParseSubstitution(`s/(\d+)/$1+1/ge`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Regexp.FreeRegexp()
+	s.Evaluate = NewSubstituter(s.Regexp, func(r MatchResult, _ string) (string, error) {
+		n, err := r.Int(1)
+		return strconv.Itoa(n + 1), err
+	}).Evaluate
+	if got, err := s.ApplyString("a9 b41"); err != nil || got != "a10 b42" {
+		t.Errorf("got %q, %v", got, err)
+	}
+}
+
+func TestMatchResultTyped(t *testing.T) {
+	re := MustCompile(`(?<x>[\d.]+)|(?<word>[a-z]+)`, 0)
+	defer re.FreeRegexp()
+	r, _ := re.MatcherString("2.5", 0).Result()
+	if x, err := r.NamedFloat("x"); err != nil || x != 2.5 {
+		t.Errorf("NamedFloat: got %v, %v", x, err)
+	}
+	if x, err := r.Float(1); err != nil || x != 2.5 {
+		t.Errorf("Float: got %v, %v", x, err)
+	}
+	var se *ScanError
+	if _, err := r.Int(1); !errors.As(err, &se) || se.Group != 1 {
+		t.Errorf("Int of 2.5: got %v", err)
+	}
+	if _, err := r.NamedInt("word"); !errors.Is(err, ErrGroupNotPresent) {
+		t.Errorf("absent group: got %v", err)
+	}
+	if _, err := r.Int(7); !errors.Is(err, ErrGroupNotPresent) || !strings.Contains(err.Error(), "group 7") {
+		t.Errorf("out of range group: got %v", err)
+	}
+}
@@ -0,0 +1,352 @@
+package pcre
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Substitution is a parsed Perl or sed style substitution command,
+// s/pattern/replacement/flags, as returned by ParseSubstitution.
+type Substitution struct {
+	Regexp     *Regexp
+	Pattern    string
+	Template   string // The replacement, as written
+	Global     bool   // g: replace every match, not only the first
+	IgnoreCase bool   // i: the pattern was compiled with CASELESS
+	Eval       bool   // e: the replacement is an expression, see Evaluate
+
+	// Evaluate computes the replacement for a match when Eval is set.
+	// It receives the Template and the Matcher holding the match.
+	// Apply fails for commands with the e flag if Evaluate is nil.
+	Evaluate func(expr string, m *Matcher) (string, error)
+
+	pieces []templatePiece
+}
+
+// templatePiece is a literal, a reference to a capture group, or a
+// case conversion.
+type templatePiece struct {
+	literal string
+	group   int  // -1 for a literal or a case conversion
+	op      byte // case conversion: 'U', 'L', 'E', 'u' or 'l'
+}
+
+// brackets maps opening delimiters to their closing counterparts.
+var brackets = map[byte]byte{'(': ')', '[': ']', '{': '}', '<': '>'}
+
+// ParseSubstitution parses and compiles a substitution command such
+// as s/foo(\d+)/bar$1/gi.  Any punctuation character may delimit the
+// parts; with bracketing delimiters, pattern and replacement are
+// enclosed separately, as in s{foo}{bar}g.  The flags are g, e and
+// the letters of Field.Flags.
+//
+// The replacement may refer to capture groups as $1, ${1}, \1,
+// ${name} or $+{name}, and to the whole match as $0, $&, or \0 as in
+// sed.  As in Perl, \U and \L convert the text that follows to upper
+// or lower case, up to \E or the next \U or \L, and \u and \l convert
+// the next character only.  Other backslash sequences stand for the
+// escaped character, except \n, \r and \t.
+func ParseSubstitution(command string) (*Substitution, error) {
+	fail := func(msg string) (*Substitution, error) {
+		return nil, errors.New("pcre.ParseSubstitution: " + msg + " in " + strconv.Quote(command))
+	}
+	if len(command) < 2 || command[0] != 's' {
+		return fail("expected s")
+	}
+	delim := command[1]
+	if !isDelimiter(delim) {
+		return fail("invalid delimiter")
+	}
+	pattern, rest, ok := cutDelimited(command[2:], delim)
+	if !ok {
+		return fail("unterminated pattern")
+	}
+	if _, ok := brackets[delim]; ok {
+		rest = strings.TrimLeft(rest, " \t")
+		if rest == "" || !isDelimiter(rest[0]) {
+			return fail("missing replacement")
+		}
+		delim, rest = rest[0], rest[1:]
+	}
+	template, letters, ok := cutDelimited(rest, delim)
+	if !ok {
+		return fail("unterminated replacement")
+	}
+	s := &Substitution{Pattern: pattern, Template: template}
+	var other strings.Builder
+	for _, c := range letters {
+		switch c {
+		case 'g':
+			s.Global = true
+		case 'e':
+			s.Eval = true
+		default:
+			other.WriteRune(c)
+		}
+	}
+	flags, err := parseFlagLetters(other.String())
+	if err != nil {
+		return fail(err.Error())
+	}
+	s.IgnoreCase = flags&CASELESS != 0
+	if s.Regexp, err = Compile(pattern, flags); err != nil {
+		return nil, err
+	}
+	if !s.Eval {
+		if s.pieces, err = parseTemplate(template, s.Regexp); err != nil {
+			s.Regexp.FreeRegexp()
+			return fail(err.Error())
+		}
+	}
+	return s, nil
+}
+
+// isDelimiter reports whether c may delimit a substitution command.
+func isDelimiter(c byte) bool {
+	return '!' <= c && c <= '~' && c != '\\' && !isDigit(c) &&
+		!('A' <= c && c <= 'Z') && !('a' <= c && c <= 'z')
+}
+
+// cutDelimited splits s at the first unescaped delim, or the matching
+// closing bracket if delim is an opening bracket.  A backslash before
+// the delimiter is dropped, unless the delimiter is a pattern
+// metacharacter, where the escape keeps it literal.
+func cutDelimited(s string, delim byte) (before, after string, ok bool) {
+	closer, nested := brackets[delim]
+	if !nested {
+		closer = delim
+	}
+	var b strings.Builder
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s):
+			if (s[i+1] != delim && s[i+1] != closer) || strings.IndexByte(`.^$|()[]{}*+?`, s[i+1]) >= 0 {
+				b.WriteByte(c)
+			}
+			i++
+			b.WriteByte(s[i])
+		case nested && c == delim:
+			depth++
+			b.WriteByte(c)
+		case c == closer && depth > 0:
+			depth--
+			b.WriteByte(c)
+		case c == closer:
+			return b.String(), s[i+1:], true
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", "", false
+}
+
+// parseTemplate splits a replacement into literals and group
+// references, which are checked against re.
+func parseTemplate(template string, re *Regexp) ([]templatePiece, error) {
+	var pieces []templatePiece
+	var lit strings.Builder
+	groups := re.Groups()
+	flush := func() {
+		if lit.Len() > 0 {
+			pieces = append(pieces, templatePiece{literal: lit.String(), group: -1})
+			lit.Reset()
+		}
+	}
+	group := func(n int) error {
+		if n > groups {
+			return errors.New("reference to missing group " + strconv.Itoa(n))
+		}
+		flush()
+		pieces = append(pieces, templatePiece{group: n})
+		return nil
+	}
+	named := func(name string) error {
+		if n, err := strconv.Atoi(name); err == nil {
+			return group(n)
+		}
+		for n, sub := range re.SubexpNames() {
+			if sub == name && name != "" {
+				return group(n)
+			}
+		}
+		return errors.New("reference to missing group " + strconv.Quote(name))
+	}
+	for i := 0; i < len(template); i++ {
+		c := template[i]
+		rest := template[i+1:]
+		var err error
+		switch {
+		case c == '\\' && rest != "":
+			i++
+			switch d := template[i]; {
+			case isDigit(d):
+				err = group(int(d - '0'))
+			case d == 'n':
+				lit.WriteByte('\n')
+			case d == 'r':
+				lit.WriteByte('\r')
+			case d == 't':
+				lit.WriteByte('\t')
+			case strings.IndexByte("ULEul", d) >= 0:
+				flush()
+				pieces = append(pieces, templatePiece{group: -1, op: d})
+			default:
+				lit.WriteByte(d)
+			}
+		case c == '$' && (strings.HasPrefix(rest, "{") || strings.HasPrefix(rest, "+{")):
+			start := strings.IndexByte(rest, '{') + 1
+			end := strings.IndexByte(rest, '}')
+			if end < 0 {
+				return nil, errors.New("unterminated ${")
+			}
+			err = named(rest[start:end])
+			i += end + 1
+		case c == '$' && strings.HasPrefix(rest, "&"):
+			err = group(0)
+			i++
+		case c == '$' && rest != "" && isDigit(rest[0]):
+			j := 0
+			for j < len(rest) && isDigit(rest[j]) {
+				j++
+			}
+			n, _ := strconv.Atoi(rest[:j])
+			err = group(n)
+			i += j
+		case c == '$':
+			return nil, errors.New("invalid $ reference")
+		default:
+			lit.WriteByte(c)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	flush()
+	return pieces, nil
+}
+
+// Apply returns a copy of subject with the first match, or every
+// match if Global is set, replaced.  Each search starts at an offset
+// in the whole subject, so that \b, ^ and lookbehind assertions see
+// the text before it.  Like Perl, after an empty match the search
+// continues one character further.
+func (s *Substitution) Apply(subject []byte) ([]byte, error) {
+	if s.Eval && s.Evaluate == nil {
+		return nil, errors.New("pcre.Substitution: e flag without Evaluate")
+	}
+	m := s.Regexp.NewMatcher()
+	utf := s.Regexp.pcreOptions()&UTF8 != 0
+	var out []byte
+	var clean *sanitized
+	last, pos := 0, 0
+	for pos <= len(subject) {
+		rc := m.execSanitizedBytes(subject, pos, 0, &clean)
+		if matched, err := m.matched(rc); !matched {
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+		from, end := int(m.ovector[0]), int(m.ovector[1])
+		out = append(out, subject[last:from]...)
+		if s.Eval {
+			repl, err := s.Evaluate(s.Template, m)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, repl...)
+		} else {
+			out = expandTemplate(out, s.pieces, m, utf)
+		}
+		last, pos = end, end
+		if from == end {
+			// Continue one character past the empty match.
+			n := 1
+			if utf && end < len(subject) {
+				_, n = utf8.DecodeRune(subject[end:])
+			}
+			pos += n
+		}
+		if !s.Global {
+			break
+		}
+	}
+	return append(out, subject[last:]...), nil
+}
+
+// ApplyString is equivalent to Apply with string arguments.
+func (s *Substitution) ApplyString(subject string) (string, error) {
+	out, err := s.Apply([]byte(subject))
+	return string(out), err
+}
+
+// expandTemplate appends the replacement for the match of m to out,
+// applying the case conversions of the template.  Without UTF-8
+// mode, only ASCII letters are converted.
+func expandTemplate(out []byte, pieces []templatePiece, m *Matcher, utf bool) []byte {
+	var mode, next byte // \U or \L in effect, \u or \l pending
+	for _, p := range pieces {
+		var text []byte
+		switch {
+		case p.op == 'u' || p.op == 'l':
+			next = p.op
+			continue
+		case p.op == 'E':
+			mode = 0
+			continue
+		case p.op != 0:
+			mode = p.op
+			continue
+		case p.group < 0:
+			text = []byte(p.literal)
+		default:
+			text = m.Group(p.group)
+		}
+		if len(text) == 0 {
+			continue
+		}
+		if next != 0 {
+			n := 1
+			if utf {
+				_, n = utf8.DecodeRune(text)
+			}
+			out = appendCase(out, text[:n], next-'a'+'A', utf)
+			text, next = text[n:], 0
+		}
+		out = appendCase(out, text, mode, utf)
+	}
+	return out
+}
+
+// appendCase appends text to out, converted to upper case if mode is
+// 'U' or to lower case if it is 'L'.
+func appendCase(out, text []byte, mode byte, utf bool) []byte {
+	switch {
+	case mode == 'U' && utf:
+		return append(out, bytes.ToUpper(text)...)
+	case mode == 'L' && utf:
+		return append(out, bytes.ToLower(text)...)
+	case mode == 'U':
+		for _, c := range text {
+			if 'a' <= c && c <= 'z' {
+				c -= 'a' - 'A'
+			}
+			out = append(out, c)
+		}
+		return out
+	case mode == 'L':
+		for _, c := range text {
+			if 'A' <= c && c <= 'Z' {
+				c += 'a' - 'A'
+			}
+			out = append(out, c)
+		}
+		return out
+	}
+	return append(out, text...)
+}
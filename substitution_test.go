@@ -0,0 +1,87 @@
+package pcre
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSubstitution(t *testing.T) {
+	for _, test := range []struct {
+		command, subject, want string
+	}{
+		{`s/foo(\d+)/bar$1/gi`, "FOO1 foo22 x", "bar1 bar22 x"},
+		{`s/foo(\d+)/bar$1/`, "foo1 foo2", "bar1 foo2"},
+		{`s/(?<k>\w+)=(\w+)/${2}=$+{k}/g`, "a=b c=d", "b=a d=c"},
+		{`s/(\w+)/<\1>/g`, "ab cd", "<ab> <cd>"},
+		{`s/x*/-/g`, "xab", "--a-b-"},
+		{`s/^/> /gm`, "a\nb", "> a\n> b"},
+		{`s/^a/b/g`, "aaa", "baa"},
+		{`s#/usr/#/opt/#`, "/usr/bin", "/opt/bin"},
+		{`s/\/+/\//g`, "a//b", "a/b"},
+		{`s{(a)}{[$1]}g`, "banana", "b[a]n[a]n[a]"},
+		{`s{a}/b/`, "a", "b"},
+		{`s|a\|b|c|g`, "a|b ab", "c ab"},
+		{`s/./$&$&/g`, "ab", "aabb"},
+		{`s/a+/<\0>/g`, "baa", "b<aa>"},
+		{`s/\bfoo//g`, "foofoo", "foo"},
+		{`s/(?<=a)b/x/g`, "abab", "axax"},
+		{`s/\s+/\t/g`, "a  b", "a\tb"},
+		{`s/\$(\d)/\$$1.00/`, "$5", "$5.00"},
+		{`s/ä/ae/gu`, "bär", "baer"},
+		{`s/(?=.)/./gu`, "äb", ".ä.b"},
+		{`s/(\w+) (\w+)/\U$1\E $2/`, "ab cd", "AB cd"},
+		{`s/(\w+)/\u\L$1/g`, "hELLO wORLD", "Hello World"},
+		{`s/(\w+)/\L\u$1/g`, "hELLO", "Hello"},
+		{`s/(\w+)=(\w+)/\U$1\L=$2X\l$1/`, "Ab=CD", "AB=cdxab"},
+		{`s/(\w*)(x)/\u$1$2/`, "x", "X"},
+		{`s/(.+)/\U$1/u`, "grün", "GRÜN"},
+		{`s/(.+)/\U$1/`, "ä", "ä"},
+	} {
+		s, err := ParseSubstitution(test.command)
+		if err != nil {
+			t.Errorf("%s: %v", test.command, err)
+			continue
+		}
+		got, err := s.ApplyString(test.subject)
+		if err != nil || got != test.want {
+			t.Errorf("%s on %q: expected %q, got %q, %v", test.command, test.subject, test.want, got, err)
+		}
+		s.Regexp.FreeRegexp()
+	}
+}
+
+func TestSubstitutionFlags(t *testing.T) {
+	s, err := ParseSubstitution(`s/a/b/gie`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Regexp.FreeRegexp()
+	if !s.Global || !s.IgnoreCase || !s.Eval || s.Pattern != "a" || s.Template != "b" {
+		t.Errorf("unexpected %+v", s)
+	}
+	if _, err := s.ApplyString("a"); err == nil {
+		t.Error("expected error for e flag without Evaluate")
+	}
+	s.Evaluate = func(expr string, m *Matcher) (string, error) {
+		return strings.ToUpper(expr) + m.GroupString(0), nil
+	}
+	if got, err := s.ApplyString("xAa"); got != "xBABa" || err != nil {
+		t.Errorf("Evaluate: %q, %v", got, err)
+	}
+}
+
+func TestParseSubstitutionErrors(t *testing.T) {
+	for _, command := range []string{
+		"", "y/a/b/", "s", "sa", `s\a\b\`, "s/a", "s/a/b", "s/a/b/q",
+		"s{a}", "s/(/b/", "s/(a)/$2/", "s/a/${x}/", "s/a/$x/", "s/a/${1/",
+	} {
+		if s, err := ParseSubstitution(command); err == nil {
+			t.Errorf("%q: expected error", command)
+			s.Regexp.FreeRegexp()
+		}
+	}
+	_, err := ParseSubstitution("s/(/b/")
+	if _, ok := err.(*CompileError); !ok {
+		t.Errorf("expected *CompileError, got %v", err)
+	}
+}
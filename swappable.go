@@ -0,0 +1,73 @@
+package pcre
+
+import "sync/atomic"
+
+// Swappable holds a Regexp which can be replaced while it is used
+// concurrently, such as a pattern from configuration that is updated
+// at run time.  Users take the current Regexp with Load and release
+// it when done; a replaced Regexp is freed once the last user has
+// released it, so it is freed promptly, but never under a match.
+//
+// The zero value holds no Regexp.
+type Swappable struct {
+	cur atomic.Pointer[swapRef]
+}
+
+// swapRef counts the references to a Regexp of a Swappable: one while
+// it is current, and one per Load not yet released.
+type swapRef struct {
+	re   *Regexp
+	refs atomic.Int64
+}
+
+// release drops a reference and frees the Regexp with the last one.
+func (r *swapRef) release() {
+	if r.refs.Add(-1) == 0 {
+		r.re.FreeRegexp()
+	}
+}
+
+// NewSwappable returns a Swappable holding re.
+func NewSwappable(re *Regexp) *Swappable {
+	s := new(Swappable)
+	s.Store(re)
+	return s
+}
+
+// Load returns the current Regexp, or nil if there is none, and a
+// function which must be called exactly once when the Regexp, and
+// Matchers of it, are no longer used.
+func (s *Swappable) Load() (re *Regexp, release func()) {
+	for {
+		r := s.cur.Load()
+		if r == nil {
+			return nil, func() {}
+		}
+		// A count of zero means r has been replaced and freed since
+		// it was loaded; the next load sees its successor.
+		if n := r.refs.Load(); n > 0 && r.refs.CompareAndSwap(n, n+1) {
+			return r.re, r.release
+		}
+	}
+}
+
+// Store makes re the current Regexp.  The Swappable takes ownership
+// of it: the previous Regexp is freed once it is released by all
+// users, and so is re after it is replaced in turn.  A nil re leaves
+// the Swappable empty.
+func (s *Swappable) Store(re *Regexp) {
+	var r *swapRef
+	if re != nil {
+		r = &swapRef{re: re}
+		r.refs.Store(1)
+	}
+	if old := s.cur.Swap(r); old != nil {
+		old.release()
+	}
+}
+
+// Close empties the Swappable, freeing the current Regexp once it is
+// released by all users.
+func (s *Swappable) Close() {
+	s.Store(nil)
+}
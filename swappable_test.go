@@ -0,0 +1,48 @@
+package pcre
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSwappable(t *testing.T) {
+	s := NewSwappable(MustCompile("a", 0))
+	re, release := s.Load()
+	s.Store(MustCompile("b", 0))
+	if !re.valid() || !re.MatcherString("a", 0).Matches() {
+		t.Fatal("replaced Regexp freed while in use")
+	}
+	release()
+	if re.valid() {
+		t.Error("replaced Regexp not freed after release")
+	}
+	re, release = s.Load()
+	if !re.MatcherString("b", 0).Matches() {
+		t.Error("new Regexp not loaded")
+	}
+	release()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				re, release := s.Load()
+				if !re.MatcherString("xb", 0).Matches() {
+					t.Error("no match")
+				}
+				release()
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		s.Store(MustCompile("b", 0))
+	}
+	wg.Wait()
+	s.Close()
+	if re, release := s.Load(); re != nil {
+		release()
+		t.Error("closed Swappable not empty")
+	}
+}
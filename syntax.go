@@ -0,0 +1,1175 @@
+package pcre
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// This file contains a parser for the PCRE pattern language, which
+// turns a pattern into a syntax tree for the pattern tools in this
+// package (AnalyzePattern and friends).  Matching never uses it; the
+// parser only has to be precise enough to describe the structure of
+// patterns which PCRE accepted.
+
+// nodeOp is the kind of a syntax tree node.
+type nodeOp uint8
+
+const (
+	opEmpty       nodeOp = iota
+	opLiteral            // a single character, r
+	opClass              // a character class, ranges
+	opAny                // ., any character but newline unless dotall
+	opAssert             // ^ $ \b \B \A \z \Z \G \K, text in name
+	opBackref            // back reference to index or name
+	opGroup              // parenthesized group of kind group
+	opConcat             // sequence of subs
+	opAlternate          // alternatives in subs
+	opRepeat             // subs[0] repeated min to max times
+	opOptions            // inline option setting, (?i) etc.
+	opVerb               // backtracking control verb, (*PRUNE) etc.
+	opRecurse            // recursion or subroutine call
+	opCallout            // (?C)
+	opConditional        // (?(cond)yes|no), condition in name
+)
+
+// groupKind is the kind of an opGroup node.
+type groupKind uint8
+
+const (
+	groupCapture groupKind = iota
+	groupNonCapture
+	groupAtomic
+	groupLookahead
+	groupNegLookahead
+	groupLookbehind
+	groupNegLookbehind
+	groupBranchReset
+)
+
+// maxRune is the largest Unicode code point.
+const maxRune = unicode.MaxRune
+
+// node is a node of the syntax tree of a pattern.
+type node struct {
+	op         nodeOp
+	pos, end   int       // byte offsets of the construct in the pattern
+	r          rune      // opLiteral
+	ranges     []rune    // opClass: sorted pairs of inclusive bounds
+	group      groupKind // opGroup
+	index      int       // capture number of opGroup, opBackref, opRecurse
+	name       string    // group or reference name, assertion or verb text
+	min, max   int       // opRepeat, max is -1 if unbounded
+	lazy       bool      // opRepeat
+	possessive bool      // opRepeat
+	caseless   bool      // opLiteral, opClass, opBackref under (?i)
+	dotall     bool      // opAny under (?s)
+	multiline  bool      // opAssert ^ and $ under (?m)
+	subs       []*node
+}
+
+// parser holds the state of parsing a pattern.
+type parser struct {
+	pattern string
+	pos     int
+	flags   int // current CASELESS, DOTALL, EXTENDED, MULTILINE, UNGREEDY
+	ucp     bool
+	utf     bool
+	ncap    int      // capture groups opened so far
+	names   []string // names of capture groups, indexed by number-1
+}
+
+// parse parses a pattern compiled with flags into a syntax tree.
+// Errors are reported as *CompileError.
+func parse(pattern string, flags int) (*node, *parser, error) {
+	p := &parser{
+		pattern: pattern,
+		flags:   flags,
+		ucp:     flags&UCP != 0,
+		utf:     flags&UTF8 != 0,
+	}
+	re, err := p.parseAlternate()
+	if err != nil {
+		return nil, nil, err
+	}
+	if p.pos < len(p.pattern) {
+		return nil, nil, p.error("unmatched parentheses")
+	}
+	return re, p, nil
+}
+
+func (p *parser) error(msg string) error {
+	return &CompileError{Pattern: p.pattern, Message: msg, Offset: p.pos}
+}
+
+func (p *parser) more() bool {
+	return p.pos < len(p.pattern)
+}
+
+func (p *parser) peek() byte {
+	if p.pos < len(p.pattern) {
+		return p.pattern[p.pos]
+	}
+	return 0
+}
+
+func (p *parser) lookingAt(s string) bool {
+	return strings.HasPrefix(p.pattern[p.pos:], s)
+}
+
+// nextRune consumes and returns the next character of the pattern.
+func (p *parser) nextRune() rune {
+	r, size := utf8.DecodeRuneInString(p.pattern[p.pos:])
+	if r == utf8.RuneError && size <= 1 {
+		r = rune(p.pattern[p.pos])
+		size = 1
+	}
+	p.pos += size
+	return r
+}
+
+// skipExtended skips white space and comments in extended mode.
+func (p *parser) skipExtended() {
+	if p.flags&EXTENDED == 0 {
+		return
+	}
+	for p.more() {
+		switch c := p.peek(); {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' ||
+			c == '\v' || c == '\f':
+			p.pos++
+		case c == '#':
+			for p.more() && p.peek() != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// skipIgnored skips what may stand between an atom and its quantifier
+// without being an atom itself: besides white space and comments in
+// extended mode, comments, \E and empty \Q\E sequences.
+func (p *parser) skipIgnored() error {
+	for {
+		p.skipExtended()
+		switch {
+		case p.lookingAt("(?#"):
+			p.pos += 3
+			if _, err := p.readUntil(')'); err != nil {
+				return err
+			}
+		case p.lookingAt(`\E`):
+			p.pos += 2
+		case p.lookingAt(`\Q\E`):
+			p.pos += 4
+		default:
+			return nil
+		}
+	}
+}
+
+func (p *parser) parseAlternate() (*node, error) {
+	start := p.pos
+	var alts []*node
+	for {
+		n, err := p.parseConcat()
+		if err != nil {
+			return nil, err
+		}
+		alts = append(alts, n)
+		if p.peek() != '|' {
+			break
+		}
+		p.pos++
+	}
+	if len(alts) == 1 {
+		return alts[0], nil
+	}
+	return &node{op: opAlternate, pos: start, end: p.pos, subs: alts}, nil
+}
+
+func (p *parser) parseConcat() (*node, error) {
+	start := p.pos
+	var items []*node
+	for {
+		p.skipExtended()
+		if !p.more() || p.peek() == '|' || p.peek() == ')' {
+			break
+		}
+		atom, err := p.parseAtom()
+		if err != nil {
+			return nil, err
+		}
+		if atom == nil {
+			continue // comment or \E
+		}
+		if atom, err = p.parseQuantifier(atom); err != nil {
+			return nil, err
+		}
+		if atom.op == opConcat && atom.name == `\Q` {
+			items = append(items, atom.subs...)
+			continue
+		}
+		items = append(items, atom)
+	}
+	if len(items) == 1 {
+		return items[0], nil
+	}
+	return &node{op: opConcat, pos: start, end: p.pos, subs: items}, nil
+}
+
+func (p *parser) parseAtom() (*node, error) {
+	start := p.pos
+	switch c := p.peek(); c {
+	case '(':
+		return p.parseGroup()
+	case '[':
+		return p.parseClass()
+	case '\\':
+		return p.parseEscape()
+	case '.':
+		p.pos++
+		return &node{op: opAny, pos: start, end: p.pos,
+			dotall: p.flags&DOTALL != 0}, nil
+	case '^', '$':
+		p.pos++
+		return &node{op: opAssert, pos: start, end: p.pos,
+			name: string(c), multiline: p.flags&MULTILINE != 0}, nil
+	case '*', '+', '?':
+		return nil, p.error("nothing to repeat")
+	}
+	return p.literal(start, p.nextRune()), nil
+}
+
+func (p *parser) literal(start int, r rune) *node {
+	return &node{op: opLiteral, pos: start, end: p.pos, r: r,
+		caseless: p.flags&CASELESS != 0}
+}
+
+// parseQuantifier parses a quantifier following atom, if any.
+func (p *parser) parseQuantifier(atom *node) (*node, error) {
+	if err := p.skipIgnored(); err != nil {
+		return nil, err
+	}
+	if !p.more() {
+		return atom, nil
+	}
+	save := p.pos
+	min, max := 0, -1
+	switch p.peek() {
+	case '*':
+		p.pos++
+	case '+':
+		min = 1
+		p.pos++
+	case '?':
+		max = 1
+		p.pos++
+	case '{':
+		var ok bool
+		if min, max, ok = p.parseBraces(); !ok {
+			p.pos = save
+			return atom, nil
+		}
+	default:
+		return atom, nil
+	}
+	if atom.op == opConcat && atom.name == `\Q` && len(atom.subs) > 0 {
+		// A quantifier after \Q...\E applies to the last character.
+		last := atom.subs[len(atom.subs)-1]
+		rep, err := p.finishRepeat(last, min, max)
+		if err != nil {
+			return nil, err
+		}
+		atom.subs[len(atom.subs)-1] = rep
+		return atom, nil
+	}
+	return p.finishRepeat(atom, min, max)
+}
+
+func (p *parser) finishRepeat(atom *node, min, max int) (*node, error) {
+	if max >= 0 && max < min {
+		return nil, p.error("numbers out of order in {} quantifier")
+	}
+	n := &node{op: opRepeat, pos: atom.pos, min: min, max: max,
+		lazy: p.flags&UNGREEDY != 0, subs: []*node{atom}}
+	switch p.peek() {
+	case '?':
+		n.lazy = !n.lazy
+		p.pos++
+	case '+':
+		n.possessive = true
+		n.lazy = false
+		p.pos++
+	}
+	n.end = p.pos
+	return n, nil
+}
+
+// parseBraces parses a {n}, {n,} or {n,m} quantifier.
+func (p *parser) parseBraces() (min, max int, ok bool) {
+	p.pos++ // {
+	min, ok = p.parseInt()
+	if !ok {
+		return
+	}
+	switch p.peek() {
+	case '}':
+		p.pos++
+		return min, min, true
+	case ',':
+		p.pos++
+	default:
+		return 0, 0, false
+	}
+	if p.peek() == '}' {
+		p.pos++
+		return min, -1, true
+	}
+	if max, ok = p.parseInt(); !ok || p.peek() != '}' {
+		return 0, 0, false
+	}
+	p.pos++
+	return min, max, true
+}
+
+func (p *parser) parseInt() (int, bool) {
+	start := p.pos
+	for p.more() && p.peek() >= '0' && p.peek() <= '9' {
+		p.pos++
+	}
+	if start == p.pos {
+		return 0, false
+	}
+	n, err := strconv.Atoi(p.pattern[start:p.pos])
+	return n, err == nil
+}
+
+// readUntil consumes up to and including the terminator and returns
+// the text before it.
+func (p *parser) readUntil(term byte) (string, error) {
+	i := strings.IndexByte(p.pattern[p.pos:], term)
+	if i < 0 {
+		return "", p.error("missing terminator " + string(term))
+	}
+	s := p.pattern[p.pos : p.pos+i]
+	p.pos += i + 1
+	return s, nil
+}
+
+// optionFlags maps inline option letters to compile flags.
+var optionFlags = map[byte]int{
+	'i': CASELESS,
+	'm': MULTILINE,
+	's': DOTALL,
+	'x': EXTENDED,
+	'U': UNGREEDY,
+	'X': EXTRA,
+	'J': DUPNAMES,
+}
+
+func (p *parser) parseGroup() (*node, error) {
+	start := p.pos
+	if p.lookingAt("(*") {
+		p.pos += 2
+		verb, err := p.readUntil(')')
+		if err != nil {
+			return nil, err
+		}
+		switch verb {
+		case "UTF8", "UTF":
+			p.utf = true
+		case "UCP":
+			p.ucp = true
+		}
+		return &node{op: opVerb, pos: start, end: p.pos, name: verb}, nil
+	}
+	if !p.lookingAt("(?") {
+		p.pos++
+		p.ncap++
+		p.names = append(p.names, "")
+		return p.parseGroupBody(start, groupCapture, p.ncap, "")
+	}
+	p.pos += 2
+	c := p.peek()
+	switch {
+	case c == '#':
+		_, err := p.readUntil(')')
+		return nil, err
+	case c == ':':
+		p.pos++
+		return p.parseGroupBody(start, groupNonCapture, 0, "")
+	case c == '|':
+		p.pos++
+		return p.parseBranchReset(start)
+	case c == '>':
+		p.pos++
+		return p.parseGroupBody(start, groupAtomic, 0, "")
+	case c == '=':
+		p.pos++
+		return p.parseGroupBody(start, groupLookahead, 0, "")
+	case c == '!':
+		p.pos++
+		return p.parseGroupBody(start, groupNegLookahead, 0, "")
+	case p.lookingAt("<="):
+		p.pos += 2
+		return p.parseGroupBody(start, groupLookbehind, 0, "")
+	case p.lookingAt("<!"):
+		p.pos += 2
+		return p.parseGroupBody(start, groupNegLookbehind, 0, "")
+	case c == '<' || c == '\'' || p.lookingAt("P<"):
+		if c == 'P' {
+			p.pos++
+		}
+		term := byte('>')
+		if p.peek() == '\'' {
+			term = '\''
+		}
+		p.pos++
+		name, err := p.readUntil(term)
+		if err != nil {
+			return nil, err
+		}
+		p.ncap++
+		p.names = append(p.names, name)
+		return p.parseGroupBody(start, groupCapture, p.ncap, name)
+	case p.lookingAt("P="):
+		p.pos += 2
+		name, err := p.readUntil(')')
+		if err != nil {
+			return nil, err
+		}
+		return &node{op: opBackref, pos: start, end: p.pos, name: name,
+			caseless: p.flags&CASELESS != 0}, nil
+	case p.lookingAt("P>") || c == '&':
+		if c == 'P' {
+			p.pos++
+		}
+		p.pos++
+		name, err := p.readUntil(')')
+		if err != nil {
+			return nil, err
+		}
+		return &node{op: opRecurse, pos: start, end: p.pos, name: name}, nil
+	case c == 'R' || c == '+' || c == '-' && p.pos+1 < len(p.pattern) &&
+		isDigit(p.pattern[p.pos+1]) || isDigit(c):
+		ref, err := p.readUntil(')')
+		if err != nil {
+			return nil, err
+		}
+		n := &node{op: opRecurse, pos: start, end: p.pos, name: ref}
+		if ref != "R" {
+			i, _ := strconv.Atoi(ref)
+			n.index = p.relative(i, ref)
+		}
+		return n, nil
+	case c == 'C':
+		p.pos++
+		arg, err := p.readUntil(')')
+		if err != nil {
+			return nil, err
+		}
+		return &node{op: opCallout, pos: start, end: p.pos, name: arg}, nil
+	case c == '(':
+		return p.parseConditional(start)
+	}
+	// Option setting, (?imsx-imsx) or (?imsx-imsx:...)
+	flags := p.flags
+	on := true
+	for p.more() {
+		c := p.peek()
+		if c == ')' || c == ':' {
+			break
+		}
+		p.pos++
+		if c == '-' {
+			on = false
+			continue
+		}
+		f, ok := optionFlags[c]
+		if !ok {
+			return nil, p.error("unrecognized character after (? or (?-")
+		}
+		if on {
+			flags |= f
+		} else {
+			flags &^= f
+		}
+	}
+	if !p.more() {
+		return nil, p.error("missing )")
+	}
+	if p.peek() == ')' {
+		p.pos++
+		p.flags = flags
+		return &node{op: opOptions, pos: start, end: p.pos,
+			name: p.pattern[start+2 : p.pos-1]}, nil
+	}
+	p.pos++ // :
+	saved := p.flags
+	p.flags = flags
+	n, err := p.parseGroupBody(start, groupNonCapture, 0, "")
+	p.flags = saved
+	return n, err
+}
+
+// parseGroupBody parses the alternatives of a group up to its
+// closing parenthesis.  Option changes inside the group end with it.
+func (p *parser) parseGroupBody(start int, kind groupKind, index int, name string) (*node, error) {
+	saved := p.flags
+	sub, err := p.parseAlternate()
+	p.flags = saved
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() != ')' {
+		return nil, p.error("missing )")
+	}
+	p.pos++
+	return &node{op: opGroup, pos: start, end: p.pos, group: kind,
+		index: index, name: name, subs: []*node{sub}}, nil
+}
+
+// parseBranchReset parses a (?|...) group, in which each alternative
+// numbers its capture groups from the same starting point.
+func (p *parser) parseBranchReset(start int) (*node, error) {
+	saved := p.flags
+	base, top := p.ncap, p.ncap
+	alt := &node{op: opAlternate, pos: p.pos}
+	for {
+		p.ncap = base
+		n, err := p.parseConcat()
+		if err != nil {
+			return nil, err
+		}
+		alt.subs = append(alt.subs, n)
+		top = max(top, p.ncap)
+		if p.peek() != '|' {
+			break
+		}
+		p.pos++
+	}
+	p.flags = saved
+	p.ncap = top
+	if len(p.names) > top {
+		p.names = p.names[:top]
+	}
+	for len(p.names) < top {
+		p.names = append(p.names, "")
+	}
+	if p.peek() != ')' {
+		return nil, p.error("missing )")
+	}
+	alt.end = p.pos
+	p.pos++
+	return &node{op: opGroup, pos: start, end: p.pos,
+		group: groupBranchReset, subs: []*node{alt}}, nil
+}
+
+// parseConditional parses a (?(condition)yes|no) group.
+func (p *parser) parseConditional(start int) (*node, error) {
+	n := &node{op: opConditional, pos: start}
+	if p.lookingAt("(?=") || p.lookingAt("(?!") ||
+		p.lookingAt("(?<=") || p.lookingAt("(?<!") {
+		cond, err := p.parseGroup()
+		if err != nil {
+			return nil, err
+		}
+		n.name = p.pattern[cond.pos:cond.end]
+		n.subs = append(n.subs, cond)
+	} else {
+		p.pos++
+		cond, err := p.readUntil(')')
+		if err != nil {
+			return nil, err
+		}
+		n.name = cond
+	}
+	saved := p.flags
+	body, err := p.parseAlternate()
+	p.flags = saved
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() != ')' {
+		return nil, p.error("missing )")
+	}
+	p.pos++
+	if body.op == opAlternate {
+		n.subs = append(n.subs, body.subs...)
+	} else {
+		n.subs = append(n.subs, body)
+	}
+	n.end = p.pos
+	return n, nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHex(c byte) bool {
+	return isDigit(c) || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+// parseEscape parses a backslash sequence outside a character class.
+func (p *parser) parseEscape() (*node, error) {
+	start := p.pos
+	p.pos++ // backslash
+	if !p.more() {
+		return nil, p.error(`\ at end of pattern`)
+	}
+	c := p.peek()
+	switch c {
+	case 'b', 'B', 'A', 'z', 'Z', 'G', 'K':
+		p.pos++
+		return &node{op: opAssert, pos: start, end: p.pos,
+			name: `\` + string(c)}, nil
+	case 'Q':
+		p.pos++
+		n := &node{op: opConcat, pos: start, name: `\Q`}
+		for p.more() && !p.lookingAt(`\E`) {
+			lstart := p.pos
+			n.subs = append(n.subs, p.literal(lstart, p.nextRune()))
+		}
+		if p.more() {
+			p.pos += 2
+		}
+		n.end = p.pos
+		if len(n.subs) == 0 {
+			return nil, nil
+		}
+		return n, nil
+	case 'E':
+		p.pos++
+		return nil, nil
+	case 'g':
+		return p.parseBackrefG(start)
+	case 'k':
+		p.pos++
+		term := map[byte]byte{'<': '>', '\'': '\'', '{': '}'}[p.peek()]
+		if term == 0 {
+			return nil, p.error(`\k is not followed by a name`)
+		}
+		p.pos++
+		name, err := p.readUntil(term)
+		if err != nil {
+			return nil, err
+		}
+		return &node{op: opBackref, pos: start, end: p.pos, name: name,
+			caseless: p.flags&CASELESS != 0}, nil
+	}
+	if c >= '1' && c <= '9' {
+		// A back reference unless it is a larger number than the
+		// groups seen so far, in which case it may be octal.
+		save := p.pos
+		n, _ := p.parseInt()
+		if n < 10 || n <= p.ncap {
+			return &node{op: opBackref, pos: start, end: p.pos, index: n,
+				caseless: p.flags&CASELESS != 0}, nil
+		}
+		p.pos = save
+	}
+	ranges, r, err := p.parseCharEscape()
+	if err != nil {
+		return nil, err
+	}
+	if ranges != nil {
+		return &node{op: opClass, pos: start, end: p.pos, ranges: ranges,
+			name:     p.pattern[start:p.pos],
+			caseless: p.flags&CASELESS != 0}, nil
+	}
+	return p.literal(start, r), nil
+}
+
+// parseBackrefG parses \g back references and subroutine calls.
+func (p *parser) parseBackrefG(start int) (*node, error) {
+	p.pos++ // g
+	caseless := p.flags&CASELESS != 0
+	switch p.peek() {
+	case '<', '\'':
+		term := byte('>')
+		if p.peek() == '\'' {
+			term = '\''
+		}
+		p.pos++
+		ref, err := p.readUntil(term)
+		if err != nil {
+			return nil, err
+		}
+		n := &node{op: opRecurse, pos: start, end: p.pos, name: ref}
+		if i, err := strconv.Atoi(ref); err == nil {
+			n.name = ""
+			n.index = p.relative(i, ref)
+		}
+		return n, nil
+	case '{':
+		p.pos++
+		ref, err := p.readUntil('}')
+		if err != nil {
+			return nil, err
+		}
+		n := &node{op: opBackref, pos: start, end: p.pos, caseless: caseless}
+		if i, err := strconv.Atoi(ref); err == nil {
+			n.index = p.relative(i, ref)
+		} else {
+			n.name = ref
+		}
+		return n, nil
+	}
+	numStart := p.pos
+	if p.peek() == '-' || p.peek() == '+' {
+		p.pos++
+	}
+	if _, ok := p.parseInt(); !ok {
+		return nil, p.error(`a numbered reference must not be zero`)
+	}
+	ref := p.pattern[numStart:p.pos]
+	i, _ := strconv.Atoi(ref)
+	return &node{op: opBackref, pos: start, end: p.pos,
+		index: p.relative(i, ref), caseless: caseless}, nil
+}
+
+// relative resolves a possibly relative group number.
+func (p *parser) relative(i int, ref string) int {
+	switch ref[0] {
+	case '-':
+		return p.ncap + i + 1
+	case '+':
+		return p.ncap + i
+	}
+	return i
+}
+
+// Character sets for escapes and classes, as sorted range pairs.
+var (
+	digitRanges   = []rune{'0', '9'}
+	wordRanges    = []rune{'0', '9', 'A', 'Z', '_', '_', 'a', 'z'}
+	spaceRanges   = []rune{'\t', '\r', ' ', ' '}
+	hspaceRanges  = []rune{'\t', '\t', ' ', ' ', 0xa0, 0xa0, 0x1680, 0x1680, 0x180e, 0x180e, 0x2000, 0x200a, 0x202f, 0x202f, 0x205f, 0x205f, 0x3000, 0x3000}
+	vspaceRanges  = []rune{'\n', '\r', 0x85, 0x85, 0x2028, 0x2029}
+	allRanges     = []rune{0, maxRune}
+	newlineRanges = vspaceRanges
+)
+
+// parseCharEscape parses the escape following a backslash, which is
+// either a character type, returned as ranges, or a single character.
+func (p *parser) parseCharEscape() (ranges []rune, r rune, err error) {
+	c := p.peek()
+	p.pos++
+	switch c {
+	case 'd', 'D':
+		ranges = digitRanges
+		if p.ucp {
+			ranges = tableRanges(unicode.Nd)
+		}
+	case 'w', 'W':
+		ranges = wordRanges
+		if p.ucp {
+			ranges = unionRanges(tableRanges(unicode.L), tableRanges(unicode.N), []rune{'_', '_'})
+		}
+	case 's', 'S':
+		ranges = spaceRanges
+		if p.ucp {
+			ranges = tableRanges(unicode.White_Space)
+		}
+	case 'h', 'H':
+		ranges = hspaceRanges
+	case 'v', 'V', 'R':
+		ranges = vspaceRanges
+	case 'N':
+		ranges = negateRanges(newlineRanges)
+	case 'X', 'C':
+		ranges = allRanges
+	case 'p', 'P':
+		ranges, err = p.parseProperty()
+		if err != nil {
+			return nil, 0, err
+		}
+		if c == 'P' {
+			ranges = negateRanges(ranges)
+		}
+		return ranges, 0, nil
+	}
+	if ranges != nil {
+		if c >= 'A' && c <= 'Z' && c != 'R' && c != 'N' && c != 'X' && c != 'C' {
+			ranges = negateRanges(ranges)
+		}
+		return ranges, 0, nil
+	}
+	switch c {
+	case 'a':
+		return nil, 7, nil
+	case 'e':
+		return nil, 27, nil
+	case 'f':
+		return nil, '\f', nil
+	case 'n':
+		return nil, '\n', nil
+	case 'r':
+		return nil, '\r', nil
+	case 't':
+		return nil, '\t', nil
+	case 'c':
+		if !p.more() {
+			return nil, 0, p.error(`\c at end of pattern`)
+		}
+		x := p.pattern[p.pos]
+		p.pos++
+		if x >= 'a' && x <= 'z' {
+			x -= 'a' - 'A'
+		}
+		return nil, rune(x ^ 0x40), nil
+	case 'x':
+		if p.peek() == '{' {
+			p.pos++
+			hex, err := p.readUntil('}')
+			if err != nil {
+				return nil, 0, err
+			}
+			v, err := strconv.ParseUint(hex, 16, 32)
+			if err != nil {
+				return nil, 0, p.error("invalid hexadecimal escape")
+			}
+			return nil, rune(v), nil
+		}
+		start := p.pos
+		for p.pos < start+2 && p.more() && isHex(p.peek()) {
+			p.pos++
+		}
+		v, _ := strconv.ParseUint("0"+p.pattern[start:p.pos], 16, 32)
+		return nil, rune(v), nil
+	case 'o':
+		if p.peek() != '{' {
+			return nil, 0, p.error(`missing opening brace after \o`)
+		}
+		p.pos++
+		oct, err := p.readUntil('}')
+		if err != nil {
+			return nil, 0, err
+		}
+		v, err := strconv.ParseUint(oct, 8, 32)
+		if err != nil {
+			return nil, 0, p.error("invalid octal escape")
+		}
+		return nil, rune(v), nil
+	}
+	if c >= '0' && c <= '7' {
+		start := p.pos - 1
+		for p.pos < start+3 && p.more() && p.peek() >= '0' && p.peek() <= '7' {
+			p.pos++
+		}
+		v, _ := strconv.ParseUint(p.pattern[start:p.pos], 8, 32)
+		return nil, rune(v), nil
+	}
+	p.pos--
+	return nil, p.nextRune(), nil
+}
+
+// parseProperty parses the name of a \p or \P escape.
+func (p *parser) parseProperty() ([]rune, error) {
+	var name string
+	if p.peek() == '{' {
+		p.pos++
+		var err error
+		if name, err = p.readUntil('}'); err != nil {
+			return nil, err
+		}
+	} else if p.more() {
+		name = string(p.nextRune())
+	}
+	negate := strings.HasPrefix(name, "^")
+	name = strings.TrimPrefix(name, "^")
+	var ranges []rune
+	switch name {
+	case "Any":
+		ranges = allRanges
+	case "L&":
+		ranges = unionRanges(tableRanges(unicode.Lu), tableRanges(unicode.Ll), tableRanges(unicode.Lt))
+	case "Xan":
+		ranges = unionRanges(tableRanges(unicode.L), tableRanges(unicode.N))
+	case "Xsp", "Xps":
+		ranges = tableRanges(unicode.White_Space)
+	case "Xwd":
+		ranges = unionRanges(tableRanges(unicode.L), tableRanges(unicode.N), []rune{'_', '_'})
+	case "Xuc":
+		ranges = []rune{'$', '$', '@', '@', '`', '`', 0xa0, 0xd7ff, 0xe000, maxRune}
+	default:
+		if t, ok := unicode.Categories[name]; ok {
+			ranges = tableRanges(t)
+		} else if t, ok := unicode.Scripts[name]; ok {
+			ranges = tableRanges(t)
+		} else {
+			return nil, p.error("unknown property name after \\P or \\p")
+		}
+	}
+	if negate {
+		ranges = negateRanges(ranges)
+	}
+	return ranges, nil
+}
+
+// posixClasses are the [:name:] classes allowed in brackets.
+var posixClasses = map[string][]rune{
+	"alnum":  {'0', '9', 'A', 'Z', 'a', 'z'},
+	"alpha":  {'A', 'Z', 'a', 'z'},
+	"ascii":  {0, 0x7f},
+	"blank":  {'\t', '\t', ' ', ' '},
+	"cntrl":  {0, 0x1f, 0x7f, 0x7f},
+	"digit":  {'0', '9'},
+	"graph":  {'!', '~'},
+	"lower":  {'a', 'z'},
+	"print":  {' ', '~'},
+	"punct":  {'!', '/', ':', '@', '[', '`', '{', '~'},
+	"space":  {'\t', '\r', ' ', ' '},
+	"upper":  {'A', 'Z'},
+	"word":   {'0', '9', 'A', 'Z', '_', '_', 'a', 'z'},
+	"xdigit": {'0', '9', 'A', 'F', 'a', 'f'},
+}
+
+// posixEnd returns the offset of the terminator of a POSIX class,
+// such as [:alpha:], or collating element, such as [.a.] or [=a=],
+// at p.pos, or -1 if there is none there.  Like check_posix_syntax
+// in PCRE, it stops at the first ] or nested [: which is not escaped,
+// and the name may be empty; without a terminator, [ is a literal.
+func (p *parser) posixEnd() int {
+	if !p.lookingAt("[:") && !p.lookingAt("[.") && !p.lookingAt("[=") {
+		return -1
+	}
+	term := p.pattern[p.pos+1]
+	next := func(i int) byte {
+		if i+1 < len(p.pattern) {
+			return p.pattern[i+1]
+		}
+		return 0
+	}
+	for i := p.pos + 2; i < len(p.pattern); i++ {
+		switch c := p.pattern[i]; {
+		case c == '\\' && (next(i) == ']' || next(i) == '\\'):
+			i++
+		case c == '[' && next(i) == term || c == ']':
+			return -1
+		case c == term && next(i) == ']':
+			return i
+		}
+	}
+	return -1
+}
+
+// parseClass parses a bracketed character class.
+func (p *parser) parseClass() (*node, error) {
+	if p.posixEnd() >= 0 {
+		if p.pattern[p.pos+1] == ':' {
+			return nil, p.error("POSIX named classes are supported only within a class")
+		}
+		return nil, p.error("POSIX collating elements are not supported")
+	}
+	start := p.pos
+	p.pos++ // [
+	negate := false
+	if p.peek() == '^' {
+		negate = true
+		p.pos++
+	}
+	var ranges []rune
+	first := true
+	for {
+		if !p.more() {
+			return nil, p.error("missing terminating ] for character class")
+		}
+		if p.peek() == ']' && !first {
+			p.pos++
+			break
+		}
+		first = false
+		if end := p.posixEnd(); end >= 0 {
+			if p.pattern[p.pos+1] != ':' {
+				return nil, p.error("POSIX collating elements are not supported")
+			}
+			name := p.pattern[p.pos+2 : end]
+			neg := strings.HasPrefix(name, "^")
+			set, ok := posixClasses[strings.TrimPrefix(name, "^")]
+			if !ok {
+				return nil, p.error("unknown POSIX class name")
+			}
+			if neg {
+				set = negateRanges(set)
+			}
+			ranges = append(ranges, set...)
+			p.pos = end + 2
+			continue
+		}
+		if p.lookingAt(`\Q`) {
+			p.pos += 2
+			for p.more() && !p.lookingAt(`\E`) {
+				r := p.nextRune()
+				ranges = append(ranges, r, r)
+			}
+			if p.more() {
+				p.pos += 2
+			}
+			continue
+		}
+		if p.lookingAt(`\E`) {
+			p.pos += 2
+			continue
+		}
+		lo, set, err := p.classChar()
+		if err != nil {
+			return nil, err
+		}
+		if set != nil {
+			ranges = append(ranges, set...)
+			continue
+		}
+		hi := lo
+		if p.peek() == '-' && p.pos+1 < len(p.pattern) && p.pattern[p.pos+1] != ']' {
+			save := p.pos
+			p.pos++
+			r, set, err := p.classChar()
+			if err != nil {
+				return nil, err
+			}
+			if set != nil {
+				// [a-\d] is a literal hyphen followed by \d
+				p.pos = save
+			} else {
+				if r < lo {
+					return nil, p.error("range out of order in character class")
+				}
+				hi = r
+			}
+		}
+		ranges = append(ranges, lo, hi)
+	}
+	ranges = normalizeRanges(ranges)
+	if negate {
+		ranges = negateRanges(ranges)
+	}
+	return &node{op: opClass, pos: start, end: p.pos, ranges: ranges,
+		name: p.pattern[start:p.pos], caseless: p.flags&CASELESS != 0}, nil
+}
+
+// classChar parses a single character or character type in a class.
+func (p *parser) classChar() (r rune, set []rune, err error) {
+	if p.peek() != '\\' {
+		return p.nextRune(), nil, nil
+	}
+	p.pos++
+	if !p.more() {
+		return 0, nil, p.error(`\ at end of pattern`)
+	}
+	if p.peek() == 'b' {
+		p.pos++
+		return '\b', nil, nil
+	}
+	set, r, err = p.parseCharEscape()
+	return r, set, err
+}
+
+// tableRanges converts a Unicode range table to range pairs.
+func tableRanges(t *unicode.RangeTable) []rune {
+	var ranges []rune
+	for _, r := range t.R16 {
+		ranges = appendStride(ranges, rune(r.Lo), rune(r.Hi), rune(r.Stride))
+	}
+	for _, r := range t.R32 {
+		ranges = appendStride(ranges, rune(r.Lo), rune(r.Hi), rune(r.Stride))
+	}
+	return normalizeRanges(ranges)
+}
+
+func appendStride(ranges []rune, lo, hi, stride rune) []rune {
+	if stride == 1 {
+		return append(ranges, lo, hi)
+	}
+	for c := lo; c <= hi; c += stride {
+		ranges = append(ranges, c, c)
+	}
+	return ranges
+}
+
+// normalizeRanges sorts range pairs and merges overlapping ones.
+func normalizeRanges(ranges []rune) []rune {
+	pairs := make([][2]rune, 0, len(ranges)/2)
+	for i := 0; i+1 < len(ranges); i += 2 {
+		pairs = append(pairs, [2]rune{ranges[i], ranges[i+1]})
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i][0] < pairs[j][0] })
+	out := make([]rune, 0, len(ranges))
+	for _, pr := range pairs {
+		if n := len(out); n > 0 && pr[0] <= out[n-1]+1 {
+			out[n-1] = max(out[n-1], pr[1])
+			continue
+		}
+		out = append(out, pr[0], pr[1])
+	}
+	return out
+}
+
+// negateRanges returns the complement of normalized ranges.
+func negateRanges(ranges []rune) []rune {
+	var out []rune
+	next := rune(0)
+	for i := 0; i+1 < len(ranges); i += 2 {
+		if ranges[i] > next {
+			out = append(out, next, ranges[i]-1)
+		}
+		next = ranges[i+1] + 1
+	}
+	if next <= maxRune {
+		out = append(out, next, maxRune)
+	}
+	return out
+}
+
+// unionRanges returns the union of several range lists.
+func unionRanges(sets ...[]rune) []rune {
+	var all []rune
+	for _, s := range sets {
+		all = append(all, s...)
+	}
+	return normalizeRanges(all)
+}
+
+// intersectRanges reports whether two normalized range lists overlap.
+func intersectRanges(a, b []rune) bool {
+	i, j := 0, 0
+	for i+1 < len(a) && j+1 < len(b) {
+		switch {
+		case a[i+1] < b[j]:
+			i += 2
+		case b[j+1] < a[i]:
+			j += 2
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// foldRanges adds the case variants of the characters in ranges.
+func foldRanges(ranges []rune) []rune {
+	out := append([]rune(nil), ranges...)
+	for i := 0; i+1 < len(ranges); i += 2 {
+		lo, hi := ranges[i], ranges[i+1]
+		if hi-lo > 0x1000 {
+			continue // large sets contain most of their variants
+		}
+		for c := lo; c <= hi; c++ {
+			for f := unicode.SimpleFold(c); f != c; f = unicode.SimpleFold(f) {
+				out = append(out, f, f)
+			}
+		}
+	}
+	return normalizeRanges(out)
+}
+
+// text returns the source text of a node.
+func (p *parser) text(n *node) string {
+	return p.pattern[n.pos:n.end]
+}
@@ -0,0 +1,91 @@
+package pcre
+
+import (
+	"errors"
+	"strings"
+)
+
+// QuoteMeta returns s with every character that is special in a
+// pattern escaped, so that the result matches s literally, also
+// within a character class and in EXTENDED mode.  Letters, digits and
+// bytes above 0x7f are kept; NUL becomes \x00.
+func QuoteMeta(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == 0:
+			b.WriteString(`\x00`)
+			continue
+		case c < 0x80 && !isWordByte(c):
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// isWordByte reports whether c is an ASCII letter, digit or underscore.
+func isWordByte(c byte) bool {
+	return isDigit(c) || c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// InterpolatePattern replaces the placeholders {{name}} in template
+// with the values of the names, quoted with QuoteMeta, so that they
+// match literally whatever they contain.  A name is a Go identifier;
+// braces not forming a placeholder are kept.  A placeholder without a
+// value is an error.
+func InterpolatePattern(template string, values map[string]string) (string, error) {
+	var b strings.Builder
+	for {
+		i := strings.Index(template, "{{")
+		if i < 0 {
+			break
+		}
+		end := strings.Index(template[i+2:], "}}")
+		name := ""
+		if end >= 0 {
+			name = template[i+2 : i+2+end]
+		}
+		if !isIdentifier(name) {
+			b.WriteString(template[:i+1])
+			template = template[i+1:]
+			continue
+		}
+		value, ok := values[name]
+		if !ok {
+			return "", errors.New("pcre.InterpolatePattern: no value for {{" + name + "}}")
+		}
+		b.WriteString(template[:i])
+		b.WriteString(QuoteMeta(value))
+		template = template[i+2+end+2:]
+	}
+	b.WriteString(template)
+	return b.String(), nil
+}
+
+// isIdentifier reports whether s is a Go identifier of ASCII
+// characters.
+func isIdentifier(s string) bool {
+	if s == "" || isDigit(s[0]) {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isWordByte(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// CompileTemplate compiles template with its placeholders replaced by
+// the quoted values, see InterpolatePattern.  Use it instead of
+// building patterns with fmt.Sprintf, where a value containing
+// metacharacters changes the meaning of the pattern.
+func CompileTemplate(template string, values map[string]string, flags int) (*Regexp, error) {
+	pattern, err := InterpolatePattern(template, values)
+	if err != nil {
+		return nil, err
+	}
+	return Compile(pattern, flags)
+}
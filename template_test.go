@@ -0,0 +1,49 @@
+package pcre
+
+import "testing"
+
+func TestQuoteMeta(t *testing.T) {
+	for _, s := range []string{
+		`a.b*c`, `[x]\E\Q(?#)`, "# not a comment\n", "tab\there", "nul\x00byte", "ünïcode", "{1,2}|$^",
+	} {
+		for _, flags := range []int{0, EXTENDED, UTF8} {
+			re, err := Compile(`^`+QuoteMeta(s)+`$`, flags)
+			if err != nil {
+				t.Errorf("%q: %v", s, err)
+				continue
+			}
+			if !re.MatcherString(s, 0).Matches() {
+				t.Errorf("%q with flags %#x does not match itself", s, flags)
+			}
+			if re.MatcherString(s+"x", 0).Matches() {
+				t.Errorf("%q with flags %#x matches more", s, flags)
+			}
+			re.FreeRegexp()
+		}
+	}
+	re := MustCompile(`^[`+QuoteMeta("]^-\\")+`]+$`, 0)
+	defer re.FreeRegexp()
+	if !re.MatcherString(`^]-\`, 0).Matches() || re.MatcherString("a", 0).Matches() {
+		t.Error("quoted characters in a class")
+	}
+}
+
+func TestCompileTemplate(t *testing.T) {
+	re, err := CompileTemplate(`^user:{{name}}\d{2}{{ suffix}}$`, map[string]string{"name": "a.b|c"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer re.FreeRegexp()
+	if !re.MatcherString("user:a.b|c42{{ suffix}}", 0).Matches() {
+		t.Error("expected match")
+	}
+	if re.MatcherString("user:axb42{{ suffix}}", 0).Matches() || re.MatcherString("c42{{ suffix}}", 0).Matches() {
+		t.Error("interpolated metacharacters were not quoted")
+	}
+	if _, err := CompileTemplate(`{{missing}}`, nil, 0); err == nil {
+		t.Error("expected error for missing value")
+	}
+	if p, _ := InterpolatePattern(`x{{{a}}}`, map[string]string{"a": "*"}); p != `x{\*}` {
+		t.Error("nested braces:", p)
+	}
+}
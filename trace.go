@@ -0,0 +1,72 @@
+package pcre
+
+import (
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// tracer serializes the trace lines of a Regexp to its writer.
+type tracer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// SetTrace makes every pcre_exec call of the Regexp write a line to w
+// with the pattern, the subject length, start offset, flags, return
+// code and duration of the call, and on a match the offsets of the
+// match and the capture groups, -1 for groups not present.  The
+// subject itself is not written.  A nil w, the default, turns tracing
+// off.  Writes are serialized; errors writing to w are ignored.
+func (re *Regexp) SetTrace(w io.Writer) {
+	if re == nil {
+		uninitialized("Regexp.SetTrace")
+		return
+	}
+	if w == nil {
+		re.trace.Store(nil)
+		return
+	}
+	re.trace.Store(&tracer{w: w})
+}
+
+// write writes the trace line of an exec.
+func (t *tracer) write(m *Matcher, length, offset, flags, rc int, d time.Duration) {
+	b := make([]byte, 0, 128)
+	b = append(b, "pcre exec "...)
+	b = strconv.AppendQuote(b, m.re.pattern)
+	b = append(b, " len="...)
+	b = strconv.AppendInt(b, int64(length), 10)
+	b = append(b, " offset="...)
+	b = strconv.AppendInt(b, int64(offset), 10)
+	b = append(b, " flags=0x"...)
+	b = strconv.AppendUint(b, uint64(uint32(flags)), 16)
+	b = append(b, " rc="...)
+	b = strconv.AppendInt(b, int64(rc), 10)
+	b = append(b, " time="...)
+	b = append(b, d.String()...)
+	pairs := 0
+	switch {
+	case rc == ERROR_PARTIAL:
+		pairs = 1
+	case rc > 0:
+		pairs = rc
+	case rc == 0:
+		pairs = len(m.ovector) / 3
+	}
+	if pairs > 0 {
+		b = append(b, " match=["...)
+		for i := 0; i < 2*pairs; i++ {
+			if i > 0 {
+				b = append(b, ' ')
+			}
+			b = strconv.AppendInt(b, int64(m.ovector[i]), 10)
+		}
+		b = append(b, ']')
+	}
+	b = append(b, '\n')
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.w.Write(b)
+}
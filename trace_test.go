@@ -0,0 +1,29 @@
+package pcre
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSetTrace(t *testing.T) {
+	re := MustCompile("a(b)?(c)", 0)
+	defer re.FreeRegexp()
+	var sb strings.Builder
+	re.SetTrace(&sb)
+	re.MatcherString("xxac", 0)
+	re.MatcherString("xx", 0)
+	re.SetTrace(nil)
+	re.MatcherString("ac", 0)
+	lines := strings.Split(strings.TrimSuffix(sb.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 trace lines, got %q", lines)
+	}
+	for _, s := range []string{`pcre exec "a(b)?(c)" `, " len=4 ", " offset=0 ", " rc=3 ", " match=[2 4 -1 -1 3 4]"} {
+		if !strings.Contains(lines[0], s) {
+			t.Errorf("match trace %q lacks %q", lines[0], s)
+		}
+	}
+	if !strings.Contains(lines[1], " rc=-1 ") || strings.Contains(lines[1], "match=") {
+		t.Errorf("unexpected no-match trace %q", lines[1])
+	}
+}
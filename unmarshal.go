@@ -0,0 +1,142 @@
+package pcre
+
+import (
+	"encoding"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoMatch is returned by Unmarshal if the pattern does not match.
+var ErrNoMatch = errors.New("pcre: no match")
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// Unmarshal matches re against subject, and stores named capture
+// groups in the fields of the struct pointed to by dst.  A field is
+// filled from the group named in its tag:
+//
+//	type Access struct {
+//		Host   string    `pcre:"host"`
+//		Status int       `pcre:"status"`
+//		Time   time.Time `pcre:"time,layout=02/Jan/2006:15:04:05 -0700"`
+//	}
+//
+// Fields may be strings, byte slices, integers, floats, bools,
+// time.Duration, time.Time, types implementing
+// encoding.TextUnmarshaler, or pointers to these.  Times are parsed
+// with the layout given in the tag, or time.RFC3339.  Fields whose
+// group is not present in the match keep their value.  With DUPNAMES,
+// a field is filled from the group of its name which is present.  If
+// the pattern does not match, Unmarshal returns ErrNoMatch.
+func Unmarshal(re *Regexp, subject string, dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return errors.New("pcre.Unmarshal: dst must be a non-nil pointer to a struct")
+	}
+	m := re.AcquireMatcher()
+	defer m.Release()
+	m.MatchString(subject, 0)
+	if err := m.Err(); err != nil {
+		return err
+	}
+	if !m.Matches() {
+		return ErrNoMatch
+	}
+	v = v.Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup("pcre")
+		if !ok || tag == "-" || !sf.IsExported() {
+			continue
+		}
+		name, layout := tag, time.RFC3339
+		if i := strings.Index(tag, ",layout="); i >= 0 {
+			name, layout = tag[:i], tag[i+len(",layout="):]
+		}
+		group := re.groupOf(name, m.Present)
+		if group < 0 {
+			return errors.New("pcre.Unmarshal: field " + sf.Name + ": unknown group " + strconv.Quote(name))
+		}
+		if !m.Present(group) {
+			continue
+		}
+		if err := setField(v.Field(i), m.GroupString(group), layout); err != nil {
+			return errors.New("pcre.Unmarshal: field " + sf.Name + ": " + err.Error())
+		}
+	}
+	return nil
+}
+
+// setField converts s to the type of f and stores it.
+func setField(f reflect.Value, s, layout string) error {
+	if f.Kind() == reflect.Ptr {
+		p := reflect.New(f.Type().Elem())
+		if err := setField(p.Elem(), s, layout); err != nil {
+			return err
+		}
+		f.Set(p)
+		return nil
+	}
+	if u, ok := f.Addr().Interface().(encoding.TextUnmarshaler); ok && f.Type() != timeType {
+		return u.UnmarshalText([]byte(s))
+	}
+	switch {
+	case f.Type() == timeType:
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			return err
+		}
+		f.Set(reflect.ValueOf(t))
+		return nil
+	case f.Type() == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		f.SetInt(int64(d))
+		return nil
+	}
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(s)
+	case reflect.Slice:
+		if f.Type().Elem().Kind() != reflect.Uint8 {
+			return errors.New("unsupported type " + f.Type().String())
+		}
+		f.SetBytes([]byte(s))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		f.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		x, err := strconv.ParseFloat(s, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetFloat(x)
+	default:
+		return errors.New("unsupported type " + f.Type().String())
+	}
+	return nil
+}
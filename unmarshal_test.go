@@ -0,0 +1,63 @@
+package pcre
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestUnmarshal(t *testing.T) {
+	re := MustCompile(`^(?<host>\S+) \[(?<time>[^]]+)\] (?<status>\d+) (?<size>-|\d+)`+
+		`(?: (?<ms>[\d.]+))?(?: (?<cached>true|false))?(?: (?<took>\w+))?`, 0)
+	type access struct {
+		Host   net.IP    `pcre:"host"`
+		Time   time.Time `pcre:"time,layout=02/Jan/2006:15:04:05 -0700"`
+		Status int       `pcre:"status"`
+		Millis float64   `pcre:"ms"`
+		Cached *bool     `pcre:"cached"`
+		Took   time.Duration
+		Other  string
+	}
+	var a access
+	err := Unmarshal(re, "10.0.0.1 [10/Oct/2000:13:55:36 -0700] 404 - 1.5 true", &a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !a.Host.Equal(net.IPv4(10, 0, 0, 1)) || a.Status != 404 || a.Millis != 1.5 ||
+		a.Cached == nil || !*a.Cached || a.Time.Day() != 10 || a.Time.Year() != 2000 {
+		t.Errorf("unexpected result %+v", a)
+	}
+
+	a = access{}
+	if err := Unmarshal(re, "1.2.3.4 [10/Oct/2000:13:55:36 -0700] 200 1", &a); err != nil || a.Cached != nil {
+		t.Error("absent group", a.Cached, err)
+	}
+	if err := Unmarshal(re, "nothing", &a); err != ErrNoMatch {
+		t.Error("expected ErrNoMatch, got", err)
+	}
+	var bad struct {
+		Size int `pcre:"size"`
+	}
+	if err := Unmarshal(re, "h [10/Oct/2000:13:55:36 -0700] 200 -", &bad); err == nil {
+		t.Error("expected conversion error")
+	}
+	var unknown struct {
+		X string `pcre:"nope"`
+	}
+	if err := Unmarshal(re, "h [10/Oct/2000:13:55:36 -0700] 200 -", &unknown); err == nil {
+		t.Error("expected unknown group error")
+	}
+	if err := Unmarshal(re, "x", a); err == nil {
+		t.Error("expected error for non-pointer")
+	}
+
+	// With DUPNAMES, the group of a name is the one which is set.
+	dup := MustCompile(`(?<n>\d+)s|(?<n>\d+)m`, DUPNAMES)
+	defer dup.FreeRegexp()
+	var d struct {
+		N int `pcre:"n"`
+	}
+	if err := Unmarshal(dup, "15m", &d); err != nil || d.N != 15 {
+		t.Errorf("duplicate names: %+v, %v", d, err)
+	}
+}
@@ -0,0 +1,38 @@
+package pcre
+
+import "unsafe"
+
+// UnsafePCRE returns the pcre pointer of the compiled pattern, for
+// calling PCRE functions that this package does not wrap, such as
+// pcre_fullinfo with options it does not query.  It returns nil if
+// the Regexp is uninitialized.
+//
+// The pointer belongs to the Regexp.  It is valid until FreeRegexp is
+// called, or the Regexp is freed by the garbage collector once it is
+// unreachable, see AUTO_FREE_CLEANUP; use runtime.KeepAlive to keep
+// it reachable while the pointer is in use.  Do not free the pattern
+// or change it, and do not use it concurrently with FreeRegexp.
+func (re *Regexp) UnsafePCRE() unsafe.Pointer {
+	if !re.rlock() {
+		uninitialized("Regexp.UnsafePCRE")
+		return nil
+	}
+	defer re.mu.RUnlock()
+	return unsafe.Pointer(re.ptr)
+}
+
+// UnsafeExtra returns the pcre_extra pointer holding the study data of
+// the pattern, or nil if it has not been studied or the Regexp is
+// uninitialized.  The pointer is valid as that of UnsafePCRE, and in
+// addition until the next call of Study, which replaces the study
+// data, as does studying by SetAutoStudy.  The Regexp passes a copy
+// of the block to pcre_exec when it sets limits, so changes to its
+// fields may or may not take effect; do not make them.
+func (re *Regexp) UnsafeExtra() unsafe.Pointer {
+	if !re.rlock() {
+		uninitialized("Regexp.UnsafeExtra")
+		return nil
+	}
+	defer re.mu.RUnlock()
+	return unsafe.Pointer(re.extra)
+}
@@ -0,0 +1,25 @@
+package pcre
+
+import "testing"
+
+func TestUnsafePointers(t *testing.T) {
+	SetSafeMode(true)
+	defer SetSafeMode(false)
+	re := MustCompile("a+b", 0)
+	if re.UnsafePCRE() == nil {
+		t.Error("UnsafePCRE: nil")
+	}
+	if re.UnsafeExtra() != nil {
+		t.Error("UnsafeExtra before Study: not nil")
+	}
+	if err := re.Study(STUDY_JIT_COMPILE); err != nil {
+		t.Fatal(err)
+	}
+	if re.UnsafeExtra() == nil {
+		t.Error("UnsafeExtra after Study: nil")
+	}
+	re.FreeRegexp()
+	if re.UnsafePCRE() != nil || re.UnsafeExtra() != nil {
+		t.Error("freed Regexp: pointers not nil")
+	}
+}
@@ -0,0 +1,75 @@
+package pcre
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// UntrustedLimits bound the patterns accepted by CompileUntrusted and
+// the work done when matching them.
+type UntrustedLimits struct {
+	MaxPatternLen  int    // Longest pattern accepted, in bytes
+	MatchLimit     uint32 // Match limit set on the compiled Regexp
+	RecursionLimit uint32 // Recursion limit set on the compiled Regexp
+}
+
+// DefaultUntrustedLimits are the limits used by CompileUntrusted.
+// The recursion limit keeps the interpreter well within the C stack
+// of a goroutine's thread.
+var DefaultUntrustedLimits = UntrustedLimits{
+	MaxPatternLen:  16 << 10,
+	MatchLimit:     1000000,
+	RecursionLimit: 10000,
+}
+
+// untrustedFlags are the compile flags accepted by CompileUntrusted.
+const untrustedFlags = CASELESS | DOLLAR_ENDONLY | DOTALL | DUPNAMES |
+	EXTENDED | EXTRA | FIRSTLINE | JAVASCRIPT_COMPAT | MULTILINE |
+	NEVER_UTF | NO_AUTO_CAPTURE | UNGREEDY | UTF8 | UCP |
+	ANCHORED | BSR_ANYCRLF | BSR_UNICODE | NEWLINE_ANY |
+	NEWLINE_ANYCRLF | NEWLINE_CR | NEWLINE_CRLF | NEWLINE_LF |
+	NO_START_OPTIMIZE
+
+// CompileUntrusted compiles a pattern from an untrusted source using
+// DefaultUntrustedLimits.  See UntrustedLimits.Compile.
+func CompileUntrusted(pattern string, flags int) (*Regexp, error) {
+	return DefaultUntrustedLimits.Compile(pattern, flags)
+}
+
+// Compile compiles a pattern from an untrusted source.  Patterns longer
+// than MaxPatternLen and unknown flags are rejected with a
+// *CompileError, and NO_UTF8_CHECK is refused, because PCRE does not
+// guard against invalid UTF-8 when it is set.  Of the flags set with
+// SetDefaultCompileFlags, only those accepted here are added.  The
+// match and recursion limits are applied to the Regexp, and
+// NO_UTF8_CHECK is also dropped from the flags of every match against
+// it.
+//
+// Together with the checks the Matcher does on every call, this is
+// meant to ensure that no sequence of calls on the Regexp can crash
+// the process in C code.  Matching can still take a long time up to
+// the limits; see also MatchTimeout and AnalyzePattern.
+func (l UntrustedLimits) Compile(pattern string, flags int) (*Regexp, error) {
+	if l.MaxPatternLen > 0 && len(pattern) > l.MaxPatternLen {
+		return nil, &CompileError{
+			Pattern: pattern,
+			Message: "pattern longer than " + strconv.Itoa(l.MaxPatternLen) + " bytes",
+			Offset:  l.MaxPatternLen,
+			Code:    errTooLarge,
+		}
+	}
+	if bad := flags &^ untrustedFlags; bad != 0 {
+		return nil, &CompileError{
+			Pattern: pattern,
+			Message: fmt.Sprintf("unsupported compile flags %#x", bad),
+			Code:    errUnknownOption,
+		}
+	}
+	re, err := compile(pattern, flags|DefaultCompileFlags()&untrustedFlags, ENCODING_DEFAULT)
+	if err != nil {
+		return nil, err
+	}
+	re.untrusted = true
+	re.SetLimits(l.MatchLimit, l.RecursionLimit)
+	return re, nil
+}
@@ -0,0 +1,50 @@
+package pcre
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompileUntrusted(t *testing.T) {
+	if _, err := CompileUntrusted(strings.Repeat("a", 20<<10), 0); err == nil {
+		t.Error("long pattern accepted")
+	}
+	if _, err := CompileUntrusted("a", NO_UTF8_CHECK); err == nil {
+		t.Error("NO_UTF8_CHECK accepted")
+	}
+	re, err := CompileUntrusted("(a+)+$", UTF8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer re.FreeRegexp()
+	m := re.MatcherString("a\xff", NO_UTF8_CHECK)
+	if _, ok := m.Err().(*UTF8Error); !ok {
+		t.Error("NO_UTF8_CHECK not dropped", m.Err())
+	}
+	m = re.MatcherString(strings.Repeat("a", 40)+"b", 0)
+	if m.Matches() || m.Err() == nil {
+		t.Error("expected match limit error")
+	}
+	if g := m.Group(5); g != nil {
+		t.Error("Group out of range", g)
+	}
+	if m.Present(-1) {
+		t.Error("Present(-1)")
+	}
+}
+
+func TestCompileUntrustedDefaults(t *testing.T) {
+	SetDefaultCompileFlags(NO_UTF8_CHECK | CASELESS)
+	defer SetDefaultCompileFlags(0)
+	if _, err := CompileUntrusted("\xff", UTF8); err == nil {
+		t.Error("default NO_UTF8_CHECK applied to an untrusted pattern")
+	}
+	re, err := CompileUntrusted("a", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer re.FreeRegexp()
+	if re.pcreOptions()&CASELESS == 0 {
+		t.Error("default CASELESS not applied")
+	}
+}
@@ -0,0 +1,24 @@
+#!/bin/bash
+# Usage: ./vendor_lib.sh
+#
+# Downloads the PCRE sources into pcre-8.45/ and generates one
+# vendor_pcre_*.c file per library source, so that cgo compiles PCRE
+# itself when building with -tags pcre_vendor.  See platform_vendor.go
+# for the configuration.
+set -e
+SRC="pcre-8.45"
+FILES="pcre_byte_order pcre_chartables pcre_compile pcre_config
+  pcre_dfa_exec pcre_exec pcre_fullinfo pcre_get pcre_globals
+  pcre_jit_compile pcre_maketables pcre_newline pcre_ord2utf8
+  pcre_refcount pcre_string_utils pcre_study pcre_tables pcre_ucd
+  pcre_valid_utf8 pcre_version pcre_xclass"
+rm -rf "$SRC"
+curl -L "https://sourceforge.net/projects/pcre/files/pcre/8.45/$SRC.tar.gz" | tar -xzf -
+cp "$SRC/config.h.generic" "$SRC/config.h"
+cp "$SRC/pcre.h.generic" "$SRC/pcre.h"
+cp "$SRC/pcre_chartables.c.dist" "$SRC/pcre_chartables.c"
+rm -f vendor_pcre_*.c
+for f in $FILES; do
+  printf '//go:build pcre_vendor\n\n#include "%s/%s.c"\n' "$SRC" "$f" > "vendor_$f.c"
+done
+echo "Vendored $SRC; build with -tags pcre_vendor"
@@ -0,0 +1,68 @@
+package pcre
+
+import (
+	"fmt"
+	"sync"
+)
+
+// WarmUp studies Regexps in the background on a fixed number of
+// goroutines, so that JIT compiling a large set of patterns does not
+// delay the start of a program.  The Regexps can be used while they
+// wait to be studied; matches use the study data once it is stored.
+type WarmUp struct {
+	jobs chan warmUpJob
+	wg   sync.WaitGroup
+}
+
+type warmUpJob struct {
+	re    *Regexp
+	flags int
+	done  func(*Regexp, error)
+}
+
+// NewWarmUp starts a WarmUp with the given number of worker goroutines,
+// at least one, and room for queued Regexps beyond which Add blocks.
+func NewWarmUp(workers, queued int) *WarmUp {
+	w := &WarmUp{jobs: make(chan warmUpJob, max(queued, 0))}
+	for i := max(workers, 1); i > 0; i-- {
+		w.wg.Add(1)
+		go w.work()
+	}
+	return w
+}
+
+func (w *WarmUp) work() {
+	defer w.wg.Done()
+	for job := range w.jobs {
+		err := job.re.studyOnce(job.flags)
+		if job.done != nil {
+			job.done(job.re, err)
+		}
+	}
+}
+
+// Add queues re to be studied with the given Study flags.  When it is
+// done, done is called, if not nil, with the error of Study; it is
+// called on a worker goroutine, so it should be quick.  A Regexp which
+// has been studied already, for example by SetAutoStudy, is left
+// as it is, without error.  A nil or freed Regexp, including one freed
+// while it is queued, fails with ErrUninitialized, reported to done
+// rather than by a panic even outside safe mode.  Add blocks while the
+// queue is full, and must not be called after Close.
+func (w *WarmUp) Add(re *Regexp, flags int, done func(re *Regexp, err error)) {
+	if !re.valid() {
+		err := fmt.Errorf("WarmUp.Add: %w", ErrUninitialized)
+		if done != nil {
+			done(re, err)
+		}
+		return
+	}
+	w.jobs <- warmUpJob{re, flags, done}
+}
+
+// Close waits until the queued Regexps have been studied and stops
+// the workers.
+func (w *WarmUp) Close() {
+	close(w.jobs)
+	w.wg.Wait()
+}
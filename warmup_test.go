@@ -0,0 +1,41 @@
+package pcre
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestWarmUp(t *testing.T) {
+	w := NewWarmUp(2, 1)
+	var mu sync.Mutex
+	studied := make(map[*Regexp]error)
+	done := func(re *Regexp, err error) {
+		mu.Lock()
+		studied[re] = err
+		mu.Unlock()
+	}
+	var res []*Regexp
+	for _, p := range []string{"a+b", "c*d", "(e|f)g"} {
+		re := MustCompile(p, 0)
+		defer re.FreeRegexp()
+		res = append(res, re)
+		w.Add(re, 0, done)
+	}
+	freed := MustCompile("freed", 0)
+	freed.FreeRegexp()
+	w.Add(freed, 0, done)
+	w.Add(nil, 0, done)
+	w.Close()
+	for _, re := range res {
+		if err, ok := studied[re]; !ok || err != nil || !re.StudyInfo().Studied {
+			t.Errorf("%s not studied: %v", re.pattern, err)
+		}
+	}
+	if err := studied[freed]; !errors.Is(err, ErrUninitialized) {
+		t.Error("freed regexp", err)
+	}
+	if err := studied[nil]; !errors.Is(err, ErrUninitialized) {
+		t.Error("nil regexp", err)
+	}
+}
@@ -0,0 +1,30 @@
+package pcre
+
+// CompileWord compiles a pattern whose matches must form whole words,
+// like grep -w: a match must not be preceded or followed by a word
+// character.  The pattern is enclosed in (?<!\w) and (?!\w) rather
+// than \b, which would demand a word character inside the match, so
+// that patterns starting or ending with other characters, such as
+// "-v" or "c\+\+", work as well.  Other matches are tried when one is
+// not a whole word: "fo+" finds the second "foo" in "foox foo".
+//
+// Group numbers are not affected.  If compilation fails, the second
+// return value holds a *CompileError for the original pattern.
+func CompileWord(pattern string, flags int) (*Regexp, error) {
+	re, err := Compile(pattern, flags)
+	if err != nil {
+		return nil, err
+	}
+	options := re.pcreOptions()
+	re.FreeRegexp()
+	return Compile(enclosePattern(pattern, options, `(?<!\w)`, `(?!\w)`), flags)
+}
+
+// MustCompileWord is like CompileWord, but panics on failure.
+func MustCompileWord(pattern string, flags int) *Regexp {
+	re, err := CompileWord(pattern, flags)
+	if err != nil {
+		panic(err)
+	}
+	return re
+}
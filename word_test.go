@@ -0,0 +1,45 @@
+package pcre
+
+import (
+	"testing"
+)
+
+func TestCompileWord(t *testing.T) {
+	for _, tc := range []struct {
+		pattern string
+		subject string
+		want    string
+	}{
+		{`foo`, "foobar foo", "foo"},
+		{`foo`, "foobar", ""},
+		{`fo+`, "foox foo", "foo"},
+		{`-v`, "grep -v x", "-v"},
+		{`-v`, "a-v", ""},
+		{`-v`, "-vx", ""},
+		{`a\+b`, "xa+b a+b", "a+b"},
+		{`(a)|b`, "ab b", "b"},
+		{`\Qc++`, "c++ x", "c++"},
+	} {
+		re := MustCompileWord(tc.pattern, 0)
+		m := re.MatcherString(tc.subject, 0)
+		got := ""
+		if m.Matches() {
+			got = m.GroupString(0)
+		}
+		if got != tc.want {
+			t.Errorf("%q in %q: expected %q, got %q", tc.pattern, tc.subject, tc.want, got)
+		}
+		re.FreeRegexp()
+	}
+
+	re := MustCompileWord(`(\w+)@(\w+) # user@host`, EXTENDED)
+	defer re.FreeRegexp()
+	if m := re.MatcherString("a@b", 0); !m.Matches() || m.GroupString(2) != "b" {
+		t.Error("extended pattern with groups")
+	}
+	if _, err := CompileWord(`a(`, 0); err == nil {
+		t.Error("expected CompileError")
+	} else if cerr, ok := err.(*CompileError); !ok || cerr.Pattern != "a(" {
+		t.Errorf("unexpected error %v", err)
+	}
+}